@@ -15,6 +15,12 @@ import (
 
 func init() {
 	config.Env = config.LoadEnv()
+
+	jwtKeys, err := config.LoadJwtKeySet(config.Env)
+	if err != nil {
+		log.Fatal(err)
+	}
+	config.JwtKeys = jwtKeys
 }
 
 func main() {
@@ -32,6 +38,12 @@ func main() {
 	engine := gin.Default()
 	logger, _ := zap.NewProduction()
 	defer logger.Sync() // flushes buffer, if any
+
+	// background jobs elect a single leader, running them in every server is safe
+	if config.Env.JobsEnabled {
+		go service.NewJobRunner(logger.Sugar()).Run(context.Background())
+	}
+
 	service := service.NewService(logger.Sugar())
 
 	server := router.NewRouterEngine(engine, service)
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/CoRide-tw/backend/internal/config"
+	"github.com/CoRide-tw/backend/internal/db"
+	"github.com/CoRide-tw/backend/internal/service"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func init() {
+	config.Env = config.LoadEnv()
+}
+
+// the worker only runs background jobs, it can be deployed next to servers started with CORIDE_JOBS_ENABLED=false
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// database connection
+	pgPool, err := pgxpool.New(ctx, config.Env.PostgresDatabaseUrl)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer pgPool.Close()
+
+	if err := db.InitDBClient(pgPool); err != nil {
+		log.Fatal(err)
+	}
+
+	logger, _ := zap.NewProduction()
+	defer logger.Sync() // flushes buffer, if any
+
+	service.NewJobRunner(logger.Sugar()).Run(ctx)
+}
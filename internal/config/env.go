@@ -3,20 +3,85 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 var Env *env
 
+const (
+	AppEnvDevelopment = "development"
+	AppEnvProduction  = "production"
+)
+
+const BlobStorageLocal = "local"
+
 type env struct {
-	PostgresDatabaseUrl     string
-	GoogleOAuthClientId     string
-	GoogleOAuthClientSecret string
-	GoogleOAuthRedirectUrl  string
-	GoogleOauthScope        string
-	CoRideJwtSecret         string
-	GoogleMapsApiKey        string
+	AppEnv                      string
+	DevLoginEnabled             bool
+	PostgresDatabaseUrl         string
+	GoogleOAuthClientId         string
+	GoogleOAuthClientSecret     string
+	GoogleMobileClientIds       []string
+	GoogleOAuthRedirectUrl      string
+	GoogleOauthScope            string
+	CoRideJwtSecret             string
+	CoRideJwtKeysDir            string
+	CoRideJwtSigningKeyId       string
+	CoRideAccessTokenTtl        time.Duration
+	CoRideRefreshTokenTtl       time.Duration
+	GoogleMapsApiKey            string
+	LineChannelId               string
+	LineChannelSecret           string
+	LineRedirectUrl             string
+	AppleClientId               string
+	AppleTeamId                 string
+	AppleKeyId                  string
+	ApplePrivateKeyPath         string
+	AppleRedirectUrl            string
+	EmailLoginUrl               string
+	EmailLoginThrottleWindow    time.Duration
+	EmailLoginMaxPerAddress     int
+	EmailLoginMaxPerIp          int
+	SmtpAddr                    string
+	SmtpUsername                string
+	SmtpPassword                string
+	SmtpFrom                    string
+	BlobStorageBackend          string
+	BlobStorageDir              string
+	ReportSuspendThreshold      int
+	ReportSuspendWindow         time.Duration
+	JobsEnabled                 bool
+	JobInterval                 time.Duration
+	SoftDeleteRetention         time.Duration
+	PickupReminderOffsets       []time.Duration
+	PendingRequestDigestLead    time.Duration
+	MeetingPointWalkingDistance int
+	InstantDispatchRadius       int
+	InstantOfferTimeout         time.Duration
+	InstantSearchTimeout        time.Duration
+	InstantPresenceTtl          time.Duration
+	AssignmentHorizon           time.Duration
+	AssignmentMaxDetour         int
+	AssignmentProposalTtl       time.Duration
+	AssignmentRetryCooldown     time.Duration
+	ItinerarySpeed              int
+	ItineraryStopDuration       time.Duration
+	TransferBuffer              time.Duration
+	TransferMaxWait             time.Duration
+	TransferAccessDistance      int
+	DemandGeohashPrecision      int
+	DemandLookback              time.Duration
+	DemandMinCount              int
+	DemandSearcherSalt          string
+	DemandTimeZone              string
+	DemandSuggestionRadius      int
+	DemandSuggestionLead        time.Duration
+	TrackSimplifyTolerance      int
+	TrackMaxPoints              int
 }
 
 func LoadEnv() *env {
@@ -25,13 +90,142 @@ func LoadEnv() *env {
 		log.Println("Error loading .env file")
 	}
 
-	return &env{
-		PostgresDatabaseUrl:     os.Getenv("POSTGRES_DATABASE_URL"),
-		GoogleOAuthClientId:     os.Getenv("GOOGLE_OAUTH_CLIENT_ID"),
-		GoogleOAuthClientSecret: os.Getenv("GOOGLE_OAUTH_CLIENT_SECRET"),
-		GoogleOAuthRedirectUrl:  os.Getenv("GOOGLE_OAUTH_REDIRECT_URL"),
-		GoogleOauthScope:        os.Getenv("GOOGLE_OAUTH_SCOPE"),
-		CoRideJwtSecret:         os.Getenv("CORIDE_JWT_SECRET"),
-		GoogleMapsApiKey:        os.Getenv("GOOGLE_MAPS_API_KEY"),
+	e := &env{
+		AppEnv:                      getEnv("CORIDE_ENV", AppEnvProduction),
+		DevLoginEnabled:             os.Getenv("CORIDE_DEV_LOGIN") == "true",
+		PostgresDatabaseUrl:         os.Getenv("POSTGRES_DATABASE_URL"),
+		GoogleOAuthClientId:         os.Getenv("GOOGLE_OAUTH_CLIENT_ID"),
+		GoogleOAuthClientSecret:     os.Getenv("GOOGLE_OAUTH_CLIENT_SECRET"),
+		GoogleMobileClientIds:       getListEnv("GOOGLE_OAUTH_MOBILE_CLIENT_IDS"),
+		GoogleOAuthRedirectUrl:      os.Getenv("GOOGLE_OAUTH_REDIRECT_URL"),
+		GoogleOauthScope:            os.Getenv("GOOGLE_OAUTH_SCOPE"),
+		CoRideJwtSecret:             os.Getenv("CORIDE_JWT_SECRET"),
+		CoRideJwtKeysDir:            os.Getenv("CORIDE_JWT_KEYS_DIR"),
+		CoRideJwtSigningKeyId:       os.Getenv("CORIDE_JWT_SIGNING_KEY_ID"),
+		CoRideAccessTokenTtl:        getDurationEnv("CORIDE_ACCESS_TOKEN_TTL", 15*time.Minute),
+		CoRideRefreshTokenTtl:       getDurationEnv("CORIDE_REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		GoogleMapsApiKey:            os.Getenv("GOOGLE_MAPS_API_KEY"),
+		LineChannelId:               os.Getenv("LINE_CHANNEL_ID"),
+		LineChannelSecret:           os.Getenv("LINE_CHANNEL_SECRET"),
+		LineRedirectUrl:             os.Getenv("LINE_REDIRECT_URL"),
+		AppleClientId:               os.Getenv("APPLE_CLIENT_ID"),
+		AppleTeamId:                 os.Getenv("APPLE_TEAM_ID"),
+		AppleKeyId:                  os.Getenv("APPLE_KEY_ID"),
+		ApplePrivateKeyPath:         os.Getenv("APPLE_PRIVATE_KEY_PATH"),
+		AppleRedirectUrl:            os.Getenv("APPLE_REDIRECT_URL"),
+		EmailLoginUrl:               os.Getenv("EMAIL_LOGIN_URL"),
+		EmailLoginThrottleWindow:    getDurationEnv("EMAIL_LOGIN_THROTTLE_WINDOW", 15*time.Minute),
+		EmailLoginMaxPerAddress:     getIntEnv("EMAIL_LOGIN_MAX_PER_ADDRESS", 3),
+		EmailLoginMaxPerIp:          getIntEnv("EMAIL_LOGIN_MAX_PER_IP", 10),
+		SmtpAddr:                    os.Getenv("SMTP_ADDR"),
+		SmtpUsername:                os.Getenv("SMTP_USERNAME"),
+		SmtpPassword:                os.Getenv("SMTP_PASSWORD"),
+		SmtpFrom:                    os.Getenv("SMTP_FROM"),
+		BlobStorageBackend:          getEnv("BLOB_STORAGE_BACKEND", BlobStorageLocal),
+		BlobStorageDir:              getEnv("BLOB_STORAGE_DIR", "data/blobs"),
+		ReportSuspendThreshold:      getIntEnv("REPORT_SUSPEND_THRESHOLD", 3),
+		ReportSuspendWindow:         getDurationEnv("REPORT_SUSPEND_WINDOW", 30*24*time.Hour),
+		JobsEnabled:                 getEnv("CORIDE_JOBS_ENABLED", "true") == "true",
+		JobInterval:                 getDurationEnv("CORIDE_JOB_INTERVAL", time.Minute),
+		SoftDeleteRetention:         getDurationEnv("SOFT_DELETE_RETENTION", 90*24*time.Hour),
+		PickupReminderOffsets:       getDurationListEnv("PICKUP_REMINDER_OFFSETS", []time.Duration{time.Hour, 15 * time.Minute}),
+		PendingRequestDigestLead:    getDurationEnv("PENDING_REQUEST_DIGEST_LEAD", 2*time.Hour),
+		MeetingPointWalkingDistance: getIntEnv("MEETING_POINT_WALKING_DISTANCE", 500),
+		InstantDispatchRadius:       getIntEnv("INSTANT_DISPATCH_RADIUS", 3000),
+		InstantOfferTimeout:         getDurationEnv("INSTANT_OFFER_TIMEOUT", 30*time.Second),
+		InstantSearchTimeout:        getDurationEnv("INSTANT_SEARCH_TIMEOUT", 5*time.Minute),
+		InstantPresenceTtl:          getDurationEnv("INSTANT_PRESENCE_TTL", 2*time.Minute),
+		AssignmentHorizon:           getDurationEnv("ASSIGNMENT_HORIZON", 24*time.Hour),
+		AssignmentMaxDetour:         getIntEnv("ASSIGNMENT_MAX_DETOUR", 5000),
+		AssignmentProposalTtl:       getDurationEnv("ASSIGNMENT_PROPOSAL_TTL", 2*time.Hour),
+		AssignmentRetryCooldown:     getDurationEnv("ASSIGNMENT_RETRY_COOLDOWN", 24*time.Hour),
+		ItinerarySpeed:              getIntEnv("ITINERARY_SPEED_KMH", 30),
+		ItineraryStopDuration:       getDurationEnv("ITINERARY_STOP_DURATION", 2*time.Minute),
+		TransferBuffer:              getDurationEnv("TRANSFER_BUFFER", 10*time.Minute),
+		TransferMaxWait:             getDurationEnv("TRANSFER_MAX_WAIT", time.Hour),
+		TransferAccessDistance:      getIntEnv("TRANSFER_ACCESS_DISTANCE", 2000),
+		DemandGeohashPrecision:      getIntEnv("DEMAND_GEOHASH_PRECISION", 6),
+		DemandLookback:              getDurationEnv("DEMAND_LOOKBACK", 28*24*time.Hour),
+		DemandMinCount:              getIntEnv("DEMAND_MIN_COUNT", 3),
+		DemandSearcherSalt:          os.Getenv("DEMAND_SEARCHER_SALT"),
+		DemandTimeZone:              getEnv("DEMAND_TIME_ZONE", "Asia/Taipei"),
+		DemandSuggestionRadius:      getIntEnv("DEMAND_SUGGESTION_RADIUS", 2000),
+		DemandSuggestionLead:        getDurationEnv("DEMAND_SUGGESTION_LEAD", 24*time.Hour),
+		TrackSimplifyTolerance:      getIntEnv("TRACK_SIMPLIFY_TOLERANCE", 10),
+		TrackMaxPoints:              getIntEnv("TRACK_MAX_POINTS", 500),
+	}
+
+	// dev login hands out tokens without any credential, it must never be reachable in production
+	if e.DevLoginEnabled && e.AppEnv == AppEnvProduction {
+		log.Fatal("CORIDE_DEV_LOGIN cannot be enabled when CORIDE_ENV is production")
+	}
+
+	return e
+}
+
+func getEnv(key string, defaultValue string) string {
+	if value, exist := os.LookupEnv(key); exist && value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// getDurationEnv parses a duration such as "15m" or "720h", falling back to the default when unset or invalid
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	value, exist := os.LookupEnv(key)
+	if !exist {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid duration %q for %s, using %s\n", value, key, defaultValue)
+		return defaultValue
+	}
+	return duration
+}
+
+// getIntEnv parses an integer, falling back to the default when unset or invalid
+func getIntEnv(key string, defaultValue int) int {
+	value, exist := os.LookupEnv(key)
+	if !exist {
+		return defaultValue
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid integer %q for %s, using %d\n", value, key, defaultValue)
+		return defaultValue
+	}
+	return number
+}
+
+// getDurationListEnv parses a comma separated list of durations, falling back to the default when unset or
+// when any item is invalid
+func getDurationListEnv(key string, defaultValue []time.Duration) []time.Duration {
+	if _, exist := os.LookupEnv(key); !exist {
+		return defaultValue
+	}
+
+	var durations []time.Duration
+	for _, value := range getListEnv(key) {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			log.Printf("Invalid duration %q for %s, using %s\n", value, key, defaultValue)
+			return defaultValue
+		}
+		durations = append(durations, duration)
+	}
+	return durations
+}
+
+// getListEnv splits a comma separated value, ignoring empty items
+func getListEnv(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
 	}
+	return values
 }
@@ -0,0 +1,168 @@
+package config
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt"
+)
+
+var JwtKeys *JwtKeySet
+
+type jwtKey struct {
+	method    jwt.SigningMethod
+	publicKey interface{}
+}
+
+// JwtKeySet signs tokens with a single active key and verifies them with every loaded key.
+// Keys are looked up by the "kid" header, tokens without one fall back to the HMAC secret.
+type JwtKeySet struct {
+	signingKeyId  string
+	signingMethod jwt.SigningMethod
+	signingKey    interface{}
+	keys          map[string]*jwtKey
+}
+
+// LoadJwtKeySet reads every <kid>.pem under CORIDE_JWT_KEYS_DIR. Private keys (RSA or Ed25519) can sign,
+// public keys only verify, which keeps tokens signed by a retired key valid until they expire.
+// Without a key directory, tokens are signed HS256 with CORIDE_JWT_SECRET as before.
+func LoadJwtKeySet(e *env) (*JwtKeySet, error) {
+	keySet := &JwtKeySet{keys: map[string]*jwtKey{}}
+
+	if e.CoRideJwtSecret != "" {
+		keySet.keys[""] = &jwtKey{method: jwt.SigningMethodHS256, publicKey: []byte(e.CoRideJwtSecret)}
+		keySet.signingMethod = jwt.SigningMethodHS256
+		keySet.signingKey = []byte(e.CoRideJwtSecret)
+	}
+
+	if e.CoRideJwtKeysDir == "" {
+		if keySet.signingKey == nil {
+			return nil, fmt.Errorf("neither CORIDE_JWT_SECRET nor CORIDE_JWT_KEYS_DIR is set")
+		}
+		return keySet, nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(e.CoRideJwtKeysDir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+		pemBytes, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		method, privateKey, publicKey, err := parseJwtKey(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse jwt key %s: %w", kid, err)
+		}
+		keySet.keys[kid] = &jwtKey{method: method, publicKey: publicKey}
+
+		if kid == e.CoRideJwtSigningKeyId {
+			if privateKey == nil {
+				return nil, fmt.Errorf("signing key %s has no private key", kid)
+			}
+			keySet.signingKeyId = kid
+			keySet.signingMethod = method
+			keySet.signingKey = privateKey
+		}
+	}
+
+	if e.CoRideJwtSigningKeyId != "" && keySet.signingKeyId == "" {
+		return nil, fmt.Errorf("signing key %s not found in %s", e.CoRideJwtSigningKeyId, e.CoRideJwtKeysDir)
+	}
+	if keySet.signingKey == nil {
+		return nil, fmt.Errorf("no jwt signing key configured")
+	}
+	return keySet, nil
+}
+
+func parseJwtKey(pemBytes []byte) (jwt.SigningMethod, crypto.PrivateKey, crypto.PublicKey, error) {
+	if key, err := jwt.ParseRSAPrivateKeyFromPEM(pemBytes); err == nil {
+		return jwt.SigningMethodRS256, key, &key.PublicKey, nil
+	}
+	if key, err := jwt.ParseEdPrivateKeyFromPEM(pemBytes); err == nil {
+		return jwt.SigningMethodEdDSA, key, key.(ed25519.PrivateKey).Public(), nil
+	}
+	if key, err := jwt.ParseRSAPublicKeyFromPEM(pemBytes); err == nil {
+		return jwt.SigningMethodRS256, nil, key, nil
+	}
+	if key, err := jwt.ParseEdPublicKeyFromPEM(pemBytes); err == nil {
+		return jwt.SigningMethodEdDSA, nil, key, nil
+	}
+	return nil, nil, nil, fmt.Errorf("unsupported key, expected RSA or Ed25519 PEM")
+}
+
+func (k *JwtKeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.signingMethod, claims)
+	if k.signingKeyId != "" {
+		token.Header["kid"] = k.signingKeyId
+	}
+	return token.SignedString(k.signingKey)
+}
+
+// Keyfunc resolves the verification key by kid and rejects tokens whose alg does not match the key type
+func (k *JwtKeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+	return key.publicKey, nil
+}
+
+type Jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type Jwks struct {
+	Keys []Jwk `json:"keys"`
+}
+
+// Jwks publishes every asymmetric verification key, the HMAC secret is never exposed
+func (k *JwtKeySet) Jwks() *Jwks {
+	jwks := &Jwks{Keys: []Jwk{}}
+	for kid, key := range k.keys {
+		switch publicKey := key.publicKey.(type) {
+		case *rsa.PublicKey:
+			jwks.Keys = append(jwks.Keys, Jwk{
+				Kty: "RSA",
+				Kid: kid,
+				Use: "sig",
+				Alg: key.method.Alg(),
+				N:   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			jwks.Keys = append(jwks.Keys, Jwk{
+				Kty: "OKP",
+				Kid: kid,
+				Use: "sig",
+				Alg: key.method.Alg(),
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(publicKey),
+			})
+		}
+	}
+	sort.Slice(jwks.Keys, func(i, j int) bool { return jwks.Keys[i].Kid < jwks.Keys[j].Kid })
+	return jwks
+}
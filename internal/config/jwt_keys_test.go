@@ -0,0 +1,115 @@
+package config
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func writeJwtKey(dir, kid string, block *pem.Block) {
+	Expect(os.WriteFile(filepath.Join(dir, kid+".pem"), pem.EncodeToMemory(block), 0o600)).To(Succeed())
+}
+
+func privateKeyBlock(key *rsa.PrivateKey) *pem.Block {
+	return &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}
+}
+
+func publicKeyBlock(key *rsa.PublicKey) *pem.Block {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	Expect(err).NotTo(HaveOccurred())
+	return &pem.Block{Type: "PUBLIC KEY", Bytes: der}
+}
+
+var _ = Describe("JwtKeySet", func() {
+	var (
+		dir           string
+		retiredKey    *rsa.PrivateKey
+		currentKey    *rsa.PrivateKey
+		claims        jwt.StandardClaims
+		signWithKidOf = func(key *rsa.PrivateKey, kid string) string {
+			token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+			token.Header["kid"] = kid
+			signed, err := token.SignedString(key)
+			Expect(err).NotTo(HaveOccurred())
+			return signed
+		}
+	)
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+
+		var err error
+		retiredKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).NotTo(HaveOccurred())
+		currentKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).NotTo(HaveOccurred())
+		claims = jwt.StandardClaims{Subject: "1", ExpiresAt: time.Now().Add(time.Hour).Unix()}
+
+		// the retired key is only kept to verify, the current one signs
+		writeJwtKey(dir, "retired", publicKeyBlock(&retiredKey.PublicKey))
+		writeJwtKey(dir, "current", privateKeyBlock(currentKey))
+	})
+
+	It("should sign with the current key and still verify tokens of the retired one", func() {
+		keySet, err := LoadJwtKeySet(&env{CoRideJwtKeysDir: dir, CoRideJwtSigningKeyId: "current"})
+		Expect(err).NotTo(HaveOccurred())
+
+		signed, err := keySet.Sign(claims)
+		Expect(err).NotTo(HaveOccurred())
+		token, err := jwt.Parse(signed, keySet.Keyfunc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token.Header["kid"]).To(Equal("current"))
+
+		_, err = jwt.Parse(signWithKidOf(retiredKey, "retired"), keySet.Keyfunc)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(keySet.Jwks().Keys).To(HaveLen(2))
+	})
+
+	It("should reject unknown key ids and keys signed under another kid", func() {
+		keySet, err := LoadJwtKeySet(&env{CoRideJwtKeysDir: dir, CoRideJwtSigningKeyId: "current"})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = jwt.Parse(signWithKidOf(currentKey, "unknown"), keySet.Keyfunc)
+		Expect(err).To(HaveOccurred())
+		_, err = jwt.Parse(signWithKidOf(currentKey, "retired"), keySet.Keyfunc)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should not accept HMAC tokens for an RSA key id", func() {
+		keySet, err := LoadJwtKeySet(&env{CoRideJwtSecret: "secret", CoRideJwtKeysDir: dir, CoRideJwtSigningKeyId: "current"})
+		Expect(err).NotTo(HaveOccurred())
+
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		token.Header["kid"] = "current"
+		signed, err := token.SignedString([]byte("secret"))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = jwt.Parse(signed, keySet.Keyfunc)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should fail on a key file that is not a key", func() {
+		Expect(os.WriteFile(filepath.Join(dir, "broken.pem"), []byte("not a key"), 0o600)).To(Succeed())
+
+		_, err := LoadJwtKeySet(&env{CoRideJwtKeysDir: dir, CoRideJwtSigningKeyId: "current"})
+		Expect(err).To(MatchError(ContainSubstring("broken")))
+	})
+
+	It("should fail when the signing key has no private key", func() {
+		_, err := LoadJwtKeySet(&env{CoRideJwtKeysDir: dir, CoRideJwtSigningKeyId: "retired"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should fail without any key configured", func() {
+		_, err := LoadJwtKeySet(&env{})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,8 @@
+package constants
+
+const (
+	AssignmentProposalStatusOpen      = "open"
+	AssignmentProposalStatusConfirmed = "confirmed"
+	AssignmentProposalStatusRejected  = "rejected"
+	AssignmentProposalStatusExpired   = "expired"
+)
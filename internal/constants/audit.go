@@ -0,0 +1,71 @@
+package constants
+
+const (
+	AuditEntityUser               = "user"
+	AuditEntityRoute              = "route"
+	AuditEntityRequest            = "request"
+	AuditEntityTrip               = "trip"
+	AuditEntityVehicle            = "vehicle"
+	AuditEntityRidePreferences    = "ride_preferences"
+	AuditEntityDriverPresence     = "driver_presence"
+	AuditEntityDriverDocument     = "driver_document"
+	AuditEntityUserReport         = "user_report"
+	AuditEntityMeetingPoint       = "meeting_point"
+	AuditEntityInstantRequest     = "instant_request"
+	AuditEntityAssignmentProposal = "assignment_proposal"
+	AuditEntityJourney            = "journey"
+)
+
+const (
+	AuditActionUserDelete                 = "user.delete"
+	AuditActionUserUpdate                 = "user.update"
+	AuditActionUserRevokeSessions         = "user.revoke_sessions"
+	AuditActionUserLinkIdentity           = "user.link_identity"
+	AuditActionUserUnlinkIdentity         = "user.unlink_identity"
+	AuditActionUserBlock                  = "user.block"
+	AuditActionUserUnblock                = "user.unblock"
+	AuditActionUserAutoSuspend            = "user.auto_suspend"
+	AuditActionUserReportCreate           = "user_report.create"
+	AuditActionRouteCreate                = "route.create"
+	AuditActionRouteDelete                = "route.delete"
+	AuditActionRequestCreate              = "request.create"
+	AuditActionRequestDeny                = "request.deny"
+	AuditActionRequestAccept              = "request.accept"
+	AuditActionRequestConfirm             = "request.confirm"
+	AuditActionRequestCounter             = "request.counter"
+	AuditActionRequestCounterAccept       = "request.counter_accept"
+	AuditActionRequestCounterReject       = "request.counter_reject"
+	AuditActionRequestDelete              = "request.delete"
+	AuditActionVehicleCreate              = "vehicle.create"
+	AuditActionVehicleUpdate              = "vehicle.update"
+	AuditActionVehicleDelete              = "vehicle.delete"
+	AuditActionVehicleSetDefault          = "vehicle.set_default"
+	AuditActionDriverDocumentUpload       = "driver_document.upload"
+	AuditActionRidePreferencesUpdate      = "ride_preferences.update"
+	AuditActionDriverPresenceOnline       = "driver_presence.online"
+	AuditActionDriverPresenceOffline      = "driver_presence.offline"
+	AuditActionInstantRequestCreate       = "instant_request.create"
+	AuditActionInstantRequestAccept       = "instant_request.accept"
+	AuditActionInstantRequestDecline      = "instant_request.decline"
+	AuditActionInstantRequestCancel       = "instant_request.cancel"
+	AuditActionInstantRequestComplete     = "instant_request.complete"
+	AuditActionInstantRequestWithdraw     = "instant_request.withdraw"
+	AuditActionAssignmentConfirm          = "assignment_proposal.confirm"
+	AuditActionAssignmentReject           = "assignment_proposal.reject"
+	AuditActionJourneyCreate              = "journey.create"
+	AuditActionJourneyCancel              = "journey.cancel"
+	AuditActionTripCreate                 = "trip.create"
+	AuditActionTripDepart                 = "trip.depart"
+	AuditActionAdminSuspendUser           = "admin.user.suspend"
+	AuditActionAdminUnsuspendUser         = "admin.user.unsuspend"
+	AuditActionAdminSetUserRoles          = "admin.user.set_roles"
+	AuditActionAdminApproveDriverDocument = "admin.driver_document.approve"
+	AuditActionAdminRejectDriverDocument  = "admin.driver_document.reject"
+	AuditActionAdminRevokeVerification    = "admin.user.revoke_verification"
+	AuditActionAdminCancelTrip            = "admin.trip.cancel"
+	AuditActionAdminDismissUserReport     = "admin.user_report.dismiss"
+	AuditActionAdminActionUserReport      = "admin.user_report.action"
+	AuditActionAdminCreateMeetingPoint    = "admin.meeting_point.create"
+	AuditActionAdminDeleteMeetingPoint    = "admin.meeting_point.delete"
+	AuditActionAdminRunAssignment         = "admin.assignment.run"
+)
@@ -0,0 +1,20 @@
+package constants
+
+const (
+	DriverDocumentKindLicense             = "license"
+	DriverDocumentKindVehicleRegistration = "vehicle_registration"
+)
+
+// DriverDocumentKinds must all be approved for a driver to be verified
+var DriverDocumentKinds = []string{
+	DriverDocumentKindLicense,
+	DriverDocumentKindVehicleRegistration,
+}
+
+const (
+	DriverDocumentStatusPending  = "pending"
+	DriverDocumentStatusApproved = "approved"
+	DriverDocumentStatusRejected = "rejected"
+)
+
+const BadgeVerifiedDriver = "verified_driver"
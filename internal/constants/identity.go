@@ -0,0 +1,9 @@
+package constants
+
+const (
+	IdentityProviderGoogle = "google"
+	IdentityProviderLine   = "line"
+	IdentityProviderApple  = "apple"
+	IdentityProviderEmail  = "email"
+	IdentityProviderDev    = "dev"
+)
@@ -0,0 +1,17 @@
+package constants
+
+const (
+	InstantRequestStatusSearching = "searching"
+	InstantRequestStatusOffered   = "offered"
+	InstantRequestStatusAccepted  = "accepted"
+	InstantRequestStatusCompleted = "completed"
+	InstantRequestStatusCancelled = "cancelled"
+	InstantRequestStatusUnmatched = "unmatched"
+)
+
+const (
+	InstantDispatchStatusOffered  = "offered"
+	InstantDispatchStatusAccepted = "accepted"
+	InstantDispatchStatusDeclined = "declined"
+	InstantDispatchStatusExpired  = "expired"
+)
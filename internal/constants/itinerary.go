@@ -0,0 +1,8 @@
+package constants
+
+const (
+	ItineraryStopStart   = "start"
+	ItineraryStopPickup  = "pickup"
+	ItineraryStopDropoff = "dropoff"
+	ItineraryStopEnd     = "end"
+)
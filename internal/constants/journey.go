@@ -0,0 +1,6 @@
+package constants
+
+const (
+	JourneyStatusActive    = "active"
+	JourneyStatusCancelled = "cancelled"
+)
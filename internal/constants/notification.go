@@ -0,0 +1,23 @@
+package constants
+
+const (
+	NotificationKindWaitlistJoined      = "waitlist_joined"
+	NotificationKindWaitlistPromoted    = "waitlist_promoted"
+	NotificationKindRequestReceived     = "request_received"
+	NotificationKindRequestAccepted     = "request_accepted"
+	NotificationKindRequestCancelled    = "request_cancelled"
+	NotificationKindPickupReminder      = "pickup_reminder"
+	NotificationKindDriverDeparted      = "driver_departed"
+	NotificationKindPendingRequests     = "pending_requests"
+	NotificationKindCounterProposed     = "counter_proposed"
+	NotificationKindCounterAccepted     = "counter_accepted"
+	NotificationKindCounterRejected     = "counter_rejected"
+	NotificationKindInstantOffer        = "instant_offer"
+	NotificationKindInstantAccepted     = "instant_accepted"
+	NotificationKindInstantUnmatched    = "instant_unmatched"
+	NotificationKindInstantWithdrawn    = "instant_withdrawn"
+	NotificationKindAssignmentProposed  = "assignment_proposed"
+	NotificationKindAssignmentConfirmed = "assignment_confirmed"
+	NotificationKindJourneyCancelled    = "journey_cancelled"
+	NotificationKindRouteDemand         = "route_demand"
+)
@@ -1,9 +1,27 @@
 package constants
 
 const (
-	RequestStatusPending   = "pending"
-	RequestStatusAccepted  = "accepted"
-	RequestStatusDenied    = "denied"
-	RequestStatusCompleted = "completed"
-	RequestStatusCancelled = "cancelled"
+	RequestStatusWaitlisted = "waitlisted"
+	RequestStatusPending    = "pending"
+	RequestStatusCountered  = "countered"
+	RequestStatusOffered    = "offered"
+	RequestStatusAccepted   = "accepted"
+	RequestStatusDenied     = "denied"
+	RequestStatusCompleted  = "completed"
+	RequestStatusCancelled  = "cancelled"
+	RequestStatusExpired    = "expired"
 )
+
+// SeatHoldingRequestStatuses take seats from the route capacity, an offer holds its seats until the rider answers
+var SeatHoldingRequestStatuses = []string{
+	RequestStatusOffered,
+	RequestStatusAccepted,
+	RequestStatusCompleted,
+}
+
+// DeniableRequestStatuses are the requests still waiting for the driver's decision
+var DeniableRequestStatuses = []string{
+	RequestStatusPending,
+	RequestStatusCountered,
+	RequestStatusWaitlisted,
+}
@@ -0,0 +1,7 @@
+package constants
+
+const (
+	RequestProposalStatusOpen     = "open"
+	RequestProposalStatusAccepted = "accepted"
+	RequestProposalStatusRejected = "rejected"
+)
@@ -0,0 +1,26 @@
+package constants
+
+const (
+	GenderFemale = "female"
+	GenderMale   = "male"
+	GenderOther  = "other"
+)
+
+var Genders = []string{
+	GenderFemale,
+	GenderMale,
+	GenderOther,
+}
+
+const (
+	LuggageNone  = "none"
+	LuggageSmall = "small"
+	LuggageLarge = "large"
+)
+
+// LuggageSizes is ordered from smallest to largest, matching compares positions in it
+var LuggageSizes = []string{
+	LuggageNone,
+	LuggageSmall,
+	LuggageLarge,
+}
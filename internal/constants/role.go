@@ -0,0 +1,10 @@
+package constants
+
+const (
+	RoleRider   = "rider"
+	RoleDriver  = "driver"
+	RoleAdmin   = "admin"
+	RoleSupport = "support"
+)
+
+var Roles = []string{RoleRider, RoleDriver, RoleAdmin, RoleSupport}
@@ -0,0 +1,8 @@
+package constants
+
+const (
+	TripStatusScheduled = "scheduled"
+	TripStatusEnRoute   = "en_route"
+	TripStatusCancelled = "cancelled"
+	TripStatusCompleted = "completed"
+)
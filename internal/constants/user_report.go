@@ -0,0 +1,25 @@
+package constants
+
+const (
+	UserReportCategoryUnsafeDriving = "unsafe_driving"
+	UserReportCategoryHarassment    = "harassment"
+	UserReportCategoryNoShow        = "no_show"
+	UserReportCategoryFraud         = "fraud"
+	UserReportCategoryInappropriate = "inappropriate"
+	UserReportCategoryOther         = "other"
+)
+
+var UserReportCategories = []string{
+	UserReportCategoryUnsafeDriving,
+	UserReportCategoryHarassment,
+	UserReportCategoryNoShow,
+	UserReportCategoryFraud,
+	UserReportCategoryInappropriate,
+	UserReportCategoryOther,
+}
+
+const (
+	UserReportStatusOpen      = "open"
+	UserReportStatusDismissed = "dismissed"
+	UserReportStatusActioned  = "actioned"
+)
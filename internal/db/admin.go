@@ -0,0 +1,209 @@
+package db
+
+import (
+	"context"
+
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/DenChenn/blunder/pkg/blunder"
+)
+
+// Admin listings include soft deleted rows, support staff need to see what happened to them
+
+const adminListUsersSQL = `
+	SELECT id, name, email, COALESCE(google_id, ''), picture_url, car_type, car_plate, roles, suspended_at, driver_verified_at, created_at, updated_at, deleted_at
+	FROM users
+	WHERE $1 = '' OR name ILIKE '%' || $1 || '%' OR email ILIKE '%' || $1 || '%' OR id::text = $1
+	ORDER BY id DESC
+	LIMIT $2 OFFSET $3;
+`
+
+func AdminListUsers(search string, limit, offset int32) ([]*model.User, error) {
+	rows, err := DBClient.pgPool.Query(context.Background(), adminListUsersSQL, search, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*model.User
+	for rows.Next() {
+		var user model.User
+		if err := rows.Scan(
+			&user.Id,
+			&user.Name,
+			&user.Email,
+			&user.GoogleId,
+			&user.PictureUrl,
+			&user.CarType,
+			&user.CarPlate,
+			&user.Roles,
+			&user.SuspendedAt,
+			&user.DriverVerifiedAt,
+			&user.CreatedAt,
+			&user.UpdatedAt,
+			&user.DeletedAt,
+		); err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		users = append(users, &user)
+	}
+	return users, nil
+}
+
+const adminListRoutesSQL = `
+	SELECT
+		id,
+		driver_id,
+		ST_X(start_location), ST_Y(start_location),
+		ST_X(end_location), ST_Y(end_location),
+		start_time, end_time,
+		capacity,
+		vehicle_id,
+		women_only, smoking_allowed, pets_allowed, max_luggage, music,
+		waitlist_by_tips, waitlist_auto_accept,
+		created_at, updated_at, deleted_at
+	FROM routes
+	WHERE $1 = 0 OR driver_id = $1
+	ORDER BY id DESC
+	LIMIT $2 OFFSET $3;
+`
+
+func AdminListRoutes(driverId int32, limit, offset int32) ([]*model.Route, error) {
+	rows, err := DBClient.pgPool.Query(context.Background(), adminListRoutesSQL, driverId, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var routes []*model.Route
+	for rows.Next() {
+		var route model.Route
+		if err := rows.Scan(
+			&route.Id,
+			&route.DriverId,
+			&route.StartLong,
+			&route.StartLat,
+			&route.EndLong,
+			&route.EndLat,
+			&route.StartTime,
+			&route.EndTime,
+			&route.Capacity,
+			&route.VehicleId,
+			&route.WomenOnly,
+			&route.SmokingAllowed,
+			&route.PetsAllowed,
+			&route.MaxLuggage,
+			&route.Music,
+			&route.WaitlistByTips,
+			&route.WaitlistAutoAccept,
+			&route.CreatedAt,
+			&route.UpdatedAt,
+			&route.DeletedAt,
+		); err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		routes = append(routes, &route)
+	}
+	return routes, nil
+}
+
+const adminListRequestsSQL = `
+	SELECT
+		id,
+		rider_id,
+		route_id,
+		ST_X(pickup_location), ST_Y(pickup_location),
+		ST_X(dropoff_location), ST_Y(dropoff_location),
+		pickup_start_time, pickup_end_time,
+		tips,
+		status,
+		women_only, smoking, pets, luggage, quiet,
+		seat_count, accepted_seats, meeting_point_id,
+		created_at, updated_at, deleted_at
+	FROM requests
+	WHERE ($1 = 0 OR rider_id = $1)
+		AND ($2 = 0 OR route_id = $2)
+		AND ($3 = '' OR status = $3)
+	ORDER BY id DESC
+	LIMIT $4 OFFSET $5;
+`
+
+func AdminListRequests(riderId, routeId int32, status string, limit, offset int32) ([]*model.Request, error) {
+	rows, err := DBClient.pgPool.Query(context.Background(), adminListRequestsSQL, riderId, routeId, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requests []*model.Request
+	for rows.Next() {
+		var request model.Request
+		if err := rows.Scan(
+			&request.Id,
+			&request.RiderId,
+			&request.RouteId,
+			&request.PickupLong,
+			&request.PickupLat,
+			&request.DropoffLong,
+			&request.DropoffLat,
+			&request.PickupStartTime,
+			&request.PickupEndTime,
+			&request.Tips,
+			&request.Status,
+			&request.WomenOnly,
+			&request.Smoking,
+			&request.Pets,
+			&request.Luggage,
+			&request.Quiet,
+			&request.SeatCount,
+			&request.AcceptedSeats,
+			&request.MeetingPointId,
+			&request.CreatedAt,
+			&request.UpdatedAt,
+			&request.DeletedAt,
+		); err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		requests = append(requests, &request)
+	}
+	return requests, nil
+}
+
+const adminListTripsSQL = `
+	SELECT id, rider_id, driver_id, request_id, route_id, status, created_at, deleted_at
+	FROM trips
+	WHERE ($1 = 0 OR rider_id = $1 OR driver_id = $1)
+		AND ($2 = '' OR status = $2)
+	ORDER BY id DESC
+	LIMIT $3 OFFSET $4;
+`
+
+func AdminListTrips(userId int32, status string, limit, offset int32) ([]*model.Trip, error) {
+	rows, err := DBClient.pgPool.Query(context.Background(), adminListTripsSQL, userId, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var trips []*model.Trip
+	for rows.Next() {
+		var trip model.Trip
+		if err := rows.Scan(
+			&trip.Id,
+			&trip.RiderId,
+			&trip.DriverId,
+			&trip.RequestId,
+			&trip.RouteId,
+			&trip.Status,
+			&trip.CreatedAt,
+			&trip.DeletedAt,
+		); err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		trips = append(trips, &trip)
+	}
+	return trips, nil
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+
+	. "github.com/DenChenn/blunder/pkg/blunder"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AdvisoryLock is a postgres session level advisory lock, it stays held as long as its connection is alive
+type AdvisoryLock struct {
+	conn *pgxpool.Conn
+	key  int64
+}
+
+const tryAdvisoryLockSQL = `
+	SELECT pg_try_advisory_lock($1);
+`
+
+const advisoryUnlockSQL = `
+	SELECT pg_advisory_unlock($1);
+`
+
+// TryAdvisoryLock takes the lock on a dedicated connection, a nil lock means another session holds it
+func TryAdvisoryLock(key int64) (*AdvisoryLock, error) {
+	ctx := context.Background()
+	conn, err := DBClient.pgPool.Acquire(ctx)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+
+	var locked bool
+	if err := conn.QueryRow(ctx, tryAdvisoryLockSQL, key).Scan(&locked); err != nil {
+		conn.Release()
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	if !locked {
+		conn.Release()
+		return nil, nil
+	}
+	return &AdvisoryLock{conn: conn, key: key}, nil
+}
+
+// Alive reports whether the connection holding the lock is still usable
+func (l *AdvisoryLock) Alive() bool {
+	return l.conn.Ping(context.Background()) == nil
+}
+
+// Release unlocks and gives the connection back to the pool, a connection that fails to unlock is closed
+// so that postgres drops the lock with the session
+func (l *AdvisoryLock) Release() {
+	ctx := context.Background()
+	if _, err := l.conn.Exec(ctx, advisoryUnlockSQL, l.key); err != nil {
+		Logger.Error(err)
+		l.conn.Conn().Close(ctx)
+	}
+	l.conn.Release()
+}
@@ -0,0 +1,369 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/DenChenn/blunder/pkg/blunder"
+	"github.com/jackc/pgx/v5"
+)
+
+const createAssignmentProposalTableSQL = `
+	CREATE TABLE IF NOT EXISTS assignment_proposals (
+		id SERIAL PRIMARY KEY,
+		request_id INT NOT NULL,
+		route_id INT NOT NULL,
+		rider_id INT NOT NULL,
+		driver_id INT NOT NULL,
+		detour DOUBLE PRECISION NOT NULL,
+		status VARCHAR(50) DEFAULT 'open' NOT NULL,
+		rider_confirmed_at TIMESTAMP WITH TIME ZONE,
+		driver_confirmed_at TIMESTAMP WITH TIME ZONE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL,
+		resolved_at TIMESTAMP WITH TIME ZONE
+	);
+
+	CREATE UNIQUE INDEX IF NOT EXISTS assignment_proposals_open_request_idx
+		ON assignment_proposals (request_id) WHERE status = 'open';
+	CREATE INDEX IF NOT EXISTS assignment_proposals_route_id_idx ON assignment_proposals (route_id);
+`
+
+func initAssignmentProposalTable() error {
+	if _, err := DBClient.pgPool.Exec(context.Background(), createAssignmentProposalTableSQL); err != nil {
+		return err
+	}
+	return nil
+}
+
+// AssignmentCandidate is a pending request a route could serve, Detour is the extra distance in meters
+// the driver covers by picking the rider up and dropping them off on the way
+type AssignmentCandidate struct {
+	RequestId int32
+	RiderId   int32
+	SeatCount int32
+	RouteId   int32
+	DriverId  int32
+	FreeSeats int32
+	Detour    float64
+}
+
+// every pending request without an open proposal is paired with the routes starting in the slice whose
+// time window covers its pickup window, applying the same hard rules as the ranking. Seats held by open
+// proposals are not free.
+const listAssignmentCandidatesSQL = `
+	SELECT q.id, q.rider_id, q.seat_count, r.id, r.driver_id, s.free_seats, d.detour
+	FROM requests q
+		JOIN routes r ON r.start_time <= q.pickup_start_time AND r.end_time >= q.pickup_end_time
+		LEFT JOIN ride_preferences rp ON rp.user_id = q.rider_id
+		LEFT JOIN ride_preferences dp ON dp.user_id = r.driver_id
+		CROSS JOIN LATERAL (
+			SELECT r.capacity
+				- COALESCE((
+					SELECT SUM(h.accepted_seats) FROM requests h
+					WHERE h.route_id = r.id AND h.status = ANY($3) AND h.deleted_at IS NULL
+				), 0)
+				- COALESCE((
+					SELECT SUM(pq.seat_count) FROM assignment_proposals p
+						JOIN requests pq ON pq.id = p.request_id
+					WHERE p.route_id = r.id AND p.status = $4
+				), 0) AS free_seats
+		) s
+		CROSS JOIN LATERAL (
+			SELECT ST_Distance(r.start_location::geography, q.pickup_location::geography)
+				+ ST_Distance(q.pickup_location::geography, q.dropoff_location::geography)
+				+ ST_Distance(q.dropoff_location::geography, r.end_location::geography)
+				- ST_Distance(r.start_location::geography, r.end_location::geography) AS detour
+		) d
+	WHERE q.status = $5 AND q.deleted_at IS NULL
+		AND r.deleted_at IS NULL AND r.start_time >= $1 AND r.start_time < $2
+		AND r.driver_id <> q.rider_id
+		AND NOT EXISTS (SELECT 1 FROM assignment_proposals p WHERE p.request_id = q.id AND p.status = $4)
+		-- journey legs are tied to their route by the transfer point, moving one would break the journey
+		AND NOT EXISTS (SELECT 1 FROM journey_legs l WHERE l.request_id = q.id)
+		AND NOT EXISTS (
+			SELECT 1 FROM assignment_proposals p
+			WHERE p.request_id = q.id AND p.route_id = r.id
+				AND (p.status = $8 OR (p.status = $9 AND p.resolved_at >= $10))
+		)
+		AND NOT EXISTS (
+			SELECT 1 FROM user_blocks b
+			WHERE (b.blocker_id = r.driver_id AND b.blocked_id = q.rider_id)
+				OR (b.blocker_id = q.rider_id AND b.blocked_id = r.driver_id)
+		)
+		AND (NOT r.women_only OR rp.gender = 'female')
+		AND (NOT q.women_only OR dp.gender = 'female')
+		AND (NOT q.smoking OR r.smoking_allowed)
+		AND (NOT q.pets OR r.pets_allowed)
+		AND array_position($6::text[], q.luggage) <= array_position($6::text[], r.max_luggage)
+		AND s.free_seats >= q.seat_count
+		AND d.detour <= $7
+	ORDER BY q.id, d.detour;
+`
+
+// ListAssignmentCandidates lists every feasible request and route pair for routes starting in [from, to).
+// A pair that was rejected is never proposed again, one whose proposal expired after expiredSince waits.
+func ListAssignmentCandidates(from, to time.Time, maxDetour float64, expiredSince time.Time) ([]*AssignmentCandidate, error) {
+	rows, err := DBClient.pgPool.Query(context.Background(), listAssignmentCandidatesSQL,
+		from,
+		to,
+		constants.SeatHoldingRequestStatuses,
+		constants.AssignmentProposalStatusOpen,
+		constants.RequestStatusPending,
+		constants.LuggageSizes,
+		maxDetour,
+		constants.AssignmentProposalStatusRejected,
+		constants.AssignmentProposalStatusExpired,
+		expiredSince,
+	)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer rows.Close()
+
+	var candidates []*AssignmentCandidate
+	for rows.Next() {
+		var candidate AssignmentCandidate
+		if err := rows.Scan(
+			&candidate.RequestId,
+			&candidate.RiderId,
+			&candidate.SeatCount,
+			&candidate.RouteId,
+			&candidate.DriverId,
+			&candidate.FreeSeats,
+			&candidate.Detour,
+		); err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		candidates = append(candidates, &candidate)
+	}
+	return candidates, nil
+}
+
+const assignmentProposalColumns = `
+	id, request_id, route_id, rider_id, driver_id, detour, status,
+	rider_confirmed_at, driver_confirmed_at, created_at, resolved_at
+`
+
+func scanAssignmentProposal(row pgx.Row) (*model.AssignmentProposal, error) {
+	var proposal model.AssignmentProposal
+	if err := row.Scan(
+		&proposal.Id,
+		&proposal.RequestId,
+		&proposal.RouteId,
+		&proposal.RiderId,
+		&proposal.DriverId,
+		&proposal.Detour,
+		&proposal.Status,
+		&proposal.RiderConfirmedAt,
+		&proposal.DriverConfirmedAt,
+		&proposal.CreatedAt,
+		&proposal.ResolvedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &proposal, nil
+}
+
+// the rider already asked for the route their request is on, so only the driver has to confirm that one
+const createAssignmentProposalSQL = `
+	INSERT INTO assignment_proposals (request_id, route_id, rider_id, driver_id, detour, rider_confirmed_at)
+	SELECT q.id, $2, q.rider_id, $3, $4, CASE WHEN q.route_id = $2 THEN NOW() END
+	FROM requests q
+	WHERE q.id = $1
+	ON CONFLICT (request_id) WHERE status = 'open' DO NOTHING
+	RETURNING` + assignmentProposalColumns + `;
+`
+
+// CreateAssignmentProposals stores the proposals of one assignment run, a request that got an open
+// proposal in the meantime is skipped
+func CreateAssignmentProposals(candidates []*AssignmentCandidate) ([]*model.AssignmentProposal, error) {
+	ctx := context.Background()
+	tx, err := DBClient.pgPool.Begin(ctx)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	var proposals []*model.AssignmentProposal
+	for _, candidate := range candidates {
+		proposal, err := scanAssignmentProposal(tx.QueryRow(ctx, createAssignmentProposalSQL,
+			candidate.RequestId,
+			candidate.RouteId,
+			candidate.DriverId,
+			candidate.Detour,
+		))
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		proposals = append(proposals, proposal)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return proposals, nil
+}
+
+const getAssignmentProposalSQL = `
+	SELECT` + assignmentProposalColumns + `
+	FROM assignment_proposals
+	WHERE id = $1;
+`
+
+func GetAssignmentProposal(id int32) (*model.AssignmentProposal, error) {
+	proposal, err := scanAssignmentProposal(DBClient.pgPool.QueryRow(context.Background(), getAssignmentProposalSQL, id))
+	if err != nil {
+		Logger.Error(err)
+		return nil, Match(err, pgx.ErrNoRows, ErrAssignmentProposalNotFound).Return()
+	}
+	return proposal, nil
+}
+
+const listOpenAssignmentProposalsByUserIdSQL = `
+	SELECT` + assignmentProposalColumns + `
+	FROM assignment_proposals
+	WHERE (rider_id = $1 OR driver_id = $1) AND status = $2
+	ORDER BY created_at DESC;
+`
+
+// ListOpenAssignmentProposalsByUserId lists the open proposals the user takes part in as rider or driver
+func ListOpenAssignmentProposalsByUserId(userId int32) ([]*model.AssignmentProposal, error) {
+	rows, err := DBClient.pgPool.Query(context.Background(), listOpenAssignmentProposalsByUserIdSQL,
+		userId,
+		constants.AssignmentProposalStatusOpen,
+	)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer rows.Close()
+
+	var proposals []*model.AssignmentProposal
+	for rows.Next() {
+		proposal, err := scanAssignmentProposal(rows)
+		if err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		proposals = append(proposals, proposal)
+	}
+	return proposals, nil
+}
+
+const confirmAssignmentProposalSQL = `
+	UPDATE assignment_proposals SET
+		rider_confirmed_at = CASE WHEN rider_id = $2 THEN COALESCE(rider_confirmed_at, NOW()) ELSE rider_confirmed_at END,
+		driver_confirmed_at = CASE WHEN driver_id = $2 THEN COALESCE(driver_confirmed_at, NOW()) ELSE driver_confirmed_at END
+	WHERE id = $1 AND status = $3 AND (rider_id = $2 OR driver_id = $2)
+	RETURNING` + assignmentProposalColumns + `;
+`
+
+const moveRequestToRouteSQL = `
+	UPDATE requests SET route_id = $2, updated_at = NOW()
+	WHERE id = $1 AND deleted_at IS NULL;
+`
+
+const resolveAssignmentProposalSQL = `
+	UPDATE assignment_proposals SET status = $2, resolved_at = NOW()
+	WHERE id = $1 AND status = $3
+	RETURNING` + assignmentProposalColumns + `;
+`
+
+// ConfirmAssignmentProposal records the user's confirmation, the second confirmation moves the request
+// onto the proposed route and accepts it in the same transaction. The trip is nil until both confirmed.
+func ConfirmAssignmentProposal(id, userId int32) (*model.AssignmentProposal, *model.Trip, error) {
+	ctx := context.Background()
+	tx, err := DBClient.pgPool.Begin(ctx)
+	if err != nil {
+		Logger.Error(err)
+		return nil, nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	proposal, err := scanAssignmentProposal(tx.QueryRow(ctx, confirmAssignmentProposalSQL,
+		id,
+		userId,
+		constants.AssignmentProposalStatusOpen,
+	))
+	if err != nil {
+		Logger.Error(err)
+		return nil, nil, Match(err, pgx.ErrNoRows, ErrAssignmentProposalNotOpen).Return()
+	}
+
+	var trip *model.Trip
+	if proposal.RiderConfirmedAt != nil && proposal.DriverConfirmedAt != nil {
+		if _, err := tx.Exec(ctx, moveRequestToRouteSQL, proposal.RequestId, proposal.RouteId); err != nil {
+			Logger.Error(err)
+			return nil, nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		if trip, err = acceptRequest(ctx, tx, proposal.RequestId, 0); err != nil {
+			return nil, nil, err
+		}
+		if proposal, err = scanAssignmentProposal(tx.QueryRow(ctx, resolveAssignmentProposalSQL,
+			proposal.Id,
+			constants.AssignmentProposalStatusConfirmed,
+			constants.AssignmentProposalStatusOpen,
+		)); err != nil {
+			Logger.Error(err)
+			return nil, nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		Logger.Error(err)
+		return nil, nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return proposal, trip, nil
+}
+
+// RejectAssignmentProposal closes the proposal, the request stays pending on the route the rider chose
+func RejectAssignmentProposal(id int32) (*model.AssignmentProposal, error) {
+	proposal, err := scanAssignmentProposal(DBClient.pgPool.QueryRow(context.Background(), resolveAssignmentProposalSQL,
+		id,
+		constants.AssignmentProposalStatusRejected,
+		constants.AssignmentProposalStatusOpen,
+	))
+	if err != nil {
+		Logger.Error(err)
+		return nil, Match(err, pgx.ErrNoRows, ErrAssignmentProposalNotOpen).Return()
+	}
+	return proposal, nil
+}
+
+const expireAssignmentProposalsSQL = `
+	UPDATE assignment_proposals p SET status = $2, resolved_at = NOW()
+	WHERE p.status = $3
+		AND (
+			p.created_at < $1
+			OR EXISTS (SELECT 1 FROM routes r WHERE r.id = p.route_id AND (r.start_time <= NOW() OR r.deleted_at IS NOT NULL))
+			OR NOT EXISTS (SELECT 1 FROM requests q WHERE q.id = p.request_id AND q.status = $4 AND q.deleted_at IS NULL)
+		);
+`
+
+// ExpireAssignmentProposals closes open proposals created before the given time, or whose route already
+// left or whose request is no longer pending, so that their seats count as free again
+func ExpireAssignmentProposals(before time.Time) (int64, error) {
+	tag, err := DBClient.pgPool.Exec(context.Background(), expireAssignmentProposalsSQL,
+		before,
+		constants.AssignmentProposalStatusExpired,
+		constants.AssignmentProposalStatusOpen,
+		constants.RequestStatusPending,
+	)
+	if err != nil {
+		Logger.Error(err)
+		return 0, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return tag.RowsAffected(), nil
+}
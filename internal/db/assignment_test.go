@@ -0,0 +1,137 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const testDeleteAssignmentProposalsSQL = `
+	DELETE FROM assignment_proposals WHERE request_id = $1;
+`
+
+var _ = Describe("DBAssignment", func() {
+	var (
+		routeId int32
+		request *model.Request
+	)
+
+	BeforeEach(func() {
+		start := time.Now().Add(time.Hour)
+		err := pgPool.QueryRow(context.Background(), testCreateRouteSQL, -1, 0, 0, 0.1, 0, start, start.Add(2*time.Hour), 2).Scan(&routeId)
+		Expect(err).NotTo(HaveOccurred())
+
+		request, err = CreateRequest(&model.Request{
+			RiderId:          -2,
+			RouteId:          routeId,
+			PickupLong:       0.02,
+			DropoffLong:      0.08,
+			PickupStartTime:  start.Add(10 * time.Minute),
+			PickupEndTime:    start.Add(30 * time.Minute),
+			Tips:             10,
+			Status:           constants.RequestStatusPending,
+			SeatCount:        1,
+			RideRequirements: model.RideRequirements{Luggage: constants.LuggageNone},
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_, err := pgPool.Exec(context.Background(), testDeleteAssignmentProposalsSQL, request.Id)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = pgPool.Exec(context.Background(), testDeleteTripsByRouteSQL, routeId)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = pgPool.Exec(context.Background(), testDeleteRequestsByRouteSQL, routeId)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = pgPool.Exec(context.Background(), testDeleteRouteSQL, routeId)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	findCandidate := func(expiredSince time.Time) *AssignmentCandidate {
+		candidates, err := ListAssignmentCandidates(time.Now(), time.Now().Add(24*time.Hour), 5000, expiredSince)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, c := range candidates {
+			if c.RequestId == request.Id && c.RouteId == routeId {
+				return c
+			}
+		}
+		return nil
+	}
+
+	proposeRoute := func() *model.AssignmentProposal {
+		candidate := findCandidate(time.Now().Add(-time.Hour))
+		Expect(candidate).NotTo(BeNil())
+		Expect(candidate.FreeSeats).To(Equal(int32(2)))
+		Expect(candidate.Detour).To(BeNumerically("<", 1))
+
+		proposals, err := CreateAssignmentProposals([]*AssignmentCandidate{candidate})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(proposals).To(HaveLen(1))
+		return proposals[0]
+	}
+
+	It("should book the ride once the driver confirms a proposal on the rider's own route", func() {
+		proposal := proposeRoute()
+		Expect(proposal.RiderConfirmedAt).NotTo(BeNil())
+		Expect(proposal.DriverConfirmedAt).To(BeNil())
+
+		again, err := CreateAssignmentProposals([]*AssignmentCandidate{{RequestId: request.Id, RouteId: routeId, DriverId: -1}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(again).To(BeEmpty())
+
+		open, err := ListOpenAssignmentProposalsByUserId(-1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(open).To(HaveLen(1))
+
+		confirmed, trip, err := ConfirmAssignmentProposal(proposal.Id, -1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(confirmed.Status).To(Equal(constants.AssignmentProposalStatusConfirmed))
+		Expect(trip.RequestId).To(Equal(request.Id))
+
+		accepted, err := GetRequest(request.Id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(accepted.Status).To(Equal(constants.RequestStatusAccepted))
+	})
+
+	It("should only let the participants answer an open proposal", func() {
+		proposal := proposeRoute()
+
+		_, _, err := ConfirmAssignmentProposal(proposal.Id, -3)
+		Expect(err).To(MatchError(ErrAssignmentProposalNotOpen))
+
+		rejected, err := RejectAssignmentProposal(proposal.Id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rejected.Status).To(Equal(constants.AssignmentProposalStatusRejected))
+
+		_, err = RejectAssignmentProposal(proposal.Id)
+		Expect(err).To(MatchError(ErrAssignmentProposalNotOpen))
+
+		pending, err := GetRequest(request.Id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pending.Status).To(Equal(constants.RequestStatusPending))
+	})
+
+	It("should not propose a rejected pair again", func() {
+		proposal := proposeRoute()
+		_, err := RejectAssignmentProposal(proposal.Id)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(findCandidate(time.Now().Add(-time.Hour))).To(BeNil())
+		Expect(findCandidate(time.Now().Add(time.Hour))).To(BeNil())
+	})
+
+	It("should only propose an expired pair again after the cooldown", func() {
+		proposeRoute()
+		_, err := ExpireAssignmentProposals(time.Now().Add(time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(findCandidate(time.Now().Add(-time.Hour))).To(BeNil())
+		Expect(findCandidate(time.Now().Add(time.Hour))).NotTo(BeNil())
+	})
+})
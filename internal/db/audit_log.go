@@ -0,0 +1,141 @@
+package db
+
+import (
+	"context"
+
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/DenChenn/blunder/pkg/blunder"
+)
+
+const createAuditLogTableSQL = `
+	CREATE TABLE IF NOT EXISTS audit_logs (
+		id BIGSERIAL,
+		actor_id INT NOT NULL,
+		action VARCHAR(100) NOT NULL,
+		entity_type VARCHAR(50) NOT NULL,
+		entity_id INT NOT NULL,
+		detail JSONB,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL
+	);
+
+	ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS before_data JSONB;
+	ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS after_data JSONB;
+	ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS request_id VARCHAR(100) DEFAULT '' NOT NULL;
+
+	CREATE INDEX IF NOT EXISTS audit_logs_entity_idx ON audit_logs (entity_type, entity_id);
+	CREATE INDEX IF NOT EXISTS audit_logs_actor_id_idx ON audit_logs (actor_id);
+	CREATE INDEX IF NOT EXISTS audit_logs_created_at_idx ON audit_logs (created_at);
+
+	-- audit logs are append-only, rows can never be changed or removed once written.
+	-- The only exception is erasing the payloads of a deleted user, who did what is still kept.
+	CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS TRIGGER AS $$
+	BEGIN
+		IF TG_OP = 'UPDATE'
+			AND NEW.before_data IS NULL AND NEW.after_data IS NULL AND NEW.detail IS NULL
+			AND (NEW.id, NEW.actor_id, NEW.action, NEW.entity_type, NEW.entity_id, NEW.request_id, NEW.created_at)
+				IS NOT DISTINCT FROM
+				(OLD.id, OLD.actor_id, OLD.action, OLD.entity_type, OLD.entity_id, OLD.request_id, OLD.created_at)
+		THEN
+			RETURN NEW;
+		END IF;
+		RAISE EXCEPTION 'audit_logs is append-only';
+	END;
+	$$ LANGUAGE plpgsql;
+
+	DROP TRIGGER IF EXISTS audit_logs_append_only ON audit_logs;
+	CREATE TRIGGER audit_logs_append_only
+		BEFORE UPDATE OR DELETE ON audit_logs
+		FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only();
+
+	DROP TRIGGER IF EXISTS audit_logs_no_truncate ON audit_logs;
+	CREATE TRIGGER audit_logs_no_truncate
+		BEFORE TRUNCATE ON audit_logs
+		FOR EACH STATEMENT EXECUTE FUNCTION audit_logs_append_only();
+`
+
+func initAuditLogTable() error {
+	if _, err := DBClient.pgPool.Exec(context.Background(), createAuditLogTableSQL); err != nil {
+		return err
+	}
+	return nil
+}
+
+const createAuditLogSQL = `
+	INSERT INTO audit_logs (actor_id, action, entity_type, entity_id, before_data, after_data, detail, request_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	RETURNING id, created_at;
+`
+
+func CreateAuditLog(auditLog *model.AuditLog) (*model.AuditLog, error) {
+	if err := DBClient.pgPool.QueryRow(context.Background(), createAuditLogSQL,
+		auditLog.ActorId,
+		auditLog.Action,
+		auditLog.EntityType,
+		auditLog.EntityId,
+		auditLog.Before,
+		auditLog.After,
+		auditLog.Detail,
+		auditLog.RequestId,
+	).Scan(
+		&auditLog.Id,
+		&auditLog.CreatedAt,
+	); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return auditLog, nil
+}
+
+const listAuditLogsSQL = `
+	SELECT id, actor_id, action, entity_type, entity_id, before_data, after_data, detail, request_id, created_at
+	FROM audit_logs
+	WHERE ($1 = 0 OR actor_id = $1)
+		AND ($2 = '' OR action = $2)
+		AND ($3 = '' OR entity_type = $3)
+		AND ($4 = 0 OR entity_id = $4)
+		AND ($5 = '' OR request_id = $5)
+		AND ($6::TIMESTAMPTZ IS NULL OR created_at >= $6)
+		AND ($7::TIMESTAMPTZ IS NULL OR created_at < $7)
+	ORDER BY id DESC
+	LIMIT $8 OFFSET $9;
+`
+
+func ListAuditLogs(filter *model.AuditLogFilter) ([]*model.AuditLog, error) {
+	rows, err := DBClient.pgPool.Query(context.Background(), listAuditLogsSQL,
+		filter.ActorId,
+		filter.Action,
+		filter.EntityType,
+		filter.EntityId,
+		filter.RequestId,
+		filter.Since,
+		filter.Until,
+		filter.Limit,
+		filter.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var auditLogs []*model.AuditLog
+	for rows.Next() {
+		var auditLog model.AuditLog
+		if err := rows.Scan(
+			&auditLog.Id,
+			&auditLog.ActorId,
+			&auditLog.Action,
+			&auditLog.EntityType,
+			&auditLog.EntityId,
+			&auditLog.Before,
+			&auditLog.After,
+			&auditLog.Detail,
+			&auditLog.RequestId,
+			&auditLog.CreatedAt,
+		); err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		auditLogs = append(auditLogs, &auditLog)
+	}
+	return auditLogs, nil
+}
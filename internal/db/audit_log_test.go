@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const testUpdateAuditLogSQL = `
+	UPDATE audit_logs SET action = 'tampered' WHERE id = $1;
+`
+
+const testDeleteAuditLogSQL = `
+	DELETE FROM audit_logs WHERE id = $1;
+`
+
+// audit logs cannot be cleaned up, so every spec writes under its own request id
+var _ = Describe("DBAuditLog", func() {
+	var (
+		existedAuditLog *model.AuditLog
+		requestId       string
+	)
+
+	BeforeEach(func() {
+		requestId = fmt.Sprintf("test-%d", time.Now().UnixNano())
+
+		var err error
+		existedAuditLog, err = CreateAuditLog(&model.AuditLog{
+			ActorId:    -1,
+			Action:     "test.update",
+			EntityType: "test",
+			EntityId:   -1,
+			Before:     json.RawMessage(`{"name":"before"}`),
+			After:      json.RawMessage(`{"name":"after"}`),
+			RequestId:  requestId,
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("ListAuditLogs", func() {
+		It("should filter by request id", func() {
+			auditLogs, err := ListAuditLogs(&model.AuditLogFilter{RequestId: requestId, Limit: 10})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(auditLogs).To(HaveLen(1))
+			Expect(auditLogs[0].Id).To(Equal(existedAuditLog.Id))
+			Expect(auditLogs[0].Before).To(MatchJSON(`{"name":"before"}`))
+			Expect(auditLogs[0].After).To(MatchJSON(`{"name":"after"}`))
+			Expect(auditLogs[0].Detail).To(BeNil())
+		})
+
+		It("should filter by time range", func() {
+			until := existedAuditLog.CreatedAt
+			auditLogs, err := ListAuditLogs(&model.AuditLogFilter{RequestId: requestId, Until: &until, Limit: 10})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(auditLogs).To(BeEmpty())
+		})
+	})
+
+	When("modifying an audit log", func() {
+		It("should refuse updates", func() {
+			_, err := pgPool.Exec(context.Background(), testUpdateAuditLogSQL, existedAuditLog.Id)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should refuse deletes", func() {
+			_, err := pgPool.Exec(context.Background(), testDeleteAuditLogSQL, existedAuditLog.Id)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
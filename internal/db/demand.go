@@ -0,0 +1,271 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	. "github.com/DenChenn/blunder/pkg/blunder"
+	"github.com/jackc/pgx/v5"
+)
+
+// route searches are kept with a salted hash of the rider instead of their id, it only tells how many
+// different riders searched and the searches only feed the demand aggregates
+const createRouteSearchTableSQL = `
+	CREATE EXTENSION IF NOT EXISTS postgis;
+
+	CREATE TABLE IF NOT EXISTS route_searches (
+		id SERIAL PRIMARY KEY,
+		pickup_location GEOMETRY(Point, 4326) NOT NULL,
+		dropoff_location GEOMETRY(Point, 4326) NOT NULL,
+		pickup_start_time TIMESTAMP WITH TIME ZONE NOT NULL,
+		result_count INT NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL
+	);
+
+	ALTER TABLE route_searches ADD COLUMN IF NOT EXISTS searcher_hash TEXT;
+
+	CREATE INDEX IF NOT EXISTS route_searches_created_at_idx ON route_searches (created_at);
+`
+
+func initRouteSearchTable() error {
+	if _, err := DBClient.pgPool.Exec(context.Background(), createRouteSearchTableSQL); err != nil {
+		return err
+	}
+	return nil
+}
+
+// the searcher is hashed the same way demandSQL hashes the riders of requests
+const recordRouteSearchSQL = `
+	INSERT INTO route_searches (pickup_location, dropoff_location, pickup_start_time, result_count, searcher_hash)
+	VALUES (
+		ST_SetSRID(ST_MakePoint($1, $2), 4326), ST_SetSRID(ST_MakePoint($3, $4), 4326), $5, $6,
+		encode(sha256(convert_to($8::TEXT || ':' || $7::TEXT, 'UTF8')), 'hex')
+	);
+`
+
+func RecordRouteSearch(riderId int32, pickupLong, pickupLat, dropoffLong, dropoffLat float64, pickupStartTime time.Time, resultCount int32, salt string) error {
+	if _, err := DBClient.pgPool.Exec(context.Background(), recordRouteSearchSQL, pickupLong, pickupLat, dropoffLong, dropoffLat, pickupStartTime, resultCount, riderId, salt); err != nil {
+		Logger.Error(err)
+		return ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return nil
+}
+
+const purgeRouteSearchesSQL = `
+	DELETE FROM route_searches WHERE created_at < $1;
+`
+
+// PurgeRouteSearches drops searches too old to count towards the demand any more
+func PurgeRouteSearches(before time.Time) (int64, error) {
+	tag, err := DBClient.pgPool.Exec(context.Background(), purgeRouteSearchesSQL, before)
+	if err != nil {
+		Logger.Error(err)
+		return 0, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return tag.RowsAffected(), nil
+}
+
+// demandSQL buckets the pickups of requests and of searches that found no route by geohash and by the
+// hour of the week the ride was wanted in, hours are counted from Monday 00:00 in the given time zone.
+// Riders are kept as the same salted hash searches are stored with, so that cells are only shown once
+// enough different riders fall into them. It expects the lookback start as $1, the geohash precision as
+// $2, the time zone as $3 and the searcher salt as $4.
+const demandSQL = `
+	WITH demand AS (
+		SELECT
+			pickup_location AS location, pickup_start_time, route_id,
+			encode(sha256(convert_to($4::TEXT || ':' || rider_id::TEXT, 'UTF8')), 'hex') AS searcher_hash,
+			1 AS requests, 0 AS unmet_searches
+		FROM requests
+		WHERE deleted_at IS NULL AND created_at >= $1
+		UNION ALL
+		SELECT pickup_location, pickup_start_time, NULL, searcher_hash, 0, 1
+		FROM route_searches
+		WHERE result_count = 0 AND created_at >= $1
+	), bucketed AS (
+		SELECT
+			ST_GeoHash(location, $2) AS geohash,
+			(EXTRACT(ISODOW FROM pickup_start_time AT TIME ZONE $3)::INT - 1) * 24
+				+ EXTRACT(HOUR FROM pickup_start_time AT TIME ZONE $3)::INT AS hour_of_week,
+			route_id, searcher_hash, requests, unmet_searches
+		FROM demand
+	)
+`
+
+// demandCellColumnsSQL reads an aggregated cell together with the bounding box of its geohash
+const demandCellColumnsSQL = `
+		c.geohash, c.hour_of_week, c.requests, c.unmet_searches,
+		ST_XMin(ST_Box2dFromGeoHash(c.geohash)), ST_YMin(ST_Box2dFromGeoHash(c.geohash)),
+		ST_XMax(ST_Box2dFromGeoHash(c.geohash)), ST_YMax(ST_Box2dFromGeoHash(c.geohash))
+`
+
+const listDemandCellsSQL = demandSQL + `, cells AS (
+		SELECT geohash, hour_of_week, SUM(requests)::INT AS requests, SUM(unmet_searches)::INT AS unmet_searches
+		FROM bucketed
+		WHERE $5::INT IS NULL OR hour_of_week = $5
+		GROUP BY geohash, hour_of_week
+		HAVING COUNT(DISTINCT searcher_hash) >= $6
+	)
+	SELECT` + demandCellColumnsSQL + `
+	FROM cells c
+	ORDER BY c.requests + c.unmet_searches DESC, c.geohash, c.hour_of_week
+	LIMIT $7;
+`
+
+type DemandParams struct {
+	Since     time.Time
+	Precision int
+	TimeZone  string
+	MinCount  int
+	Salt      string
+}
+
+type DemandCell struct {
+	Geohash       string  `json:"geohash"`
+	HourOfWeek    int32   `json:"hourOfWeek"`
+	Requests      int32   `json:"requests"`
+	UnmetSearches int32   `json:"unmetSearches"`
+	MinLong       float64 `json:"minLong"`
+	MinLat        float64 `json:"minLat"`
+	MaxLong       float64 `json:"maxLong"`
+	MaxLat        float64 `json:"maxLat"`
+}
+
+type RouteDemandSuggestion struct {
+	DemandCell
+	Detour float64 `json:"detour"`
+}
+
+// ListDemandCells lists the busiest cells, all hours of the week unless one is given. Cells with fewer
+// different riders than the minimum count are left out so that single riders cannot be told apart.
+func ListDemandCells(hourOfWeek *int32, params *DemandParams, limit int32) ([]*DemandCell, error) {
+	rows, err := DBClient.pgPool.Query(context.Background(), listDemandCellsSQL,
+		params.Since, params.Precision, params.TimeZone, params.Salt, hourOfWeek, params.MinCount, limit)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer rows.Close()
+
+	var cells []*DemandCell
+	for rows.Next() {
+		cell, err := scanDemandCell(rows)
+		if err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		cells = append(cells, cell)
+	}
+	return cells, nil
+}
+
+func scanDemandCell(row pgx.Row, extra ...interface{}) (*DemandCell, error) {
+	var cell DemandCell
+	if err := row.Scan(append([]interface{}{
+		&cell.Geohash,
+		&cell.HourOfWeek,
+		&cell.Requests,
+		&cell.UnmetSearches,
+		&cell.MinLong,
+		&cell.MinLat,
+		&cell.MaxLong,
+		&cell.MaxLat,
+	}, extra...)...); err != nil {
+		return nil, err
+	}
+	return &cell, nil
+}
+
+// the cells are matched at the hour of the week the route starts in, requests already sent to the route are
+// not counted. The detour is how much longer the straight line gets when it runs through the cell center.
+const listRouteDemandSuggestionsSQL = demandSQL + `, route AS (
+		SELECT
+			start_location, end_location,
+			(EXTRACT(ISODOW FROM start_time AT TIME ZONE $3)::INT - 1) * 24
+				+ EXTRACT(HOUR FROM start_time AT TIME ZONE $3)::INT AS hour_of_week
+		FROM routes
+		WHERE id = $5 AND deleted_at IS NULL
+	), cells AS (
+		SELECT
+			b.geohash, b.hour_of_week, SUM(b.requests)::INT AS requests, SUM(b.unmet_searches)::INT AS unmet_searches,
+			ST_Centroid(ST_GeomFromGeoHash(b.geohash))::geography AS center
+		FROM bucketed b
+			JOIN route r ON b.hour_of_week = r.hour_of_week
+		WHERE b.route_id IS DISTINCT FROM $5
+		GROUP BY b.geohash, b.hour_of_week
+		HAVING COUNT(DISTINCT b.searcher_hash) >= $6
+	), suggestions AS (
+		SELECT c.*,
+			ST_Distance(r.start_location::geography, c.center) + ST_Distance(c.center, r.end_location::geography)
+				- ST_Distance(r.start_location::geography, r.end_location::geography) AS detour
+		FROM cells c, route r
+		WHERE ST_DWithin(c.center, ST_MakeLine(r.start_location, r.end_location)::geography, $7)
+	)
+	SELECT` + demandCellColumnsSQL + `, c.detour
+	FROM suggestions c
+	ORDER BY c.requests + c.unmet_searches DESC, c.detour, c.geohash
+	LIMIT $8;
+`
+
+// ListRouteDemandSuggestions lists the busy cells near the route at the time it runs, busiest first
+func ListRouteDemandSuggestions(routeId int32, params *DemandParams, radius float64, limit int32) ([]*RouteDemandSuggestion, error) {
+	rows, err := DBClient.pgPool.Query(context.Background(), listRouteDemandSuggestionsSQL,
+		params.Since, params.Precision, params.TimeZone, params.Salt, routeId, params.MinCount, radius, limit)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer rows.Close()
+
+	var suggestions []*RouteDemandSuggestion
+	for rows.Next() {
+		var detour float64
+		cell, err := scanDemandCell(rows, &detour)
+		if err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		suggestions = append(suggestions, &RouteDemandSuggestion{DemandCell: *cell, Detour: detour})
+	}
+	return suggestions, nil
+}
+
+const listUndemandedUpcomingRoutesSQL = `
+	SELECT r.id, r.driver_id, r.start_time
+	FROM routes r
+	WHERE r.deleted_at IS NULL AND r.start_time > NOW() AND r.start_time <= $1
+		AND NOT EXISTS (
+			SELECT 1 FROM sent_reminders s
+			WHERE s.kind = $2 AND s.entity_id = r.id
+		)
+	ORDER BY r.start_time;
+`
+
+type UpcomingRoute struct {
+	RouteId   int32     `json:"routeId"`
+	DriverId  int32     `json:"driverId"`
+	StartTime time.Time `json:"startTime"`
+}
+
+// ListUndemandedUpcomingRoutes lists routes starting between now and the given time that were not looked
+// at for the demand near them yet
+func ListUndemandedUpcomingRoutes(before time.Time) ([]*UpcomingRoute, error) {
+	rows, err := DBClient.pgPool.Query(context.Background(), listUndemandedUpcomingRoutesSQL, before, constants.NotificationKindRouteDemand)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer rows.Close()
+
+	var routes []*UpcomingRoute
+	for rows.Next() {
+		var route UpcomingRoute
+		if err := rows.Scan(&route.RouteId, &route.DriverId, &route.StartTime); err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		routes = append(routes, &route)
+	}
+	return routes, nil
+}
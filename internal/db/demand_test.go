@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const testDeleteRouteSearchesSQL = `
+	DELETE FROM route_searches
+	WHERE ST_X(pickup_location) BETWEEN 120.4 AND 120.7 AND ST_Y(pickup_location) BETWEEN 23.4 AND 23.6;
+`
+
+func findDemandCell(cells []*DemandCell, long, lat float64) *DemandCell {
+	for _, cell := range cells {
+		if cell.MinLong <= long && long <= cell.MaxLong && cell.MinLat <= lat && lat <= cell.MaxLat {
+			return cell
+		}
+	}
+	return nil
+}
+
+var _ = Describe("DBDemand", func() {
+	// a Monday 08:30 in UTC, the 9th hour of the week
+	pickupTime := time.Date(2030, 1, 7, 8, 30, 0, 0, time.UTC)
+	params := &DemandParams{Precision: 6, TimeZone: "UTC", MinCount: 3, Salt: "test"}
+
+	BeforeEach(func() {
+		params.Since = time.Now().Add(-time.Hour)
+		for i := int32(1); i <= 3; i++ {
+			Expect(RecordRouteSearch(-i, 120.55, 23.501, 120.6, 23.55, pickupTime, 0, params.Salt)).To(Succeed())
+		}
+		// a search that found routes is no unmet demand
+		Expect(RecordRouteSearch(-4, 120.55, 23.501, 120.6, 23.55, pickupTime, 2, params.Salt)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		_, err := pgPool.Exec(context.Background(), testDeleteRouteSearchesSQL)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should bucket unmet searches by cell and hour of week", func() {
+		hourOfWeek := int32(8)
+		cells, err := ListDemandCells(&hourOfWeek, params, 1000)
+		Expect(err).NotTo(HaveOccurred())
+
+		cell := findDemandCell(cells, 120.55, 23.501)
+		Expect(cell).NotTo(BeNil())
+		Expect(cell.HourOfWeek).To(Equal(hourOfWeek))
+		Expect(cell.UnmetSearches).To(Equal(int32(3)))
+		Expect(cell.Geohash).To(HaveLen(6))
+
+		otherHour := int32(9)
+		cells, err = ListDemandCells(&otherHour, params, 1000)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findDemandCell(cells, 120.55, 23.501)).To(BeNil())
+	})
+
+	It("should leave out cells below the minimum count", func() {
+		cells, err := ListDemandCells(nil, &DemandParams{Since: params.Since, Precision: 6, TimeZone: "UTC", MinCount: 4, Salt: params.Salt}, 1000)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findDemandCell(cells, 120.55, 23.501)).To(BeNil())
+	})
+
+	It("should count a rider searching over and over only once", func() {
+		for i := 0; i < 3; i++ {
+			Expect(RecordRouteSearch(-5, 120.45, 23.401, 120.6, 23.55, pickupTime, 0, params.Salt)).To(Succeed())
+		}
+
+		cells, err := ListDemandCells(nil, params, 1000)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findDemandCell(cells, 120.45, 23.401)).To(BeNil())
+	})
+
+	It("should suggest busy cells near a route at the hour it starts", func() {
+		var routeId, otherRouteId int32
+		err := pgPool.QueryRow(context.Background(), testCreateRouteSQL, -1, 120.5, 23.5, 120.6, 23.5, pickupTime.AddDate(0, 0, 7), pickupTime.AddDate(0, 0, 7).Add(time.Hour), 3).Scan(&routeId)
+		Expect(err).NotTo(HaveOccurred())
+		defer pgPool.Exec(context.Background(), testDeleteRouteSQL, routeId)
+		err = pgPool.QueryRow(context.Background(), testCreateRouteSQL, -1, 120.5, 23.5, 120.6, 23.5, pickupTime.Add(5*time.Hour), pickupTime.Add(6*time.Hour), 3).Scan(&otherRouteId)
+		Expect(err).NotTo(HaveOccurred())
+		defer pgPool.Exec(context.Background(), testDeleteRouteSQL, otherRouteId)
+
+		suggestions, err := ListRouteDemandSuggestions(routeId, params, 2000, 3)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(suggestions).NotTo(BeEmpty())
+		Expect(suggestions[0].UnmetSearches).To(Equal(int32(3)))
+		Expect(suggestions[0].Detour).To(BeNumerically(">=", 0))
+		Expect(suggestions[0].Detour).To(BeNumerically("<", 1000))
+
+		suggestions, err = ListRouteDemandSuggestions(otherRouteId, params, 2000, 3)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(suggestions).To(BeEmpty())
+	})
+})
@@ -0,0 +1,244 @@
+package db
+
+import (
+	"context"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/DenChenn/blunder/pkg/blunder"
+	"github.com/jackc/pgx/v5"
+)
+
+const createDriverDocumentTableSQL = `
+	CREATE TABLE IF NOT EXISTS driver_documents (
+		id SERIAL,
+		user_id INT NOT NULL,
+		kind VARCHAR(50) NOT NULL,
+		blob_key VARCHAR(300) NOT NULL,
+		content_type VARCHAR(100) NOT NULL,
+		size_bytes BIGINT NOT NULL,
+		status VARCHAR(50) DEFAULT 'pending' NOT NULL,
+		review_note VARCHAR(500) DEFAULT '' NOT NULL,
+		reviewer_id INT,
+		reviewed_at TIMESTAMP WITH TIME ZONE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL,
+		deleted_at TIMESTAMP WITH TIME ZONE
+	);
+
+	CREATE INDEX IF NOT EXISTS driver_documents_user_id_idx ON driver_documents (user_id);
+	CREATE INDEX IF NOT EXISTS driver_documents_pending_idx ON driver_documents (created_at) WHERE status = 'pending';
+`
+
+func initDriverDocumentTable() error {
+	if _, err := DBClient.pgPool.Exec(context.Background(), createDriverDocumentTableSQL); err != nil {
+		return err
+	}
+	return nil
+}
+
+const createDriverDocumentSQL = `
+	INSERT INTO driver_documents (user_id, kind, blob_key, content_type, size_bytes)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id, status, review_note, created_at;
+`
+
+const supersedeDriverDocumentsSQL = `
+	UPDATE driver_documents SET deleted_at = NOW()
+	WHERE user_id = $1 AND kind = $2 AND deleted_at IS NULL;
+`
+
+const unverifyDriverSQL = `
+	UPDATE users SET driver_verified_at = NULL, updated_at = NOW()
+	WHERE id = $1 AND driver_verified_at IS NOT NULL;
+`
+
+// CreateDriverDocument replaces the driver's earlier documents of the kind, the driver is no longer verified
+// until the new one is approved
+func CreateDriverDocument(document *model.DriverDocument) (*model.DriverDocument, error) {
+	ctx := context.Background()
+	tx, err := DBClient.pgPool.Begin(ctx)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, supersedeDriverDocumentsSQL, document.UserId, document.Kind); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	if _, err := tx.Exec(ctx, unverifyDriverSQL, document.UserId); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	if err := tx.QueryRow(ctx, createDriverDocumentSQL,
+		document.UserId,
+		document.Kind,
+		document.BlobKey,
+		document.ContentType,
+		document.SizeBytes,
+	).Scan(
+		&document.Id,
+		&document.Status,
+		&document.ReviewNote,
+		&document.CreatedAt,
+	); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return document, nil
+}
+
+const getDriverDocumentSQL = `
+	SELECT id, user_id, kind, blob_key, content_type, size_bytes, status, review_note, reviewer_id, reviewed_at, created_at, deleted_at
+	FROM driver_documents
+	WHERE id = $1 AND deleted_at IS NULL;
+`
+
+func GetDriverDocument(id int32) (*model.DriverDocument, error) {
+	var document model.DriverDocument
+	if err := DBClient.pgPool.QueryRow(context.Background(), getDriverDocumentSQL, id).Scan(
+		&document.Id,
+		&document.UserId,
+		&document.Kind,
+		&document.BlobKey,
+		&document.ContentType,
+		&document.SizeBytes,
+		&document.Status,
+		&document.ReviewNote,
+		&document.ReviewerId,
+		&document.ReviewedAt,
+		&document.CreatedAt,
+		&document.DeletedAt,
+	); err != nil {
+		Logger.Error(err)
+		return nil, Match(err, pgx.ErrNoRows, ErrDriverDocumentNotFound).Return()
+	}
+	return &document, nil
+}
+
+const listDriverDocumentsByUserIdSQL = `
+	SELECT id, user_id, kind, blob_key, content_type, size_bytes, status, review_note, reviewer_id, reviewed_at, created_at, deleted_at
+	FROM driver_documents
+	WHERE user_id = $1 AND deleted_at IS NULL
+	ORDER BY id DESC;
+`
+
+func ListDriverDocumentsByUserId(userId int32) ([]*model.DriverDocument, error) {
+	return listDriverDocuments(listDriverDocumentsByUserIdSQL, userId)
+}
+
+// the review queue is served oldest first
+const listPendingDriverDocumentsSQL = `
+	SELECT id, user_id, kind, blob_key, content_type, size_bytes, status, review_note, reviewer_id, reviewed_at, created_at, deleted_at
+	FROM driver_documents
+	WHERE status = 'pending' AND deleted_at IS NULL
+	ORDER BY created_at ASC
+	LIMIT $1 OFFSET $2;
+`
+
+func ListPendingDriverDocuments(limit, offset int32) ([]*model.DriverDocument, error) {
+	return listDriverDocuments(listPendingDriverDocumentsSQL, limit, offset)
+}
+
+func listDriverDocuments(sql string, args ...interface{}) ([]*model.DriverDocument, error) {
+	rows, err := DBClient.pgPool.Query(context.Background(), sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	documents := []*model.DriverDocument{}
+	for rows.Next() {
+		var document model.DriverDocument
+		if err := rows.Scan(
+			&document.Id,
+			&document.UserId,
+			&document.Kind,
+			&document.BlobKey,
+			&document.ContentType,
+			&document.SizeBytes,
+			&document.Status,
+			&document.ReviewNote,
+			&document.ReviewerId,
+			&document.ReviewedAt,
+			&document.CreatedAt,
+			&document.DeletedAt,
+		); err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		documents = append(documents, &document)
+	}
+	return documents, nil
+}
+
+const reviewDriverDocumentSQL = `
+	UPDATE driver_documents SET status = $2, review_note = $3, reviewer_id = $4, reviewed_at = NOW()
+	WHERE id = $1 AND status = 'pending' AND deleted_at IS NULL
+	RETURNING user_id;
+`
+
+// a driver is verified once every document kind has an approved document
+const verifyDriverSQL = `
+	UPDATE users SET driver_verified_at = NOW(), updated_at = NOW()
+	WHERE id = $1 AND driver_verified_at IS NULL AND deleted_at IS NULL
+		AND (
+			SELECT COUNT(DISTINCT kind) FROM driver_documents
+			WHERE user_id = $1 AND status = 'approved' AND deleted_at IS NULL AND kind = ANY($2)
+		) = cardinality($2::TEXT[]);
+`
+
+// ReviewDriverDocument approves or rejects a pending document, approving the last missing kind verifies the driver
+// and rejecting one takes the verification back
+func ReviewDriverDocument(id, reviewerId int32, status, note string) error {
+	ctx := context.Background()
+	tx, err := DBClient.pgPool.Begin(ctx)
+	if err != nil {
+		Logger.Error(err)
+		return ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	var userId int32
+	if err := tx.QueryRow(ctx, reviewDriverDocumentSQL, id, status, note, reviewerId).Scan(&userId); err != nil {
+		Logger.Error(err)
+		return Match(err, pgx.ErrNoRows, ErrDriverDocumentAlreadyReviewed).Return()
+	}
+	if status == constants.DriverDocumentStatusApproved {
+		if _, err := tx.Exec(ctx, verifyDriverSQL, userId, constants.DriverDocumentKinds); err != nil {
+			Logger.Error(err)
+			return ErrUndefined.WithCustomMessage(err.Error())
+		}
+	} else if _, err := tx.Exec(ctx, unverifyDriverSQL, userId); err != nil {
+		Logger.Error(err)
+		return ErrUndefined.WithCustomMessage(err.Error())
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		Logger.Error(err)
+		return ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return nil
+}
+
+const revokeDriverVerificationSQL = `
+	UPDATE users SET driver_verified_at = NULL, updated_at = NOW()
+	WHERE id = $1 AND deleted_at IS NULL
+	RETURNING id;
+`
+
+// RevokeDriverVerification takes the verification back, the driver is verified again once a new document is approved
+func RevokeDriverVerification(userId int32) error {
+	if err := DBClient.pgPool.QueryRow(context.Background(), revokeDriverVerificationSQL, userId).Scan(&userId); err != nil {
+		Logger.Error(err)
+		return Match(err, pgx.ErrNoRows, ErrUserNotFound).Return()
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package db
+
+import (
+	"context"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const testDeleteDriverDocumentsSQL = `
+	DELETE FROM driver_documents WHERE user_id = $1;
+`
+
+const testVerifyDriverSQL = `
+	UPDATE users SET driver_verified_at = NOW() WHERE id = $1;
+`
+
+var _ = Describe("DBDriverDocument", func() {
+	var (
+		userId  int32
+		license *model.DriverDocument
+	)
+
+	BeforeEach(func() {
+		err := pgPool.QueryRow(context.Background(), testCreateUserSQL, "test", "test", "test-driver-document", "test").Scan(&userId)
+		Expect(err).NotTo(HaveOccurred())
+
+		license, err = CreateDriverDocument(&model.DriverDocument{
+			UserId:      userId,
+			Kind:        constants.DriverDocumentKindLicense,
+			BlobKey:     "test/license.png",
+			ContentType: "image/png",
+			SizeBytes:   1,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(license.Status).To(Equal(constants.DriverDocumentStatusPending))
+	})
+
+	AfterEach(func() {
+		_, err := pgPool.Exec(context.Background(), testDeleteDriverDocumentsSQL, userId)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = pgPool.Exec(context.Background(), testDeleteUserSQL, userId)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("ListPendingDriverDocuments", func() {
+		It("should contain the new document", func() {
+			documents, err := ListPendingDriverDocuments(200, 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(documents).To(ContainElement(HaveField("Id", license.Id)))
+		})
+	})
+
+	Describe("ReviewDriverDocument", func() {
+		It("should not verify the driver until every kind is approved", func() {
+			Expect(ReviewDriverDocument(license.Id, -1, constants.DriverDocumentStatusApproved, "")).To(Succeed())
+			user, err := GetUser(userId)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(user.DriverVerifiedAt).To(BeNil())
+
+			registration, err := CreateDriverDocument(&model.DriverDocument{
+				UserId:      userId,
+				Kind:        constants.DriverDocumentKindVehicleRegistration,
+				BlobKey:     "test/registration.pdf",
+				ContentType: "application/pdf",
+				SizeBytes:   1,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ReviewDriverDocument(registration.Id, -1, constants.DriverDocumentStatusApproved, "")).To(Succeed())
+
+			user, err = GetUser(userId)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(user.DriverVerifiedAt).NotTo(BeNil())
+
+			profile, err := GetPublicProfile(userId)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(profile.Badges).To(ContainElement(constants.BadgeVerifiedDriver))
+		})
+
+		It("should take the verification back once a document is replaced or rejected", func() {
+			Expect(ReviewDriverDocument(license.Id, -1, constants.DriverDocumentStatusApproved, "")).To(Succeed())
+			registration, err := CreateDriverDocument(&model.DriverDocument{
+				UserId:      userId,
+				Kind:        constants.DriverDocumentKindVehicleRegistration,
+				BlobKey:     "test/registration.pdf",
+				ContentType: "application/pdf",
+				SizeBytes:   1,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ReviewDriverDocument(registration.Id, -1, constants.DriverDocumentStatusApproved, "")).To(Succeed())
+
+			renewed, err := CreateDriverDocument(&model.DriverDocument{
+				UserId:      userId,
+				Kind:        constants.DriverDocumentKindLicense,
+				BlobKey:     "test/renewed.png",
+				ContentType: "image/png",
+				SizeBytes:   1,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			user, err := GetUser(userId)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(user.DriverVerifiedAt).To(BeNil())
+			_, err = GetDriverDocument(license.Id)
+			Expect(err).To(MatchError(ErrDriverDocumentNotFound))
+
+			Expect(ReviewDriverDocument(renewed.Id, -1, constants.DriverDocumentStatusRejected, "expired")).To(Succeed())
+			user, err = GetUser(userId)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(user.DriverVerifiedAt).To(BeNil())
+		})
+
+		It("should refuse reviewing twice", func() {
+			Expect(ReviewDriverDocument(license.Id, -1, constants.DriverDocumentStatusRejected, "blurry")).To(Succeed())
+			err := ReviewDriverDocument(license.Id, -1, constants.DriverDocumentStatusApproved, "")
+			Expect(err).To(MatchError(ErrDriverDocumentAlreadyReviewed))
+		})
+	})
+
+	Describe("RevokeDriverVerification", func() {
+		It("should take the verification back", func() {
+			_, err := pgPool.Exec(context.Background(), testVerifyDriverSQL, userId)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(RevokeDriverVerification(userId)).To(Succeed())
+			user, err := GetUser(userId)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(user.DriverVerifiedAt).To(BeNil())
+		})
+
+		It("should fail for an unknown user", func() {
+			Expect(RevokeDriverVerification(-99)).To(MatchError(ErrUserNotFound))
+		})
+	})
+})
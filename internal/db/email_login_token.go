@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	. "github.com/DenChenn/blunder/pkg/blunder"
+	"github.com/jackc/pgx/v5"
+)
+
+const createEmailLoginTokenTableSQL = `
+	CREATE TABLE IF NOT EXISTS email_login_tokens (
+		id SERIAL,
+		email VARCHAR(200) NOT NULL,
+		token_hash VARCHAR(64) NOT NULL UNIQUE,
+		expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+		used_at TIMESTAMP WITH TIME ZONE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL
+	);
+
+	ALTER TABLE email_login_tokens ADD COLUMN IF NOT EXISTS requested_ip VARCHAR(64) NOT NULL DEFAULT '';
+
+	CREATE INDEX IF NOT EXISTS email_login_tokens_email_idx ON email_login_tokens (email, created_at);
+	CREATE INDEX IF NOT EXISTS email_login_tokens_requested_ip_idx ON email_login_tokens (requested_ip, created_at);
+`
+
+func initEmailLoginTokenTable() error {
+	if _, err := DBClient.pgPool.Exec(context.Background(), createEmailLoginTokenTableSQL); err != nil {
+		return err
+	}
+	return nil
+}
+
+// the two key form keeps these locks apart from the job leader lock, addresses and ips get a class each.
+// Every caller takes the address lock before the ip lock, so concurrent requests cannot deadlock.
+const lockEmailLoginThrottleSQL = `
+	SELECT pg_advisory_xact_lock(1, hashtext($1)), pg_advisory_xact_lock(2, hashtext($2));
+`
+
+// the token is only created while the address and the ip asked for fewer links than allowed since $5
+const createEmailLoginTokenSQL = `
+	INSERT INTO email_login_tokens (email, requested_ip, token_hash, expires_at)
+	SELECT $1, $2, $3, $4
+	WHERE (SELECT COUNT(*) FROM email_login_tokens WHERE email = $1 AND created_at > $5) < $6
+		AND (SELECT COUNT(*) FROM email_login_tokens WHERE requested_ip = $2 AND created_at > $5) < $7
+	RETURNING id;
+`
+
+// EmailLoginThrottle bounds how many login links an address and an ip may ask for within the window
+type EmailLoginThrottle struct {
+	Window        time.Duration
+	MaxPerAddress int
+	MaxPerIp      int
+}
+
+// CreateEmailLoginToken stores a login link token, ErrEmailLoginThrottled is returned once the address or
+// the ip asked for too many links. Requests for the same address or ip are serialised so that they cannot
+// all pass the count at once.
+func CreateEmailLoginToken(email, requestedIp, tokenHash string, expiresAt time.Time, throttle *EmailLoginThrottle) error {
+	ctx := context.Background()
+	tx, err := DBClient.pgPool.Begin(ctx)
+	if err != nil {
+		Logger.Error(err)
+		return ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, lockEmailLoginThrottleSQL, email, requestedIp); err != nil {
+		Logger.Error(err)
+		return ErrUndefined.WithCustomMessage(err.Error())
+	}
+	var id int32
+	if err := tx.QueryRow(ctx, createEmailLoginTokenSQL,
+		email, requestedIp, tokenHash, expiresAt,
+		time.Now().Add(-throttle.Window), throttle.MaxPerAddress, throttle.MaxPerIp,
+	).Scan(&id); err != nil {
+		Logger.Error(err)
+		return Match(err, pgx.ErrNoRows, ErrEmailLoginThrottled).Return()
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		Logger.Error(err)
+		return ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return nil
+}
+
+const consumeEmailLoginTokenSQL = `
+	UPDATE email_login_tokens SET used_at = NOW()
+	WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+	RETURNING email;
+`
+
+// ConsumeEmailLoginToken marks the token used and returns the email it was sent to, each token works once
+func ConsumeEmailLoginToken(tokenHash string) (string, error) {
+	var email string
+	if err := DBClient.pgPool.QueryRow(context.Background(), consumeEmailLoginTokenSQL, tokenHash).Scan(&email); err != nil {
+		Logger.Error(err)
+		return "", Match(err, pgx.ErrNoRows, ErrEmailLoginTokenNotFound).Return()
+	}
+	return email, nil
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const testDeleteEmailLoginTokensSQL = `
+	DELETE FROM email_login_tokens WHERE requested_ip = $1;
+`
+
+var _ = Describe("DBEmailLoginToken", func() {
+	const testIp = "192.0.2.1"
+	throttle := &EmailLoginThrottle{Window: time.Hour, MaxPerAddress: 2, MaxPerIp: 3}
+
+	AfterEach(func() {
+		_, err := pgPool.Exec(context.Background(), testDeleteEmailLoginTokensSQL, testIp)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	create := func(email string, n int) error {
+		return CreateEmailLoginToken(email, testIp, fmt.Sprintf("test-hash-%s-%d", email, n), time.Now().Add(time.Minute), throttle)
+	}
+
+	It("should throttle an address asking for too many links", func() {
+		Expect(create("first@test", 1)).To(Succeed())
+		Expect(create("first@test", 2)).To(Succeed())
+		Expect(create("first@test", 3)).To(MatchError(ErrEmailLoginThrottled))
+	})
+
+	It("should throttle an ip asking for too many addresses", func() {
+		Expect(create("first@test", 1)).To(Succeed())
+		Expect(create("second@test", 1)).To(Succeed())
+		Expect(create("third@test", 1)).To(Succeed())
+		Expect(create("fourth@test", 1)).To(MatchError(ErrEmailLoginThrottled))
+	})
+
+	It("should not let concurrent requests all pass the count", func() {
+		var (
+			wg      sync.WaitGroup
+			created int32
+		)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(n int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				if create("first@test", n) == nil {
+					atomic.AddInt32(&created, 1)
+				}
+			}(i)
+		}
+		wg.Wait()
+		Expect(created).To(Equal(int32(throttle.MaxPerAddress)))
+	})
+})
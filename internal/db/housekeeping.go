@@ -0,0 +1,170 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	. "github.com/DenChenn/blunder/pkg/blunder"
+)
+
+// the self join reads the status each request had before it expired
+const expireStaleRequestsSQL = `
+	UPDATE requests q SET
+		status = $1,
+		updated_at = NOW()
+	FROM requests old
+	WHERE old.id = q.id AND q.status = ANY($2) AND q.pickup_end_time < NOW() AND q.deleted_at IS NULL
+	RETURNING q.id, q.rider_id, q.route_id, old.status;
+`
+
+type ExpiredRequest struct {
+	RequestId int32  `json:"requestId"`
+	RiderId   int32  `json:"riderId"`
+	RouteId   int32  `json:"routeId"`
+	Status    string `json:"status"`
+}
+
+// ExpireStaleRequests moves requests nobody answered before the pickup window closed to expired and returns
+// them with the status they had
+func ExpireStaleRequests() ([]*ExpiredRequest, error) {
+	rows, err := DBClient.pgPool.Query(context.Background(), expireStaleRequestsSQL,
+		constants.RequestStatusExpired,
+		[]string{
+			constants.RequestStatusWaitlisted,
+			constants.RequestStatusPending,
+			constants.RequestStatusCountered,
+			constants.RequestStatusOffered,
+		},
+	)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer rows.Close()
+
+	var expired []*ExpiredRequest
+	for rows.Next() {
+		var request ExpiredRequest
+		if err := rows.Scan(&request.RequestId, &request.RiderId, &request.RouteId, &request.Status); err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		expired = append(expired, &request)
+	}
+	return expired, nil
+}
+
+const completeFinishedTripsSQL = `
+	WITH completed AS (
+		UPDATE trips t SET status = $1
+		FROM routes r
+		WHERE t.route_id = r.id AND t.status = ANY($2) AND t.deleted_at IS NULL AND r.end_time < NOW()
+		RETURNING t.request_id
+	), completed_requests AS (
+		UPDATE requests SET
+			status = $3,
+			updated_at = NOW()
+		WHERE id IN (SELECT request_id FROM completed) AND status = $4
+	)
+	SELECT COUNT(*) FROM completed;
+`
+
+// CompleteFinishedTrips completes scheduled and en route trips whose route has ended together with the requests they fulfil
+func CompleteFinishedTrips() (int64, error) {
+	var count int64
+	if err := DBClient.pgPool.QueryRow(context.Background(), completeFinishedTripsSQL,
+		constants.TripStatusCompleted,
+		[]string{constants.TripStatusScheduled, constants.TripStatusEnRoute},
+		constants.RequestStatusCompleted,
+		constants.RequestStatusAccepted,
+	).Scan(&count); err != nil {
+		Logger.Error(err)
+		return 0, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return count, nil
+}
+
+// rows are purged children first, a row still referenced by a remaining row is kept until its referrers go
+// away so that joins never lose their parent
+const purgeDeletedTripsSQL = `
+	DELETE FROM trips WHERE deleted_at < $1;
+`
+
+const purgeDeletedRequestsSQL = `
+	DELETE FROM requests q
+	WHERE q.deleted_at < $1
+		AND NOT EXISTS (SELECT 1 FROM trips t WHERE t.request_id = q.id);
+`
+
+const purgeOrphanedRequestProposalsSQL = `
+	DELETE FROM request_proposals p
+	WHERE p.created_at < $1
+		AND NOT EXISTS (SELECT 1 FROM requests q WHERE q.id = p.request_id);
+`
+
+const purgeDeletedRoutesSQL = `
+	DELETE FROM routes r
+	WHERE r.deleted_at < $1
+		AND NOT EXISTS (SELECT 1 FROM trips t WHERE t.route_id = r.id)
+		AND NOT EXISTS (SELECT 1 FROM requests q WHERE q.route_id = r.id);
+`
+
+const purgeOrphanedRoutePathsSQL = `
+	DELETE FROM route_paths p
+	WHERE p.created_at < $1
+		AND NOT EXISTS (SELECT 1 FROM routes r WHERE r.id = p.route_id);
+`
+
+const purgeDeletedVehiclesSQL = `
+	DELETE FROM vehicles v
+	WHERE v.deleted_at < $1
+		AND NOT EXISTS (SELECT 1 FROM routes r WHERE r.vehicle_id = v.id);
+`
+
+const purgeDeletedMeetingPointsSQL = `
+	DELETE FROM meeting_points mp
+	WHERE mp.deleted_at < $1
+		AND NOT EXISTS (SELECT 1 FROM requests q WHERE q.meeting_point_id = mp.id);
+`
+
+const purgeDeletedDriverDocumentsSQL = `
+	DELETE FROM driver_documents WHERE deleted_at < $1;
+`
+
+// PurgeSoftDeletedRows removes rows soft deleted before the given time, users are kept since trips, reports
+// and audit logs keep pointing at the anonymised account
+func PurgeSoftDeletedRows(before time.Time) (int64, error) {
+	ctx := context.Background()
+	tx, err := DBClient.pgPool.Begin(ctx)
+	if err != nil {
+		Logger.Error(err)
+		return 0, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	var purged int64
+	for _, sql := range []string{
+		purgeDeletedTripsSQL,
+		purgeDeletedRequestsSQL,
+		purgeOrphanedRequestProposalsSQL,
+		purgeDeletedRoutesSQL,
+		purgeOrphanedRoutePathsSQL,
+		purgeDeletedVehiclesSQL,
+		purgeDeletedMeetingPointsSQL,
+		purgeDeletedDriverDocumentsSQL,
+	} {
+		tag, err := tx.Exec(ctx, sql, before)
+		if err != nil {
+			Logger.Error(err)
+			return 0, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		purged += tag.RowsAffected()
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		Logger.Error(err)
+		return 0, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return purged, nil
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DBHousekeeping", func() {
+	var routeId int32
+
+	newRequest := func(pickupEndTime time.Time) *model.Request {
+		request, err := CreateRequest(&model.Request{
+			RiderId:          -1,
+			RouteId:          routeId,
+			PickupStartTime:  pickupEndTime.Add(-time.Hour),
+			PickupEndTime:    pickupEndTime,
+			Status:           constants.RequestStatusPending,
+			SeatCount:        1,
+			RideRequirements: model.RideRequirements{Luggage: constants.LuggageNone},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		return request
+	}
+
+	BeforeEach(func() {
+		start := time.Now().Add(-3 * time.Hour)
+		err := pgPool.QueryRow(context.Background(), testCreateRouteSQL, -1, 0, 0, 0, 0, start, start.Add(time.Hour), 2).Scan(&routeId)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_, err := pgPool.Exec(context.Background(), testDeleteTripsByRouteSQL, routeId)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = pgPool.Exec(context.Background(), testDeleteRequestsByRouteSQL, routeId)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = pgPool.Exec(context.Background(), testDeleteRouteSQL, routeId)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("ExpireStaleRequests", func() {
+		It("should expire only requests whose pickup window has closed", func() {
+			stale := newRequest(time.Now().Add(-time.Hour))
+			open := newRequest(time.Now().Add(time.Hour))
+
+			expired, err := ExpireStaleRequests()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(expired).To(ContainElement(&ExpiredRequest{RequestId: stale.Id, RiderId: -1, RouteId: routeId, Status: constants.RequestStatusPending}))
+			Expect(expired).NotTo(ContainElement(HaveField("RequestId", open.Id)))
+
+			request, err := GetRequest(stale.Id)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(request.Status).To(Equal(constants.RequestStatusExpired))
+
+			request, err = GetRequest(open.Id)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(request.Status).To(Equal(constants.RequestStatusPending))
+		})
+	})
+
+	Describe("CompleteFinishedTrips", func() {
+		It("should complete trips of ended routes with their requests", func() {
+			request := newRequest(time.Now().Add(-2 * time.Hour))
+			trip, err := AcceptRequest(request.Id, 0)
+			Expect(err).NotTo(HaveOccurred())
+
+			completed, err := CompleteFinishedTrips()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(completed).To(BeNumerically(">=", 1))
+
+			completedTrip, err := GetTrip(trip.Id)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(completedTrip.Status).To(Equal(constants.TripStatusCompleted))
+
+			completedRequest, err := GetRequest(request.Id)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(completedRequest.Status).To(Equal(constants.RequestStatusCompleted))
+		})
+	})
+})
+
+var _ = Describe("DBAdvisoryLock", func() {
+	It("should only be held by one session at a time", func() {
+		lock, err := TryAdvisoryLock(-1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock).NotTo(BeNil())
+		Expect(lock.Alive()).To(BeTrue())
+
+		other, err := TryAdvisoryLock(-1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(other).To(BeNil())
+
+		lock.Release()
+		other, err = TryAdvisoryLock(-1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(other).NotTo(BeNil())
+		other.Release()
+	})
+})
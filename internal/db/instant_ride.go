@@ -0,0 +1,551 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/DenChenn/blunder/pkg/blunder"
+	"github.com/jackc/pgx/v5"
+)
+
+const createInstantRideTableSQL = `
+	CREATE EXTENSION IF NOT EXISTS postgis;
+
+	CREATE TABLE IF NOT EXISTS driver_presences (
+		user_id INT PRIMARY KEY,
+		online BOOLEAN DEFAULT FALSE NOT NULL,
+		location GEOMETRY(Point, 4326) NOT NULL,
+		destination GEOMETRY(Point, 4326) NOT NULL,
+		seats INT NOT NULL,
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS driver_presences_location_idx
+		ON driver_presences USING gist((location::geography)) WHERE online;
+
+	CREATE TABLE IF NOT EXISTS instant_requests (
+		id SERIAL PRIMARY KEY,
+		rider_id INT NOT NULL,
+		driver_id INT,
+		pickup_location GEOMETRY(Point, 4326) NOT NULL,
+		dropoff_location GEOMETRY(Point, 4326) NOT NULL,
+		seat_count INT DEFAULT 1 NOT NULL,
+		status VARCHAR(50) NOT NULL,
+		offer_expires_at TIMESTAMP WITH TIME ZONE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL,
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS instant_requests_status_idx ON instant_requests (status);
+
+	-- every driver a request was offered to, so that a fallback never offers it to the same driver twice
+	CREATE TABLE IF NOT EXISTS instant_dispatches (
+		request_id INT NOT NULL,
+		driver_id INT NOT NULL,
+		status VARCHAR(50) NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL,
+		PRIMARY KEY (request_id, driver_id)
+	);
+`
+
+func initInstantRideTable() error {
+	if _, err := DBClient.pgPool.Exec(context.Background(), createInstantRideTableSQL); err != nil {
+		return err
+	}
+	return nil
+}
+
+const getDriverPresenceSQL = `
+	SELECT user_id, online, ST_X(location), ST_Y(location), ST_X(destination), ST_Y(destination), seats, updated_at
+	FROM driver_presences
+	WHERE user_id = $1;
+`
+
+func GetDriverPresence(userId int32) (*model.DriverPresence, error) {
+	presence, err := scanDriverPresence(DBClient.pgPool.QueryRow(context.Background(), getDriverPresenceSQL, userId))
+	if err != nil {
+		Logger.Error(err)
+		return nil, Match(err, pgx.ErrNoRows, ErrDriverPresenceNotFound).Return()
+	}
+	return presence, nil
+}
+
+func scanDriverPresence(row pgx.Row) (*model.DriverPresence, error) {
+	var presence model.DriverPresence
+	if err := row.Scan(
+		&presence.UserId,
+		&presence.Online,
+		&presence.Long,
+		&presence.Lat,
+		&presence.DestinationLong,
+		&presence.DestinationLat,
+		&presence.Seats,
+		&presence.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &presence, nil
+}
+
+const upsertDriverPresenceSQL = `
+	INSERT INTO driver_presences (user_id, online, location, destination, seats, updated_at)
+	VALUES ($1, TRUE, ST_SetSRID(ST_MakePoint($2, $3), 4326), ST_SetSRID(ST_MakePoint($4, $5), 4326), $6, NOW())
+	ON CONFLICT (user_id) DO UPDATE SET
+		online = TRUE,
+		location = EXCLUDED.location,
+		destination = EXCLUDED.destination,
+		seats = EXCLUDED.seats,
+		updated_at = NOW()
+	RETURNING user_id, online, ST_X(location), ST_Y(location), ST_X(destination), ST_Y(destination), seats, updated_at;
+`
+
+// UpsertDriverPresence puts the driver online at their current location, drivers keep calling it as they move
+func UpsertDriverPresence(presence *model.DriverPresence) (*model.DriverPresence, error) {
+	updated, err := scanDriverPresence(DBClient.pgPool.QueryRow(context.Background(), upsertDriverPresenceSQL,
+		presence.UserId,
+		presence.Long,
+		presence.Lat,
+		presence.DestinationLong,
+		presence.DestinationLat,
+		presence.Seats,
+	))
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return updated, nil
+}
+
+const setDriverOfflineSQL = `
+	UPDATE driver_presences SET online = FALSE, updated_at = NOW()
+	WHERE user_id = $1;
+`
+
+func SetDriverOffline(userId int32) error {
+	if _, err := DBClient.pgPool.Exec(context.Background(), setDriverOfflineSQL, userId); err != nil {
+		Logger.Error(err)
+		return ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return nil
+}
+
+const instantRequestColumns = `
+	id, rider_id, driver_id,
+	ST_X(pickup_location), ST_Y(pickup_location),
+	ST_X(dropoff_location), ST_Y(dropoff_location),
+	seat_count, status, offer_expires_at, created_at, updated_at
+`
+
+const getInstantRequestSQL = `
+	SELECT` + instantRequestColumns + `
+	FROM instant_requests
+	WHERE id = $1;
+`
+
+func GetInstantRequest(id int32) (*model.InstantRequest, error) {
+	request, err := scanInstantRequest(DBClient.pgPool.QueryRow(context.Background(), getInstantRequestSQL, id))
+	if err != nil {
+		Logger.Error(err)
+		return nil, Match(err, pgx.ErrNoRows, ErrInstantRequestNotFound).Return()
+	}
+	return request, nil
+}
+
+func scanInstantRequest(row pgx.Row) (*model.InstantRequest, error) {
+	var request model.InstantRequest
+	if err := row.Scan(
+		&request.Id,
+		&request.RiderId,
+		&request.DriverId,
+		&request.PickupLong,
+		&request.PickupLat,
+		&request.DropoffLong,
+		&request.DropoffLat,
+		&request.SeatCount,
+		&request.Status,
+		&request.OfferExpiresAt,
+		&request.CreatedAt,
+		&request.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+const createInstantRequestSQL = `
+	INSERT INTO instant_requests (rider_id, pickup_location, dropoff_location, seat_count, status)
+	VALUES ($1, ST_SetSRID(ST_MakePoint($2, $3), 4326), ST_SetSRID(ST_MakePoint($4, $5), 4326), $6, $7)
+	RETURNING` + instantRequestColumns + `;
+`
+
+func CreateInstantRequest(request *model.InstantRequest) (*model.InstantRequest, error) {
+	created, err := scanInstantRequest(DBClient.pgPool.QueryRow(context.Background(), createInstantRequestSQL,
+		request.RiderId,
+		request.PickupLong,
+		request.PickupLat,
+		request.DropoffLong,
+		request.DropoffLat,
+		request.SeatCount,
+		constants.InstantRequestStatusSearching,
+	))
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return created, nil
+}
+
+const listDispatchableInstantRequestsSQL = `
+	SELECT id FROM instant_requests
+	WHERE status = $1 OR (status = $2 AND offer_expires_at <= NOW())
+	ORDER BY created_at;
+`
+
+// ListDispatchableInstantRequests lists requests still looking for a driver or whose offer timed out
+func ListDispatchableInstantRequests() ([]int32, error) {
+	rows, err := DBClient.pgPool.Query(context.Background(), listDispatchableInstantRequestsSQL,
+		constants.InstantRequestStatusSearching,
+		constants.InstantRequestStatusOffered,
+	)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer rows.Close()
+
+	var ids []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// InstantDispatchParams bounds which online drivers a request may be offered to
+type InstantDispatchParams struct {
+	Radius        float64
+	MinHeadingCos float64
+	OfferTimeout  time.Duration
+	SearchTimeout time.Duration
+	PresenceTtl   time.Duration
+}
+
+const lockInstantRequestSQL = `
+	SELECT` + instantRequestColumns + `
+	FROM instant_requests
+	WHERE id = $1
+	FOR UPDATE;
+`
+
+const updateInstantDispatchSQL = `
+	UPDATE instant_dispatches SET status = $3
+	WHERE request_id = $1 AND driver_id = $2 AND status = $4;
+`
+
+const setInstantRequestStatusSQL = `
+	UPDATE instant_requests SET status = $2, driver_id = $3, offer_expires_at = $4, updated_at = NOW()
+	WHERE id = $1;
+`
+
+// candidates are online drivers near the pickup with enough seats whose heading is close to the ride's,
+// nearest first. Drivers already asked, busy answering another offer or blocked either way are skipped,
+// and a driver locked by a concurrent dispatch is left to it.
+const findInstantDriverSQL = `
+	SELECT p.user_id
+	FROM driver_presences p, instant_requests q
+	WHERE q.id = $1
+		AND p.online AND p.updated_at > $2
+		AND p.user_id <> q.rider_id
+		AND p.seats >= q.seat_count
+		AND ST_DWithin(p.location::geography, q.pickup_location::geography, $3)
+		AND cos(ST_Azimuth(p.location::geography, p.destination::geography)
+			- ST_Azimuth(q.pickup_location::geography, q.dropoff_location::geography)) >= $4
+		AND NOT EXISTS (SELECT 1 FROM instant_dispatches d WHERE d.request_id = q.id AND d.driver_id = p.user_id)
+		AND NOT EXISTS (
+			SELECT 1 FROM instant_requests o
+			WHERE o.driver_id = p.user_id AND o.status = $5 AND o.offer_expires_at > NOW()
+		)
+		AND NOT EXISTS (
+			SELECT 1 FROM user_blocks b
+			WHERE (b.blocker_id = p.user_id AND b.blocked_id = q.rider_id)
+				OR (b.blocker_id = q.rider_id AND b.blocked_id = p.user_id)
+		)
+	ORDER BY ST_Distance(p.location::geography, q.pickup_location::geography)
+	LIMIT 1
+	FOR UPDATE OF p SKIP LOCKED;
+`
+
+const createInstantDispatchSQL = `
+	INSERT INTO instant_dispatches (request_id, driver_id, status)
+	VALUES ($1, $2, $3);
+`
+
+// DispatchInstantRequest moves the request along: a timed out offer falls back to searching, a searching
+// request is offered to the next candidate driver or given up once it searched for too long.
+// changed reports whether a new offer was made or the request was given up on.
+func DispatchInstantRequest(id int32, params *InstantDispatchParams) (request *model.InstantRequest, changed bool, err error) {
+	ctx := context.Background()
+	tx, err := DBClient.pgPool.Begin(ctx)
+	if err != nil {
+		Logger.Error(err)
+		return nil, false, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	request, err = scanInstantRequest(tx.QueryRow(ctx, lockInstantRequestSQL, id))
+	if err != nil {
+		Logger.Error(err)
+		return nil, false, Match(err, pgx.ErrNoRows, ErrInstantRequestNotFound).Return()
+	}
+
+	now := time.Now()
+	if request.Status == constants.InstantRequestStatusOffered && !request.OfferExpiresAt.After(now) {
+		if _, err := tx.Exec(ctx, updateInstantDispatchSQL, id, *request.DriverId,
+			constants.InstantDispatchStatusExpired, constants.InstantDispatchStatusOffered); err != nil {
+			Logger.Error(err)
+			return nil, false, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		request.Status, request.DriverId, request.OfferExpiresAt = constants.InstantRequestStatusSearching, nil, nil
+	}
+
+	if request.Status == constants.InstantRequestStatusSearching {
+		if now.Sub(request.CreatedAt) > params.SearchTimeout {
+			request.Status, changed = constants.InstantRequestStatusUnmatched, true
+		} else {
+			var driverId int32
+			err := tx.QueryRow(ctx, findInstantDriverSQL,
+				id,
+				now.Add(-params.PresenceTtl),
+				params.Radius,
+				params.MinHeadingCos,
+				constants.InstantRequestStatusOffered,
+			).Scan(&driverId)
+			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				Logger.Error(err)
+				return nil, false, ErrUndefined.WithCustomMessage(err.Error())
+			}
+			if err == nil {
+				if _, err := tx.Exec(ctx, createInstantDispatchSQL, id, driverId, constants.InstantDispatchStatusOffered); err != nil {
+					Logger.Error(err)
+					return nil, false, ErrUndefined.WithCustomMessage(err.Error())
+				}
+				expiresAt := now.Add(params.OfferTimeout)
+				request.Status, request.DriverId, request.OfferExpiresAt = constants.InstantRequestStatusOffered, &driverId, &expiresAt
+				changed = true
+			}
+		}
+		if _, err := tx.Exec(ctx, setInstantRequestStatusSQL, id, request.Status, request.DriverId, request.OfferExpiresAt); err != nil {
+			Logger.Error(err)
+			return nil, false, ErrUndefined.WithCustomMessage(err.Error())
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		Logger.Error(err)
+		return nil, false, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return request, changed, nil
+}
+
+const acceptInstantRequestSQL = `
+	UPDATE instant_requests SET status = $3, offer_expires_at = NULL, updated_at = NOW()
+	WHERE id = $1 AND driver_id = $2 AND status = $4 AND offer_expires_at > NOW()
+	RETURNING` + instantRequestColumns + `;
+`
+
+// AcceptInstantRequest books the ride for the driver it is offered to, the driver goes offline until the
+// ride is completed or withdrawn
+func AcceptInstantRequest(id, driverId int32) (*model.InstantRequest, error) {
+	ctx := context.Background()
+	tx, err := DBClient.pgPool.Begin(ctx)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	request, err := scanInstantRequest(tx.QueryRow(ctx, acceptInstantRequestSQL,
+		id,
+		driverId,
+		constants.InstantRequestStatusAccepted,
+		constants.InstantRequestStatusOffered,
+	))
+	if err != nil {
+		Logger.Error(err)
+		return nil, Match(err, pgx.ErrNoRows, ErrInstantOfferNotActive).Return()
+	}
+	if _, err := tx.Exec(ctx, updateInstantDispatchSQL, id, driverId,
+		constants.InstantDispatchStatusAccepted, constants.InstantDispatchStatusOffered); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	if _, err := tx.Exec(ctx, setDriverOfflineSQL, driverId); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return request, nil
+}
+
+const completeInstantRequestSQL = `
+	UPDATE instant_requests SET status = $3, updated_at = NOW()
+	WHERE id = $1 AND driver_id = $2 AND status = $4
+	RETURNING` + instantRequestColumns + `;
+`
+
+const setDriverOnlineAtDropoffSQL = `
+	UPDATE driver_presences p SET online = TRUE, location = q.dropoff_location, updated_at = NOW()
+	FROM instant_requests q
+	WHERE p.user_id = $1 AND q.id = $2;
+`
+
+// CompleteInstantRequest ends the accepted ride, the driver is back online at the dropoff for the next one
+func CompleteInstantRequest(id, driverId int32) (*model.InstantRequest, error) {
+	ctx := context.Background()
+	tx, err := DBClient.pgPool.Begin(ctx)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	request, err := scanInstantRequest(tx.QueryRow(ctx, completeInstantRequestSQL,
+		id,
+		driverId,
+		constants.InstantRequestStatusCompleted,
+		constants.InstantRequestStatusAccepted,
+	))
+	if err != nil {
+		Logger.Error(err)
+		return nil, Match(err, pgx.ErrNoRows, ErrInstantRideNotAccepted).Return()
+	}
+	if _, err := tx.Exec(ctx, setDriverOnlineAtDropoffSQL, driverId, id); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return request, nil
+}
+
+const declineInstantRequestSQL = `
+	UPDATE instant_requests SET status = $3, driver_id = NULL, offer_expires_at = NULL, updated_at = NOW()
+	WHERE id = $1 AND driver_id = $2 AND status = $4
+	RETURNING id;
+`
+
+// DeclineInstantRequest hands the request back to dispatch, it will not be offered to this driver again
+func DeclineInstantRequest(id, driverId int32) error {
+	ctx := context.Background()
+	tx, err := DBClient.pgPool.Begin(ctx)
+	if err != nil {
+		Logger.Error(err)
+		return ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	if err := tx.QueryRow(ctx, declineInstantRequestSQL,
+		id,
+		driverId,
+		constants.InstantRequestStatusSearching,
+		constants.InstantRequestStatusOffered,
+	).Scan(&id); err != nil {
+		Logger.Error(err)
+		return Match(err, pgx.ErrNoRows, ErrInstantOfferNotActive).Return()
+	}
+	if _, err := tx.Exec(ctx, updateInstantDispatchSQL, id, driverId,
+		constants.InstantDispatchStatusDeclined, constants.InstantDispatchStatusOffered); err != nil {
+		Logger.Error(err)
+		return ErrUndefined.WithCustomMessage(err.Error())
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		Logger.Error(err)
+		return ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return nil
+}
+
+const withdrawInstantRequestSQL = `
+	UPDATE instant_requests SET status = $3, driver_id = NULL, updated_at = NOW()
+	WHERE id = $1 AND driver_id = $2 AND status = $4
+	RETURNING` + instantRequestColumns + `;
+`
+
+const setDriverOnlineSQL = `
+	UPDATE driver_presences SET online = TRUE, updated_at = NOW()
+	WHERE user_id = $1;
+`
+
+// WithdrawInstantRequest lets the driver back out of an accepted ride, the request goes back to dispatch
+// without them and the driver is back online
+func WithdrawInstantRequest(id, driverId int32) (*model.InstantRequest, error) {
+	ctx := context.Background()
+	tx, err := DBClient.pgPool.Begin(ctx)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	request, err := scanInstantRequest(tx.QueryRow(ctx, withdrawInstantRequestSQL,
+		id,
+		driverId,
+		constants.InstantRequestStatusSearching,
+		constants.InstantRequestStatusAccepted,
+	))
+	if err != nil {
+		Logger.Error(err)
+		return nil, Match(err, pgx.ErrNoRows, ErrInstantRideNotAccepted).Return()
+	}
+	if _, err := tx.Exec(ctx, updateInstantDispatchSQL, id, driverId,
+		constants.InstantDispatchStatusDeclined, constants.InstantDispatchStatusAccepted); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	if _, err := tx.Exec(ctx, setDriverOnlineSQL, driverId); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return request, nil
+}
+
+const cancelInstantRequestSQL = `
+	UPDATE instant_requests SET status = $3, offer_expires_at = NULL, updated_at = NOW()
+	WHERE id = $1 AND rider_id = $2 AND status = ANY($4)
+	RETURNING id;
+`
+
+// CancelInstantRequest stops the search, a ride a driver already accepted cannot be cancelled this way
+func CancelInstantRequest(id, riderId int32) error {
+	if err := DBClient.pgPool.QueryRow(context.Background(), cancelInstantRequestSQL,
+		id,
+		riderId,
+		constants.InstantRequestStatusCancelled,
+		[]string{constants.InstantRequestStatusSearching, constants.InstantRequestStatusOffered},
+	).Scan(&id); err != nil {
+		Logger.Error(err)
+		return Match(err, pgx.ErrNoRows, ErrInstantRequestNotActive).Return()
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const testDeleteDriverPresenceSQL = `
+	DELETE FROM driver_presences WHERE user_id = $1;
+`
+
+const testDeleteInstantDispatchesSQL = `
+	DELETE FROM instant_dispatches WHERE request_id = $1;
+`
+
+const testDeleteInstantRequestSQL = `
+	DELETE FROM instant_requests WHERE id = $1;
+`
+
+var _ = Describe("DBInstantRide", func() {
+	var (
+		request *model.InstantRequest
+		params  *InstantDispatchParams
+		drivers = []*model.DriverPresence{
+			{UserId: -11, Long: 0.001, Lat: 0, DestinationLong: 0.2, DestinationLat: 0, Seats: 3},
+			{UserId: -12, Long: 0.002, Lat: 0, DestinationLong: 0.2, DestinationLat: 0, Seats: 3},
+			// closest of all but heading the other way
+			{UserId: -13, Long: 0.0005, Lat: 0, DestinationLong: -0.2, DestinationLat: 0, Seats: 3},
+		}
+	)
+
+	BeforeEach(func() {
+		for _, driver := range drivers {
+			_, err := UpsertDriverPresence(driver)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		var err error
+		request, err = CreateInstantRequest(&model.InstantRequest{RiderId: -1, PickupLong: 0, PickupLat: 0, DropoffLong: 0.1, DropoffLat: 0, SeatCount: 1})
+		Expect(err).NotTo(HaveOccurred())
+
+		params = &InstantDispatchParams{
+			Radius:        3000,
+			MinHeadingCos: 0.5,
+			OfferTimeout:  time.Minute,
+			SearchTimeout: time.Hour,
+			PresenceTtl:   time.Hour,
+		}
+	})
+
+	AfterEach(func() {
+		for _, driver := range drivers {
+			_, err := pgPool.Exec(context.Background(), testDeleteDriverPresenceSQL, driver.UserId)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		_, err := pgPool.Exec(context.Background(), testDeleteInstantDispatchesSQL, request.Id)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = pgPool.Exec(context.Background(), testDeleteInstantRequestSQL, request.Id)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should offer to the nearest driver heading the same way and fall back on decline", func() {
+		offered, changed, err := DispatchInstantRequest(request.Id, params)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(BeTrue())
+		Expect(offered.Status).To(Equal(constants.InstantRequestStatusOffered))
+		Expect(*offered.DriverId).To(Equal(int32(-11)))
+
+		Expect(DeclineInstantRequest(request.Id, -11)).To(Succeed())
+		offered, _, err = DispatchInstantRequest(request.Id, params)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*offered.DriverId).To(Equal(int32(-12)))
+
+		accepted, err := AcceptInstantRequest(request.Id, -12)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(accepted.Status).To(Equal(constants.InstantRequestStatusAccepted))
+
+		presence, err := GetDriverPresence(-12)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(presence.Online).To(BeFalse())
+	})
+
+	It("should not let a driver accept a timed out offer", func() {
+		params.OfferTimeout = -time.Second
+		_, _, err := DispatchInstantRequest(request.Id, params)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = AcceptInstantRequest(request.Id, -11)
+		Expect(err).To(MatchError(ErrInstantOfferNotActive))
+
+		params.OfferTimeout = time.Minute
+		offered, _, err := DispatchInstantRequest(request.Id, params)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*offered.DriverId).To(Equal(int32(-12)))
+	})
+
+	It("should put the driver back online at the dropoff once the ride is completed", func() {
+		_, _, err := DispatchInstantRequest(request.Id, params)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = CompleteInstantRequest(request.Id, -11)
+		Expect(err).To(MatchError(ErrInstantRideNotAccepted))
+
+		_, err = AcceptInstantRequest(request.Id, -11)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = CompleteInstantRequest(request.Id, -12)
+		Expect(err).To(MatchError(ErrInstantRideNotAccepted))
+
+		completed, err := CompleteInstantRequest(request.Id, -11)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(completed.Status).To(Equal(constants.InstantRequestStatusCompleted))
+
+		presence, err := GetDriverPresence(-11)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(presence.Online).To(BeTrue())
+		Expect(presence.Long).To(BeNumerically("~", 0.1, 1e-9))
+	})
+
+	It("should hand a withdrawn ride back to dispatch without the driver", func() {
+		_, _, err := DispatchInstantRequest(request.Id, params)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = AcceptInstantRequest(request.Id, -11)
+		Expect(err).NotTo(HaveOccurred())
+
+		withdrawn, err := WithdrawInstantRequest(request.Id, -11)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(withdrawn.Status).To(Equal(constants.InstantRequestStatusSearching))
+		Expect(withdrawn.DriverId).To(BeNil())
+
+		presence, err := GetDriverPresence(-11)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(presence.Online).To(BeTrue())
+
+		offered, _, err := DispatchInstantRequest(request.Id, params)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*offered.DriverId).To(Equal(int32(-12)))
+	})
+
+	It("should give up once the search timed out", func() {
+		params.SearchTimeout = 0
+		unmatched, changed, err := DispatchInstantRequest(request.Id, params)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(BeTrue())
+		Expect(unmatched.Status).To(Equal(constants.InstantRequestStatusUnmatched))
+	})
+})
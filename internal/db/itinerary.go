@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	. "github.com/DenChenn/blunder/pkg/blunder"
+)
+
+// ItineraryRequest is an accepted request as far as planning the driver's stops is concerned
+type ItineraryRequest struct {
+	Id              int32
+	RiderId         int32
+	RiderName       string
+	PickupLong      float64
+	PickupLat       float64
+	DropoffLong     float64
+	DropoffLat      float64
+	PickupStartTime time.Time
+	PickupEndTime   time.Time
+	Seats           int32
+}
+
+const listItineraryRequestsSQL = `
+	SELECT
+		r.id,
+		r.rider_id,
+		u.name,
+		ST_X(r.pickup_location), ST_Y(r.pickup_location),
+		ST_X(r.dropoff_location), ST_Y(r.dropoff_location),
+		r.pickup_start_time,
+		r.pickup_end_time,
+		r.accepted_seats
+	FROM requests r
+		JOIN users u ON r.rider_id = u.id
+	WHERE r.route_id = $1 AND r.status = $2 AND r.deleted_at IS NULL
+	ORDER BY r.id;
+`
+
+// ListItineraryRequests lists the accepted requests of the route, the riders the driver has to pick up
+func ListItineraryRequests(routeId int32) ([]*ItineraryRequest, error) {
+	rows, err := DBClient.pgPool.Query(context.Background(), listItineraryRequestsSQL, routeId, constants.RequestStatusAccepted)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer rows.Close()
+
+	var requests []*ItineraryRequest
+	for rows.Next() {
+		var request ItineraryRequest
+		if err := rows.Scan(
+			&request.Id,
+			&request.RiderId,
+			&request.RiderName,
+			&request.PickupLong,
+			&request.PickupLat,
+			&request.DropoffLong,
+			&request.DropoffLat,
+			&request.PickupStartTime,
+			&request.PickupEndTime,
+			&request.Seats,
+		); err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		requests = append(requests, &request)
+	}
+	return requests, nil
+}
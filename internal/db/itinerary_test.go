@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DBItinerary", func() {
+	var (
+		riderId int32
+		routeId int32
+	)
+
+	BeforeEach(func() {
+		err := pgPool.QueryRow(context.Background(), testCreateUserSQL, "rider", "test", "test-itinerary", "test").Scan(&riderId)
+		Expect(err).NotTo(HaveOccurred())
+		err = pgPool.QueryRow(context.Background(), testCreateRouteSQL, -1, 0, 0, 0.1, 0, time.Now(), time.Now().Add(time.Hour), 3).Scan(&routeId)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_, err := pgPool.Exec(context.Background(), testDeleteTripsByRouteSQL, routeId)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = pgPool.Exec(context.Background(), testDeleteRequestsByRouteSQL, routeId)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = pgPool.Exec(context.Background(), testDeleteRouteSQL, routeId)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = pgPool.Exec(context.Background(), testDeleteUserSQL, riderId)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should only list the accepted requests of the route", func() {
+		var requests []*model.Request
+		for i := 0; i < 2; i++ {
+			request, err := CreateRequest(&model.Request{
+				RiderId:          riderId,
+				RouteId:          routeId,
+				PickupLong:       0.02,
+				DropoffLong:      0.08,
+				PickupStartTime:  time.Now(),
+				PickupEndTime:    time.Now().Add(time.Hour),
+				Status:           constants.RequestStatusPending,
+				SeatCount:        2 - int32(i),
+				RideRequirements: model.RideRequirements{Luggage: constants.LuggageNone},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			requests = append(requests, request)
+		}
+		_, err := AcceptRequest(requests[0].Id, 0)
+		Expect(err).NotTo(HaveOccurred())
+
+		itinerary, err := ListItineraryRequests(routeId)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(itinerary).To(HaveLen(1))
+		Expect(itinerary[0].Id).To(Equal(requests[0].Id))
+		Expect(itinerary[0].RiderName).To(Equal("rider"))
+		Expect(itinerary[0].Seats).To(Equal(int32(2)))
+		Expect(itinerary[0].DropoffLong).To(BeNumerically("~", 0.08, 1e-9))
+	})
+})
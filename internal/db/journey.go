@@ -0,0 +1,474 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/DenChenn/blunder/pkg/blunder"
+	"github.com/jackc/pgx/v5"
+)
+
+const createJourneyTableSQL = `
+	CREATE TABLE IF NOT EXISTS journeys (
+		id SERIAL PRIMARY KEY,
+		rider_id INT NOT NULL,
+		status VARCHAR(50) DEFAULT 'active' NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL,
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS journey_legs (
+		journey_id INT NOT NULL,
+		leg INT NOT NULL,
+		request_id INT NOT NULL UNIQUE,
+		PRIMARY KEY (journey_id, leg)
+	);
+`
+
+func initJourneyTable() error {
+	if _, err := DBClient.pgPool.Exec(context.Background(), createJourneyTableSQL); err != nil {
+		return err
+	}
+	return nil
+}
+
+// TransferParams bounds where and when a rider may change drivers
+type TransferParams struct {
+	WalkingDistance float64
+	// AccessDistance is how far the first route may pass from the pickup and the second from the dropoff
+	AccessDistance float64
+	Buffer         time.Duration
+	MaxWait        time.Duration
+}
+
+type JourneyLegOption struct {
+	RouteId         int32     `json:"routeId"`
+	DriverId        int32     `json:"driverId"`
+	DriverName      *string   `json:"driverName"`
+	DriverVerified  bool      `json:"driverVerified"`
+	FreeSeats       int32     `json:"freeSeats"`
+	PickupLong      float64   `json:"pickupLong"`
+	PickupLat       float64   `json:"pickupLat"`
+	DropoffLong     float64   `json:"dropoffLong"`
+	DropoffLat      float64   `json:"dropoffLat"`
+	PickupStartTime time.Time `json:"pickupStartTime"`
+	PickupEndTime   time.Time `json:"pickupEndTime"`
+}
+
+// MultiLegRouteOption is a pair of routes the rider can combine, the legs carry the pickup and dropoff to
+// book each one with
+type MultiLegRouteOption struct {
+	Legs              []*JourneyLegOption `json:"legs"`
+	TransferDistance  float64             `json:"transferDistance"`
+	TransferArrival   time.Time           `json:"transferArrival"`
+	TransferDeparture time.Time           `json:"transferDeparture"`
+}
+
+// routes are straight lines from start to end and are assumed to move along them at a steady pace, the
+// transfer happens where the two lines come closest. The first route has to pass the pickup before the
+// transfer and the second one the dropoff after it.
+const listMultiLegRoutesSQL = `
+	WITH rider_requirements AS (
+		SELECT
+			ST_SetSRID(ST_MakePoint($1, $2), 4326) AS pickup_point,
+			ST_SetSRID(ST_MakePoint($3, $4), 4326) AS dropoff_point,
+			$5::timestamp with time zone AS pickup_start_time,
+			$6::timestamp with time zone AS pickup_end_time,
+			$7::boolean AS verified_only,
+			$8::int AS rider_id,
+			$9::varchar AS rider_gender,
+			$10::boolean AS women_only,
+			$11::boolean AS smoking,
+			$12::boolean AS pets,
+			array_position($14::text[], $13) AS luggage_size,
+			$15::int AS seats,
+			$18::double precision * INTERVAL '1 second' AS buffer,
+			$19::double precision * INTERVAL '1 second' AS max_wait,
+			$20::double precision AS access_distance
+	), eligible_routes AS (
+		SELECT
+			r.id,
+			r.driver_id,
+			r.start_time,
+			r.end_time,
+			ST_MakeLine(r.start_location, r.end_location) AS path,
+			u.name AS driver_name,
+			u.driver_verified_at IS NOT NULL AS driver_verified,
+			s.free_seats,
+			ST_DWithin(ST_MakeLine(r.start_location, r.end_location)::geography, rr.pickup_point::geography, rr.access_distance) AS near_pickup,
+			ST_DWithin(ST_MakeLine(r.start_location, r.end_location)::geography, rr.dropoff_point::geography, rr.access_distance) AS near_dropoff
+		FROM rider_requirements rr, routes r
+			JOIN users u ON r.driver_id = u.id
+			LEFT JOIN ride_preferences dp ON r.driver_id = dp.user_id
+			CROSS JOIN LATERAL (
+				SELECT r.capacity - COALESCE(SUM(q.accepted_seats), 0) AS free_seats
+				FROM requests q
+				WHERE q.route_id = r.id AND q.status = ANY($16) AND q.deleted_at IS NULL
+			) s
+		WHERE
+			r.deleted_at IS NULL
+			AND r.end_time >= rr.pickup_start_time
+			AND NOT ST_Equals(r.start_location, r.end_location)
+			AND r.driver_id <> rr.rider_id
+			AND (NOT rr.verified_only OR u.driver_verified_at IS NOT NULL)
+			AND NOT EXISTS (
+				SELECT 1 FROM user_blocks b
+				WHERE (b.blocker_id = r.driver_id AND b.blocked_id = rr.rider_id)
+					OR (b.blocker_id = rr.rider_id AND b.blocked_id = r.driver_id)
+			)
+			AND (NOT r.women_only OR rr.rider_gender = 'female')
+			AND (NOT rr.women_only OR dp.gender = 'female')
+			AND (NOT rr.smoking OR r.smoking_allowed)
+			AND (NOT rr.pets OR r.pets_allowed)
+			AND rr.luggage_size <= array_position($14::text[], r.max_luggage)
+			AND s.free_seats >= rr.seats
+			AND (
+				ST_DWithin(ST_MakeLine(r.start_location, r.end_location)::geography, rr.pickup_point::geography, rr.access_distance)
+				OR ST_DWithin(ST_MakeLine(r.start_location, r.end_location)::geography, rr.dropoff_point::geography, rr.access_distance)
+			)
+	)
+	SELECT
+		f.id, f.driver_id, f.driver_name, f.driver_verified, f.free_seats,
+		ST_X(t.drop_point), ST_Y(t.drop_point),
+		rr.pickup_start_time, rr.pickup_end_time,
+		s.id, s.driver_id, s.driver_name, s.driver_verified, s.free_seats,
+		ST_X(t.pick_point), ST_Y(t.pick_point),
+		GREATEST(e.arrival + rr.buffer, s.start_time), e.departure,
+		ST_Distance(t.drop_point::geography, t.pick_point::geography),
+		e.arrival,
+		e.departure
+	FROM rider_requirements rr, eligible_routes f
+		-- pairs that cannot meet in time or place are dropped before the transfer point is worked out
+		JOIN eligible_routes s ON s.id <> f.id AND s.driver_id <> f.driver_id
+			AND f.near_pickup AND s.near_dropoff
+			AND s.start_time <= f.end_time + rr.buffer + rr.max_wait
+			AND s.end_time >= f.start_time + rr.buffer
+			AND ST_DWithin(f.path::geography, s.path::geography, $17)
+		CROSS JOIN LATERAL (SELECT ST_ShortestLine(f.path, s.path) AS link) k
+		CROSS JOIN LATERAL (SELECT ST_StartPoint(k.link) AS drop_point, ST_EndPoint(k.link) AS pick_point) t
+		CROSS JOIN LATERAL (
+			SELECT
+				f.start_time + (f.end_time - f.start_time) * ST_LineLocatePoint(f.path, t.drop_point) AS arrival,
+				s.start_time + (s.end_time - s.start_time) * ST_LineLocatePoint(s.path, t.pick_point) AS departure
+		) e
+	WHERE
+		f.start_time <= rr.pickup_start_time
+		AND f.end_time >= rr.pickup_end_time
+		AND ST_LineLocatePoint(f.path, rr.pickup_point) < ST_LineLocatePoint(f.path, t.drop_point)
+		AND ST_LineLocatePoint(s.path, rr.dropoff_point) > ST_LineLocatePoint(s.path, t.pick_point)
+		AND ST_Distance(t.drop_point::geography, t.pick_point::geography) <= $17
+		AND e.departure >= e.arrival + rr.buffer
+		AND e.departure <= e.arrival + rr.buffer + rr.max_wait
+	ORDER BY (
+		ST_Distance(f.path::geography, rr.pickup_point::geography) +
+		ST_Distance(t.drop_point::geography, t.pick_point::geography) +
+		ST_Distance(s.path::geography, rr.dropoff_point::geography)
+	) ASC, e.departure - e.arrival ASC
+	LIMIT 10
+`
+
+// ListMultiLegRoutes finds pairs of routes that take the rider from pickup to dropoff with one transfer,
+// applying the rider's requirements to both drivers
+func ListMultiLegRoutes(
+	pickupLong, pickupLat, dropoffLong, dropoffLat float64,
+	pickupStartTime, pickupEndTime time.Time,
+	verifiedOnly bool,
+	riderId int32,
+	riderGender string,
+	requirements *model.RideRequirements,
+	seats int32,
+	params *TransferParams,
+) ([]*MultiLegRouteOption, error) {
+	rows, err := DBClient.pgPool.Query(context.Background(), listMultiLegRoutesSQL,
+		pickupLong, pickupLat, dropoffLong, dropoffLat, pickupStartTime, pickupEndTime, verifiedOnly, riderId,
+		riderGender, requirements.WomenOnly, requirements.Smoking, requirements.Pets, requirements.Luggage,
+		constants.LuggageSizes, seats, constants.SeatHoldingRequestStatuses,
+		params.WalkingDistance, params.Buffer.Seconds(), params.MaxWait.Seconds(), params.AccessDistance)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer rows.Close()
+
+	var options []*MultiLegRouteOption
+	for rows.Next() {
+		first := JourneyLegOption{PickupLong: pickupLong, PickupLat: pickupLat}
+		second := JourneyLegOption{DropoffLong: dropoffLong, DropoffLat: dropoffLat}
+		option := MultiLegRouteOption{Legs: []*JourneyLegOption{&first, &second}}
+		if err := rows.Scan(
+			&first.RouteId,
+			&first.DriverId,
+			&first.DriverName,
+			&first.DriverVerified,
+			&first.FreeSeats,
+			&first.DropoffLong,
+			&first.DropoffLat,
+			&first.PickupStartTime,
+			&first.PickupEndTime,
+			&second.RouteId,
+			&second.DriverId,
+			&second.DriverName,
+			&second.DriverVerified,
+			&second.FreeSeats,
+			&second.PickupLong,
+			&second.PickupLat,
+			&second.PickupStartTime,
+			&second.PickupEndTime,
+			&option.TransferDistance,
+			&option.TransferArrival,
+			&option.TransferDeparture,
+		); err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		options = append(options, &option)
+	}
+	return options, nil
+}
+
+const createJourneySQL = `
+	INSERT INTO journeys (rider_id, status)
+	VALUES ($1, $2)
+	RETURNING id, status, created_at, updated_at;
+`
+
+const createJourneyLegSQL = `
+	INSERT INTO journey_legs (journey_id, leg, request_id)
+	VALUES ($1, $2, $3);
+`
+
+// CreateJourney stores the journey and a request for each leg in one transaction, so that a rider never
+// ends up with half a journey
+func CreateJourney(journey *model.Journey) (*model.Journey, error) {
+	ctx := context.Background()
+	tx, err := DBClient.pgPool.Begin(ctx)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	if err := tx.QueryRow(ctx, createJourneySQL, journey.RiderId, constants.JourneyStatusActive).Scan(
+		&journey.Id,
+		&journey.Status,
+		&journey.CreatedAt,
+		&journey.UpdatedAt,
+	); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	for i, leg := range journey.Legs {
+		if _, err := createRequest(ctx, tx, leg); err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(ctx, createJourneyLegSQL, journey.Id, i+1, leg.Id); err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return journey, nil
+}
+
+const getJourneySQL = `
+	SELECT id, rider_id, status, created_at, updated_at
+	FROM journeys
+	WHERE id = $1;
+`
+
+const listJourneyLegsSQL = `
+	SELECT
+		r.id,
+		r.rider_id,
+		r.route_id,
+		ST_X(r.pickup_location), ST_Y(r.pickup_location),
+		ST_X(r.dropoff_location), ST_Y(r.dropoff_location),
+		r.pickup_start_time, r.pickup_end_time,
+		r.tips,
+		r.status,
+		r.women_only, r.smoking, r.pets, r.luggage, r.quiet,
+		r.seat_count, r.accepted_seats, r.meeting_point_id,
+		r.created_at, r.updated_at, r.deleted_at
+	FROM journey_legs l
+		JOIN requests r ON r.id = l.request_id
+	WHERE l.journey_id = $1
+	ORDER BY l.leg;
+`
+
+// GetJourney returns the journey with its legs in travel order, legs the rider deleted are kept so the
+// journey always shows both
+func GetJourney(id int32) (*model.Journey, error) {
+	var journey model.Journey
+	if err := DBClient.pgPool.QueryRow(context.Background(), getJourneySQL, id).Scan(
+		&journey.Id,
+		&journey.RiderId,
+		&journey.Status,
+		&journey.CreatedAt,
+		&journey.UpdatedAt,
+	); err != nil {
+		Logger.Error(err)
+		return nil, Match(err, pgx.ErrNoRows, ErrJourneyNotFound).Return()
+	}
+
+	rows, err := DBClient.pgPool.Query(context.Background(), listJourneyLegsSQL, id)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var request model.Request
+		if err := rows.Scan(
+			&request.Id,
+			&request.RiderId,
+			&request.RouteId,
+			&request.PickupLong,
+			&request.PickupLat,
+			&request.DropoffLong,
+			&request.DropoffLat,
+			&request.PickupStartTime,
+			&request.PickupEndTime,
+			&request.Tips,
+			&request.Status,
+			&request.WomenOnly,
+			&request.Smoking,
+			&request.Pets,
+			&request.Luggage,
+			&request.Quiet,
+			&request.SeatCount,
+			&request.AcceptedSeats,
+			&request.MeetingPointId,
+			&request.CreatedAt,
+			&request.UpdatedAt,
+			&request.DeletedAt,
+		); err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		journey.Legs = append(journey.Legs, &request)
+	}
+	return &journey, nil
+}
+
+const getRequestJourneyIdSQL = `
+	SELECT journey_id FROM journey_legs WHERE request_id = $1;
+`
+
+// GetRequestJourneyId tells which journey the request is a leg of, nil for a plain request
+func GetRequestJourneyId(requestId int32) (*int32, error) {
+	var journeyId int32
+	err := DBClient.pgPool.QueryRow(context.Background(), getRequestJourneyIdSQL, requestId).Scan(&journeyId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return &journeyId, nil
+}
+
+// CancelledJourneyLeg is a leg request cancelled together with its journey, Status is what it was before
+type CancelledJourneyLeg struct {
+	RequestId int32
+	RouteId   int32
+	DriverId  int32
+	Status    string
+}
+
+const cancelJourneySQL = `
+	UPDATE journeys SET status = $2, updated_at = NOW()
+	WHERE id = $1 AND status = $3;
+`
+
+// the previous status is read in a sub-select, RETURNING only sees the updated row
+const cancelJourneyLegsSQL = `
+	UPDATE requests q SET status = $3, updated_at = NOW()
+	FROM (
+		SELECT r.id, r.status, o.driver_id
+		FROM journey_legs l
+			JOIN requests r ON r.id = l.request_id
+			JOIN routes o ON o.id = r.route_id
+		WHERE l.journey_id = $1 AND r.id <> $2 AND r.status = ANY($4) AND r.deleted_at IS NULL
+		FOR UPDATE OF r
+	) old
+	WHERE q.id = old.id
+	RETURNING q.id, q.route_id, old.driver_id, old.status;
+`
+
+const cancelJourneyTripsSQL = `
+	UPDATE trips SET status = $2
+	WHERE request_id = ANY($1) AND status = $3 AND deleted_at IS NULL;
+`
+
+// CancelJourney cancels the journey and every leg still open except the given one, which the caller already
+// took care of, and the trips of legs that were booked
+func CancelJourney(id, exceptRequestId int32) ([]*CancelledJourneyLeg, error) {
+	ctx := context.Background()
+	tx, err := DBClient.pgPool.Begin(ctx)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, cancelJourneySQL, id, constants.JourneyStatusCancelled, constants.JourneyStatusActive)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrJourneyNotActive
+	}
+
+	rows, err := tx.Query(ctx, cancelJourneyLegsSQL, id, exceptRequestId, constants.RequestStatusCancelled, []string{
+		constants.RequestStatusWaitlisted,
+		constants.RequestStatusPending,
+		constants.RequestStatusCountered,
+		constants.RequestStatusOffered,
+		constants.RequestStatusAccepted,
+	})
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	var (
+		legs       []*CancelledJourneyLeg
+		requestIds []int32
+	)
+	for rows.Next() {
+		var leg CancelledJourneyLeg
+		if err := rows.Scan(&leg.RequestId, &leg.RouteId, &leg.DriverId, &leg.Status); err != nil {
+			rows.Close()
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		legs = append(legs, &leg)
+		requestIds = append(requestIds, leg.RequestId)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+
+	if _, err := tx.Exec(ctx, cancelJourneyTripsSQL, requestIds, constants.TripStatusCancelled, constants.TripStatusScheduled); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return legs, nil
+}
@@ -0,0 +1,149 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const testDeleteJourneyLegsSQL = `
+	DELETE FROM journey_legs WHERE journey_id = $1;
+`
+
+const testDeleteJourneySQL = `
+	DELETE FROM journeys WHERE id = $1;
+`
+
+var _ = Describe("DBJourney", func() {
+	var (
+		driverIds [2]int32
+		routeIds  [2]int32
+		journeyId int32
+		start     time.Time
+	)
+
+	BeforeEach(func() {
+		journeyId = 0
+		start = time.Now().Add(time.Hour).Truncate(time.Second)
+		for i, googleId := range []string{"test-journey-first", "test-journey-second"} {
+			err := pgPool.QueryRow(context.Background(), testCreateUserSQL, "driver", "test", googleId, "test").Scan(&driverIds[i])
+			Expect(err).NotTo(HaveOccurred())
+		}
+		// the first route heads east and ends where the second one starts heading north
+		err := pgPool.QueryRow(context.Background(), testCreateRouteSQL, driverIds[0], 0, 0, 0.1, 0, start, start.Add(time.Hour), 2).Scan(&routeIds[0])
+		Expect(err).NotTo(HaveOccurred())
+		err = pgPool.QueryRow(context.Background(), testCreateRouteSQL, driverIds[1], 0.1, 0, 0.1, 0.1, start.Add(75*time.Minute), start.Add(135*time.Minute), 2).Scan(&routeIds[1])
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_, err := pgPool.Exec(context.Background(), testDeleteJourneyLegsSQL, journeyId)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = pgPool.Exec(context.Background(), testDeleteJourneySQL, journeyId)
+		Expect(err).NotTo(HaveOccurred())
+		for i := range routeIds {
+			_, err := pgPool.Exec(context.Background(), testDeleteTripsByRouteSQL, routeIds[i])
+			Expect(err).NotTo(HaveOccurred())
+			_, err = pgPool.Exec(context.Background(), testDeleteRequestsByRouteSQL, routeIds[i])
+			Expect(err).NotTo(HaveOccurred())
+			_, err = pgPool.Exec(context.Background(), testDeleteRouteSQL, routeIds[i])
+			Expect(err).NotTo(HaveOccurred())
+			_, err = pgPool.Exec(context.Background(), testDeleteUserSQL, driverIds[i])
+			Expect(err).NotTo(HaveOccurred())
+		}
+	})
+
+	It("should combine two routes that meet in time", func() {
+		options, err := ListMultiLegRoutes(0.02, 0, 0.1, 0.08, start, start.Add(30*time.Minute), false, -1, "",
+			&model.RideRequirements{Luggage: constants.LuggageNone}, 1,
+			&TransferParams{WalkingDistance: 500, AccessDistance: 2000, Buffer: 10 * time.Minute, MaxWait: time.Hour},
+		)
+		Expect(err).NotTo(HaveOccurred())
+
+		var found *MultiLegRouteOption
+		for _, option := range options {
+			if option.Legs[0].RouteId == routeIds[0] && option.Legs[1].RouteId == routeIds[1] {
+				found = option
+			}
+		}
+		Expect(found).NotTo(BeNil())
+		Expect(found.TransferDistance).To(BeNumerically("<", 1))
+		Expect(found.TransferArrival).To(BeTemporally("~", start.Add(time.Hour), time.Second))
+		Expect(found.Legs[0].DropoffLong).To(BeNumerically("~", 0.1, 1e-9))
+		Expect(found.Legs[1].PickupStartTime).To(BeTemporally("~", start.Add(75*time.Minute), time.Second))
+
+		options, err = ListMultiLegRoutes(0.02, 0, 0.1, 0.08, start, start.Add(30*time.Minute), false, -1, "",
+			&model.RideRequirements{Luggage: constants.LuggageNone}, 1,
+			&TransferParams{WalkingDistance: 500, AccessDistance: 2000, Buffer: 20 * time.Minute, MaxWait: time.Hour},
+		)
+		Expect(err).NotTo(HaveOccurred())
+		for _, option := range options {
+			Expect(option.Legs[0].RouteId == routeIds[0] && option.Legs[1].RouteId == routeIds[1]).To(BeFalse())
+		}
+	})
+
+	It("should not combine routes passing far from the pickup", func() {
+		// about 5.5 km north of the first route
+		options, err := ListMultiLegRoutes(0.02, 0.05, 0.1, 0.08, start, start.Add(30*time.Minute), false, -1, "",
+			&model.RideRequirements{Luggage: constants.LuggageNone}, 1,
+			&TransferParams{WalkingDistance: 500, AccessDistance: 2000, Buffer: 10 * time.Minute, MaxWait: time.Hour},
+		)
+		Expect(err).NotTo(HaveOccurred())
+		for _, option := range options {
+			Expect(option.Legs[0].RouteId == routeIds[0] && option.Legs[1].RouteId == routeIds[1]).To(BeFalse())
+		}
+	})
+
+	It("should cancel the other leg once one is denied", func() {
+		var legs []*model.Request
+		for i, routeId := range routeIds {
+			legs = append(legs, &model.Request{
+				RiderId:          -1,
+				RouteId:          routeId,
+				PickupStartTime:  start.Add(time.Duration(i) * time.Hour),
+				PickupEndTime:    start.Add(time.Duration(i)*time.Hour + 30*time.Minute),
+				Status:           constants.RequestStatusPending,
+				SeatCount:        1,
+				RideRequirements: model.RideRequirements{Luggage: constants.LuggageNone},
+			})
+		}
+		journey, err := CreateJourney(&model.Journey{RiderId: -1, Legs: legs})
+		Expect(err).NotTo(HaveOccurred())
+		journeyId = journey.Id
+		Expect(journey.Status).To(Equal(constants.JourneyStatusActive))
+
+		legJourneyId, err := GetRequestJourneyId(legs[1].Id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*legJourneyId).To(Equal(journey.Id))
+
+		trip, err := AcceptRequest(legs[0].Id, 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(UpdateRequestStatus(legs[1].Id, constants.RequestStatusDenied)).To(Succeed())
+
+		cancelled, err := CancelJourney(journey.Id, legs[1].Id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cancelled).To(HaveLen(1))
+		Expect(cancelled[0].RequestId).To(Equal(legs[0].Id))
+		Expect(cancelled[0].DriverId).To(Equal(driverIds[0]))
+		Expect(cancelled[0].Status).To(Equal(constants.RequestStatusAccepted))
+
+		stored, err := GetJourney(journey.Id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stored.Status).To(Equal(constants.JourneyStatusCancelled))
+		Expect(stored.Legs).To(HaveLen(2))
+		Expect(stored.Legs[0].Status).To(Equal(constants.RequestStatusCancelled))
+		Expect(stored.Legs[1].Status).To(Equal(constants.RequestStatusDenied))
+
+		cancelledTrip, err := GetTrip(trip.Id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cancelledTrip.Status).To(Equal(constants.TripStatusCancelled))
+
+		_, err = CancelJourney(journey.Id, 0)
+		Expect(err).To(MatchError(ErrJourneyNotActive))
+	})
+})
@@ -40,6 +40,78 @@ func InitDBClient(pgPool *pgxpool.Pool) error {
 		log.Println("Init trips table failed")
 		return err
 	}
+	if err := initSessionTable(); err != nil {
+		log.Println("Init sessions table failed")
+		return err
+	}
+	if err := initUserIdentityTable(); err != nil {
+		log.Println("Init user identities table failed")
+		return err
+	}
+	if err := initEmailLoginTokenTable(); err != nil {
+		log.Println("Init email login tokens table failed")
+		return err
+	}
+	if err := initAuditLogTable(); err != nil {
+		log.Println("Init audit logs table failed")
+		return err
+	}
+	if err := initVehicleTable(); err != nil {
+		log.Println("Init vehicles table failed")
+		return err
+	}
+	if err := initDriverDocumentTable(); err != nil {
+		log.Println("Init driver documents table failed")
+		return err
+	}
+	if err := initUserBlockTable(); err != nil {
+		log.Println("Init user blocks table failed")
+		return err
+	}
+	if err := initUserReportTable(); err != nil {
+		log.Println("Init user reports table failed")
+		return err
+	}
+	if err := initRidePreferenceTable(); err != nil {
+		log.Println("Init ride preferences table failed")
+		return err
+	}
+	if err := initNotificationTable(); err != nil {
+		log.Println("Init notifications table failed")
+		return err
+	}
+	if err := initMeetingPointTable(); err != nil {
+		log.Println("Init meeting points table failed")
+		return err
+	}
+	if err := initRequestProposalTable(); err != nil {
+		log.Println("Init request proposals table failed")
+		return err
+	}
+	if err := initInstantRideTable(); err != nil {
+		log.Println("Init instant ride tables failed")
+		return err
+	}
+	if err := initJourneyTable(); err != nil {
+		log.Println("Init journeys table failed")
+		return err
+	}
+	if err := initAssignmentProposalTable(); err != nil {
+		log.Println("Init assignment proposals table failed")
+		return err
+	}
+	if err := initSentReminderTable(); err != nil {
+		log.Println("Init sent reminders table failed")
+		return err
+	}
+	if err := initRouteSearchTable(); err != nil {
+		log.Println("Init route searches table failed")
+		return err
+	}
+	if err := initRoutePathTable(); err != nil {
+		log.Println("Init route paths table failed")
+		return err
+	}
 
 	// init logger
 	logger, _ := zap.NewProduction()
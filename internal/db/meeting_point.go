@@ -0,0 +1,224 @@
+package db
+
+import (
+	"context"
+
+	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/DenChenn/blunder/pkg/blunder"
+	"github.com/jackc/pgx/v5"
+)
+
+const createMeetingPointTableSQL = `
+	CREATE EXTENSION IF NOT EXISTS postgis;
+
+	CREATE TABLE IF NOT EXISTS meeting_points (
+		id SERIAL PRIMARY KEY,
+		name VARCHAR(200) NOT NULL,
+		location GEOMETRY(Point, 4326) NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL,
+		deleted_at TIMESTAMP WITH TIME ZONE
+	);
+
+	CREATE INDEX IF NOT EXISTS meeting_points_location_idx
+		ON meeting_points USING gist((location::geography)) WHERE deleted_at IS NULL;
+
+	-- requests are created before meeting points, so their reference is only added here
+	DO $$
+	BEGIN
+		ALTER TABLE requests ADD CONSTRAINT requests_meeting_point_id_fkey
+			FOREIGN KEY (meeting_point_id) REFERENCES meeting_points(id);
+	EXCEPTION WHEN duplicate_object THEN NULL;
+	END $$;
+`
+
+func initMeetingPointTable() error {
+	if _, err := DBClient.pgPool.Exec(context.Background(), createMeetingPointTableSQL); err != nil {
+		return err
+	}
+	return nil
+}
+
+const listMeetingPointsSQL = `
+	SELECT id, name, ST_X(location), ST_Y(location), created_at, deleted_at
+	FROM meeting_points
+	WHERE deleted_at IS NULL
+	ORDER BY id DESC
+	LIMIT $1 OFFSET $2;
+`
+
+func ListMeetingPoints(limit, offset int32) ([]*model.MeetingPoint, error) {
+	rows, err := DBClient.pgPool.Query(context.Background(), listMeetingPointsSQL, limit, offset)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer rows.Close()
+
+	var meetingPoints []*model.MeetingPoint
+	for rows.Next() {
+		meetingPoint, err := scanMeetingPoint(rows)
+		if err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		meetingPoints = append(meetingPoints, meetingPoint)
+	}
+	return meetingPoints, nil
+}
+
+func scanMeetingPoint(row pgx.Row) (*model.MeetingPoint, error) {
+	var meetingPoint model.MeetingPoint
+	if err := row.Scan(
+		&meetingPoint.Id,
+		&meetingPoint.Name,
+		&meetingPoint.Long,
+		&meetingPoint.Lat,
+		&meetingPoint.CreatedAt,
+		&meetingPoint.DeletedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &meetingPoint, nil
+}
+
+// the route path is the straight line between its endpoints, as for the suggestions
+const getMeetingPointDistanceSQL = `
+	SELECT
+		mp.id, mp.name, ST_X(mp.location), ST_Y(mp.location), mp.created_at, mp.deleted_at,
+		ST_Distance(mp.location::geography, ST_SetSRID(ST_MakePoint($3, $4), 4326)::geography),
+		ST_Distance(mp.location::geography, ST_MakeLine(r.start_location, r.end_location)::geography)
+	FROM meeting_points mp, routes r
+	WHERE mp.id = $1 AND mp.deleted_at IS NULL AND r.id = $2;
+`
+
+// GetMeetingPointDistance loads the meeting point together with its distance in meters from the given point
+// and from the route
+func GetMeetingPointDistance(id, routeId int32, long, lat float64) (*model.MeetingPoint, float64, float64, error) {
+	var (
+		meetingPoint  model.MeetingPoint
+		distance      float64
+		routeDistance float64
+	)
+	if err := DBClient.pgPool.QueryRow(context.Background(), getMeetingPointDistanceSQL, id, routeId, long, lat).Scan(
+		&meetingPoint.Id,
+		&meetingPoint.Name,
+		&meetingPoint.Long,
+		&meetingPoint.Lat,
+		&meetingPoint.CreatedAt,
+		&meetingPoint.DeletedAt,
+		&distance,
+		&routeDistance,
+	); err != nil {
+		Logger.Error(err)
+		return nil, 0, 0, Match(err, pgx.ErrNoRows, ErrMeetingPointNotFound).Return()
+	}
+	return &meetingPoint, distance, routeDistance, nil
+}
+
+const createMeetingPointSQL = `
+	INSERT INTO meeting_points (name, location)
+	VALUES ($1, ST_SetSRID(ST_MakePoint($2, $3), 4326))
+	RETURNING id, name, ST_X(location), ST_Y(location), created_at, deleted_at;
+`
+
+func CreateMeetingPoint(meetingPoint *model.MeetingPoint) (*model.MeetingPoint, error) {
+	created, err := scanMeetingPoint(DBClient.pgPool.QueryRow(context.Background(), createMeetingPointSQL,
+		meetingPoint.Name,
+		meetingPoint.Long,
+		meetingPoint.Lat,
+	))
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return created, nil
+}
+
+const deleteMeetingPointSQL = `
+	UPDATE meeting_points SET deleted_at = NOW()
+	WHERE id = $1 AND deleted_at IS NULL
+	RETURNING id;
+`
+
+func DeleteMeetingPoint(id int32) error {
+	if err := DBClient.pgPool.QueryRow(context.Background(), deleteMeetingPointSQL, id).Scan(&id); err != nil {
+		Logger.Error(err)
+		return Match(err, pgx.ErrNoRows, ErrMeetingPointNotFound).Return()
+	}
+	return nil
+}
+
+// routes only keep their endpoints, so the path is taken as the straight line between them. Curated spots
+// within walking distance are suggested together with the closest point on the path itself, the ones
+// closest to the path first since they cost the driver the least detour.
+const suggestMeetingPointsSQL = `
+	WITH route_path AS (
+		SELECT ST_MakeLine(start_location, end_location) AS line
+		FROM routes
+		WHERE id = $1 AND deleted_at IS NULL
+	), rider AS (
+		SELECT ST_SetSRID(ST_MakePoint($2, $3), 4326) AS point
+	), suggestions AS (
+		SELECT
+			mp.id,
+			mp.name,
+			mp.location,
+			ST_Distance(mp.location::geography, rider.point::geography) AS walking_distance,
+			ST_Distance(mp.location::geography, route_path.line::geography) AS detour_distance
+		FROM meeting_points mp, route_path, rider
+		WHERE mp.deleted_at IS NULL
+			AND ST_DWithin(mp.location::geography, rider.point::geography, $4)
+		UNION ALL
+		SELECT
+			NULL,
+			'',
+			projected.location,
+			ST_Distance(projected.location::geography, rider.point::geography),
+			0
+		FROM (SELECT ST_ClosestPoint(route_path.line, rider.point) AS location FROM route_path, rider) projected, rider
+		WHERE ST_DWithin(projected.location::geography, rider.point::geography, $4)
+	)
+	SELECT id, name, ST_X(location), ST_Y(location), walking_distance, detour_distance
+	FROM suggestions
+	ORDER BY detour_distance, walking_distance
+	LIMIT $5;
+`
+
+type MeetingPointSuggestion struct {
+	MeetingPointId  *int32  `json:"meetingPointId,omitempty"`
+	Name            string  `json:"name"`
+	Long            float64 `json:"long"`
+	Lat             float64 `json:"lat"`
+	WalkingDistance float64 `json:"walkingDistance"`
+	DetourDistance  float64 `json:"detourDistance"`
+}
+
+// SuggestMeetingPoints lists places on or near the route within walkingDistance meters of the rider's point,
+// a suggestion without a meeting point id is a point on the route itself
+func SuggestMeetingPoints(routeId int32, long, lat float64, walkingDistance float64, limit int32) ([]*MeetingPointSuggestion, error) {
+	rows, err := DBClient.pgPool.Query(context.Background(), suggestMeetingPointsSQL, routeId, long, lat, walkingDistance, limit)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer rows.Close()
+
+	var suggestions []*MeetingPointSuggestion
+	for rows.Next() {
+		var suggestion MeetingPointSuggestion
+		if err := rows.Scan(
+			&suggestion.MeetingPointId,
+			&suggestion.Name,
+			&suggestion.Long,
+			&suggestion.Lat,
+			&suggestion.WalkingDistance,
+			&suggestion.DetourDistance,
+		); err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		suggestions = append(suggestions, &suggestion)
+	}
+	return suggestions, nil
+}
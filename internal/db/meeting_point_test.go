@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const testDeleteMeetingPointSQL = `
+	DELETE FROM meeting_points WHERE id = $1;
+`
+
+var _ = Describe("DBMeetingPoint", func() {
+	var (
+		routeId int32
+		near    *model.MeetingPoint
+		far     *model.MeetingPoint
+	)
+
+	BeforeEach(func() {
+		err := pgPool.QueryRow(context.Background(), testCreateRouteSQL, -1, 0, 0, 0.01, 0, time.Now(), time.Now(), 2).Scan(&routeId)
+		Expect(err).NotTo(HaveOccurred())
+
+		near, err = CreateMeetingPoint(&model.MeetingPoint{Name: "near", Long: 0.005, Lat: 0.001})
+		Expect(err).NotTo(HaveOccurred())
+		far, err = CreateMeetingPoint(&model.MeetingPoint{Name: "far", Long: 0.005, Lat: 0.02})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		for _, id := range []int32{near.Id, far.Id} {
+			_, err := pgPool.Exec(context.Background(), testDeleteMeetingPointSQL, id)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		_, err := pgPool.Exec(context.Background(), testDeleteRouteSQL, routeId)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("SuggestMeetingPoints", func() {
+		It("should suggest the point on the route before curated spots off it", func() {
+			suggestions, err := SuggestMeetingPoints(routeId, 0.005, 0.002, 500, 5)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(suggestions).To(HaveLen(2))
+
+			Expect(suggestions[0].MeetingPointId).To(BeNil())
+			Expect(suggestions[0].DetourDistance).To(BeZero())
+			Expect(suggestions[0].Long).To(BeNumerically("~", 0.005, 1e-9))
+
+			Expect(*suggestions[1].MeetingPointId).To(Equal(near.Id))
+			Expect(suggestions[1].DetourDistance).To(BeNumerically("~", 111, 1))
+		})
+	})
+
+	Describe("GetMeetingPointDistance", func() {
+		It("should measure from both the pickup and the route", func() {
+			_, distance, routeDistance, err := GetMeetingPointDistance(far.Id, routeId, 0.005, 0.02)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(distance).To(BeNumerically("~", 0, 1))
+			Expect(routeDistance).To(BeNumerically("~", 2211, 20))
+		})
+	})
+
+	Describe("DeleteMeetingPoint", func() {
+		It("should no longer be found once deleted", func() {
+			Expect(DeleteMeetingPoint(near.Id)).To(Succeed())
+			_, _, _, err := GetMeetingPointDistance(near.Id, routeId, 0, 0)
+			Expect(err).To(MatchError(ErrMeetingPointNotFound))
+		})
+	})
+})
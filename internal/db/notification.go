@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+
+	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/DenChenn/blunder/pkg/blunder"
+	"github.com/jackc/pgx/v5"
+)
+
+const createNotificationTableSQL = `
+	CREATE TABLE IF NOT EXISTS notifications (
+		id SERIAL,
+		user_id INT NOT NULL,
+		kind VARCHAR(50) NOT NULL,
+		title VARCHAR(200) NOT NULL,
+		body VARCHAR(2000) DEFAULT '' NOT NULL,
+		data JSONB,
+		read_at TIMESTAMP WITH TIME ZONE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS notifications_user_id_idx ON notifications (user_id, created_at);
+`
+
+func initNotificationTable() error {
+	if _, err := DBClient.pgPool.Exec(context.Background(), createNotificationTableSQL); err != nil {
+		return err
+	}
+	return nil
+}
+
+const createNotificationSQL = `
+	INSERT INTO notifications (user_id, kind, title, body, data)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id, created_at;
+`
+
+func CreateNotification(notification *model.Notification) (*model.Notification, error) {
+	if err := DBClient.pgPool.QueryRow(context.Background(), createNotificationSQL,
+		notification.UserId,
+		notification.Kind,
+		notification.Title,
+		notification.Body,
+		notification.Data,
+	).Scan(
+		&notification.Id,
+		&notification.CreatedAt,
+	); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return notification, nil
+}
+
+const listNotificationsByUserIdSQL = `
+	SELECT id, user_id, kind, title, body, data, read_at, created_at
+	FROM notifications
+	WHERE user_id = $1 AND ($2 = FALSE OR read_at IS NULL)
+	ORDER BY created_at DESC
+	LIMIT $3 OFFSET $4;
+`
+
+func ListNotificationsByUserId(userId int32, unreadOnly bool, limit, offset int32) ([]*model.Notification, error) {
+	rows, err := DBClient.pgPool.Query(context.Background(), listNotificationsByUserIdSQL, userId, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []*model.Notification{}
+	for rows.Next() {
+		var notification model.Notification
+		if err := rows.Scan(
+			&notification.Id,
+			&notification.UserId,
+			&notification.Kind,
+			&notification.Title,
+			&notification.Body,
+			&notification.Data,
+			&notification.ReadAt,
+			&notification.CreatedAt,
+		); err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		notifications = append(notifications, &notification)
+	}
+	return notifications, nil
+}
+
+// reading a notification twice keeps the first read time
+const markNotificationReadSQL = `
+	UPDATE notifications SET read_at = COALESCE(read_at, NOW())
+	WHERE id = $1 AND user_id = $2
+	RETURNING id;
+`
+
+func MarkNotificationRead(userId, id int32) error {
+	if err := DBClient.pgPool.QueryRow(context.Background(), markNotificationReadSQL, id, userId).Scan(&id); err != nil {
+		Logger.Error(err)
+		return Match(err, pgx.ErrNoRows, ErrNotificationNotFound).Return()
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+
+	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const testDeleteNotificationsSQL = `
+	DELETE FROM notifications WHERE user_id = $1;
+`
+
+var _ = Describe("DBNotification", func() {
+	var notification *model.Notification
+
+	BeforeEach(func() {
+		var err error
+		notification, err = CreateNotification(&model.Notification{UserId: -1, Kind: "test", Title: "test"})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_, err := pgPool.Exec(context.Background(), testDeleteNotificationsSQL, -1)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("MarkNotificationRead", func() {
+		It("should leave read notifications out of the unread list", func() {
+			Expect(MarkNotificationRead(-1, notification.Id)).To(Succeed())
+
+			notifications, err := ListNotificationsByUserId(-1, true, 50, 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(notifications).To(BeEmpty())
+
+			notifications, err = ListNotificationsByUserId(-1, false, 50, 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(notifications).To(HaveLen(1))
+		})
+
+		It("should return error for another user's notification", func() {
+			Expect(MarkNotificationRead(-2, notification.Id)).To(MatchError(ErrNotificationNotFound))
+		})
+	})
+})
@@ -0,0 +1,163 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	. "github.com/DenChenn/blunder/pkg/blunder"
+)
+
+const createSentReminderTableSQL = `
+	CREATE TABLE IF NOT EXISTS sent_reminders (
+		kind VARCHAR(50) NOT NULL,
+		entity_id INT NOT NULL,
+		slot VARCHAR(50) NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL,
+		PRIMARY KEY (kind, entity_id, slot)
+	);
+`
+
+func initSentReminderTable() error {
+	if _, err := DBClient.pgPool.Exec(context.Background(), createSentReminderTableSQL); err != nil {
+		return err
+	}
+	return nil
+}
+
+const claimReminderSQL = `
+	INSERT INTO sent_reminders (kind, entity_id, slot)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (kind, entity_id, slot) DO NOTHING;
+`
+
+// ClaimReminder records that a reminder is being sent, false means it was already sent before
+func ClaimReminder(kind string, entityId int32, slot string) (bool, error) {
+	tag, err := DBClient.pgPool.Exec(context.Background(), claimReminderSQL, kind, entityId, slot)
+	if err != nil {
+		Logger.Error(err)
+		return false, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// reminders about trips and routes that ended, or no longer exist, can never be claimed again
+const purgeSentRemindersSQL = `
+	DELETE FROM sent_reminders s
+	WHERE (s.kind = $2 AND NOT EXISTS (
+			SELECT 1 FROM trips t
+				JOIN routes r ON t.route_id = r.id
+			WHERE t.id = s.entity_id AND r.end_time >= $1
+		))
+		OR (s.kind = ANY($3) AND NOT EXISTS (
+			SELECT 1 FROM routes r WHERE r.id = s.entity_id AND r.end_time >= $1
+		));
+`
+
+// PurgeSentReminders drops the reminders of trips and routes that ended before the given time
+func PurgeSentReminders(before time.Time) (int64, error) {
+	tag, err := DBClient.pgPool.Exec(context.Background(), purgeSentRemindersSQL,
+		before,
+		constants.NotificationKindPickupReminder,
+		[]string{constants.NotificationKindPendingRequests, constants.NotificationKindRouteDemand},
+	)
+	if err != nil {
+		Logger.Error(err)
+		return 0, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return tag.RowsAffected(), nil
+}
+
+const listUpcomingPickupsSQL = `
+	SELECT t.id, t.rider_id, t.driver_id, t.request_id, t.route_id, q.pickup_start_time
+	FROM trips t
+		JOIN requests q ON t.request_id = q.id
+	WHERE t.status = $1 AND t.deleted_at IS NULL
+		AND q.status = $3 AND q.deleted_at IS NULL
+		AND q.pickup_start_time > NOW() AND q.pickup_start_time <= $2
+	ORDER BY q.pickup_start_time;
+`
+
+type UpcomingPickup struct {
+	TripId          int32     `json:"tripId"`
+	RiderId         int32     `json:"riderId"`
+	DriverId        int32     `json:"driverId"`
+	RequestId       int32     `json:"requestId"`
+	RouteId         int32     `json:"routeId"`
+	PickupStartTime time.Time `json:"pickupStartTime"`
+}
+
+// ListUpcomingPickups lists scheduled trips of accepted requests whose pickup starts between now and the given time
+func ListUpcomingPickups(before time.Time) ([]*UpcomingPickup, error) {
+	rows, err := DBClient.pgPool.Query(context.Background(), listUpcomingPickupsSQL,
+		constants.TripStatusScheduled,
+		before,
+		constants.RequestStatusAccepted,
+	)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer rows.Close()
+
+	var pickups []*UpcomingPickup
+	for rows.Next() {
+		var pickup UpcomingPickup
+		if err := rows.Scan(
+			&pickup.TripId,
+			&pickup.RiderId,
+			&pickup.DriverId,
+			&pickup.RequestId,
+			&pickup.RouteId,
+			&pickup.PickupStartTime,
+		); err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		pickups = append(pickups, &pickup)
+	}
+	return pickups, nil
+}
+
+const listPendingRequestDigestsSQL = `
+	SELECT r.id, r.driver_id, r.start_time, COUNT(q.id)
+	FROM routes r
+		JOIN requests q ON q.route_id = r.id AND q.status = $1 AND q.deleted_at IS NULL
+	WHERE r.deleted_at IS NULL AND r.start_time > NOW() AND r.start_time <= $2
+	GROUP BY r.id
+	ORDER BY r.start_time;
+`
+
+type PendingRequestDigest struct {
+	RouteId      int32     `json:"routeId"`
+	DriverId     int32     `json:"driverId"`
+	StartTime    time.Time `json:"startTime"`
+	PendingCount int32     `json:"pendingCount"`
+}
+
+// ListPendingRequestDigests lists routes starting between now and the given time that still have
+// requests waiting for the driver
+func ListPendingRequestDigests(before time.Time) ([]*PendingRequestDigest, error) {
+	rows, err := DBClient.pgPool.Query(context.Background(), listPendingRequestDigestsSQL, constants.RequestStatusPending, before)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer rows.Close()
+
+	var digests []*PendingRequestDigest
+	for rows.Next() {
+		var digest PendingRequestDigest
+		if err := rows.Scan(
+			&digest.RouteId,
+			&digest.DriverId,
+			&digest.StartTime,
+			&digest.PendingCount,
+		); err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		digests = append(digests, &digest)
+	}
+	return digests, nil
+}
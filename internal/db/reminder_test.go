@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	"github.com/CoRide-tw/backend/internal/model"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const testDeleteSentRemindersSQL = `
+	DELETE FROM sent_reminders WHERE entity_id = $1;
+`
+
+var _ = Describe("DBReminder", func() {
+	AfterEach(func() {
+		_, err := pgPool.Exec(context.Background(), testDeleteSentRemindersSQL, -1)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("ClaimReminder", func() {
+		It("should only be claimed once per slot", func() {
+			claimed, err := ClaimReminder("test", -1, "1h0m0s")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(claimed).To(BeTrue())
+
+			claimed, err = ClaimReminder("test", -1, "1h0m0s")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(claimed).To(BeFalse())
+
+			claimed, err = ClaimReminder("test", -1, "15m0s")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(claimed).To(BeTrue())
+		})
+	})
+
+	Describe("PurgeSentReminders", func() {
+		var endedRouteId, upcomingRouteId int32
+
+		BeforeEach(func() {
+			err := pgPool.QueryRow(context.Background(), testCreateRouteSQL, -1, 0, 0, 0.01, 0, time.Now().Add(-49*time.Hour), time.Now().Add(-48*time.Hour), 2).Scan(&endedRouteId)
+			Expect(err).NotTo(HaveOccurred())
+			err = pgPool.QueryRow(context.Background(), testCreateRouteSQL, -1, 0, 0, 0.01, 0, time.Now().Add(time.Hour), time.Now().Add(2*time.Hour), 2).Scan(&upcomingRouteId)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			for _, id := range []int32{endedRouteId, upcomingRouteId} {
+				_, err := pgPool.Exec(context.Background(), testDeleteSentRemindersSQL, id)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = pgPool.Exec(context.Background(), testDeleteRouteSQL, id)
+				Expect(err).NotTo(HaveOccurred())
+			}
+		})
+
+		It("should only forget reminders of routes that ended before the cutoff", func() {
+			for _, id := range []int32{endedRouteId, upcomingRouteId} {
+				claimed, err := ClaimReminder(constants.NotificationKindPendingRequests, id, "before_start")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(claimed).To(BeTrue())
+			}
+
+			_, err := PurgeSentReminders(time.Now().Add(-24 * time.Hour))
+			Expect(err).NotTo(HaveOccurred())
+
+			claimed, err := ClaimReminder(constants.NotificationKindPendingRequests, endedRouteId, "before_start")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(claimed).To(BeTrue())
+
+			claimed, err = ClaimReminder(constants.NotificationKindPendingRequests, upcomingRouteId, "before_start")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(claimed).To(BeFalse())
+		})
+	})
+
+	Describe("ListUpcomingPickups", func() {
+		var routeId int32
+
+		BeforeEach(func() {
+			err := pgPool.QueryRow(context.Background(), testCreateRouteSQL, -1, 0, 0, 0.01, 0, time.Now().Add(time.Hour), time.Now().Add(2*time.Hour), 2).Scan(&routeId)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			_, err := pgPool.Exec(context.Background(), testDeleteTripsByRouteSQL, routeId)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = pgPool.Exec(context.Background(), testDeleteRequestsByRouteSQL, routeId)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = pgPool.Exec(context.Background(), testDeleteRouteSQL, routeId)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should skip trips whose request is no longer accepted", func() {
+			request, err := CreateRequest(&model.Request{
+				RiderId:          -1,
+				RouteId:          routeId,
+				PickupStartTime:  time.Now().Add(time.Hour),
+				PickupEndTime:    time.Now().Add(2 * time.Hour),
+				Status:           constants.RequestStatusPending,
+				SeatCount:        1,
+				RideRequirements: model.RideRequirements{Luggage: constants.LuggageNone},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			trip, err := AcceptRequest(request.Id, 0)
+			Expect(err).NotTo(HaveOccurred())
+
+			tripIds := func() []int32 {
+				pickups, err := ListUpcomingPickups(time.Now().Add(3 * time.Hour))
+				Expect(err).NotTo(HaveOccurred())
+				var ids []int32
+				for _, pickup := range pickups {
+					ids = append(ids, pickup.TripId)
+				}
+				return ids
+			}
+			Expect(tripIds()).To(ContainElement(trip.Id))
+
+			Expect(UpdateRequestStatus(request.Id, constants.RequestStatusCancelled)).To(Succeed())
+			Expect(tripIds()).NotTo(ContainElement(trip.Id))
+		})
+	})
+})
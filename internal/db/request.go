@@ -31,6 +31,17 @@ const createRequestTable = `
 
 	CREATE INDEX IF NOT EXISTS requests_pickup_location_dropoff_location_idx 
 		ON requests USING gist(pickup_location, dropoff_location);
+
+	-- requests booked a single seat before riders could travel as a group
+	ALTER TABLE requests ADD COLUMN IF NOT EXISTS seat_count INT DEFAULT 1 NOT NULL;
+	ALTER TABLE requests ADD COLUMN IF NOT EXISTS accepted_seats INT;
+	UPDATE requests SET accepted_seats = CASE WHEN status IN ('accepted', 'completed') THEN seat_count ELSE 0 END
+	WHERE accepted_seats IS NULL;
+	ALTER TABLE requests ALTER COLUMN accepted_seats SET DEFAULT 0;
+	ALTER TABLE requests ALTER COLUMN accepted_seats SET NOT NULL;
+	CREATE INDEX IF NOT EXISTS requests_route_id_idx ON requests (route_id);
+
+	ALTER TABLE requests ADD COLUMN IF NOT EXISTS meeting_point_id INT;
 `
 
 func initRequestTable() error {
@@ -50,6 +61,8 @@ const getRequestSQL = `
 		pickup_start_time, pickup_end_time,
 		tips,
 		status,
+		women_only, smoking, pets, luggage, quiet,
+		seat_count, accepted_seats, meeting_point_id,
 		created_at, updated_at
 	FROM requests
 	WHERE id = $1 AND deleted_at IS NULL;
@@ -69,6 +82,14 @@ func GetRequest(id int32) (*model.Request, error) {
 		&request.PickupEndTime,
 		&request.Tips,
 		&request.Status,
+		&request.WomenOnly,
+		&request.Smoking,
+		&request.Pets,
+		&request.Luggage,
+		&request.Quiet,
+		&request.SeatCount,
+		&request.AcceptedSeats,
+		&request.MeetingPointId,
 		&request.CreatedAt,
 		&request.UpdatedAt,
 	); err != nil {
@@ -89,6 +110,8 @@ const listRequestsByRiderIdSQL = `
 		pickup_end_time,
 		tips,
 		status,
+		women_only, smoking, pets, luggage, quiet,
+		seat_count, accepted_seats, meeting_point_id,
 		created_at, 
 		updated_at
 	FROM requests
@@ -117,6 +140,14 @@ func ListRequestsByRiderId(riderId int32) ([]*model.Request, error) {
 			&request.PickupEndTime,
 			&request.Tips,
 			&request.Status,
+			&request.WomenOnly,
+			&request.Smoking,
+			&request.Pets,
+			&request.Luggage,
+			&request.Quiet,
+			&request.SeatCount,
+			&request.AcceptedSeats,
+			&request.MeetingPointId,
 			&request.CreatedAt,
 			&request.UpdatedAt,
 		); err != nil {
@@ -139,13 +170,20 @@ const listRequestsByRouteIdSQL = `
 		r.pickup_end_time,
 		r.tips,
 		r.status,
+		r.women_only, r.smoking, r.pets, r.luggage, r.quiet,
+		r.seat_count, r.accepted_seats, r.meeting_point_id,
 		u.name,
 		u.picture_url,
 		r.created_at, 
 		r.updated_at
 	FROM requests r
 		JOIN users u ON r.rider_id = u.id
-	WHERE r.route_id = $1 AND r.deleted_at IS NULL;
+	WHERE r.route_id = $1 AND r.deleted_at IS NULL
+		AND NOT EXISTS (
+			SELECT 1 FROM user_blocks b
+			WHERE (b.blocker_id = $2 AND b.blocked_id = r.rider_id)
+				OR (b.blocker_id = r.rider_id AND b.blocked_id = $2)
+		);
 `
 
 type ListRequestsByRouteIdResp struct {
@@ -160,15 +198,20 @@ type ListRequestsByRouteIdResp struct {
 	PickupEndTime   time.Time  `json:"pickupEndTime"`
 	Tips            int32      `json:"tips"`
 	Status          string     `json:"status"`
+	SeatCount       int32      `json:"seatCount"`
+	AcceptedSeats   int32      `json:"acceptedSeats"`
+	MeetingPointId  *int32     `json:"meetingPointId,omitempty"`
 	RiderName       string     `json:"riderName"`
 	RiderPictureUrl string     `json:"riderPictureUrl"`
 	CreatedAt       time.Time  `json:"createdAt"`
 	UpdatedAt       time.Time  `json:"updatedAt"`
 	DeletedAt       *time.Time `json:"deletedAt,omitempty"`
+	model.RideRequirements
 }
 
-func ListRequestsByRouteId(routeId int32) ([]*ListRequestsByRouteIdResp, error) {
-	rows, err := DBClient.pgPool.Query(context.Background(), listRequestsByRouteIdSQL, routeId)
+// requests from riders blocked by or blocking the viewer are left out
+func ListRequestsByRouteId(routeId, viewerId int32) ([]*ListRequestsByRouteIdResp, error) {
+	rows, err := DBClient.pgPool.Query(context.Background(), listRequestsByRouteIdSQL, routeId, viewerId)
 	if err != nil {
 		return nil, err
 	}
@@ -189,6 +232,14 @@ func ListRequestsByRouteId(routeId int32) ([]*ListRequestsByRouteIdResp, error)
 			&request.PickupEndTime,
 			&request.Tips,
 			&request.Status,
+			&request.WomenOnly,
+			&request.Smoking,
+			&request.Pets,
+			&request.Luggage,
+			&request.Quiet,
+			&request.SeatCount,
+			&request.AcceptedSeats,
+			&request.MeetingPointId,
 			&request.RiderName,
 			&request.RiderPictureUrl,
 			&request.CreatedAt,
@@ -203,7 +254,10 @@ func ListRequestsByRouteId(routeId int32) ([]*ListRequestsByRouteIdResp, error)
 }
 
 const createRequestSQL = `
-	INSERT INTO requests (rider_id, route_id, pickup_location, dropoff_location, pickup_start_time, pickup_end_time, tips, status)
+	INSERT INTO requests (
+		rider_id, route_id, pickup_location, dropoff_location, pickup_start_time, pickup_end_time, tips, status,
+		women_only, smoking, pets, luggage, quiet, seat_count, meeting_point_id
+	)
 	VALUES (
 		$1,
 		$2,
@@ -212,13 +266,24 @@ const createRequestSQL = `
 		$7,
 		$8,
 		$9,
-		$10
+		$10,
+		$11, $12, $13, $14, $15, $16, $17
 	)
 	RETURNING id, status, created_at, updated_at;
 `
 
+// CreateRequest stores the request with the status the caller picked, pending or waitlisted
 func CreateRequest(request *model.Request) (*model.Request, error) {
-	if err := DBClient.pgPool.QueryRow(context.Background(), createRequestSQL,
+	return createRequest(context.Background(), DBClient.pgPool, request)
+}
+
+// rowQuerier is either the pool or a transaction
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+func createRequest(ctx context.Context, q rowQuerier, request *model.Request) (*model.Request, error) {
+	if err := q.QueryRow(ctx, createRequestSQL,
 		request.RiderId,
 		request.RouteId,
 		request.PickupLong,
@@ -228,7 +293,14 @@ func CreateRequest(request *model.Request) (*model.Request, error) {
 		request.PickupStartTime,
 		request.PickupEndTime,
 		request.Tips,
-		constants.RequestStatusPending,
+		request.Status,
+		request.WomenOnly,
+		request.Smoking,
+		request.Pets,
+		request.Luggage,
+		request.Quiet,
+		request.SeatCount,
+		request.MeetingPointId,
 	).Scan(
 		&request.Id,
 		&request.Status,
@@ -248,6 +320,25 @@ const updateRequestStatusSQL = `
 	WHERE id = $1 AND deleted_at IS NULL
 `
 
+// only requests the driver has not committed seats to can be denied
+const denyRequestSQL = `
+	UPDATE requests SET
+		status = $2,
+		updated_at = NOW()
+	WHERE id = $1 AND status = ANY($3) AND deleted_at IS NULL
+	RETURNING id;
+`
+
+func DenyRequest(id int32) error {
+	if err := DBClient.pgPool.QueryRow(context.Background(), denyRequestSQL,
+		id, constants.RequestStatusDenied, constants.DeniableRequestStatuses,
+	).Scan(&id); err != nil {
+		Logger.Error(err)
+		return Match(err, pgx.ErrNoRows, ErrRequestNotPending).Return()
+	}
+	return nil
+}
+
 func UpdateRequestStatus(id int32, status string) error {
 	if _, err := DBClient.pgPool.Exec(context.Background(), updateRequestStatusSQL,
 		id, status,
@@ -262,16 +353,216 @@ const deleteRequestSQL = `
 	UPDATE requests SET
 		status = $2,
 		deleted_at = NOW()
-	WHERE id = $1 AND deleted_at IS NULL
+	WHERE id = $1 AND status <> ALL($3) AND deleted_at IS NULL
+	RETURNING id;
 `
 
+const cancelRequestTripSQL = `
+	UPDATE trips SET status = $2
+	WHERE request_id = $1 AND status = ANY($3) AND deleted_at IS NULL;
+`
+
+// DeleteRequest cancels the request together with the trip it may already have, completed and cancelled
+// requests are kept as they are
 func DeleteRequest(id int32) error {
-	if _, err := DBClient.pgPool.Exec(context.Background(), deleteRequestSQL,
+	ctx := context.Background()
+	tx, err := DBClient.pgPool.Begin(ctx)
+	if err != nil {
+		Logger.Error(err)
+		return ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	if err := tx.QueryRow(ctx, deleteRequestSQL,
 		id,
 		constants.RequestStatusCancelled,
+		[]string{constants.RequestStatusCompleted, constants.RequestStatusCancelled},
+	).Scan(&id); err != nil {
+		Logger.Error(err)
+		return Match(err, pgx.ErrNoRows, ErrRequestNotCancellable).Return()
+	}
+	if _, err := tx.Exec(ctx, cancelRequestTripSQL,
+		id,
+		constants.TripStatusCancelled,
+		[]string{constants.TripStatusScheduled, constants.TripStatusEnRoute},
 	); err != nil {
 		Logger.Error(err)
 		return ErrUndefined.WithCustomMessage(err.Error())
 	}
+
+	if err := tx.Commit(ctx); err != nil {
+		Logger.Error(err)
+		return ErrUndefined.WithCustomMessage(err.Error())
+	}
 	return nil
 }
+
+const lockRequestSQL = `
+	SELECT route_id, seat_count, status
+	FROM requests
+	WHERE id = $1 AND deleted_at IS NULL
+	FOR UPDATE;
+`
+
+// locking the route row serialises every seat change on it
+const lockRouteSeatsSQL = `
+	SELECT
+		r.driver_id,
+		r.capacity - COALESCE((
+			SELECT SUM(q.accepted_seats) FROM requests q
+			WHERE q.route_id = r.id AND q.status = ANY($2) AND q.deleted_at IS NULL
+		), 0)
+	FROM routes r
+	WHERE r.id = $1 AND r.deleted_at IS NULL
+	FOR UPDATE;
+`
+
+const acceptRequestSeatsSQL = `
+	UPDATE requests SET status = $2, accepted_seats = $3, updated_at = NOW()
+	WHERE id = $1;
+`
+
+const createTripFromRequestSQL = `
+	INSERT INTO trips (rider_id, driver_id, request_id, route_id)
+	SELECT q.rider_id, r.driver_id, q.id, q.route_id
+	FROM requests q
+		JOIN routes r ON q.route_id = r.id
+	WHERE q.id = $1
+	RETURNING id, rider_id, driver_id, request_id, route_id, status, created_at;
+`
+
+// AcceptRequest gives a pending request seats on its route, zero seats means every requested seat.
+// Accepting all of them books the trip right away, fewer seats are offered to the rider to confirm
+// and the returned trip is nil.
+func AcceptRequest(id, seats int32) (*model.Trip, error) {
+	ctx := context.Background()
+	tx, err := DBClient.pgPool.Begin(ctx)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	trip, err := acceptRequest(ctx, tx, id, seats)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return trip, nil
+}
+
+func acceptRequest(ctx context.Context, tx pgx.Tx, id, seats int32) (*model.Trip, error) {
+	var (
+		routeId   int32
+		seatCount int32
+		status    string
+	)
+	if err := tx.QueryRow(ctx, lockRequestSQL, id).Scan(&routeId, &seatCount, &status); err != nil {
+		Logger.Error(err)
+		return nil, Match(err, pgx.ErrNoRows, ErrRequestNotFound).Return()
+	}
+	if status != constants.RequestStatusPending {
+		return nil, ErrRequestNotPending
+	}
+	if seats == 0 {
+		seats = seatCount
+	}
+
+	var driverId, freeSeats int32
+	if err := tx.QueryRow(ctx, lockRouteSeatsSQL, routeId, constants.SeatHoldingRequestStatuses).Scan(&driverId, &freeSeats); err != nil {
+		Logger.Error(err)
+		return nil, Match(err, pgx.ErrNoRows, ErrRouteNotFound).Return()
+	}
+	if seats > freeSeats {
+		return nil, ErrRouteSeatsUnavailable
+	}
+
+	var trip *model.Trip
+	if seats < seatCount {
+		if _, err := tx.Exec(ctx, acceptRequestSeatsSQL, id, constants.RequestStatusOffered, seats); err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+	} else {
+		if _, err := tx.Exec(ctx, acceptRequestSeatsSQL, id, constants.RequestStatusAccepted, seats); err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		var err error
+		if trip, err = createTripFromRequest(ctx, tx, id); err != nil {
+			return nil, err
+		}
+	}
+	return trip, nil
+}
+
+const confirmRequestOfferSQL = `
+	UPDATE requests SET status = $2, updated_at = NOW()
+	WHERE id = $1 AND status = $3 AND deleted_at IS NULL
+	RETURNING id;
+`
+
+// ConfirmRequestOffer lets the rider take the seats a driver offered, the seats were held since the offer
+func ConfirmRequestOffer(id int32) (*model.Trip, error) {
+	ctx := context.Background()
+	tx, err := DBClient.pgPool.Begin(ctx)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	if err := tx.QueryRow(ctx, confirmRequestOfferSQL, id, constants.RequestStatusAccepted, constants.RequestStatusOffered).Scan(&id); err != nil {
+		Logger.Error(err)
+		return nil, Match(err, pgx.ErrNoRows, ErrRequestNotOffered).Return()
+	}
+	trip, err := createTripFromRequest(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return trip, nil
+}
+
+func createTripFromRequest(ctx context.Context, tx pgx.Tx, requestId int32) (*model.Trip, error) {
+	var trip model.Trip
+	if err := tx.QueryRow(ctx, createTripFromRequestSQL, requestId).Scan(
+		&trip.Id,
+		&trip.RiderId,
+		&trip.DriverId,
+		&trip.RequestId,
+		&trip.RouteId,
+		&trip.Status,
+		&trip.CreatedAt,
+	); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return &trip, nil
+}
+
+const getRouteFreeSeatsSQL = `
+	SELECT r.capacity - COALESCE((
+		SELECT SUM(q.accepted_seats) FROM requests q
+		WHERE q.route_id = r.id AND q.status = ANY($2) AND q.deleted_at IS NULL
+	), 0)
+	FROM routes r
+	WHERE r.id = $1 AND r.deleted_at IS NULL;
+`
+
+func GetRouteFreeSeats(routeId int32) (int32, error) {
+	var freeSeats int32
+	if err := DBClient.pgPool.QueryRow(context.Background(), getRouteFreeSeatsSQL, routeId, constants.SeatHoldingRequestStatuses).Scan(&freeSeats); err != nil {
+		Logger.Error(err)
+		return 0, Match(err, pgx.ErrNoRows, ErrRouteNotFound).Return()
+	}
+	return freeSeats, nil
+}
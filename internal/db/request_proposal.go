@@ -0,0 +1,243 @@
+package db
+
+import (
+	"context"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/DenChenn/blunder/pkg/blunder"
+	"github.com/jackc/pgx/v5"
+)
+
+const createRequestProposalTableSQL = `
+	CREATE TABLE IF NOT EXISTS request_proposals (
+		id SERIAL PRIMARY KEY,
+		request_id INT NOT NULL,
+		round INT NOT NULL,
+		proposer_id INT NOT NULL,
+		pickup_start_time TIMESTAMP WITH TIME ZONE,
+		pickup_end_time TIMESTAMP WITH TIME ZONE,
+		pickup_location GEOMETRY(Point, 4326),
+		tips INT,
+		status VARCHAR(50) DEFAULT 'open' NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL,
+		resolved_at TIMESTAMP WITH TIME ZONE,
+		UNIQUE (request_id, round)
+	);
+`
+
+func initRequestProposalTable() error {
+	if _, err := DBClient.pgPool.Exec(context.Background(), createRequestProposalTableSQL); err != nil {
+		return err
+	}
+	return nil
+}
+
+const listRequestProposalsSQL = `
+	SELECT
+		id, request_id, round, proposer_id,
+		pickup_start_time, pickup_end_time,
+		ST_X(pickup_location), ST_Y(pickup_location),
+		tips, status, created_at, resolved_at
+	FROM request_proposals
+	WHERE request_id = $1
+	ORDER BY round;
+`
+
+func ListRequestProposals(requestId int32) ([]*model.RequestProposal, error) {
+	rows, err := DBClient.pgPool.Query(context.Background(), listRequestProposalsSQL, requestId)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer rows.Close()
+
+	var proposals []*model.RequestProposal
+	for rows.Next() {
+		proposal, err := scanRequestProposal(rows)
+		if err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		proposals = append(proposals, proposal)
+	}
+	return proposals, nil
+}
+
+func scanRequestProposal(row pgx.Row) (*model.RequestProposal, error) {
+	var proposal model.RequestProposal
+	if err := row.Scan(
+		&proposal.Id,
+		&proposal.RequestId,
+		&proposal.Round,
+		&proposal.ProposerId,
+		&proposal.PickupStartTime,
+		&proposal.PickupEndTime,
+		&proposal.PickupLong,
+		&proposal.PickupLat,
+		&proposal.Tips,
+		&proposal.Status,
+		&proposal.CreatedAt,
+		&proposal.ResolvedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &proposal, nil
+}
+
+// a meeting point left out of the proposal makes ST_MakePoint NULL, keeping the column NULL
+const createRequestProposalSQL = `
+	INSERT INTO request_proposals (request_id, round, proposer_id, pickup_start_time, pickup_end_time, pickup_location, tips)
+	SELECT
+		$1,
+		COALESCE(MAX(round), 0) + 1,
+		$2,
+		$3,
+		$4,
+		ST_SetSRID(ST_MakePoint($5::float8, $6::float8), 4326),
+		$7
+	FROM request_proposals
+	WHERE request_id = $1
+	RETURNING
+		id, request_id, round, proposer_id,
+		pickup_start_time, pickup_end_time,
+		ST_X(pickup_location), ST_Y(pickup_location),
+		tips, status, created_at, resolved_at;
+`
+
+// CreateRequestProposal opens a new negotiation round on a pending request, the request waits for the
+// rider's answer until the proposal is accepted or rejected
+func CreateRequestProposal(proposal *model.RequestProposal) (*model.RequestProposal, error) {
+	ctx := context.Background()
+	tx, err := DBClient.pgPool.Begin(ctx)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		routeId   int32
+		seatCount int32
+		status    string
+	)
+	if err := tx.QueryRow(ctx, lockRequestSQL, proposal.RequestId).Scan(&routeId, &seatCount, &status); err != nil {
+		Logger.Error(err)
+		return nil, Match(err, pgx.ErrNoRows, ErrRequestNotFound).Return()
+	}
+	if status != constants.RequestStatusPending {
+		return nil, ErrRequestNotPending
+	}
+
+	created, err := scanRequestProposal(tx.QueryRow(ctx, createRequestProposalSQL,
+		proposal.RequestId,
+		proposal.ProposerId,
+		proposal.PickupStartTime,
+		proposal.PickupEndTime,
+		proposal.PickupLong,
+		proposal.PickupLat,
+		proposal.Tips,
+	))
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	if _, err := tx.Exec(ctx, updateRequestStatusSQL, proposal.RequestId, constants.RequestStatusCountered); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return created, nil
+}
+
+const resolveRequestProposalSQL = `
+	UPDATE request_proposals SET status = $2, resolved_at = NOW()
+	WHERE request_id = $1 AND status = $3
+	RETURNING id;
+`
+
+// the request takes the proposed terms and goes back to pending so that it can be accepted as usual
+const applyRequestProposalSQL = `
+	UPDATE requests q SET
+		pickup_start_time = COALESCE(p.pickup_start_time, q.pickup_start_time),
+		pickup_end_time = COALESCE(p.pickup_end_time, q.pickup_end_time),
+		pickup_location = COALESCE(p.pickup_location, q.pickup_location),
+		tips = COALESCE(p.tips, q.tips),
+		status = $2,
+		updated_at = NOW()
+	FROM request_proposals p
+	WHERE p.id = $1 AND q.id = p.request_id;
+`
+
+// AcceptRequestProposal lets the rider agree to the open proposal, the request takes its terms and is
+// accepted with every requested seat in the same transaction
+func AcceptRequestProposal(requestId int32) (*model.Trip, error) {
+	ctx := context.Background()
+	tx, err := DBClient.pgPool.Begin(ctx)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	var proposalId int32
+	if err := tx.QueryRow(ctx, resolveRequestProposalSQL,
+		requestId,
+		constants.RequestProposalStatusAccepted,
+		constants.RequestProposalStatusOpen,
+	).Scan(&proposalId); err != nil {
+		Logger.Error(err)
+		return nil, Match(err, pgx.ErrNoRows, ErrRequestProposalNotOpen).Return()
+	}
+	if _, err := tx.Exec(ctx, applyRequestProposalSQL, proposalId, constants.RequestStatusPending); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	trip, err := acceptRequest(ctx, tx, requestId, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return trip, nil
+}
+
+// RejectRequestProposal turns down the open proposal, the request goes back to pending for the driver to
+// accept, deny or counter again
+func RejectRequestProposal(requestId int32) error {
+	ctx := context.Background()
+	tx, err := DBClient.pgPool.Begin(ctx)
+	if err != nil {
+		Logger.Error(err)
+		return ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	var proposalId int32
+	if err := tx.QueryRow(ctx, resolveRequestProposalSQL,
+		requestId,
+		constants.RequestProposalStatusRejected,
+		constants.RequestProposalStatusOpen,
+	).Scan(&proposalId); err != nil {
+		Logger.Error(err)
+		return Match(err, pgx.ErrNoRows, ErrRequestProposalNotOpen).Return()
+	}
+	if _, err := tx.Exec(ctx, updateRequestStatusSQL, requestId, constants.RequestStatusPending); err != nil {
+		Logger.Error(err)
+		return ErrUndefined.WithCustomMessage(err.Error())
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		Logger.Error(err)
+		return ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const testDeleteRequestProposalsSQL = `
+	DELETE FROM request_proposals WHERE request_id = $1;
+`
+
+var _ = Describe("DBRequestProposal", func() {
+	var (
+		routeId int32
+		request *model.Request
+	)
+
+	BeforeEach(func() {
+		err := pgPool.QueryRow(context.Background(), testCreateRouteSQL, -1, 0, 0, 0, 0, time.Now(), time.Now(), 2).Scan(&routeId)
+		Expect(err).NotTo(HaveOccurred())
+
+		request, err = CreateRequest(&model.Request{
+			RiderId:          -1,
+			RouteId:          routeId,
+			PickupStartTime:  time.Now(),
+			PickupEndTime:    time.Now(),
+			Tips:             10,
+			Status:           constants.RequestStatusPending,
+			SeatCount:        1,
+			RideRequirements: model.RideRequirements{Luggage: constants.LuggageNone},
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_, err := pgPool.Exec(context.Background(), testDeleteRequestProposalsSQL, request.Id)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = pgPool.Exec(context.Background(), testDeleteTripsByRouteSQL, routeId)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = pgPool.Exec(context.Background(), testDeleteRequestsByRouteSQL, routeId)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = pgPool.Exec(context.Background(), testDeleteRouteSQL, routeId)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should book the ride with the proposed terms once accepted", func() {
+		tips := int32(50)
+		proposal, err := CreateRequestProposal(&model.RequestProposal{RequestId: request.Id, ProposerId: -1, Tips: &tips})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(proposal.Round).To(Equal(int32(1)))
+		Expect(proposal.PickupLong).To(BeNil())
+
+		countered, err := GetRequest(request.Id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(countered.Status).To(Equal(constants.RequestStatusCountered))
+
+		trip, err := AcceptRequestProposal(request.Id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(trip.RequestId).To(Equal(request.Id))
+
+		accepted, err := GetRequest(request.Id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(accepted.Status).To(Equal(constants.RequestStatusAccepted))
+		Expect(accepted.Tips).To(Equal(tips))
+	})
+
+	It("should open a new round after a rejection", func() {
+		tips := int32(50)
+		_, err := CreateRequestProposal(&model.RequestProposal{RequestId: request.Id, ProposerId: -1, Tips: &tips})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(RejectRequestProposal(request.Id)).To(Succeed())
+		Expect(RejectRequestProposal(request.Id)).To(MatchError(ErrRequestProposalNotOpen))
+
+		long, lat := 121.5, 25.0
+		proposal, err := CreateRequestProposal(&model.RequestProposal{RequestId: request.Id, ProposerId: -1, PickupLong: &long, PickupLat: &lat})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(proposal.Round).To(Equal(int32(2)))
+		Expect(*proposal.PickupLong).To(Equal(long))
+
+		proposals, err := ListRequestProposals(request.Id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(proposals).To(HaveLen(2))
+		Expect(proposals[0].Status).To(Equal(constants.RequestProposalStatusRejected))
+	})
+})
@@ -298,3 +298,126 @@ var _ = Describe("DBRequest", func() {
 		})
 	})
 })
+
+const testDeleteTripsByRouteSQL = `
+	DELETE FROM trips WHERE route_id = $1;
+`
+
+const testDeleteRequestsByRouteSQL = `
+	DELETE FROM requests WHERE route_id = $1;
+`
+
+var _ = Describe("DBRequestSeats", func() {
+	var routeId int32
+
+	newRequest := func(seatCount int32) *model.Request {
+		request, err := CreateRequest(&model.Request{
+			RiderId:          -1,
+			RouteId:          routeId,
+			PickupStartTime:  time.Now(),
+			PickupEndTime:    time.Now(),
+			Status:           constants.RequestStatusPending,
+			SeatCount:        seatCount,
+			RideRequirements: model.RideRequirements{Luggage: constants.LuggageNone},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		return request
+	}
+
+	BeforeEach(func() {
+		err := pgPool.QueryRow(context.Background(), testCreateRouteSQL, -1, 0, 0, 0, 0, time.Now(), time.Now(), 2).Scan(&routeId)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_, err := pgPool.Exec(context.Background(), testDeleteTripsByRouteSQL, routeId)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = pgPool.Exec(context.Background(), testDeleteRequestsByRouteSQL, routeId)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = pgPool.Exec(context.Background(), testDeleteRouteSQL, routeId)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("AcceptRequest", func() {
+		It("should book every seat and create the trip", func() {
+			request := newRequest(2)
+
+			trip, err := AcceptRequest(request.Id, 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(trip.RequestId).To(Equal(request.Id))
+
+			freeSeats, err := GetRouteFreeSeats(routeId)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(freeSeats).To(BeZero())
+		})
+
+		It("should hold offered seats until the rider confirms", func() {
+			request := newRequest(2)
+
+			trip, err := AcceptRequest(request.Id, 1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(trip).To(BeNil())
+
+			freeSeats, err := GetRouteFreeSeats(routeId)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(freeSeats).To(Equal(int32(1)))
+
+			trip, err = ConfirmRequestOffer(request.Id)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(trip.RequestId).To(Equal(request.Id))
+
+			_, err = ConfirmRequestOffer(request.Id)
+			Expect(err).To(MatchError(ErrRequestNotOffered))
+		})
+
+		It("should not overbook the route", func() {
+			_, err := AcceptRequest(newRequest(2).Id, 0)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = AcceptRequest(newRequest(1).Id, 0)
+			Expect(err).To(MatchError(ErrRouteSeatsUnavailable))
+		})
+
+		It("should only accept pending requests", func() {
+			request := newRequest(1)
+			_, err := AcceptRequest(request.Id, 0)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = AcceptRequest(request.Id, 0)
+			Expect(err).To(MatchError(ErrRequestNotPending))
+		})
+	})
+
+	Describe("DenyRequest", func() {
+		It("should only deny requests without seats", func() {
+			pending := newRequest(1)
+			Expect(DenyRequest(pending.Id)).To(Succeed())
+
+			accepted := newRequest(1)
+			_, err := AcceptRequest(accepted.Id, 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(DenyRequest(accepted.Id)).To(MatchError(ErrRequestNotPending))
+		})
+	})
+
+	Describe("DeleteRequest", func() {
+		It("should cancel the trip of an accepted request", func() {
+			request := newRequest(1)
+			trip, err := AcceptRequest(request.Id, 0)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(DeleteRequest(request.Id)).To(Succeed())
+
+			trip, err = GetTrip(trip.Id)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(trip.Status).To(Equal(constants.TripStatusCancelled))
+		})
+
+		It("should keep completed requests", func() {
+			request := newRequest(1)
+			Expect(UpdateRequestStatus(request.Id, constants.RequestStatusCompleted)).To(Succeed())
+
+			Expect(DeleteRequest(request.Id)).To(MatchError(ErrRequestNotCancellable))
+		})
+	})
+})
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/DenChenn/blunder/pkg/blunder"
+	"github.com/jackc/pgx/v5"
+)
+
+const createRidePreferenceTableSQL = `
+	CREATE TABLE IF NOT EXISTS ride_preferences (
+		user_id INT PRIMARY KEY,
+		gender VARCHAR(20) DEFAULT '' NOT NULL,
+		women_only BOOLEAN DEFAULT FALSE NOT NULL,
+		smoking BOOLEAN DEFAULT FALSE NOT NULL,
+		pets BOOLEAN DEFAULT FALSE NOT NULL,
+		luggage VARCHAR(20) DEFAULT 'none' NOT NULL,
+		quiet BOOLEAN DEFAULT FALSE NOT NULL,
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL
+	);
+
+	ALTER TABLE routes ADD COLUMN IF NOT EXISTS women_only BOOLEAN DEFAULT FALSE NOT NULL;
+	ALTER TABLE routes ADD COLUMN IF NOT EXISTS smoking_allowed BOOLEAN DEFAULT FALSE NOT NULL;
+	ALTER TABLE routes ADD COLUMN IF NOT EXISTS pets_allowed BOOLEAN DEFAULT FALSE NOT NULL;
+	ALTER TABLE routes ADD COLUMN IF NOT EXISTS max_luggage VARCHAR(20) DEFAULT 'small' NOT NULL;
+	ALTER TABLE routes ADD COLUMN IF NOT EXISTS music BOOLEAN DEFAULT FALSE NOT NULL;
+
+	ALTER TABLE requests ADD COLUMN IF NOT EXISTS women_only BOOLEAN DEFAULT FALSE NOT NULL;
+	ALTER TABLE requests ADD COLUMN IF NOT EXISTS smoking BOOLEAN DEFAULT FALSE NOT NULL;
+	ALTER TABLE requests ADD COLUMN IF NOT EXISTS pets BOOLEAN DEFAULT FALSE NOT NULL;
+	ALTER TABLE requests ADD COLUMN IF NOT EXISTS luggage VARCHAR(20) DEFAULT 'none' NOT NULL;
+	ALTER TABLE requests ADD COLUMN IF NOT EXISTS quiet BOOLEAN DEFAULT FALSE NOT NULL;
+`
+
+func initRidePreferenceTable() error {
+	if _, err := DBClient.pgPool.Exec(context.Background(), createRidePreferenceTableSQL); err != nil {
+		return err
+	}
+	return nil
+}
+
+// users without saved preferences get the column defaults
+const getRidePreferencesSQL = `
+	SELECT
+		u.id,
+		COALESCE(p.gender, ''),
+		COALESCE(p.women_only, FALSE),
+		COALESCE(p.smoking, FALSE),
+		COALESCE(p.pets, FALSE),
+		COALESCE(p.luggage, $2),
+		COALESCE(p.quiet, FALSE),
+		COALESCE(p.updated_at, u.created_at)
+	FROM users u
+		LEFT JOIN ride_preferences p ON p.user_id = u.id
+	WHERE u.id = $1 AND u.deleted_at IS NULL;
+`
+
+func GetRidePreferences(userId int32) (*model.RidePreferences, error) {
+	var preferences model.RidePreferences
+	if err := DBClient.pgPool.QueryRow(context.Background(), getRidePreferencesSQL, userId, constants.LuggageNone).Scan(
+		&preferences.UserId,
+		&preferences.Gender,
+		&preferences.WomenOnly,
+		&preferences.Smoking,
+		&preferences.Pets,
+		&preferences.Luggage,
+		&preferences.Quiet,
+		&preferences.UpdatedAt,
+	); err != nil {
+		Logger.Error(err)
+		return nil, Match(err, pgx.ErrNoRows, ErrUserNotFound).Return()
+	}
+	return &preferences, nil
+}
+
+const upsertRidePreferencesSQL = `
+	INSERT INTO ride_preferences (user_id, gender, women_only, smoking, pets, luggage, quiet)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (user_id) DO UPDATE SET
+		gender = EXCLUDED.gender,
+		women_only = EXCLUDED.women_only,
+		smoking = EXCLUDED.smoking,
+		pets = EXCLUDED.pets,
+		luggage = EXCLUDED.luggage,
+		quiet = EXCLUDED.quiet,
+		updated_at = NOW()
+	RETURNING updated_at;
+`
+
+func UpsertRidePreferences(preferences *model.RidePreferences) (*model.RidePreferences, error) {
+	if err := DBClient.pgPool.QueryRow(context.Background(), upsertRidePreferencesSQL,
+		preferences.UserId,
+		preferences.Gender,
+		preferences.WomenOnly,
+		preferences.Smoking,
+		preferences.Pets,
+		preferences.Luggage,
+		preferences.Quiet,
+	).Scan(&preferences.UpdatedAt); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return preferences, nil
+}
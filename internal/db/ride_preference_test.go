@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const testDeleteRidePreferencesSQL = `
+	DELETE FROM ride_preferences WHERE user_id = $1;
+`
+
+var _ = Describe("DBRidePreference", func() {
+	var userId int32
+
+	BeforeEach(func() {
+		err := pgPool.QueryRow(context.Background(), testCreateUserSQL, "test", "test", "test-ride-preference", "test").Scan(&userId)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_, err := pgPool.Exec(context.Background(), testDeleteRidePreferencesSQL, userId)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = pgPool.Exec(context.Background(), testDeleteUserSQL, userId)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("GetRidePreferences", func() {
+		It("should return defaults before anything is saved", func() {
+			preferences, err := GetRidePreferences(userId)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(preferences.Gender).To(BeEmpty())
+			Expect(preferences.Luggage).To(Equal(constants.LuggageNone))
+		})
+
+		It("should return error for an unknown user", func() {
+			_, err := GetRidePreferences(-1)
+			Expect(err).To(MatchError(ErrUserNotFound))
+		})
+	})
+
+	Describe("UpsertRidePreferences", func() {
+		It("should overwrite saved preferences", func() {
+			_, err := UpsertRidePreferences(&model.RidePreferences{UserId: userId, RideRequirements: model.RideRequirements{Luggage: constants.LuggageNone}})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = UpsertRidePreferences(&model.RidePreferences{
+				UserId:           userId,
+				Gender:           constants.GenderFemale,
+				RideRequirements: model.RideRequirements{WomenOnly: true, Luggage: constants.LuggageLarge},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			preferences, err := GetRidePreferences(userId)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(preferences.WomenOnly).To(BeTrue())
+			Expect(preferences.Luggage).To(Equal(constants.LuggageLarge))
+		})
+	})
+})
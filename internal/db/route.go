@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/CoRide-tw/backend/internal/constants"
 	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
 	"github.com/CoRide-tw/backend/internal/model"
 	. "github.com/DenChenn/blunder/pkg/blunder"
@@ -28,6 +29,9 @@ const createRouteTableSQL = `
 
 	CREATE INDEX IF NOT EXISTS routes_start_location_end_location_idx 
 		ON routes USING gist(start_location, end_location);
+
+	ALTER TABLE routes ADD COLUMN IF NOT EXISTS waitlist_by_tips BOOLEAN DEFAULT FALSE NOT NULL;
+	ALTER TABLE routes ADD COLUMN IF NOT EXISTS waitlist_auto_accept BOOLEAN DEFAULT FALSE NOT NULL;
 `
 
 func initRouteTable() error {
@@ -45,6 +49,9 @@ const getRouteSQL = `
 		ST_X(end_location), ST_Y(end_location), 
 		start_time, end_time, 
 		capacity, 
+		vehicle_id,
+		women_only, smoking_allowed, pets_allowed, max_luggage, music,
+		waitlist_by_tips, waitlist_auto_accept,
 		created_at, updated_at, deleted_at
 	FROM routes
 	WHERE id = $1 AND deleted_at IS NULL;
@@ -62,6 +69,14 @@ func GetRoute(id int32) (*model.Route, error) {
 		&route.StartTime,
 		&route.EndTime,
 		&route.Capacity,
+		&route.VehicleId,
+		&route.WomenOnly,
+		&route.SmokingAllowed,
+		&route.PetsAllowed,
+		&route.MaxLuggage,
+		&route.Music,
+		&route.WaitlistByTips,
+		&route.WaitlistAutoAccept,
 		&route.CreatedAt,
 		&route.UpdatedAt,
 		&route.DeletedAt,
@@ -79,7 +94,16 @@ const listNearestRouteSQL = `
 			ST_SetSRID(ST_MakePoint($1, $2), 4326) AS pickup_point,
 			ST_SetSRID(ST_MakePoint($3, $4), 4326) AS dropoff_point,
 			$5::timestamp with time zone AS pickup_start_time,
-			$6::timestamp with time zone AS pickup_end_time
+			$6::timestamp with time zone AS pickup_end_time,
+			$7::boolean AS verified_only,
+			$8::int AS rider_id,
+			$9::varchar AS rider_gender,
+			$10::boolean AS women_only,
+			$11::boolean AS smoking,
+			$12::boolean AS pets,
+			array_position($15::text[], $13) AS luggage_size,
+			$14::boolean AS quiet,
+			$16::int AS seats
 	)
 	SELECT 
 		r.id,
@@ -97,16 +121,53 @@ const listNearestRouteSQL = `
 		u.name,
 		u.picture_url,
 		u.car_type,
-		u.car_plate
-	FROM rider_requirements, routes r 
+		u.car_plate,
+		u.driver_verified_at IS NOT NULL,
+		v.id,
+		v.make,
+		v.model,
+		v.color,
+		v.plate,
+		v.seat_count,
+		v.amenities,
+		r.women_only,
+		r.smoking_allowed,
+		r.pets_allowed,
+		r.max_luggage,
+		r.music,
+		s.free_seats
+	FROM rider_requirements rr, routes r 
 		JOIN users u ON r.driver_id = u.id
+		LEFT JOIN vehicles v ON r.vehicle_id = v.id
+		LEFT JOIN ride_preferences dp ON r.driver_id = dp.user_id
+		CROSS JOIN LATERAL (
+			SELECT r.capacity - COALESCE(SUM(q.accepted_seats), 0) AS free_seats
+			FROM requests q
+			WHERE q.route_id = r.id AND q.status = ANY($17) AND q.deleted_at IS NULL
+		) s
 	WHERE 
 		r.deleted_at IS NULL 
 		AND start_time <= (SELECT pickup_start_time FROM rider_requirements)
 		AND end_time >= (SELECT pickup_end_time FROM rider_requirements)
+		AND (NOT (SELECT verified_only FROM rider_requirements) OR u.driver_verified_at IS NOT NULL)
+		AND NOT EXISTS (
+			SELECT 1 FROM user_blocks b
+			WHERE (b.blocker_id = r.driver_id AND b.blocked_id = rr.rider_id)
+				OR (b.blocker_id = rr.rider_id AND b.blocked_id = r.driver_id)
+		)
+		-- hard requirements on either side rule a route out
+		AND (NOT r.women_only OR rr.rider_gender = 'female')
+		AND (NOT rr.women_only OR dp.gender = 'female')
+		AND (NOT rr.smoking OR r.smoking_allowed)
+		AND (NOT rr.pets OR r.pets_allowed)
+		AND rr.luggage_size <= array_position($15::text[], r.max_luggage)
+		AND s.free_seats >= rr.seats
+	-- soft preferences only push a route down, each mismatch costs half its distance again
 	ORDER BY (
 		ST_Distance(start_location, (SELECT pickup_point FROM rider_requirements)) + 
 		ST_Distance(end_location, (SELECT dropoff_point FROM rider_requirements))
+	) * (
+		1 + 0.5 * ((NOT rr.smoking AND r.smoking_allowed)::int + (rr.quiet AND r.music)::int)
 	) ASC
 	LIMIT 30
 `
@@ -128,14 +189,31 @@ type ListNearestRoutesQueryResp struct {
 	DriverPictureUrl *string    `json:"driverPictureUrl"`
 	DriverCarType    *string    `json:"driverCarType"`
 	DriverCarPlate   *string    `json:"driverCarPlate"`
+	DriverVerified   bool       `json:"driverVerified"`
+	VehicleId        *int32     `json:"vehicleId"`
+	VehicleMake      *string    `json:"vehicleMake"`
+	VehicleModel     *string    `json:"vehicleModel"`
+	VehicleColor     *string    `json:"vehicleColor"`
+	VehiclePlate     *string    `json:"vehiclePlate"`
+	VehicleSeatCount *int32     `json:"vehicleSeatCount"`
+	VehicleAmenities []string   `json:"vehicleAmenities"`
+	FreeSeats        int32      `json:"freeSeats"`
+	model.RouteRules
 }
 
 func ListNearestRoutes(
 	pickupLong, pickupLat, dropoffLong, dropoffLat float64,
 	pickupStartTime, pickupEndTime time.Time,
+	verifiedOnly bool,
+	riderId int32,
+	riderGender string,
+	requirements *model.RideRequirements,
+	seats int32,
 ) ([]*ListNearestRoutesQueryResp, error) {
 	rows, err := DBClient.pgPool.Query(context.Background(), listNearestRouteSQL,
-		pickupLong, pickupLat, dropoffLong, dropoffLat, pickupStartTime, pickupEndTime)
+		pickupLong, pickupLat, dropoffLong, dropoffLat, pickupStartTime, pickupEndTime, verifiedOnly, riderId,
+		riderGender, requirements.WomenOnly, requirements.Smoking, requirements.Pets, requirements.Luggage, requirements.Quiet,
+		constants.LuggageSizes, seats, constants.SeatHoldingRequestStatuses)
 	if err != nil {
 		return nil, err
 	}
@@ -161,6 +239,20 @@ func ListNearestRoutes(
 			&item.DriverPictureUrl,
 			&item.DriverCarType,
 			&item.DriverCarPlate,
+			&item.DriverVerified,
+			&item.VehicleId,
+			&item.VehicleMake,
+			&item.VehicleModel,
+			&item.VehicleColor,
+			&item.VehiclePlate,
+			&item.VehicleSeatCount,
+			&item.VehicleAmenities,
+			&item.WomenOnly,
+			&item.SmokingAllowed,
+			&item.PetsAllowed,
+			&item.MaxLuggage,
+			&item.Music,
+			&item.FreeSeats,
 		); err != nil {
 			Logger.Error(err)
 			return nil, ErrUndefined.WithCustomMessage(err.Error())
@@ -171,20 +263,31 @@ func ListNearestRoutes(
 }
 
 const createRouteSQL = `
-	INSERT INTO routes (driver_id, start_location, end_location, start_time, end_time, capacity)
+	INSERT INTO routes (
+		driver_id, start_location, end_location, start_time, end_time, capacity, vehicle_id,
+		women_only, smoking_allowed, pets_allowed, max_luggage, music,
+		waitlist_by_tips, waitlist_auto_accept
+	)
 	VALUES (
 		$1, 
 		ST_SetSRID(ST_MakePoint($2, $3), 4326), 
 		ST_SetSRID(ST_MakePoint($4, $5), 4326), 
 		$6, 
 		$7, 
-		$8
+		$8,
+		$9,
+		$10, $11, $12, $13, $14,
+		$15, $16
 	)
 	RETURNING id, created_at, updated_at;
 `
 
 func CreateRoute(route *model.Route) (*model.Route, error) {
-	if err := DBClient.pgPool.QueryRow(context.Background(), createRouteSQL,
+	return createRoute(context.Background(), DBClient.pgPool, route)
+}
+
+func createRoute(ctx context.Context, q rowQuerier, route *model.Route) (*model.Route, error) {
+	if err := q.QueryRow(ctx, createRouteSQL,
 		route.DriverId,
 		route.StartLong,
 		route.StartLat,
@@ -193,6 +296,14 @@ func CreateRoute(route *model.Route) (*model.Route, error) {
 		route.StartTime,
 		route.EndTime,
 		route.Capacity,
+		route.VehicleId,
+		route.WomenOnly,
+		route.SmokingAllowed,
+		route.PetsAllowed,
+		route.MaxLuggage,
+		route.Music,
+		route.WaitlistByTips,
+		route.WaitlistAutoAccept,
 	).Scan(
 		&route.Id,
 		&route.CreatedAt,
@@ -0,0 +1,169 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/DenChenn/blunder/pkg/blunder"
+	"github.com/jackc/pgx/v5"
+)
+
+// routes created from a track keep the driven path next to their start and end points, other routes are a
+// straight line between them
+const createRoutePathTableSQL = `
+	CREATE EXTENSION IF NOT EXISTS postgis;
+
+	CREATE TABLE IF NOT EXISTS route_paths (
+		route_id INT PRIMARY KEY,
+		path GEOMETRY(LineString, 4326) NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL
+	);
+`
+
+func initRoutePathTable() error {
+	if _, err := DBClient.pgPool.Exec(context.Background(), createRoutePathTableSQL); err != nil {
+		return err
+	}
+	return nil
+}
+
+const createRoutePathSQL = `
+	INSERT INTO route_paths (route_id, path)
+	VALUES ($1, ST_SetSRID(ST_GeomFromGeoJSON($2), 4326));
+`
+
+// CreateRouteWithPath creates the route together with its path, given as a GeoJSON LineString
+func CreateRouteWithPath(route *model.Route, path string) (*model.Route, error) {
+	ctx := context.Background()
+	tx, err := DBClient.pgPool.Begin(ctx)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	created, err := createRoute(ctx, tx, route)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, createRoutePathSQL, created.Id, path); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return created, nil
+}
+
+const getRouteGeometrySQL = `
+	SELECT ST_AsGeoJSON(COALESCE(p.path, ST_MakeLine(r.start_location, r.end_location)))
+	FROM routes r
+		LEFT JOIN route_paths p ON p.route_id = r.id
+	WHERE r.id = $1 AND r.deleted_at IS NULL;
+`
+
+// GetRouteGeometry returns the path of the route as a GeoJSON LineString
+func GetRouteGeometry(routeId int32) (string, error) {
+	var geometry string
+	if err := DBClient.pgPool.QueryRow(context.Background(), getRouteGeometrySQL, routeId).Scan(&geometry); err != nil {
+		Logger.Error(err)
+		return "", Match(err, pgx.ErrNoRows, ErrRouteNotFound).Return()
+	}
+	return geometry, nil
+}
+
+// a trip follows the route path between pickup and dropoff when the path passes them in that order,
+// otherwise it is drawn as a straight line
+const tripGeometrySQL = `
+	SELECT
+		t.id, t.rider_id, t.driver_id, t.request_id, t.route_id, t.status,
+		q.pickup_start_time, q.pickup_end_time,
+		ST_AsGeoJSON(CASE
+			WHEN p.path IS NOT NULL
+				AND ST_LineLocatePoint(p.path, q.pickup_location) < ST_LineLocatePoint(p.path, q.dropoff_location)
+			THEN ST_MakeLine(ARRAY[
+				q.pickup_location,
+				ST_LineSubstring(p.path, ST_LineLocatePoint(p.path, q.pickup_location), ST_LineLocatePoint(p.path, q.dropoff_location)),
+				q.dropoff_location
+			])
+			ELSE ST_MakeLine(q.pickup_location, q.dropoff_location)
+		END)
+	FROM trips t
+		JOIN requests q ON t.request_id = q.id
+		LEFT JOIN route_paths p ON p.route_id = t.route_id
+`
+
+const getTripGeometrySQL = tripGeometrySQL + `
+	WHERE t.id = $1 AND t.deleted_at IS NULL;
+`
+
+const listTripGeometriesByUserIdSQL = tripGeometrySQL + `
+	WHERE (t.rider_id = $1 OR t.driver_id = $1) AND t.deleted_at IS NULL
+	ORDER BY q.pickup_start_time DESC, t.id DESC;
+`
+
+type TripGeometry struct {
+	TripId          int32     `json:"tripId"`
+	RiderId         int32     `json:"riderId"`
+	DriverId        int32     `json:"driverId"`
+	RequestId       int32     `json:"requestId"`
+	RouteId         int32     `json:"routeId"`
+	Status          string    `json:"status"`
+	PickupStartTime time.Time `json:"pickupStartTime"`
+	PickupEndTime   time.Time `json:"pickupEndTime"`
+	Geometry        string    `json:"geometry"`
+}
+
+func scanTripGeometry(row pgx.Row) (*TripGeometry, error) {
+	var trip TripGeometry
+	if err := row.Scan(
+		&trip.TripId,
+		&trip.RiderId,
+		&trip.DriverId,
+		&trip.RequestId,
+		&trip.RouteId,
+		&trip.Status,
+		&trip.PickupStartTime,
+		&trip.PickupEndTime,
+		&trip.Geometry,
+	); err != nil {
+		return nil, err
+	}
+	return &trip, nil
+}
+
+// GetTripGeometry returns the trip with the way from pickup to dropoff as a GeoJSON LineString
+func GetTripGeometry(tripId int32) (*TripGeometry, error) {
+	trip, err := scanTripGeometry(DBClient.pgPool.QueryRow(context.Background(), getTripGeometrySQL, tripId))
+	if err != nil {
+		Logger.Error(err)
+		return nil, Match(err, pgx.ErrNoRows, ErrTripNotFound).Return()
+	}
+	return trip, nil
+}
+
+// ListTripGeometriesByUserId lists the trips the user took part in as rider or driver, latest first
+func ListTripGeometriesByUserId(userId int32) ([]*TripGeometry, error) {
+	rows, err := DBClient.pgPool.Query(context.Background(), listTripGeometriesByUserIdSQL, userId)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer rows.Close()
+
+	var trips []*TripGeometry
+	for rows.Next() {
+		trip, err := scanTripGeometry(rows)
+		if err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		trips = append(trips, trip)
+	}
+	return trips, nil
+}
@@ -0,0 +1,109 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const testDeleteRoutePathSQL = `
+	DELETE FROM route_paths WHERE route_id = $1;
+`
+
+func testLineString(geometry string) [][]float64 {
+	var parsed struct {
+		Type        string      `json:"type"`
+		Coordinates [][]float64 `json:"coordinates"`
+	}
+	Expect(json.Unmarshal([]byte(geometry), &parsed)).To(Succeed())
+	Expect(parsed.Type).To(Equal("LineString"))
+	return parsed.Coordinates
+}
+
+var _ = Describe("DBRoutePath", func() {
+	var (
+		riderId int32
+		route   *model.Route
+	)
+
+	BeforeEach(func() {
+		err := pgPool.QueryRow(context.Background(), testCreateUserSQL, "rider", "test", "test-route-path", "test").Scan(&riderId)
+		Expect(err).NotTo(HaveOccurred())
+
+		route, err = CreateRouteWithPath(&model.Route{
+			DriverId:   -1,
+			StartLong:  0,
+			StartLat:   0,
+			EndLong:    0.1,
+			EndLat:     0.1,
+			StartTime:  time.Now(),
+			EndTime:    time.Now().Add(time.Hour),
+			Capacity:   3,
+			RouteRules: model.RouteRules{MaxLuggage: constants.LuggageSmall},
+		}, `{"type": "LineString", "coordinates": [[0, 0], [0.1, 0], [0.1, 0.1]]}`)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_, err := pgPool.Exec(context.Background(), testDeleteTripsByRouteSQL, route.Id)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = pgPool.Exec(context.Background(), testDeleteRequestsByRouteSQL, route.Id)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = pgPool.Exec(context.Background(), testDeleteRoutePathSQL, route.Id)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = pgPool.Exec(context.Background(), testDeleteRouteSQL, route.Id)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = pgPool.Exec(context.Background(), testDeleteUserSQL, riderId)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should return the stored path of the route", func() {
+		geometry, err := GetRouteGeometry(route.Id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(testLineString(geometry)).To(Equal([][]float64{{0, 0}, {0.1, 0}, {0.1, 0.1}}))
+	})
+
+	It("should draw a route without a path as a straight line", func() {
+		var routeId int32
+		err := pgPool.QueryRow(context.Background(), testCreateRouteSQL, -1, 0, 0, 0.2, 0.2, time.Now(), time.Now().Add(time.Hour), 3).Scan(&routeId)
+		Expect(err).NotTo(HaveOccurred())
+		defer pgPool.Exec(context.Background(), testDeleteRouteSQL, routeId)
+
+		geometry, err := GetRouteGeometry(routeId)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(testLineString(geometry)).To(Equal([][]float64{{0, 0}, {0.2, 0.2}}))
+	})
+
+	It("should follow the route path from pickup to dropoff for trips", func() {
+		request, err := CreateRequest(&model.Request{
+			RiderId:          riderId,
+			RouteId:          route.Id,
+			PickupLong:       0.05,
+			DropoffLong:      0.1,
+			DropoffLat:       0.05,
+			PickupStartTime:  time.Now(),
+			PickupEndTime:    time.Now().Add(time.Hour),
+			Status:           constants.RequestStatusPending,
+			SeatCount:        1,
+			RideRequirements: model.RideRequirements{Luggage: constants.LuggageNone},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		trip, err := AcceptRequest(request.Id, 0)
+		Expect(err).NotTo(HaveOccurred())
+
+		geometry, err := GetTripGeometry(trip.Id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(geometry.RiderId).To(Equal(riderId))
+		Expect(testLineString(geometry.Geometry)).To(ContainElement([]float64{0.1, 0}))
+
+		trips, err := ListTripGeometriesByUserId(riderId)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(trips).To(HaveLen(1))
+		Expect(trips[0].TripId).To(Equal(trip.Id))
+	})
+})
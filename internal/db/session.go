@@ -0,0 +1,164 @@
+package db
+
+import (
+	"context"
+
+	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/DenChenn/blunder/pkg/blunder"
+	"github.com/jackc/pgx/v5"
+)
+
+const createSessionTableSQL = `
+	CREATE TABLE IF NOT EXISTS sessions (
+		id SERIAL,
+		user_id INT NOT NULL,
+		family_id VARCHAR(64) NOT NULL,
+		refresh_token_hash VARCHAR(64) NOT NULL UNIQUE,
+		user_agent VARCHAR(500) NOT NULL DEFAULT '',
+		expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+		revoked_at TIMESTAMP WITH TIME ZONE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS sessions_family_id_idx ON sessions (family_id);
+	CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions (user_id);
+`
+
+func initSessionTable() error {
+	if _, err := DBClient.pgPool.Exec(context.Background(), createSessionTableSQL); err != nil {
+		return err
+	}
+	return nil
+}
+
+const getSessionByRefreshTokenHashSQL = `
+	SELECT id, user_id, family_id, refresh_token_hash, user_agent, expires_at, revoked_at, created_at
+	FROM sessions
+	WHERE refresh_token_hash = $1;
+`
+
+// GetSessionByRefreshTokenHash also returns revoked sessions, so that callers can detect token reuse
+func GetSessionByRefreshTokenHash(hash string) (*model.Session, error) {
+	var session model.Session
+	if err := DBClient.pgPool.QueryRow(context.Background(), getSessionByRefreshTokenHashSQL, hash).Scan(
+		&session.Id,
+		&session.UserId,
+		&session.FamilyId,
+		&session.RefreshTokenHash,
+		&session.UserAgent,
+		&session.ExpiresAt,
+		&session.RevokedAt,
+		&session.CreatedAt,
+	); err != nil {
+		Logger.Error(err)
+		return nil, Match(err, pgx.ErrNoRows, ErrSessionNotFound).Return()
+	}
+	return &session, nil
+}
+
+const createSessionSQL = `
+	INSERT INTO sessions (user_id, family_id, refresh_token_hash, user_agent, expires_at)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id, created_at;
+`
+
+func CreateSession(session *model.Session) (*model.Session, error) {
+	if err := DBClient.pgPool.QueryRow(context.Background(), createSessionSQL,
+		session.UserId,
+		session.FamilyId,
+		session.RefreshTokenHash,
+		session.UserAgent,
+		session.ExpiresAt,
+	).Scan(
+		&session.Id,
+		&session.CreatedAt,
+	); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return session, nil
+}
+
+const revokeSessionSQL = `
+	UPDATE sessions SET revoked_at = NOW()
+	WHERE id = $1 AND revoked_at IS NULL
+	RETURNING id;
+`
+
+// RotateSession revokes the old session and creates its successor in one transaction.
+// ErrSessionRevoked is returned if the old session was revoked concurrently.
+func RotateSession(oldId int32, session *model.Session) (*model.Session, error) {
+	ctx := context.Background()
+	tx, err := DBClient.pgPool.Begin(ctx)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	var revokedId int32
+	if err := tx.QueryRow(ctx, revokeSessionSQL, oldId).Scan(&revokedId); err != nil {
+		Logger.Error(err)
+		return nil, Match(err, pgx.ErrNoRows, ErrSessionRevoked).Return()
+	}
+
+	if err := tx.QueryRow(ctx, createSessionSQL,
+		session.UserId,
+		session.FamilyId,
+		session.RefreshTokenHash,
+		session.UserAgent,
+		session.ExpiresAt,
+	).Scan(
+		&session.Id,
+		&session.CreatedAt,
+	); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return session, nil
+}
+
+const revokeSessionByRefreshTokenHashSQL = `
+	UPDATE sessions SET revoked_at = NOW()
+	WHERE refresh_token_hash = $1 AND revoked_at IS NULL;
+`
+
+func RevokeSessionByRefreshTokenHash(hash string) error {
+	if _, err := DBClient.pgPool.Exec(context.Background(), revokeSessionByRefreshTokenHashSQL, hash); err != nil {
+		Logger.Error(err)
+		return ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return nil
+}
+
+const revokeSessionFamilySQL = `
+	UPDATE sessions SET revoked_at = NOW()
+	WHERE family_id = $1 AND revoked_at IS NULL;
+`
+
+func RevokeSessionFamily(familyId string) error {
+	if _, err := DBClient.pgPool.Exec(context.Background(), revokeSessionFamilySQL, familyId); err != nil {
+		Logger.Error(err)
+		return ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return nil
+}
+
+const revokeUserSessionsSQL = `
+	UPDATE sessions SET revoked_at = NOW()
+	WHERE user_id = $1 AND revoked_at IS NULL;
+`
+
+func RevokeUserSessions(userId int32) error {
+	if _, err := DBClient.pgPool.Exec(context.Background(), revokeUserSessionsSQL, userId); err != nil {
+		Logger.Error(err)
+		return ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const testDeleteSessionsByFamilySQL = `
+	DELETE FROM sessions WHERE family_id = $1;
+`
+
+var _ = Describe("DBSession", func() {
+	var existedSession *model.Session
+
+	BeforeEach(func() {
+		var err error
+		existedSession, err = CreateSession(&model.Session{
+			UserId:           -1,
+			FamilyId:         "test-family",
+			RefreshTokenHash: "test-hash",
+			UserAgent:        "test",
+			ExpiresAt:        time.Now().Add(time.Hour),
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_, err := pgPool.Exec(context.Background(), testDeleteSessionsByFamilySQL, "test-family")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("GetSessionByRefreshTokenHash", func() {
+		var (
+			session *model.Session
+			hash    string
+			err     error
+		)
+
+		JustBeforeEach(func() {
+			session, err = GetSessionByRefreshTokenHash(hash)
+		})
+
+		When("session does not exist", func() {
+			BeforeEach(func() {
+				hash = "not-exist"
+			})
+
+			It("should return error", func() {
+				Expect(err).To(MatchError(ErrSessionNotFound))
+				Expect(session).To(BeNil())
+			})
+		})
+
+		When("session exists", func() {
+			BeforeEach(func() {
+				hash = existedSession.RefreshTokenHash
+			})
+
+			It("should return session", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(session.Id).To(Equal(existedSession.Id))
+				Expect(session.UserId).To(Equal(existedSession.UserId))
+				Expect(session.FamilyId).To(Equal(existedSession.FamilyId))
+				Expect(session.RevokedAt).To(BeNil())
+			})
+		})
+	})
+
+	Describe("RotateSession", func() {
+		var (
+			rotated *model.Session
+			err     error
+		)
+
+		JustBeforeEach(func() {
+			rotated, err = RotateSession(existedSession.Id, &model.Session{
+				UserId:           -1,
+				FamilyId:         "test-family",
+				RefreshTokenHash: "test-hash-rotated",
+				ExpiresAt:        time.Now().Add(time.Hour),
+			})
+		})
+
+		It("should revoke the old session and create a new one", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rotated.Id).NotTo(Equal(existedSession.Id))
+
+			old, getErr := GetSessionByRefreshTokenHash(existedSession.RefreshTokenHash)
+			Expect(getErr).NotTo(HaveOccurred())
+			Expect(old.RevokedAt).NotTo(BeNil())
+		})
+
+		When("the old session is rotated twice", func() {
+			It("should return error", func() {
+				_, err = RotateSession(existedSession.Id, &model.Session{
+					UserId:           -1,
+					FamilyId:         "test-family",
+					RefreshTokenHash: "test-hash-rotated-again",
+					ExpiresAt:        time.Now().Add(time.Hour),
+				})
+				Expect(err).To(MatchError(ErrSessionRevoked))
+			})
+		})
+	})
+
+	Describe("RevokeSessionFamily", func() {
+		It("should revoke every session in the family", func() {
+			Expect(RevokeSessionFamily("test-family")).To(Succeed())
+
+			session, err := GetSessionByRefreshTokenHash(existedSession.RefreshTokenHash)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(session.RevokedAt).NotTo(BeNil())
+		})
+	})
+
+	Describe("RevokeUserSessions", func() {
+		It("should revoke every session of the user", func() {
+			Expect(RevokeUserSessions(-1)).To(Succeed())
+
+			session, err := GetSessionByRefreshTokenHash(existedSession.RefreshTokenHash)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(session.RevokedAt).NotTo(BeNil())
+		})
+	})
+})
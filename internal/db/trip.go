@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/CoRide-tw/backend/internal/constants"
 	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
 	. "github.com/DenChenn/blunder/pkg/blunder"
 	"github.com/jackc/pgx/v5"
@@ -21,6 +22,8 @@ const createTripTableSQL = `
 		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL,
 		deleted_at TIMESTAMP
 	);
+
+	ALTER TABLE trips ADD COLUMN IF NOT EXISTS status VARCHAR(50) DEFAULT 'scheduled' NOT NULL;
 `
 
 func initTripTable() error {
@@ -49,12 +52,20 @@ const listTripByRiderIdSQL = `
 		ST_X(rout.end_location), ST_Y(rout.end_location), 
 		ST_X(req.pickup_location), ST_Y(req.pickup_location),
 		ST_X(req.dropoff_location), ST_Y(req.dropoff_location),
+		v.id,
+		v.make,
+		v.model,
+		v.color,
+		v.plate,
+		v.photo_url,
+		t.status,
 		t.created_at,
 		t.deleted_at
 	FROM trips t
 		JOIN users u ON t.driver_id = u.id
 		JOIN requests req ON t.request_id = req.id
 		JOIN routes rout ON t.route_id = rout.id
+		LEFT JOIN vehicles v ON rout.vehicle_id = v.id
 	WHERE t.rider_id = $1 AND t.deleted_at IS NULL;
 `
 
@@ -80,6 +91,13 @@ type ListTripResp struct {
 	PickupLocationLat     float64    `json:"pickupLocationLat"`
 	DropoffLocationLng    float64    `json:"dropoffLocationLng"`
 	DropoffLocationLat    float64    `json:"dropoffLocationLat"`
+	VehicleId             *int32     `json:"vehicleId"`
+	VehicleMake           *string    `json:"vehicleMake"`
+	VehicleModel          *string    `json:"vehicleModel"`
+	VehicleColor          *string    `json:"vehicleColor"`
+	VehiclePlate          *string    `json:"vehiclePlate"`
+	VehiclePhotoUrl       *string    `json:"vehiclePhotoUrl"`
+	Status                string     `json:"status"`
 	CreatedAt             time.Time  `json:"createdAt"`
 	DeletedAt             *time.Time `json:"deletedAt,omitempty"`
 }
@@ -115,6 +133,13 @@ func ListTripByRiderId(riderId int32) ([]*ListTripResp, error) {
 			&trip.PickupLocationLat,
 			&trip.DropoffLocationLng,
 			&trip.DropoffLocationLat,
+			&trip.VehicleId,
+			&trip.VehicleMake,
+			&trip.VehicleModel,
+			&trip.VehicleColor,
+			&trip.VehiclePlate,
+			&trip.VehiclePhotoUrl,
+			&trip.Status,
 			&trip.CreatedAt,
 			&trip.DeletedAt,
 		); err != nil {
@@ -145,12 +170,20 @@ const listTripByDriverIdSQL = `
 		ST_X(rout.end_location), ST_Y(rout.end_location), 
 		ST_X(req.pickup_location), ST_Y(req.pickup_location),
 		ST_X(req.dropoff_location), ST_Y(req.dropoff_location),
+		v.id,
+		v.make,
+		v.model,
+		v.color,
+		v.plate,
+		v.photo_url,
+		t.status,
 		t.created_at,
 		t.deleted_at
 	FROM trips t
 		JOIN users u ON t.driver_id = u.id
 		JOIN requests req ON t.request_id = req.id
 		JOIN routes rout ON t.route_id = rout.id
+		LEFT JOIN vehicles v ON rout.vehicle_id = v.id
 	WHERE t.rider_id = $1 AND t.deleted_at IS NULL;
 `
 
@@ -185,6 +218,13 @@ func ListTripByDriverId(driverId int32) ([]*ListTripResp, error) {
 			&trip.PickupLocationLat,
 			&trip.DropoffLocationLng,
 			&trip.DropoffLocationLat,
+			&trip.VehicleId,
+			&trip.VehicleMake,
+			&trip.VehicleModel,
+			&trip.VehicleColor,
+			&trip.VehiclePlate,
+			&trip.VehiclePhotoUrl,
+			&trip.Status,
 			&trip.CreatedAt,
 			&trip.DeletedAt,
 		); err != nil {
@@ -197,7 +237,7 @@ func ListTripByDriverId(driverId int32) ([]*ListTripResp, error) {
 }
 
 const getTripSQL = `
-	SELECT *
+	SELECT id, rider_id, driver_id, request_id, route_id, status, created_at, deleted_at
 	FROM trips
 	WHERE id = $1 AND deleted_at IS NULL;
 `
@@ -210,6 +250,7 @@ func GetTrip(id int32) (*model.Trip, error) {
 		&trip.DriverId,
 		&trip.RequestId,
 		&trip.RouteId,
+		&trip.Status,
 		&trip.CreatedAt,
 		&trip.DeletedAt,
 	); err != nil {
@@ -219,6 +260,35 @@ func GetTrip(id int32) (*model.Trip, error) {
 	return &trip, nil
 }
 
+const departTripSQL = `
+	UPDATE trips SET status = $2
+	WHERE id = $1 AND status = $3 AND deleted_at IS NULL
+	RETURNING id, rider_id, driver_id, request_id, route_id, status, created_at, deleted_at;
+`
+
+// DepartTrip puts a scheduled trip en route once the driver leaves for the pickup
+func DepartTrip(id int32) (*model.Trip, error) {
+	var trip model.Trip
+	if err := DBClient.pgPool.QueryRow(context.Background(), departTripSQL,
+		id,
+		constants.TripStatusEnRoute,
+		constants.TripStatusScheduled,
+	).Scan(
+		&trip.Id,
+		&trip.RiderId,
+		&trip.DriverId,
+		&trip.RequestId,
+		&trip.RouteId,
+		&trip.Status,
+		&trip.CreatedAt,
+		&trip.DeletedAt,
+	); err != nil {
+		Logger.Error(err)
+		return nil, Match(err, pgx.ErrNoRows, ErrTripNotScheduled).Return()
+	}
+	return &trip, nil
+}
+
 const createTripSQL = `
 	INSERT INTO trips (rider_id, driver_id, request_id, route_id)
 	VALUES (
@@ -227,7 +297,7 @@ const createTripSQL = `
 		$3, 
 		$4
 	)
-	RETURNING id, created_at;
+	RETURNING id, status, created_at;
 `
 
 func CreateTrip(trip *model.Trip) (*model.Trip, error) {
@@ -238,6 +308,7 @@ func CreateTrip(trip *model.Trip) (*model.Trip, error) {
 		trip.RouteId,
 	).Scan(
 		&trip.Id,
+		&trip.Status,
 		&trip.CreatedAt,
 	); err != nil {
 		Logger.Error(err)
@@ -245,3 +316,40 @@ func CreateTrip(trip *model.Trip) (*model.Trip, error) {
 	}
 	return trip, nil
 }
+
+const cancelTripSQL = `
+	UPDATE trips SET status = $2
+	WHERE id = $1 AND deleted_at IS NULL AND status = ANY($3)
+	RETURNING request_id;
+`
+
+// CancelTrip cancels a scheduled or en route trip together with the request it fulfils
+func CancelTrip(id int32) error {
+	ctx := context.Background()
+	tx, err := DBClient.pgPool.Begin(ctx)
+	if err != nil {
+		Logger.Error(err)
+		return ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	var requestId int32
+	if err := tx.QueryRow(ctx, cancelTripSQL,
+		id,
+		constants.TripStatusCancelled,
+		[]string{constants.TripStatusScheduled, constants.TripStatusEnRoute},
+	).Scan(&requestId); err != nil {
+		Logger.Error(err)
+		return Match(err, pgx.ErrNoRows, ErrTripNotActive).Return()
+	}
+	if _, err := tx.Exec(ctx, updateRequestStatusSQL, requestId, constants.RequestStatusCancelled); err != nil {
+		Logger.Error(err)
+		return ErrUndefined.WithCustomMessage(err.Error())
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		Logger.Error(err)
+		return ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return nil
+}
@@ -2,6 +2,8 @@ package db
 
 import (
 	"context"
+	"github.com/CoRide-tw/backend/internal/constants"
+	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
 	"github.com/CoRide-tw/backend/internal/model"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -22,6 +24,10 @@ const testDeleteTripSQL = `
 	DELETE FROM trips WHERE id = $1;
 `
 
+const testSetTripStatusSQL = `
+	UPDATE trips SET status = $2 WHERE id = $1;
+`
+
 var _ = Describe("DBTrip", func() {
 	var existedTrips []model.Trip
 
@@ -158,4 +164,21 @@ var _ = Describe("DBTrip", func() {
 			})
 		})
 	})
+
+	Describe("CancelTrip", func() {
+		It("should cancel a scheduled trip", func() {
+			Expect(CancelTrip(existedTrips[0].Id)).To(Succeed())
+
+			trip, err := GetTrip(existedTrips[0].Id)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(trip.Status).To(Equal(constants.TripStatusCancelled))
+		})
+
+		It("should keep completed trips", func() {
+			_, err := pgPool.Exec(context.Background(), testSetTripStatusSQL, existedTrips[0].Id, constants.TripStatusCompleted)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(CancelTrip(existedTrips[0].Id)).To(MatchError(ErrTripNotActive))
+		})
+	})
 })
@@ -2,7 +2,9 @@ package db
 
 import (
 	"context"
+	"time"
 
+	"github.com/CoRide-tw/backend/internal/constants"
 	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
 	"github.com/CoRide-tw/backend/internal/model"
 	. "github.com/DenChenn/blunder/pkg/blunder"
@@ -14,7 +16,7 @@ const createUsersTableSQL = `
 	    id SERIAL,
 	    name VARCHAR(200) NOT NULL,
 		email VARCHAR(200) NOT NULL,
-	    google_id VARCHAR(200) NOT NULL UNIQUE,
+	    google_id VARCHAR(200) UNIQUE,
 		picture_url VARCHAR(200),
 		car_type VARCHAR(200),
 		car_plate VARCHAR(200),
@@ -22,6 +24,13 @@ const createUsersTableSQL = `
 	    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL,
 	    deleted_at TIMESTAMP WITH TIME ZONE
 	);
+
+	-- users signing in with other identity providers have no google id
+	ALTER TABLE users ALTER COLUMN google_id DROP NOT NULL;
+
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS roles TEXT[] DEFAULT '{rider}' NOT NULL;
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMP WITH TIME ZONE;
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS driver_verified_at TIMESTAMP WITH TIME ZONE;
 `
 
 func initUserTable() error {
@@ -32,7 +41,7 @@ func initUserTable() error {
 }
 
 const getUserSQL = `
-	SELECT *
+	SELECT id, name, email, COALESCE(google_id, ''), picture_url, car_type, car_plate, roles, suspended_at, driver_verified_at, created_at, updated_at, deleted_at
 	FROM users
 	WHERE id = $1 AND deleted_at IS NULL;
 `
@@ -47,6 +56,9 @@ func GetUser(id int32) (*model.User, error) {
 		&user.PictureUrl,
 		&user.CarType,
 		&user.CarPlate,
+		&user.Roles,
+		&user.SuspendedAt,
+		&user.DriverVerifiedAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.DeletedAt,
@@ -61,16 +73,18 @@ const createUserSQL = `
 	INSERT INTO users (name, email, google_id, picture_url)
 	VALUES ($1, $2, $3, $4)
 	ON CONFLICT (google_id)
-	DO UPDATE SET name = $1, email = $2, picture_url = $4, updated_at = NOW(), deleted_at = NULL
-	RETURNING id, car_type, car_plate, created_at, updated_at;
+	DO UPDATE SET name = $1, email = $2, picture_url = $4, updated_at = NOW()
+	WHERE users.deleted_at IS NULL
+	RETURNING id, car_type, car_plate, roles, created_at, updated_at;
 `
 
 func UpsertUser(user *model.User) (*model.User, error) {
 	if err := DBClient.pgPool.QueryRow(context.Background(), createUserSQL,
 		user.Name, user.Email, user.GoogleId, user.PictureUrl).Scan(
-		&user.Id, &user.CarType, &user.CarPlate, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		&user.Id, &user.CarType, &user.CarPlate, &user.Roles, &user.CreatedAt, &user.UpdatedAt); err != nil {
 		Logger.Error(err)
-		return nil, ErrUndefined.WithCustomMessage(err.Error())
+		// the conflicting account is deleted, it is not brought back
+		return nil, Match(err, pgx.ErrNoRows, ErrUserDeleted).Return()
 	}
 	return user, nil
 }
@@ -80,9 +94,14 @@ const updateUserSQL = `
 		email = COALESCE(NULLIF($2, ''), email),
 		car_type = COALESCE(NULLIF($3, ''), car_type),
 		car_plate = COALESCE(NULLIF($4, ''), car_plate),
+		-- registering a car makes the user a driver
+		roles = CASE
+			WHEN NULLIF($4, '') IS NOT NULL AND NOT 'driver' = ANY(roles) THEN array_append(roles, 'driver')
+			ELSE roles
+		END,
 		updated_at = NOW()
 	WHERE id = $1 AND deleted_at IS NULL
-	RETURNING *;
+	RETURNING id, name, email, COALESCE(google_id, ''), picture_url, car_type, car_plate, roles, suspended_at, driver_verified_at, created_at, updated_at, deleted_at;
 `
 
 func UpdateUser(id int32, user *model.User) (*model.User, error) {
@@ -96,6 +115,9 @@ func UpdateUser(id int32, user *model.User) (*model.User, error) {
 		&updatedUser.PictureUrl,
 		&updatedUser.CarType,
 		&updatedUser.CarPlate,
+		&updatedUser.Roles,
+		&updatedUser.SuspendedAt,
+		&updatedUser.DriverVerifiedAt,
 		&updatedUser.CreatedAt,
 		&updatedUser.UpdatedAt,
 		&updatedUser.DeletedAt); err != nil {
@@ -107,15 +129,276 @@ func UpdateUser(id int32, user *model.User) (*model.User, error) {
 }
 
 const deleteUserSQL = `
-	UPDATE users SET deleted_at = NOW()
-	WHERE id = $1 AND deleted_at IS NULL;
+	UPDATE users SET
+		name = 'Deleted user',
+		email = '',
+		google_id = NULL,
+		picture_url = '',
+		car_type = '',
+		car_plate = '',
+		roles = '{}',
+		driver_verified_at = NULL,
+		updated_at = NOW(),
+		deleted_at = NOW()
+	WHERE id = $1 AND deleted_at IS NULL
+	RETURNING id;
+`
+
+const tombstoneUserIdentitiesSQL = `
+	INSERT INTO deleted_user_identities (provider, subject_hash)
+	SELECT provider, encode(sha256(convert_to(subject, 'UTF8')), 'hex')
+	FROM user_identities
+	WHERE user_id = $1
+	ON CONFLICT (provider, subject_hash) DO NOTHING;
+`
+
+const deleteUserIdentitiesSQL = `
+	DELETE FROM user_identities WHERE user_id = $1;
+`
+
+// the scheduled trips of the cancelled requests are cancelled with them, the self join reads the status
+// each request had before
+const cancelUserFutureRequestsSQL = `
+	WITH cancelled AS (
+		UPDATE requests r SET status = $2, updated_at = NOW()
+		FROM routes ro, requests old
+		WHERE ro.id = r.route_id AND old.id = r.id
+			AND (r.rider_id = $1 OR ro.driver_id = $1)
+			AND r.status = ANY($3) AND r.deleted_at IS NULL
+			AND r.pickup_start_time > NOW()
+		RETURNING r.id, r.route_id, r.rider_id, ro.driver_id, old.status
+	), cancelled_trips AS (
+		UPDATE trips SET status = $4
+		WHERE request_id IN (SELECT id FROM cancelled) AND status = $5 AND deleted_at IS NULL
+	)
+	SELECT id, route_id, rider_id, driver_id, status FROM cancelled;
+`
+
+type CancelledUserRequest struct {
+	RequestId int32
+	RouteId   int32
+	RiderId   int32
+	DriverId  int32
+	Status    string
+}
+
+const expireUserAssignmentProposalsSQL = `
+	UPDATE assignment_proposals SET status = $2, resolved_at = NOW()
+	WHERE (rider_id = $1 OR driver_id = $1) AND status = $3;
+`
+
+const cancelUserJourneysSQL = `
+	UPDATE journeys SET status = $2, updated_at = NOW()
+	WHERE rider_id = $1 AND status = $3;
+`
+
+const deleteUserVehiclesSQL = `
+	UPDATE vehicles SET plate = '', photo_url = '', deleted_at = COALESCE(deleted_at, NOW()), updated_at = NOW()
+	WHERE user_id = $1;
+`
+
+const deleteUserDriverDocumentsSQL = `
+	UPDATE driver_documents SET deleted_at = NOW()
+	WHERE user_id = $1 AND deleted_at IS NULL;
 `
 
-func DeleteUser(id int32) error {
-	if _, err := DBClient.pgPool.Exec(context.Background(), deleteUserSQL,
-		id); err != nil {
+// blocks the user created go with the account, blocks against them stay so the blocker is still protected
+// should the account be restored by an admin
+const deleteUserBlocksSQL = `
+	DELETE FROM user_blocks WHERE blocker_id = $1;
+`
+
+const deleteUserRidePreferencesSQL = `
+	DELETE FROM ride_preferences WHERE user_id = $1;
+`
+
+const deleteUserNotificationsSQL = `
+	DELETE FROM notifications WHERE user_id = $1;
+`
+
+const deleteUserDriverPresenceSQL = `
+	DELETE FROM driver_presences WHERE user_id = $1;
+`
+
+const cancelUserInstantRequestsSQL = `
+	UPDATE instant_requests SET status = $2, offer_expires_at = NULL, updated_at = NOW()
+	WHERE rider_id = $1 AND status = ANY($3);
+`
+
+const deleteUserFutureRoutesSQL = `
+	UPDATE routes SET deleted_at = NOW(), updated_at = NOW()
+	WHERE driver_id = $1 AND deleted_at IS NULL AND start_time > NOW();
+`
+
+// audit entries keep who did what, the payloads may contain the user's personal data
+const redactUserAuditLogsSQL = `
+	UPDATE audit_logs SET before_data = NULL, after_data = NULL, detail = NULL
+	WHERE (actor_id = $1 OR (entity_type = 'user' AND entity_id = $1))
+		AND (before_data IS NOT NULL OR after_data IS NOT NULL OR detail IS NOT NULL);
+`
+
+// DeleteUser anonymizes the account, cancels the rides it is committed to and ends its sessions. The
+// cancelled requests are returned with the status they had, as the other party still has to hear of them.
+// Its identities are remembered by hash only, so signing in with them again is refused instead of
+// silently bringing the account back.
+func DeleteUser(id int32) ([]*CancelledUserRequest, error) {
+	ctx := context.Background()
+	tx, err := DBClient.pgPool.Begin(ctx)
+	if err != nil {
 		Logger.Error(err)
-		return ErrUndefined.WithCustomMessage(err.Error())
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	if err := tx.QueryRow(ctx, deleteUserSQL, id).Scan(&id); err != nil {
+		Logger.Error(err)
+		return nil, Match(err, pgx.ErrNoRows, ErrUserNotFound).Return()
+	}
+
+	cancelled, err := cancelUserFutureRequests(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, statement := range []struct {
+		sql  string
+		args []interface{}
+	}{
+		{tombstoneUserIdentitiesSQL, []interface{}{id}},
+		{deleteUserIdentitiesSQL, []interface{}{id}},
+		{expireUserAssignmentProposalsSQL, []interface{}{id, constants.AssignmentProposalStatusExpired, constants.AssignmentProposalStatusOpen}},
+		{cancelUserJourneysSQL, []interface{}{id, constants.JourneyStatusCancelled, constants.JourneyStatusActive}},
+		{deleteUserFutureRoutesSQL, []interface{}{id}},
+		{deleteUserVehiclesSQL, []interface{}{id}},
+		{deleteUserDriverDocumentsSQL, []interface{}{id}},
+		{deleteUserBlocksSQL, []interface{}{id}},
+		{deleteUserRidePreferencesSQL, []interface{}{id}},
+		{deleteUserNotificationsSQL, []interface{}{id}},
+		{deleteUserDriverPresenceSQL, []interface{}{id}},
+		{cancelUserInstantRequestsSQL, []interface{}{id, constants.InstantRequestStatusCancelled,
+			[]string{constants.InstantRequestStatusSearching, constants.InstantRequestStatusOffered}}},
+		{revokeUserSessionsSQL, []interface{}{id}},
+		{redactUserAuditLogsSQL, []interface{}{id}},
+	} {
+		if _, err := tx.Exec(ctx, statement.sql, statement.args...); err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return cancelled, nil
+}
+
+func cancelUserFutureRequests(ctx context.Context, tx pgx.Tx, id int32) ([]*CancelledUserRequest, error) {
+	rows, err := tx.Query(ctx, cancelUserFutureRequestsSQL,
+		id,
+		constants.RequestStatusCancelled,
+		[]string{
+			constants.RequestStatusWaitlisted,
+			constants.RequestStatusPending,
+			constants.RequestStatusCountered,
+			constants.RequestStatusOffered,
+			constants.RequestStatusAccepted,
+		},
+		constants.TripStatusCancelled,
+		constants.TripStatusScheduled,
+	)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer rows.Close()
+
+	var cancelled []*CancelledUserRequest
+	for rows.Next() {
+		var request CancelledUserRequest
+		if err := rows.Scan(&request.RequestId, &request.RouteId, &request.RiderId, &request.DriverId, &request.Status); err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		cancelled = append(cancelled, &request)
+	}
+	if err := rows.Err(); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return cancelled, nil
+}
+
+const setUserRolesSQL = `
+	UPDATE users SET roles = $2, updated_at = NOW()
+	WHERE id = $1 AND deleted_at IS NULL
+	RETURNING id;
+`
+
+func SetUserRoles(id int32, roles []string) error {
+	if err := DBClient.pgPool.QueryRow(context.Background(), setUserRolesSQL, id, roles).Scan(&id); err != nil {
+		Logger.Error(err)
+		return Match(err, pgx.ErrNoRows, ErrUserNotFound).Return()
 	}
 	return nil
 }
+
+const suspendUserSQL = `
+	UPDATE users SET suspended_at = NOW(), updated_at = NOW()
+	WHERE id = $1 AND deleted_at IS NULL
+	RETURNING id;
+`
+
+const unsuspendUserSQL = `
+	UPDATE users SET suspended_at = NULL, updated_at = NOW()
+	WHERE id = $1 AND deleted_at IS NULL
+	RETURNING id;
+`
+
+// SetUserSuspended suspends or reinstates a user, suspended users cannot start or refresh sessions
+func SetUserSuspended(id int32, suspended bool) error {
+	sql := unsuspendUserSQL
+	if suspended {
+		sql = suspendUserSQL
+	}
+	if err := DBClient.pgPool.QueryRow(context.Background(), sql, id).Scan(&id); err != nil {
+		Logger.Error(err)
+		return Match(err, pgx.ErrNoRows, ErrUserNotFound).Return()
+	}
+	return nil
+}
+
+const getPublicProfileSQL = `
+	SELECT
+		u.id,
+		u.name,
+		COALESCE(u.picture_url, ''),
+		(
+			SELECT COUNT(*) FROM trips t
+			WHERE (t.rider_id = u.id OR t.driver_id = u.id) AND t.deleted_at IS NULL AND t.status <> $2
+		),
+		u.driver_verified_at,
+		u.created_at
+	FROM users u
+	WHERE u.id = $1 AND u.deleted_at IS NULL;
+`
+
+func GetPublicProfile(id int32) (*model.PublicProfile, error) {
+	var driverVerifiedAt *time.Time
+	profile := model.PublicProfile{Badges: []string{}}
+	if err := DBClient.pgPool.QueryRow(context.Background(), getPublicProfileSQL, id, constants.TripStatusCancelled).Scan(
+		&profile.Id,
+		&profile.Name,
+		&profile.PictureUrl,
+		&profile.TripCount,
+		&driverVerifiedAt,
+		&profile.MemberSince,
+	); err != nil {
+		Logger.Error(err)
+		return nil, Match(err, pgx.ErrNoRows, ErrUserNotFound).Return()
+	}
+	if driverVerifiedAt != nil {
+		profile.Badges = append(profile.Badges, constants.BadgeVerifiedDriver)
+	}
+	return &profile, nil
+}
@@ -0,0 +1,113 @@
+package db
+
+import (
+	"context"
+
+	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/DenChenn/blunder/pkg/blunder"
+	"github.com/jackc/pgx/v5"
+)
+
+const createUserBlockTableSQL = `
+	CREATE TABLE IF NOT EXISTS user_blocks (
+		blocker_id INT NOT NULL,
+		blocked_id INT NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL,
+		PRIMARY KEY (blocker_id, blocked_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS user_blocks_blocked_id_idx ON user_blocks (blocked_id);
+`
+
+func initUserBlockTable() error {
+	if _, err := DBClient.pgPool.Exec(context.Background(), createUserBlockTableSQL); err != nil {
+		return err
+	}
+	return nil
+}
+
+const listUserBlocksSQL = `
+	SELECT blocker_id, blocked_id, created_at
+	FROM user_blocks
+	WHERE blocker_id = $1
+	ORDER BY created_at DESC;
+`
+
+func ListUserBlocks(blockerId int32) ([]*model.UserBlock, error) {
+	rows, err := DBClient.pgPool.Query(context.Background(), listUserBlocksSQL, blockerId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	blocks := []*model.UserBlock{}
+	for rows.Next() {
+		var block model.UserBlock
+		if err := rows.Scan(&block.BlockerId, &block.BlockedId, &block.CreatedAt); err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		blocks = append(blocks, &block)
+	}
+	return blocks, nil
+}
+
+// blocking twice keeps the original timestamp
+const createUserBlockSQL = `
+	WITH inserted AS (
+		INSERT INTO user_blocks (blocker_id, blocked_id)
+		SELECT $1, id FROM users WHERE id = $2 AND deleted_at IS NULL
+		ON CONFLICT (blocker_id, blocked_id) DO NOTHING
+		RETURNING blocker_id, blocked_id, created_at
+	)
+	SELECT blocker_id, blocked_id, created_at FROM inserted
+	UNION ALL
+	SELECT blocker_id, blocked_id, created_at FROM user_blocks
+	WHERE blocker_id = $1 AND blocked_id = $2;
+`
+
+func CreateUserBlock(blockerId, blockedId int32) (*model.UserBlock, error) {
+	var block model.UserBlock
+	if err := DBClient.pgPool.QueryRow(context.Background(), createUserBlockSQL, blockerId, blockedId).Scan(
+		&block.BlockerId,
+		&block.BlockedId,
+		&block.CreatedAt,
+	); err != nil {
+		Logger.Error(err)
+		return nil, Match(err, pgx.ErrNoRows, ErrUserNotFound).Return()
+	}
+	return &block, nil
+}
+
+const deleteUserBlockSQL = `
+	DELETE FROM user_blocks
+	WHERE blocker_id = $1 AND blocked_id = $2
+	RETURNING blocked_id;
+`
+
+func DeleteUserBlock(blockerId, blockedId int32) error {
+	var id int32
+	if err := DBClient.pgPool.QueryRow(context.Background(), deleteUserBlockSQL, blockerId, blockedId).Scan(&id); err != nil {
+		Logger.Error(err)
+		return Match(err, pgx.ErrNoRows, ErrUserBlockNotFound).Return()
+	}
+	return nil
+}
+
+// a block hides both users from each other, whoever created it
+const isBlockedSQL = `
+	SELECT EXISTS (
+		SELECT 1 FROM user_blocks
+		WHERE (blocker_id = $1 AND blocked_id = $2) OR (blocker_id = $2 AND blocked_id = $1)
+	);
+`
+
+func IsBlocked(userId, otherUserId int32) (bool, error) {
+	var blocked bool
+	if err := DBClient.pgPool.QueryRow(context.Background(), isBlockedSQL, userId, otherUserId).Scan(&blocked); err != nil {
+		Logger.Error(err)
+		return false, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return blocked, nil
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+
+	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const testDeleteUserBlocksSQL = `
+	DELETE FROM user_blocks WHERE blocker_id = $1 OR blocked_id = $1;
+`
+
+var _ = Describe("DBUserBlock", func() {
+	var blockedId int32
+
+	BeforeEach(func() {
+		err := pgPool.QueryRow(context.Background(), testCreateUserSQL, "test", "test", "test-user-block", "test").Scan(&blockedId)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_, err := pgPool.Exec(context.Background(), testDeleteUserBlocksSQL, blockedId)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = pgPool.Exec(context.Background(), testDeleteUserSQL, blockedId)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("CreateUserBlock", func() {
+		It("should block in both directions", func() {
+			_, err := CreateUserBlock(-1, blockedId)
+			Expect(err).NotTo(HaveOccurred())
+
+			blocked, err := IsBlocked(blockedId, -1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(blocked).To(BeTrue())
+		})
+
+		It("should keep the first block when blocking twice", func() {
+			first, err := CreateUserBlock(-1, blockedId)
+			Expect(err).NotTo(HaveOccurred())
+			second, err := CreateUserBlock(-1, blockedId)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(second.CreatedAt).To(Equal(first.CreatedAt))
+		})
+
+		It("should return error for an unknown user", func() {
+			_, err := CreateUserBlock(-1, -2)
+			Expect(err).To(MatchError(ErrUserNotFound))
+		})
+	})
+
+	Describe("DeleteUserBlock", func() {
+		It("should unblock", func() {
+			_, err := CreateUserBlock(-1, blockedId)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(DeleteUserBlock(-1, blockedId)).To(Succeed())
+			blocked, err := IsBlocked(-1, blockedId)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(blocked).To(BeFalse())
+		})
+
+		It("should return error when not blocked", func() {
+			Expect(DeleteUserBlock(-1, blockedId)).To(MatchError(ErrUserBlockNotFound))
+		})
+	})
+})
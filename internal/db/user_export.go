@@ -0,0 +1,437 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/DenChenn/blunder/pkg/blunder"
+	"github.com/jackc/pgx/v5"
+)
+
+const exportUserSessionsSQL = `
+	SELECT id, user_id, family_id, user_agent, expires_at, revoked_at, created_at
+	FROM sessions
+	WHERE user_id = $1
+	ORDER BY id ASC;
+`
+
+const exportUserVehiclesSQL = `
+	SELECT id, user_id, make, model, color, plate, seat_count, photo_url, amenities, is_default, created_at, updated_at, deleted_at
+	FROM vehicles
+	WHERE user_id = $1
+	ORDER BY id ASC;
+`
+
+const exportUserDriverDocumentsSQL = `
+	SELECT id, user_id, kind, blob_key, content_type, size_bytes, status, review_note, reviewer_id, reviewed_at, created_at, deleted_at
+	FROM driver_documents
+	WHERE user_id = $1
+	ORDER BY id ASC;
+`
+
+const exportUserBlocksSQL = `
+	SELECT blocker_id, blocked_id, created_at
+	FROM user_blocks
+	WHERE blocker_id = $1
+	ORDER BY created_at ASC;
+`
+
+// only reports the user filed, reports about them would identify the reporters
+const exportUserReportsSQL = `
+	SELECT id, reporter_id, reported_id, trip_id, category, description, status, reviewer_id, review_note, resolved_at, created_at
+	FROM user_reports
+	WHERE reporter_id = $1
+	ORDER BY id ASC;
+`
+
+const exportUserNotificationsSQL = `
+	SELECT id, user_id, kind, title, body, data, read_at, created_at
+	FROM notifications
+	WHERE user_id = $1
+	ORDER BY id ASC;
+`
+
+const exportUserRoutesSQL = `
+	SELECT
+		id,
+		driver_id,
+		ST_X(start_location), ST_Y(start_location),
+		ST_X(end_location), ST_Y(end_location),
+		start_time, end_time,
+		capacity,
+		vehicle_id,
+		women_only, smoking_allowed, pets_allowed, max_luggage, music,
+		waitlist_by_tips, waitlist_auto_accept,
+		created_at, updated_at, deleted_at
+	FROM routes
+	WHERE driver_id = $1
+	ORDER BY id ASC;
+`
+
+const exportUserRequestsSQL = `
+	SELECT
+		id,
+		rider_id,
+		route_id,
+		ST_X(pickup_location), ST_Y(pickup_location),
+		ST_X(dropoff_location), ST_Y(dropoff_location),
+		pickup_start_time, pickup_end_time,
+		tips,
+		status,
+		women_only, smoking, pets, luggage, quiet,
+		seat_count, accepted_seats, meeting_point_id,
+		created_at, updated_at, deleted_at
+	FROM requests
+	WHERE rider_id = $1
+	ORDER BY id ASC;
+`
+
+const exportUserTripsSQL = `
+	SELECT id, rider_id, driver_id, request_id, route_id, status, created_at, deleted_at
+	FROM trips
+	WHERE rider_id = $1 OR driver_id = $1
+	ORDER BY id ASC;
+`
+
+// ExportUserData collects everything stored about the user from a single snapshot
+func ExportUserData(id int32) (*model.UserExport, error) {
+	ctx := context.Background()
+	tx, err := DBClient.pgPool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	export := &model.UserExport{
+		Identities:    []*model.UserIdentity{},
+		Sessions:      []*model.Session{},
+		Vehicles:      []*model.Vehicle{},
+		Documents:     []*model.DriverDocument{},
+		Blocks:        []*model.UserBlock{},
+		Reports:       []*model.UserReport{},
+		Notifications: []*model.Notification{},
+		Routes:        []*model.Route{},
+		Requests:      []*model.Request{},
+		Trips:         []*model.Trip{},
+		ExportedAt:    time.Now(),
+	}
+
+	var user model.User
+	if err := tx.QueryRow(ctx, getUserSQL, id).Scan(
+		&user.Id,
+		&user.Name,
+		&user.Email,
+		&user.GoogleId,
+		&user.PictureUrl,
+		&user.CarType,
+		&user.CarPlate,
+		&user.Roles,
+		&user.SuspendedAt,
+		&user.DriverVerifiedAt,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&user.DeletedAt,
+	); err != nil {
+		Logger.Error(err)
+		return nil, Match(err, pgx.ErrNoRows, ErrUserNotFound).Return()
+	}
+	export.User = &user
+
+	var preferences model.RidePreferences
+	if err := tx.QueryRow(ctx, getRidePreferencesSQL, id, constants.LuggageNone).Scan(
+		&preferences.UserId,
+		&preferences.Gender,
+		&preferences.WomenOnly,
+		&preferences.Smoking,
+		&preferences.Pets,
+		&preferences.Luggage,
+		&preferences.Quiet,
+		&preferences.UpdatedAt,
+	); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	export.Preferences = &preferences
+
+	identityRows, err := tx.Query(ctx, listUserIdentitiesSQL, id)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	for identityRows.Next() {
+		var identity model.UserIdentity
+		if err := identityRows.Scan(
+			&identity.Id,
+			&identity.UserId,
+			&identity.Provider,
+			&identity.Subject,
+			&identity.Email,
+			&identity.CreatedAt,
+		); err != nil {
+			identityRows.Close()
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		export.Identities = append(export.Identities, &identity)
+	}
+	identityRows.Close()
+
+	sessionRows, err := tx.Query(ctx, exportUserSessionsSQL, id)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	for sessionRows.Next() {
+		var session model.Session
+		if err := sessionRows.Scan(
+			&session.Id,
+			&session.UserId,
+			&session.FamilyId,
+			&session.UserAgent,
+			&session.ExpiresAt,
+			&session.RevokedAt,
+			&session.CreatedAt,
+		); err != nil {
+			sessionRows.Close()
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		export.Sessions = append(export.Sessions, &session)
+	}
+	sessionRows.Close()
+
+	vehicleRows, err := tx.Query(ctx, exportUserVehiclesSQL, id)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	for vehicleRows.Next() {
+		var vehicle model.Vehicle
+		if err := vehicleRows.Scan(
+			&vehicle.Id,
+			&vehicle.UserId,
+			&vehicle.Make,
+			&vehicle.Model,
+			&vehicle.Color,
+			&vehicle.Plate,
+			&vehicle.SeatCount,
+			&vehicle.PhotoUrl,
+			&vehicle.Amenities,
+			&vehicle.IsDefault,
+			&vehicle.CreatedAt,
+			&vehicle.UpdatedAt,
+			&vehicle.DeletedAt,
+		); err != nil {
+			vehicleRows.Close()
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		export.Vehicles = append(export.Vehicles, &vehicle)
+	}
+	vehicleRows.Close()
+
+	documentRows, err := tx.Query(ctx, exportUserDriverDocumentsSQL, id)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	for documentRows.Next() {
+		var document model.DriverDocument
+		if err := documentRows.Scan(
+			&document.Id,
+			&document.UserId,
+			&document.Kind,
+			&document.BlobKey,
+			&document.ContentType,
+			&document.SizeBytes,
+			&document.Status,
+			&document.ReviewNote,
+			&document.ReviewerId,
+			&document.ReviewedAt,
+			&document.CreatedAt,
+			&document.DeletedAt,
+		); err != nil {
+			documentRows.Close()
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		export.Documents = append(export.Documents, &document)
+	}
+	documentRows.Close()
+
+	blockRows, err := tx.Query(ctx, exportUserBlocksSQL, id)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	for blockRows.Next() {
+		var block model.UserBlock
+		if err := blockRows.Scan(&block.BlockerId, &block.BlockedId, &block.CreatedAt); err != nil {
+			blockRows.Close()
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		export.Blocks = append(export.Blocks, &block)
+	}
+	blockRows.Close()
+
+	reportRows, err := tx.Query(ctx, exportUserReportsSQL, id)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	for reportRows.Next() {
+		var report model.UserReport
+		if err := reportRows.Scan(
+			&report.Id,
+			&report.ReporterId,
+			&report.ReportedId,
+			&report.TripId,
+			&report.Category,
+			&report.Description,
+			&report.Status,
+			&report.ReviewerId,
+			&report.ReviewNote,
+			&report.ResolvedAt,
+			&report.CreatedAt,
+		); err != nil {
+			reportRows.Close()
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		export.Reports = append(export.Reports, &report)
+	}
+	reportRows.Close()
+
+	notificationRows, err := tx.Query(ctx, exportUserNotificationsSQL, id)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	for notificationRows.Next() {
+		var notification model.Notification
+		if err := notificationRows.Scan(
+			&notification.Id,
+			&notification.UserId,
+			&notification.Kind,
+			&notification.Title,
+			&notification.Body,
+			&notification.Data,
+			&notification.ReadAt,
+			&notification.CreatedAt,
+		); err != nil {
+			notificationRows.Close()
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		export.Notifications = append(export.Notifications, &notification)
+	}
+	notificationRows.Close()
+
+	routeRows, err := tx.Query(ctx, exportUserRoutesSQL, id)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	for routeRows.Next() {
+		var route model.Route
+		if err := routeRows.Scan(
+			&route.Id,
+			&route.DriverId,
+			&route.StartLong,
+			&route.StartLat,
+			&route.EndLong,
+			&route.EndLat,
+			&route.StartTime,
+			&route.EndTime,
+			&route.Capacity,
+			&route.VehicleId,
+			&route.WomenOnly,
+			&route.SmokingAllowed,
+			&route.PetsAllowed,
+			&route.MaxLuggage,
+			&route.Music,
+			&route.WaitlistByTips,
+			&route.WaitlistAutoAccept,
+			&route.CreatedAt,
+			&route.UpdatedAt,
+			&route.DeletedAt,
+		); err != nil {
+			routeRows.Close()
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		export.Routes = append(export.Routes, &route)
+	}
+	routeRows.Close()
+
+	requestRows, err := tx.Query(ctx, exportUserRequestsSQL, id)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	for requestRows.Next() {
+		var request model.Request
+		if err := requestRows.Scan(
+			&request.Id,
+			&request.RiderId,
+			&request.RouteId,
+			&request.PickupLong,
+			&request.PickupLat,
+			&request.DropoffLong,
+			&request.DropoffLat,
+			&request.PickupStartTime,
+			&request.PickupEndTime,
+			&request.Tips,
+			&request.Status,
+			&request.WomenOnly,
+			&request.Smoking,
+			&request.Pets,
+			&request.Luggage,
+			&request.Quiet,
+			&request.SeatCount,
+			&request.AcceptedSeats,
+			&request.MeetingPointId,
+			&request.CreatedAt,
+			&request.UpdatedAt,
+			&request.DeletedAt,
+		); err != nil {
+			requestRows.Close()
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		export.Requests = append(export.Requests, &request)
+	}
+	requestRows.Close()
+
+	tripRows, err := tx.Query(ctx, exportUserTripsSQL, id)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	for tripRows.Next() {
+		var trip model.Trip
+		if err := tripRows.Scan(
+			&trip.Id,
+			&trip.RiderId,
+			&trip.DriverId,
+			&trip.RequestId,
+			&trip.RouteId,
+			&trip.Status,
+			&trip.CreatedAt,
+			&trip.DeletedAt,
+		); err != nil {
+			tripRows.Close()
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		export.Trips = append(export.Trips, &trip)
+	}
+	tripRows.Close()
+
+	return export, nil
+}
@@ -0,0 +1,338 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/DenChenn/blunder/pkg/blunder"
+	"github.com/jackc/pgx/v5"
+)
+
+const createUserIdentityTableSQL = `
+	CREATE TABLE IF NOT EXISTS user_identities (
+		id SERIAL,
+		user_id INT NOT NULL,
+		provider VARCHAR(50) NOT NULL,
+		subject VARCHAR(200) NOT NULL,
+		email VARCHAR(200) NOT NULL DEFAULT '',
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL,
+		UNIQUE (provider, subject)
+	);
+
+	CREATE INDEX IF NOT EXISTS user_identities_user_id_idx ON user_identities (user_id);
+
+	-- users created before identities existed signed in with google
+	INSERT INTO user_identities (user_id, provider, subject, email)
+	SELECT id, 'google', google_id, email FROM users WHERE google_id IS NOT NULL
+	ON CONFLICT (provider, subject) DO NOTHING;
+
+	-- identities of deleted accounts, only the subject hash is kept so that a login can be refused
+	CREATE TABLE IF NOT EXISTS deleted_user_identities (
+		provider VARCHAR(50) NOT NULL,
+		subject_hash VARCHAR(64) NOT NULL,
+		deleted_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL,
+		PRIMARY KEY (provider, subject_hash)
+	);
+`
+
+func initUserIdentityTable() error {
+	if _, err := DBClient.pgPool.Exec(context.Background(), createUserIdentityTableSQL); err != nil {
+		return err
+	}
+	return nil
+}
+
+const getUserIdentitySQL = `
+	SELECT id, user_id, provider, subject, email, created_at
+	FROM user_identities
+	WHERE provider = $1 AND subject = $2;
+`
+
+const createIdentityUserSQL = `
+	INSERT INTO users (name, email, google_id, picture_url)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id;
+`
+
+const updateIdentityUserSQL = `
+	UPDATE users SET
+		name = COALESCE(NULLIF($2, ''), name),
+		email = COALESCE(NULLIF($3, ''), email),
+		picture_url = COALESCE(NULLIF($4, ''), picture_url),
+		updated_at = NOW()
+	WHERE id = $1 AND deleted_at IS NULL
+	RETURNING id;
+`
+
+const deletedUserIdentityExistsSQL = `
+	SELECT EXISTS (
+		SELECT 1 FROM deleted_user_identities
+		WHERE provider = $1 AND subject_hash = encode(sha256(convert_to($2, 'UTF8')), 'hex')
+	);
+`
+
+const createUserIdentitySQL = `
+	INSERT INTO user_identities (user_id, provider, subject, email)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, created_at;
+`
+
+// UpsertUserByIdentity finds the user an external identity belongs to and refreshes its profile,
+// or creates a new user owning that identity. Identities of deleted accounts are refused with ErrUserDeleted
+// until ReleaseDeletedUserIdentity is called.
+func UpsertUserByIdentity(identity *model.UserIdentity, user *model.User) (*model.User, error) {
+	ctx := context.Background()
+	tx, err := DBClient.pgPool.Begin(ctx)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	var existed model.UserIdentity
+	err = tx.QueryRow(ctx, getUserIdentitySQL, identity.Provider, identity.Subject).Scan(
+		&existed.Id,
+		&existed.UserId,
+		&existed.Provider,
+		&existed.Subject,
+		&existed.Email,
+		&existed.CreatedAt,
+	)
+	switch {
+	case err == nil:
+		if err := tx.QueryRow(ctx, updateIdentityUserSQL,
+			existed.UserId, user.Name, user.Email, user.PictureUrl,
+		).Scan(&identity.UserId); err != nil {
+			Logger.Error(err)
+			return nil, Match(err, pgx.ErrNoRows, ErrUserDeleted).Return()
+		}
+	case errors.Is(err, pgx.ErrNoRows):
+		var deleted bool
+		if err := tx.QueryRow(ctx, deletedUserIdentityExistsSQL, identity.Provider, identity.Subject).Scan(&deleted); err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		if deleted {
+			return nil, ErrUserDeleted
+		}
+
+		var googleId *string
+		if identity.Provider == constants.IdentityProviderGoogle {
+			googleId = &identity.Subject
+		}
+		if err := tx.QueryRow(ctx, createIdentityUserSQL, user.Name, user.Email, googleId, user.PictureUrl).Scan(&identity.UserId); err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		if err := tx.QueryRow(ctx, createUserIdentitySQL,
+			identity.UserId, identity.Provider, identity.Subject, identity.Email,
+		).Scan(&identity.Id, &identity.CreatedAt); err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+	default:
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return GetUser(identity.UserId)
+}
+
+const linkUserIdentitySQL = `
+	INSERT INTO user_identities (user_id, provider, subject, email)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (provider, subject) DO UPDATE SET email = EXCLUDED.email
+	WHERE user_identities.user_id = EXCLUDED.user_id
+	RETURNING id, created_at;
+`
+
+// locking the user row serialises the links of a user
+const lockIdentityUserSQL = `
+	SELECT id FROM users WHERE id = $1 FOR UPDATE;
+`
+
+const otherProviderIdentityExistsSQL = `
+	SELECT EXISTS (
+		SELECT 1 FROM user_identities
+		WHERE user_id = $1 AND provider = $2 AND subject <> $3
+	);
+`
+
+const linkGoogleIdSQL = `
+	UPDATE users SET google_id = $2, updated_at = NOW()
+	WHERE id = $1;
+`
+
+// LinkUserIdentity attaches an identity to an existing user, identities owned by another user and a second
+// account of a provider the user already linked are refused
+func LinkUserIdentity(userId int32, identity *model.UserIdentity) (*model.UserIdentity, error) {
+	ctx := context.Background()
+	tx, err := DBClient.pgPool.Begin(ctx)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, lockIdentityUserSQL, userId); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	var linked bool
+	if err := tx.QueryRow(ctx, otherProviderIdentityExistsSQL, userId, identity.Provider, identity.Subject).Scan(&linked); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	if linked {
+		return nil, ErrUserIdentityProviderLinked
+	}
+
+	identity.UserId = userId
+	if err := tx.QueryRow(ctx, linkUserIdentitySQL,
+		identity.UserId, identity.Provider, identity.Subject, identity.Email,
+	).Scan(&identity.Id, &identity.CreatedAt); err != nil {
+		Logger.Error(err)
+		return nil, Match(err, pgx.ErrNoRows, ErrUserIdentityAlreadyLinked).Return()
+	}
+	if identity.Provider == constants.IdentityProviderGoogle {
+		if _, err := tx.Exec(ctx, linkGoogleIdSQL, userId, identity.Subject); err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return identity, nil
+}
+
+const listUserIdentitiesSQL = `
+	SELECT id, user_id, provider, subject, email, created_at
+	FROM user_identities
+	WHERE user_id = $1
+	ORDER BY created_at ASC;
+`
+
+func ListUserIdentities(userId int32) ([]*model.UserIdentity, error) {
+	rows, err := DBClient.pgPool.Query(context.Background(), listUserIdentitiesSQL, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var identities []*model.UserIdentity
+	for rows.Next() {
+		var identity model.UserIdentity
+		if err := rows.Scan(
+			&identity.Id,
+			&identity.UserId,
+			&identity.Provider,
+			&identity.Subject,
+			&identity.Email,
+			&identity.CreatedAt,
+		); err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		identities = append(identities, &identity)
+	}
+	return identities, nil
+}
+
+const deleteUserIdentitySQL = `
+	DELETE FROM user_identities
+	WHERE user_id = $1 AND provider = $2 AND subject = $3
+	RETURNING id;
+`
+
+const unlinkGoogleIdSQL = `
+	UPDATE users SET google_id = NULL, updated_at = NOW()
+	WHERE id = $1 AND google_id = $2;
+`
+
+func DeleteUserIdentity(userId int32, provider, subject string) error {
+	ctx := context.Background()
+	tx, err := DBClient.pgPool.Begin(ctx)
+	if err != nil {
+		Logger.Error(err)
+		return ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	var id int32
+	if err := tx.QueryRow(ctx, deleteUserIdentitySQL, userId, provider, subject).Scan(&id); err != nil {
+		Logger.Error(err)
+		return Match(err, pgx.ErrNoRows, ErrUserIdentityNotFound).Return()
+	}
+	if provider == constants.IdentityProviderGoogle {
+		if _, err := tx.Exec(ctx, unlinkGoogleIdSQL, userId, subject); err != nil {
+			Logger.Error(err)
+			return ErrUndefined.WithCustomMessage(err.Error())
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		Logger.Error(err)
+		return ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return nil
+}
+
+const releaseDeletedUserIdentitySQL = `
+	DELETE FROM deleted_user_identities
+	WHERE provider = $1 AND subject_hash = encode(sha256(convert_to($2, 'UTF8')), 'hex');
+`
+
+const releaseSoftDeletedUserIdentitySQL = `
+	DELETE FROM user_identities i
+	USING users u
+	WHERE u.id = i.user_id AND u.deleted_at IS NOT NULL AND i.provider = $1 AND i.subject = $2;
+`
+
+const releaseSoftDeletedGoogleIdSQL = `
+	UPDATE users SET google_id = NULL
+	WHERE google_id = $1 AND deleted_at IS NOT NULL;
+`
+
+// ReleaseDeletedUserIdentity lets an identity of a deleted account register again as a brand new user,
+// the deleted account itself is never restored
+func ReleaseDeletedUserIdentity(identity *model.UserIdentity) error {
+	ctx := context.Background()
+	tx, err := DBClient.pgPool.Begin(ctx)
+	if err != nil {
+		Logger.Error(err)
+		return ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, releaseDeletedUserIdentitySQL, identity.Provider, identity.Subject); err != nil {
+		Logger.Error(err)
+		return ErrUndefined.WithCustomMessage(err.Error())
+	}
+	// accounts soft deleted before anonymization existed still hold on to their identity
+	if _, err := tx.Exec(ctx, releaseSoftDeletedUserIdentitySQL, identity.Provider, identity.Subject); err != nil {
+		Logger.Error(err)
+		return ErrUndefined.WithCustomMessage(err.Error())
+	}
+	if identity.Provider == constants.IdentityProviderGoogle {
+		if _, err := tx.Exec(ctx, releaseSoftDeletedGoogleIdSQL, identity.Subject); err != nil {
+			Logger.Error(err)
+			return ErrUndefined.WithCustomMessage(err.Error())
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		Logger.Error(err)
+		return ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return nil
+}
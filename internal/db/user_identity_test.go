@@ -0,0 +1,210 @@
+package db
+
+import (
+	"context"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const testDeleteUserIdentitiesSQL = `
+	DELETE FROM user_identities WHERE user_id = $1;
+`
+
+const testGetUserNameEmailSQL = `
+	SELECT name, email FROM users WHERE id = $1;
+`
+
+const testDeleteDeletedUserIdentitiesSQL = `
+	DELETE FROM deleted_user_identities WHERE provider = $1;
+`
+
+var _ = Describe("DBUserIdentity", func() {
+	var existedUser *model.User
+
+	BeforeEach(func() {
+		var err error
+		existedUser, err = UpsertUserByIdentity(
+			&model.UserIdentity{Provider: constants.IdentityProviderLine, Subject: "test-line-sub", Email: "test@line"},
+			&model.User{Name: "test", Email: "test@line", PictureUrl: "test"},
+		)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_, err := pgPool.Exec(context.Background(), testDeleteUserIdentitiesSQL, existedUser.Id)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = pgPool.Exec(context.Background(), testDeleteUserSQL, existedUser.Id)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("UpsertUserByIdentity", func() {
+		var (
+			user *model.User
+			err  error
+		)
+
+		When("identity is already known", func() {
+			JustBeforeEach(func() {
+				user, err = UpsertUserByIdentity(
+					&model.UserIdentity{Provider: constants.IdentityProviderLine, Subject: "test-line-sub"},
+					&model.User{Name: "renamed"},
+				)
+			})
+
+			It("should return the same user with refreshed profile", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(user.Id).To(Equal(existedUser.Id))
+				Expect(user.Name).To(Equal("renamed"))
+				Expect(user.Email).To(Equal(existedUser.Email))
+				Expect(user.GoogleId).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("LinkUserIdentity", func() {
+		var (
+			identity *model.UserIdentity
+			err      error
+			userId   int32
+		)
+
+		JustBeforeEach(func() {
+			identity, err = LinkUserIdentity(userId, &model.UserIdentity{
+				Provider: constants.IdentityProviderEmail,
+				Subject:  "test@email",
+				Email:    "test@email",
+			})
+		})
+
+		When("identity is not linked yet", func() {
+			BeforeEach(func() {
+				userId = existedUser.Id
+			})
+
+			It("should link the identity", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(identity.UserId).To(Equal(existedUser.Id))
+
+				identities, listErr := ListUserIdentities(existedUser.Id)
+				Expect(listErr).NotTo(HaveOccurred())
+				Expect(identities).To(HaveLen(2))
+			})
+		})
+
+		When("identity is linked to another user", func() {
+			BeforeEach(func() {
+				userId = existedUser.Id
+				_, linkErr := LinkUserIdentity(-1, &model.UserIdentity{
+					Provider: constants.IdentityProviderEmail,
+					Subject:  "test@email",
+				})
+				Expect(linkErr).NotTo(HaveOccurred())
+				DeferCleanup(func() {
+					_, cleanupErr := pgPool.Exec(context.Background(), testDeleteUserIdentitiesSQL, -1)
+					Expect(cleanupErr).NotTo(HaveOccurred())
+				})
+			})
+
+			It("should return error", func() {
+				Expect(err).To(MatchError(ErrUserIdentityAlreadyLinked))
+			})
+		})
+
+		When("user already linked another account of the provider", func() {
+			BeforeEach(func() {
+				userId = existedUser.Id
+				_, linkErr := LinkUserIdentity(existedUser.Id, &model.UserIdentity{
+					Provider: constants.IdentityProviderEmail,
+					Subject:  "other@email",
+				})
+				Expect(linkErr).NotTo(HaveOccurred())
+			})
+
+			It("should return error", func() {
+				Expect(err).To(MatchError(ErrUserIdentityProviderLinked))
+				Expect(identity).To(BeNil())
+			})
+		})
+	})
+
+	Describe("DeleteUserIdentity", func() {
+		var (
+			provider string
+			err      error
+		)
+
+		JustBeforeEach(func() {
+			err = DeleteUserIdentity(existedUser.Id, provider, "test-line-sub")
+		})
+
+		When("identity does not exist", func() {
+			BeforeEach(func() {
+				provider = constants.IdentityProviderApple
+			})
+
+			It("should return error", func() {
+				Expect(err).To(MatchError(ErrUserIdentityNotFound))
+			})
+		})
+
+		When("identity exists", func() {
+			BeforeEach(func() {
+				provider = constants.IdentityProviderLine
+			})
+
+			It("should delete the identity", func() {
+				Expect(err).NotTo(HaveOccurred())
+
+				identities, listErr := ListUserIdentities(existedUser.Id)
+				Expect(listErr).NotTo(HaveOccurred())
+				Expect(identities).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("UpsertUserByIdentity after DeleteUser", func() {
+		var lineIdentity *model.UserIdentity
+
+		BeforeEach(func() {
+			lineIdentity = &model.UserIdentity{Provider: constants.IdentityProviderLine, Subject: "test-line-sub"}
+			_, err := DeleteUser(existedUser.Id)
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(func() {
+				_, cleanupErr := pgPool.Exec(context.Background(), testDeleteDeletedUserIdentitiesSQL, constants.IdentityProviderLine)
+				Expect(cleanupErr).NotTo(HaveOccurred())
+			})
+		})
+
+		It("should anonymize the deleted user", func() {
+			var name, email string
+			Expect(pgPool.QueryRow(context.Background(), testGetUserNameEmailSQL, existedUser.Id).
+				Scan(&name, &email)).To(Succeed())
+			Expect(name).NotTo(Equal(existedUser.Name))
+			Expect(email).To(BeEmpty())
+		})
+
+		It("should refuse to resurrect the account", func() {
+			user, err := UpsertUserByIdentity(lineIdentity, &model.User{Name: "test"})
+			Expect(err).To(MatchError(ErrUserDeleted))
+			Expect(user).To(BeNil())
+		})
+
+		It("should create a new account once released", func() {
+			Expect(ReleaseDeletedUserIdentity(lineIdentity)).To(Succeed())
+
+			user, err := UpsertUserByIdentity(lineIdentity, &model.User{Name: "test", Email: "test@line"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(user.Id).NotTo(Equal(existedUser.Id))
+			DeferCleanup(func() {
+				_, cleanupErr := pgPool.Exec(context.Background(), testDeleteUserIdentitiesSQL, user.Id)
+				Expect(cleanupErr).NotTo(HaveOccurred())
+				_, cleanupErr = pgPool.Exec(context.Background(), testDeleteUserSQL, user.Id)
+				Expect(cleanupErr).NotTo(HaveOccurred())
+			})
+		})
+	})
+})
@@ -0,0 +1,175 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/DenChenn/blunder/pkg/blunder"
+	"github.com/jackc/pgx/v5"
+)
+
+const createUserReportTableSQL = `
+	CREATE TABLE IF NOT EXISTS user_reports (
+		id SERIAL,
+		reporter_id INT NOT NULL,
+		reported_id INT NOT NULL,
+		trip_id INT,
+		category VARCHAR(50) NOT NULL,
+		description VARCHAR(2000) DEFAULT '' NOT NULL,
+		status VARCHAR(50) DEFAULT 'open' NOT NULL,
+		reviewer_id INT,
+		review_note VARCHAR(500) DEFAULT '' NOT NULL,
+		resolved_at TIMESTAMP WITH TIME ZONE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS user_reports_reported_id_idx ON user_reports (reported_id, created_at);
+	CREATE INDEX IF NOT EXISTS user_reports_open_idx ON user_reports (created_at) WHERE status = 'open';
+`
+
+func initUserReportTable() error {
+	if _, err := DBClient.pgPool.Exec(context.Background(), createUserReportTableSQL); err != nil {
+		return err
+	}
+	return nil
+}
+
+const createUserReportSQL = `
+	INSERT INTO user_reports (reporter_id, reported_id, trip_id, category, description)
+	SELECT $1, id, $3, $4, $5 FROM users WHERE id = $2 AND deleted_at IS NULL
+	RETURNING id, status, review_note, created_at;
+`
+
+func CreateUserReport(report *model.UserReport) (*model.UserReport, error) {
+	if err := DBClient.pgPool.QueryRow(context.Background(), createUserReportSQL,
+		report.ReporterId,
+		report.ReportedId,
+		report.TripId,
+		report.Category,
+		report.Description,
+	).Scan(
+		&report.Id,
+		&report.Status,
+		&report.ReviewNote,
+		&report.CreatedAt,
+	); err != nil {
+		Logger.Error(err)
+		return nil, Match(err, pgx.ErrNoRows, ErrUserNotFound).Return()
+	}
+	return report, nil
+}
+
+const getUserReportSQL = `
+	SELECT id, reporter_id, reported_id, trip_id, category, description, status, reviewer_id, review_note, resolved_at, created_at
+	FROM user_reports
+	WHERE id = $1;
+`
+
+func GetUserReport(id int32) (*model.UserReport, error) {
+	var report model.UserReport
+	if err := DBClient.pgPool.QueryRow(context.Background(), getUserReportSQL, id).Scan(
+		&report.Id,
+		&report.ReporterId,
+		&report.ReportedId,
+		&report.TripId,
+		&report.Category,
+		&report.Description,
+		&report.Status,
+		&report.ReviewerId,
+		&report.ReviewNote,
+		&report.ResolvedAt,
+		&report.CreatedAt,
+	); err != nil {
+		Logger.Error(err)
+		return nil, Match(err, pgx.ErrNoRows, ErrUserReportNotFound).Return()
+	}
+	return &report, nil
+}
+
+// the moderation queue is served oldest first, an empty status lists every report
+const listUserReportsSQL = `
+	SELECT id, reporter_id, reported_id, trip_id, category, description, status, reviewer_id, review_note, resolved_at, created_at
+	FROM user_reports
+	WHERE ($1 = '' OR status = $1) AND ($2 = 0 OR reported_id = $2)
+	ORDER BY created_at ASC
+	LIMIT $3 OFFSET $4;
+`
+
+func ListUserReports(status string, reportedId, limit, offset int32) ([]*model.UserReport, error) {
+	return listUserReports(listUserReportsSQL, status, reportedId, limit, offset)
+}
+
+const listUserReportsByReporterIdSQL = `
+	SELECT id, reporter_id, reported_id, trip_id, category, description, status, reviewer_id, review_note, resolved_at, created_at
+	FROM user_reports
+	WHERE reporter_id = $1
+	ORDER BY id DESC;
+`
+
+func ListUserReportsByReporterId(reporterId int32) ([]*model.UserReport, error) {
+	return listUserReports(listUserReportsByReporterIdSQL, reporterId)
+}
+
+func listUserReports(sql string, args ...interface{}) ([]*model.UserReport, error) {
+	rows, err := DBClient.pgPool.Query(context.Background(), sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []*model.UserReport{}
+	for rows.Next() {
+		var report model.UserReport
+		if err := rows.Scan(
+			&report.Id,
+			&report.ReporterId,
+			&report.ReportedId,
+			&report.TripId,
+			&report.Category,
+			&report.Description,
+			&report.Status,
+			&report.ReviewerId,
+			&report.ReviewNote,
+			&report.ResolvedAt,
+			&report.CreatedAt,
+		); err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		reports = append(reports, &report)
+	}
+	return reports, nil
+}
+
+const resolveUserReportSQL = `
+	UPDATE user_reports SET status = $2, review_note = $3, reviewer_id = $4, resolved_at = NOW()
+	WHERE id = $1 AND status = 'open'
+	RETURNING id;
+`
+
+func ResolveUserReport(id, reviewerId int32, status, note string) error {
+	if err := DBClient.pgPool.QueryRow(context.Background(), resolveUserReportSQL, id, status, note, reviewerId).Scan(&id); err != nil {
+		Logger.Error(err)
+		return Match(err, pgx.ErrNoRows, ErrUserReportAlreadyResolved).Return()
+	}
+	return nil
+}
+
+// only reports backed by a shared trip count, dismissed ones do not, and a single reporter counts once
+// however many reports they file
+const countRecentReportersSQL = `
+	SELECT COUNT(DISTINCT reporter_id)
+	FROM user_reports
+	WHERE reported_id = $1 AND trip_id IS NOT NULL AND status <> 'dismissed' AND created_at >= $2;
+`
+
+func CountRecentReporters(reportedId int32, since time.Time) (int32, error) {
+	var count int32
+	if err := DBClient.pgPool.QueryRow(context.Background(), countRecentReportersSQL, reportedId, since).Scan(&count); err != nil {
+		Logger.Error(err)
+		return 0, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return count, nil
+}
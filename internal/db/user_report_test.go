@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const testDeleteUserReportsSQL = `
+	DELETE FROM user_reports WHERE reported_id = $1;
+`
+
+var _ = Describe("DBUserReport", func() {
+	var (
+		reportedId int32
+		report     *model.UserReport
+	)
+
+	BeforeEach(func() {
+		err := pgPool.QueryRow(context.Background(), testCreateUserSQL, "test", "test", "test-user-report", "test").Scan(&reportedId)
+		Expect(err).NotTo(HaveOccurred())
+
+		report, err = CreateUserReport(&model.UserReport{
+			ReporterId: -1,
+			ReportedId: reportedId,
+			Category:   constants.UserReportCategoryNoShow,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Status).To(Equal(constants.UserReportStatusOpen))
+	})
+
+	AfterEach(func() {
+		_, err := pgPool.Exec(context.Background(), testDeleteUserReportsSQL, reportedId)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = pgPool.Exec(context.Background(), testDeleteUserSQL, reportedId)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("ListUserReports", func() {
+		It("should filter by status and reported user", func() {
+			reports, err := ListUserReports(constants.UserReportStatusOpen, reportedId, 200, 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reports).To(ConsistOf(HaveField("Id", report.Id)))
+
+			reports, err = ListUserReports(constants.UserReportStatusDismissed, reportedId, 200, 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reports).To(BeEmpty())
+		})
+	})
+
+	Describe("ResolveUserReport", func() {
+		It("should only resolve an open report once", func() {
+			Expect(ResolveUserReport(report.Id, -1, constants.UserReportStatusDismissed, "")).To(Succeed())
+			Expect(ResolveUserReport(report.Id, -1, constants.UserReportStatusActioned, "")).To(MatchError(ErrUserReportAlreadyResolved))
+		})
+	})
+
+	Describe("CountRecentReporters", func() {
+		It("should count each reporter with a trip once and skip dismissed reports", func() {
+			tripId := int32(-1)
+			_, err := CreateUserReport(&model.UserReport{ReporterId: -1, ReportedId: reportedId, TripId: &tripId, Category: constants.UserReportCategoryOther})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = CreateUserReport(&model.UserReport{ReporterId: -1, ReportedId: reportedId, TripId: &tripId, Category: constants.UserReportCategoryNoShow})
+			Expect(err).NotTo(HaveOccurred())
+			// reports without a trip wait for review but do not count
+			_, err = CreateUserReport(&model.UserReport{ReporterId: -3, ReportedId: reportedId, Category: constants.UserReportCategoryOther})
+			Expect(err).NotTo(HaveOccurred())
+			dismissed, err := CreateUserReport(&model.UserReport{ReporterId: -2, ReportedId: reportedId, TripId: &tripId, Category: constants.UserReportCategoryOther})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ResolveUserReport(dismissed.Id, -1, constants.UserReportStatusDismissed, "")).To(Succeed())
+
+			count, err := CountRecentReporters(reportedId, time.Now().Add(-time.Hour))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(count).To(Equal(int32(1)))
+		})
+	})
+})
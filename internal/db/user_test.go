@@ -2,10 +2,12 @@ package db
 
 import (
 	"context"
+	"github.com/CoRide-tw/backend/internal/constants"
 	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
 	"github.com/CoRide-tw/backend/internal/model"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"time"
 )
 
 const testCreateUserSQL = `
@@ -161,14 +163,15 @@ var _ = Describe("DBUser", func() {
 
 	Describe("DeleteUser", func() {
 		var (
-			id     int32
-			err    error
-			getErr error
-			user   *model.User
+			id        int32
+			err       error
+			getErr    error
+			user      *model.User
+			cancelled []*CancelledUserRequest
 		)
 
 		JustBeforeEach(func() {
-			err = DeleteUser(id)
+			cancelled, err = DeleteUser(id)
 			user, getErr = GetUser(id)
 		})
 
@@ -183,5 +186,88 @@ var _ = Describe("DBUser", func() {
 				Expect(user).To(BeNil())
 			})
 		})
+
+		When("user has an upcoming ride", func() {
+			var (
+				routeId int32
+				request *model.Request
+			)
+
+			BeforeEach(func() {
+				id = existedUser.Id
+				err := pgPool.QueryRow(context.Background(), testCreateRouteSQL, -1, 0, 0, 0.01, 0, time.Now().Add(time.Hour), time.Now().Add(2*time.Hour), 2).Scan(&routeId)
+				Expect(err).NotTo(HaveOccurred())
+				request, err = CreateRequest(&model.Request{
+					RiderId:          id,
+					RouteId:          routeId,
+					PickupStartTime:  time.Now().Add(time.Hour),
+					PickupEndTime:    time.Now().Add(2 * time.Hour),
+					Status:           constants.RequestStatusPending,
+					SeatCount:        1,
+					RideRequirements: model.RideRequirements{Luggage: constants.LuggageNone},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				_, err = AcceptRequest(request.Id, 0)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			AfterEach(func() {
+				_, err := pgPool.Exec(context.Background(), testDeleteTripsByRouteSQL, routeId)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = pgPool.Exec(context.Background(), testDeleteRequestsByRouteSQL, routeId)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = pgPool.Exec(context.Background(), testDeleteRouteSQL, routeId)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("should return the cancelled request with the status it had", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cancelled).To(ConsistOf(&CancelledUserRequest{
+					RequestId: request.Id,
+					RouteId:   routeId,
+					RiderId:   id,
+					DriverId:  -1,
+					Status:    constants.RequestStatusAccepted,
+				}))
+			})
+		})
+	})
+
+	Describe("GetPublicProfile", func() {
+		var (
+			id      int32
+			err     error
+			profile *model.PublicProfile
+		)
+
+		JustBeforeEach(func() {
+			profile, err = GetPublicProfile(id)
+		})
+
+		When("user does not exist", func() {
+			BeforeEach(func() {
+				id = 0
+			})
+
+			It("should return error", func() {
+				Expect(err).To(MatchError(ErrUserNotFound))
+				Expect(profile).To(BeNil())
+			})
+		})
+
+		When("user exists", func() {
+			BeforeEach(func() {
+				id = existedUser.Id
+			})
+
+			It("should return public fields only", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(profile.Id).To(Equal(existedUser.Id))
+				Expect(profile.Name).To(Equal(existedUser.Name))
+				Expect(profile.PictureUrl).To(Equal(existedUser.PictureUrl))
+				Expect(profile.TripCount).To(BeZero())
+				Expect(profile.Badges).To(BeEmpty())
+			})
+		})
 	})
 })
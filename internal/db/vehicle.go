@@ -0,0 +1,363 @@
+package db
+
+import (
+	"context"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/DenChenn/blunder/pkg/blunder"
+	"github.com/jackc/pgx/v5"
+)
+
+const createVehicleTableSQL = `
+	CREATE TABLE IF NOT EXISTS vehicles (
+		id SERIAL,
+		user_id INT NOT NULL,
+		make VARCHAR(100) NOT NULL DEFAULT '',
+		model VARCHAR(100) NOT NULL DEFAULT '',
+		color VARCHAR(50) NOT NULL DEFAULT '',
+		plate VARCHAR(50) NOT NULL,
+		seat_count INT NOT NULL,
+		photo_url VARCHAR(500) NOT NULL DEFAULT '',
+		amenities TEXT[] DEFAULT '{}' NOT NULL,
+		is_default BOOLEAN DEFAULT FALSE NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL,
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL,
+		deleted_at TIMESTAMP WITH TIME ZONE
+	);
+
+	CREATE INDEX IF NOT EXISTS vehicles_user_id_idx ON vehicles (user_id);
+	CREATE UNIQUE INDEX IF NOT EXISTS vehicles_user_id_default_idx
+		ON vehicles (user_id) WHERE is_default AND deleted_at IS NULL;
+
+	ALTER TABLE routes ADD COLUMN IF NOT EXISTS vehicle_id INT;
+
+	-- drivers registered their car on the user before vehicles existed, the seat count is
+	-- taken from the largest route they offered
+	INSERT INTO vehicles (user_id, make, plate, seat_count, is_default)
+	SELECT
+		u.id,
+		COALESCE(u.car_type, ''),
+		u.car_plate,
+		COALESCE((SELECT MAX(r.capacity) FROM routes r WHERE r.driver_id = u.id), 4),
+		TRUE
+	FROM users u
+	WHERE u.car_plate IS NOT NULL AND u.car_plate <> '' AND u.deleted_at IS NULL
+		AND NOT EXISTS (SELECT 1 FROM vehicles v WHERE v.user_id = u.id);
+`
+
+func initVehicleTable() error {
+	if _, err := DBClient.pgPool.Exec(context.Background(), createVehicleTableSQL); err != nil {
+		return err
+	}
+	return nil
+}
+
+const getVehicleSQL = `
+	SELECT id, user_id, make, model, color, plate, seat_count, photo_url, amenities, is_default, created_at, updated_at, deleted_at
+	FROM vehicles
+	WHERE id = $1 AND deleted_at IS NULL;
+`
+
+func GetVehicle(id int32) (*model.Vehicle, error) {
+	var vehicle model.Vehicle
+	if err := DBClient.pgPool.QueryRow(context.Background(), getVehicleSQL, id).Scan(
+		&vehicle.Id,
+		&vehicle.UserId,
+		&vehicle.Make,
+		&vehicle.Model,
+		&vehicle.Color,
+		&vehicle.Plate,
+		&vehicle.SeatCount,
+		&vehicle.PhotoUrl,
+		&vehicle.Amenities,
+		&vehicle.IsDefault,
+		&vehicle.CreatedAt,
+		&vehicle.UpdatedAt,
+		&vehicle.DeletedAt,
+	); err != nil {
+		Logger.Error(err)
+		return nil, Match(err, pgx.ErrNoRows, ErrVehicleNotFound).Return()
+	}
+	return &vehicle, nil
+}
+
+const getDefaultVehicleSQL = `
+	SELECT id FROM vehicles
+	WHERE user_id = $1 AND is_default AND deleted_at IS NULL;
+`
+
+func GetDefaultVehicle(userId int32) (*model.Vehicle, error) {
+	var id int32
+	if err := DBClient.pgPool.QueryRow(context.Background(), getDefaultVehicleSQL, userId).Scan(&id); err != nil {
+		Logger.Error(err)
+		return nil, Match(err, pgx.ErrNoRows, ErrVehicleNotFound).Return()
+	}
+	return GetVehicle(id)
+}
+
+const listVehiclesByUserIdSQL = `
+	SELECT id, user_id, make, model, color, plate, seat_count, photo_url, amenities, is_default, created_at, updated_at, deleted_at
+	FROM vehicles
+	WHERE user_id = $1 AND deleted_at IS NULL
+	ORDER BY is_default DESC, id ASC;
+`
+
+func ListVehiclesByUserId(userId int32) ([]*model.Vehicle, error) {
+	rows, err := DBClient.pgPool.Query(context.Background(), listVehiclesByUserIdSQL, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	vehicles := []*model.Vehicle{}
+	for rows.Next() {
+		var vehicle model.Vehicle
+		if err := rows.Scan(
+			&vehicle.Id,
+			&vehicle.UserId,
+			&vehicle.Make,
+			&vehicle.Model,
+			&vehicle.Color,
+			&vehicle.Plate,
+			&vehicle.SeatCount,
+			&vehicle.PhotoUrl,
+			&vehicle.Amenities,
+			&vehicle.IsDefault,
+			&vehicle.CreatedAt,
+			&vehicle.UpdatedAt,
+			&vehicle.DeletedAt,
+		); err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		vehicles = append(vehicles, &vehicle)
+	}
+	return vehicles, nil
+}
+
+const unsetDefaultVehicleSQL = `
+	UPDATE vehicles SET is_default = FALSE, updated_at = NOW()
+	WHERE user_id = $1 AND is_default AND deleted_at IS NULL;
+`
+
+const createVehicleSQL = `
+	INSERT INTO vehicles (user_id, make, model, color, plate, seat_count, photo_url, amenities, is_default)
+	VALUES (
+		$1, $2, $3, $4, $5, $6, $7, $8,
+		-- the first vehicle of a user is always the default one
+		$9 OR NOT EXISTS (SELECT 1 FROM vehicles WHERE user_id = $1 AND deleted_at IS NULL)
+	)
+	RETURNING id, is_default, created_at, updated_at;
+`
+
+// registering a vehicle makes the user a driver
+const grantDriverRoleSQL = `
+	UPDATE users SET roles = array_append(roles, 'driver'), updated_at = NOW()
+	WHERE id = $1 AND NOT 'driver' = ANY(roles);
+`
+
+func CreateVehicle(vehicle *model.Vehicle) (*model.Vehicle, error) {
+	ctx := context.Background()
+	tx, err := DBClient.pgPool.Begin(ctx)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	if vehicle.IsDefault {
+		if _, err := tx.Exec(ctx, unsetDefaultVehicleSQL, vehicle.UserId); err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+	}
+	if vehicle.Amenities == nil {
+		vehicle.Amenities = []string{}
+	}
+	if err := tx.QueryRow(ctx, createVehicleSQL,
+		vehicle.UserId,
+		vehicle.Make,
+		vehicle.Model,
+		vehicle.Color,
+		vehicle.Plate,
+		vehicle.SeatCount,
+		vehicle.PhotoUrl,
+		vehicle.Amenities,
+		vehicle.IsDefault,
+	).Scan(
+		&vehicle.Id,
+		&vehicle.IsDefault,
+		&vehicle.CreatedAt,
+		&vehicle.UpdatedAt,
+	); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	if _, err := tx.Exec(ctx, grantDriverRoleSQL, vehicle.UserId); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return vehicle, nil
+}
+
+const updateVehicleSQL = `
+	UPDATE vehicles SET
+		make = COALESCE(NULLIF($2, ''), make),
+		model = COALESCE(NULLIF($3, ''), model),
+		color = COALESCE(NULLIF($4, ''), color),
+		plate = COALESCE(NULLIF($5, ''), plate),
+		seat_count = COALESCE(NULLIF($6, 0), seat_count),
+		photo_url = COALESCE(NULLIF($7, ''), photo_url),
+		amenities = COALESCE($8, amenities),
+		updated_at = NOW()
+	WHERE id = $1 AND deleted_at IS NULL
+	RETURNING id, seat_count;
+`
+
+// the route rows are locked by the update, so no seat can be taken between the cap and the check
+const capVehicleRoutesSQL = `
+	UPDATE routes SET capacity = $2, updated_at = NOW()
+	WHERE vehicle_id = $1 AND capacity > $2 AND start_time > NOW() AND deleted_at IS NULL;
+`
+
+const vehicleRoutesOverbookedSQL = `
+	SELECT EXISTS (
+		SELECT 1 FROM routes r
+		WHERE r.vehicle_id = $1 AND r.start_time > NOW() AND r.deleted_at IS NULL
+			AND (
+				SELECT COALESCE(SUM(q.accepted_seats), 0) FROM requests q
+				WHERE q.route_id = r.id AND q.status = ANY($3) AND q.deleted_at IS NULL
+			) > $2
+	);
+`
+
+// UpdateVehicle overwrites the non-empty fields of vehicle, amenities are replaced when given. Upcoming
+// routes of the vehicle are capped at the new seat count, which is refused when one of them already holds more.
+func UpdateVehicle(id int32, vehicle *model.Vehicle) (*model.Vehicle, error) {
+	ctx := context.Background()
+	tx, err := DBClient.pgPool.Begin(ctx)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	var seatCount int32
+	if err := tx.QueryRow(ctx, updateVehicleSQL,
+		id,
+		vehicle.Make,
+		vehicle.Model,
+		vehicle.Color,
+		vehicle.Plate,
+		vehicle.SeatCount,
+		vehicle.PhotoUrl,
+		vehicle.Amenities,
+	).Scan(&id, &seatCount); err != nil {
+		Logger.Error(err)
+		return nil, Match(err, pgx.ErrNoRows, ErrVehicleNotFound).Return()
+	}
+	if _, err := tx.Exec(ctx, capVehicleRoutesSQL, id, seatCount); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	var overbooked bool
+	if err := tx.QueryRow(ctx, vehicleRoutesOverbookedSQL, id, seatCount, constants.SeatHoldingRequestStatuses).Scan(&overbooked); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	if overbooked {
+		return nil, ErrVehicleSeatsTaken
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return GetVehicle(id)
+}
+
+const setDefaultVehicleSQL = `
+	UPDATE vehicles SET is_default = TRUE, updated_at = NOW()
+	WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+	RETURNING id;
+`
+
+func SetDefaultVehicle(userId, id int32) error {
+	ctx := context.Background()
+	tx, err := DBClient.pgPool.Begin(ctx)
+	if err != nil {
+		Logger.Error(err)
+		return ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, unsetDefaultVehicleSQL, userId); err != nil {
+		Logger.Error(err)
+		return ErrUndefined.WithCustomMessage(err.Error())
+	}
+	if err := tx.QueryRow(ctx, setDefaultVehicleSQL, id, userId).Scan(&id); err != nil {
+		Logger.Error(err)
+		return Match(err, pgx.ErrNoRows, ErrVehicleNotFound).Return()
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		Logger.Error(err)
+		return ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return nil
+}
+
+const deleteVehicleSQL = `
+	UPDATE vehicles SET deleted_at = NOW(), updated_at = NOW()
+	WHERE id = $1 AND deleted_at IS NULL
+	RETURNING user_id, is_default;
+`
+
+// the oldest remaining vehicle takes over when the default one is deleted
+const promoteDefaultVehicleSQL = `
+	UPDATE vehicles SET is_default = TRUE, updated_at = NOW()
+	WHERE id = (
+		SELECT id FROM vehicles
+		WHERE user_id = $1 AND deleted_at IS NULL
+		ORDER BY id ASC
+		LIMIT 1
+	);
+`
+
+func DeleteVehicle(id int32) error {
+	ctx := context.Background()
+	tx, err := DBClient.pgPool.Begin(ctx)
+	if err != nil {
+		Logger.Error(err)
+		return ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		userId    int32
+		isDefault bool
+	)
+	if err := tx.QueryRow(ctx, deleteVehicleSQL, id).Scan(&userId, &isDefault); err != nil {
+		Logger.Error(err)
+		return Match(err, pgx.ErrNoRows, ErrVehicleNotFound).Return()
+	}
+	if isDefault {
+		if _, err := tx.Exec(ctx, promoteDefaultVehicleSQL, userId); err != nil {
+			Logger.Error(err)
+			return ErrUndefined.WithCustomMessage(err.Error())
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		Logger.Error(err)
+		return ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return nil
+}
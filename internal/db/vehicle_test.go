@@ -0,0 +1,132 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const testDeleteVehiclesSQL = `
+	DELETE FROM vehicles WHERE user_id = $1;
+`
+
+const testSetRouteVehicleSQL = `
+	UPDATE routes SET vehicle_id = $2 WHERE id = $1;
+`
+
+var _ = Describe("DBVehicle", func() {
+	var existedVehicle *model.Vehicle
+
+	BeforeEach(func() {
+		var err error
+		existedVehicle, err = CreateVehicle(&model.Vehicle{
+			UserId:    -1,
+			Make:      "test",
+			Plate:     "test-1",
+			SeatCount: 3,
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_, err := pgPool.Exec(context.Background(), testDeleteVehiclesSQL, -1)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("CreateVehicle", func() {
+		It("should make the first vehicle the default one", func() {
+			Expect(existedVehicle.IsDefault).To(BeTrue())
+
+			second, err := CreateVehicle(&model.Vehicle{UserId: -1, Plate: "test-2", SeatCount: 4})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(second.IsDefault).To(BeFalse())
+		})
+	})
+
+	Describe("SetDefaultVehicle", func() {
+		It("should move the default to the given vehicle", func() {
+			second, err := CreateVehicle(&model.Vehicle{UserId: -1, Plate: "test-2", SeatCount: 4})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(SetDefaultVehicle(-1, second.Id)).To(Succeed())
+
+			vehicle, err := GetDefaultVehicle(-1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(vehicle.Id).To(Equal(second.Id))
+		})
+
+		It("should return error for another user's vehicle", func() {
+			Expect(SetDefaultVehicle(-2, existedVehicle.Id)).To(MatchError(ErrVehicleNotFound))
+		})
+	})
+
+	Describe("UpdateVehicle", func() {
+		It("should only overwrite given fields", func() {
+			vehicle, err := UpdateVehicle(existedVehicle.Id, &model.Vehicle{Color: "red", Amenities: []string{"usb"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(vehicle.Color).To(Equal("red"))
+			Expect(vehicle.Make).To(Equal(existedVehicle.Make))
+			Expect(vehicle.SeatCount).To(Equal(existedVehicle.SeatCount))
+			Expect(vehicle.Amenities).To(Equal([]string{"usb"}))
+		})
+
+		It("should cap upcoming routes at fewer seats unless they already hold more", func() {
+			var routeId int32
+			start := time.Now().Add(time.Hour)
+			err := pgPool.QueryRow(context.Background(), testCreateRouteSQL, -1, 0, 0, 0, 0, start, start.Add(time.Hour), 3).Scan(&routeId)
+			Expect(err).NotTo(HaveOccurred())
+			defer pgPool.Exec(context.Background(), testDeleteRouteSQL, routeId)
+			defer pgPool.Exec(context.Background(), testDeleteRequestsByRouteSQL, routeId)
+			defer pgPool.Exec(context.Background(), testDeleteTripsByRouteSQL, routeId)
+			_, err = pgPool.Exec(context.Background(), testSetRouteVehicleSQL, routeId, existedVehicle.Id)
+			Expect(err).NotTo(HaveOccurred())
+
+			request, err := CreateRequest(&model.Request{
+				RiderId:          -2,
+				RouteId:          routeId,
+				PickupStartTime:  start,
+				PickupEndTime:    start,
+				Status:           constants.RequestStatusPending,
+				SeatCount:        2,
+				RideRequirements: model.RideRequirements{Luggage: constants.LuggageNone},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = AcceptRequest(request.Id, 0)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = UpdateVehicle(existedVehicle.Id, &model.Vehicle{SeatCount: 1})
+			Expect(err).To(MatchError(ErrVehicleSeatsTaken))
+			route, err := GetRoute(routeId)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(route.Capacity).To(Equal(int32(3)))
+
+			vehicle, err := UpdateVehicle(existedVehicle.Id, &model.Vehicle{SeatCount: 2})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(vehicle.SeatCount).To(Equal(int32(2)))
+			route, err = GetRoute(routeId)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(route.Capacity).To(Equal(int32(2)))
+		})
+	})
+
+	Describe("DeleteVehicle", func() {
+		It("should promote another vehicle to default", func() {
+			second, err := CreateVehicle(&model.Vehicle{UserId: -1, Plate: "test-2", SeatCount: 4})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(DeleteVehicle(existedVehicle.Id)).To(Succeed())
+
+			vehicle, err := GetDefaultVehicle(-1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(vehicle.Id).To(Equal(second.Id))
+
+			_, err = GetVehicle(existedVehicle.Id)
+			Expect(err).To(MatchError(ErrVehicleNotFound))
+		})
+	})
+})
@@ -0,0 +1,158 @@
+package db
+
+import (
+	"context"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	. "github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/DenChenn/blunder/pkg/blunder"
+	"github.com/jackc/pgx/v5"
+)
+
+const listWaitlistSQL = `
+	SELECT
+		q.id,
+		q.rider_id,
+		q.route_id,
+		ST_X(q.pickup_location), ST_Y(q.pickup_location),
+		ST_X(q.dropoff_location), ST_Y(q.dropoff_location),
+		q.pickup_start_time, q.pickup_end_time,
+		q.tips,
+		q.status,
+		q.women_only, q.smoking, q.pets, q.luggage, q.quiet,
+		q.seat_count, q.accepted_seats, q.meeting_point_id,
+		q.created_at, q.updated_at
+	FROM requests q
+		JOIN routes r ON q.route_id = r.id
+	WHERE q.route_id = $1 AND q.status = $2 AND q.deleted_at IS NULL
+	ORDER BY CASE WHEN r.waitlist_by_tips THEN q.tips ELSE 0 END DESC, q.created_at ASC, q.id ASC
+`
+
+// ListWaitlist lists the waitlisted requests of a route in the order they will be promoted
+func ListWaitlist(routeId int32) ([]*model.Request, error) {
+	rows, err := DBClient.pgPool.Query(context.Background(), listWaitlistSQL, routeId, constants.RequestStatusWaitlisted)
+	if err != nil {
+		return nil, err
+	}
+	return scanWaitlist(rows)
+}
+
+func scanWaitlist(rows pgx.Rows) ([]*model.Request, error) {
+	defer rows.Close()
+
+	requests := []*model.Request{}
+	for rows.Next() {
+		var request model.Request
+		if err := rows.Scan(
+			&request.Id,
+			&request.RiderId,
+			&request.RouteId,
+			&request.PickupLong,
+			&request.PickupLat,
+			&request.DropoffLong,
+			&request.DropoffLat,
+			&request.PickupStartTime,
+			&request.PickupEndTime,
+			&request.Tips,
+			&request.Status,
+			&request.WomenOnly,
+			&request.Smoking,
+			&request.Pets,
+			&request.Luggage,
+			&request.Quiet,
+			&request.SeatCount,
+			&request.AcceptedSeats,
+			&request.MeetingPointId,
+			&request.CreatedAt,
+			&request.UpdatedAt,
+		); err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		requests = append(requests, &request)
+	}
+	return requests, nil
+}
+
+const lockRouteWaitlistSQL = `
+	SELECT
+		r.capacity - COALESCE((
+			SELECT SUM(q.accepted_seats) FROM requests q
+			WHERE q.route_id = r.id AND q.status = ANY($2) AND q.deleted_at IS NULL
+		), 0),
+		r.waitlist_auto_accept
+	FROM routes r
+	WHERE r.id = $1 AND r.deleted_at IS NULL
+	FOR UPDATE;
+`
+
+const promoteWaitlistedRequestSQL = `
+	UPDATE requests SET status = $2, updated_at = NOW()
+	WHERE id = $1;
+`
+
+// PromoteWaitlist moves waitlisted requests that fit the route's free seats off the waitlist.
+// With auto accept every request that fits is accepted in waitlist order, otherwise the first one that fits
+// becomes pending for the driver to answer. The promoted requests are returned with their new status.
+func PromoteWaitlist(routeId int32) ([]*model.Request, error) {
+	ctx := context.Background()
+	tx, err := DBClient.pgPool.Begin(ctx)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		freeSeats  int32
+		autoAccept bool
+	)
+	if err := tx.QueryRow(ctx, lockRouteWaitlistSQL, routeId, constants.SeatHoldingRequestStatuses).Scan(&freeSeats, &autoAccept); err != nil {
+		Logger.Error(err)
+		return nil, Match(err, pgx.ErrNoRows, ErrRouteNotFound).Return()
+	}
+
+	rows, err := tx.Query(ctx, listWaitlistSQL, routeId, constants.RequestStatusWaitlisted)
+	if err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	waitlist, err := scanWaitlist(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	promoted := []*model.Request{}
+	for _, request := range waitlist {
+		if request.SeatCount > freeSeats {
+			continue
+		}
+		if !autoAccept {
+			if _, err := tx.Exec(ctx, promoteWaitlistedRequestSQL, request.Id, constants.RequestStatusPending); err != nil {
+				Logger.Error(err)
+				return nil, ErrUndefined.WithCustomMessage(err.Error())
+			}
+			request.Status = constants.RequestStatusPending
+			promoted = append(promoted, request)
+			break
+		}
+
+		if _, err := tx.Exec(ctx, acceptRequestSeatsSQL, request.Id, constants.RequestStatusAccepted, request.SeatCount); err != nil {
+			Logger.Error(err)
+			return nil, ErrUndefined.WithCustomMessage(err.Error())
+		}
+		if _, err := createTripFromRequest(ctx, tx, request.Id); err != nil {
+			return nil, err
+		}
+		request.Status, request.AcceptedSeats = constants.RequestStatusAccepted, request.SeatCount
+		promoted = append(promoted, request)
+		freeSeats -= request.SeatCount
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		Logger.Error(err)
+		return nil, ErrUndefined.WithCustomMessage(err.Error())
+	}
+	return promoted, nil
+}
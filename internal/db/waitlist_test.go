@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const testSetWaitlistAutoAcceptSQL = `
+	UPDATE routes SET waitlist_auto_accept = TRUE WHERE id = $1;
+`
+
+var _ = Describe("DBWaitlist", func() {
+	var (
+		routeId  int32
+		accepted *model.Request
+	)
+
+	newRequest := func(status string, seatCount int32) *model.Request {
+		request, err := CreateRequest(&model.Request{
+			RiderId:          -1,
+			RouteId:          routeId,
+			PickupStartTime:  time.Now(),
+			PickupEndTime:    time.Now(),
+			Status:           status,
+			SeatCount:        seatCount,
+			RideRequirements: model.RideRequirements{Luggage: constants.LuggageNone},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		return request
+	}
+
+	BeforeEach(func() {
+		err := pgPool.QueryRow(context.Background(), testCreateRouteSQL, -1, 0, 0, 0, 0, time.Now(), time.Now(), 2).Scan(&routeId)
+		Expect(err).NotTo(HaveOccurred())
+
+		accepted = newRequest(constants.RequestStatusPending, 2)
+		_, err = AcceptRequest(accepted.Id, 0)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_, err := pgPool.Exec(context.Background(), testDeleteTripsByRouteSQL, routeId)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = pgPool.Exec(context.Background(), testDeleteRequestsByRouteSQL, routeId)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = pgPool.Exec(context.Background(), testDeleteRouteSQL, routeId)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("PromoteWaitlist", func() {
+		It("should not promote while the route is full", func() {
+			newRequest(constants.RequestStatusWaitlisted, 1)
+
+			promoted, err := PromoteWaitlist(routeId)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(promoted).To(BeEmpty())
+		})
+
+		It("should make the first waitlisted request pending", func() {
+			first := newRequest(constants.RequestStatusWaitlisted, 1)
+			newRequest(constants.RequestStatusWaitlisted, 1)
+			Expect(DeleteRequest(accepted.Id)).To(Succeed())
+
+			promoted, err := PromoteWaitlist(routeId)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(promoted).To(ConsistOf(HaveField("Id", first.Id)))
+			Expect(promoted[0].Status).To(Equal(constants.RequestStatusPending))
+		})
+
+		It("should accept every request that fits when the driver enabled it", func() {
+			_, err := pgPool.Exec(context.Background(), testSetWaitlistAutoAcceptSQL, routeId)
+			Expect(err).NotTo(HaveOccurred())
+			newRequest(constants.RequestStatusWaitlisted, 1)
+			newRequest(constants.RequestStatusWaitlisted, 1)
+			newRequest(constants.RequestStatusWaitlisted, 1)
+			Expect(DeleteRequest(accepted.Id)).To(Succeed())
+
+			promoted, err := PromoteWaitlist(routeId)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(promoted).To(HaveLen(2))
+			Expect(promoted).To(HaveEach(HaveField("Status", constants.RequestStatusAccepted)))
+
+			waitlist, err := ListWaitlist(routeId)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(waitlist).To(HaveLen(1))
+		})
+	})
+})
@@ -35,6 +35,237 @@ var (
 		ErrorCode:      "ErrUserNotFound",
 		Message:        "User not found",
 	}
+	ErrSessionNotFound = &dberr{
+		Id:             "d18c821c32b5c6fc5009c30363937b73",
+		HttpStatusCode: 404,
+		GrpcStatusCode: 5,
+		ErrorCode:      "ErrSessionNotFound",
+		Message:        "Session not found",
+	}
+	ErrSessionRevoked = &dberr{
+		Id:             "276726c71af59816e10b764ee9bc49b3",
+		HttpStatusCode: 409,
+		GrpcStatusCode: 9,
+		ErrorCode:      "ErrSessionRevoked",
+		Message:        "Session already revoked",
+	}
+	ErrUserIdentityNotFound = &dberr{
+		Id:             "1d76c592229e25f3235b5a7e70d5acea",
+		HttpStatusCode: 404,
+		GrpcStatusCode: 5,
+		ErrorCode:      "ErrUserIdentityNotFound",
+		Message:        "User identity not found",
+	}
+	ErrUserIdentityAlreadyLinked = &dberr{
+		Id:             "a0c7a9ee0e2147fc8baaff8915b8f645",
+		HttpStatusCode: 409,
+		GrpcStatusCode: 6,
+		ErrorCode:      "ErrUserIdentityAlreadyLinked",
+		Message:        "Identity is already linked to another user",
+	}
+	ErrEmailLoginThrottled = &dberr{
+		Id:             "714a4ae58650a15f55cbeba58d8ee5dc",
+		HttpStatusCode: 429,
+		GrpcStatusCode: 8,
+		ErrorCode:      "ErrEmailLoginThrottled",
+		Message:        "Too many login links were asked for, please wait a few minutes",
+	}
+	ErrUserIdentityProviderLinked = &dberr{
+		Id:             "2dfcd824c362668e33ee1a4b2947527c",
+		HttpStatusCode: 409,
+		GrpcStatusCode: 6,
+		ErrorCode:      "ErrUserIdentityProviderLinked",
+		Message:        "Another account of this provider is already linked, unlink it first",
+	}
+	ErrEmailLoginTokenNotFound = &dberr{
+		Id:             "2e8bdfee61183c1f9e14a4053a7b858f",
+		HttpStatusCode: 404,
+		GrpcStatusCode: 5,
+		ErrorCode:      "ErrEmailLoginTokenNotFound",
+		Message:        "Email login token not found or expired",
+	}
+	ErrUserDeleted = &dberr{
+		Id:             "ab89084e8ba4826a4176ab781a498a17",
+		HttpStatusCode: 410,
+		GrpcStatusCode: 9,
+		ErrorCode:      "ErrUserDeleted",
+		Message:        "Account was deleted, register again to create a new one",
+	}
+	ErrVehicleNotFound = &dberr{
+		Id:             "740cb7bd33eabb6fd6619bc82e86075d",
+		HttpStatusCode: 404,
+		GrpcStatusCode: 5,
+		ErrorCode:      "ErrVehicleNotFound",
+		Message:        "Vehicle not found",
+	}
+	ErrVehicleSeatsTaken = &dberr{
+		Id:             "b775b8f2c48e4ab3026d80c99c41c720",
+		HttpStatusCode: 409,
+		GrpcStatusCode: 9,
+		ErrorCode:      "ErrVehicleSeatsTaken",
+		Message:        "An upcoming route of the vehicle already has more seats taken than the new seat count",
+	}
+	ErrDriverDocumentNotFound = &dberr{
+		Id:             "0c318814b5dfb82db211e3ed4f5ee0c5",
+		HttpStatusCode: 404,
+		GrpcStatusCode: 5,
+		ErrorCode:      "ErrDriverDocumentNotFound",
+		Message:        "Driver document not found",
+	}
+	ErrDriverDocumentAlreadyReviewed = &dberr{
+		Id:             "e7017b268e913bf9c866f95f7289bc25",
+		HttpStatusCode: 409,
+		GrpcStatusCode: 9,
+		ErrorCode:      "ErrDriverDocumentAlreadyReviewed",
+		Message:        "Driver document was already reviewed",
+	}
+	ErrUserBlockNotFound = &dberr{
+		Id:             "f27a5aa6e2425abb692cbe9fd27ef95f",
+		HttpStatusCode: 404,
+		GrpcStatusCode: 5,
+		ErrorCode:      "ErrUserBlockNotFound",
+		Message:        "User is not blocked",
+	}
+	ErrUserReportNotFound = &dberr{
+		Id:             "0e6fbdafc83e8010d59058c2d9a30c09",
+		HttpStatusCode: 404,
+		GrpcStatusCode: 5,
+		ErrorCode:      "ErrUserReportNotFound",
+		Message:        "User report not found",
+	}
+	ErrUserReportAlreadyResolved = &dberr{
+		Id:             "ca1e005aad8927d4b3d17baa2a637af1",
+		HttpStatusCode: 409,
+		GrpcStatusCode: 9,
+		ErrorCode:      "ErrUserReportAlreadyResolved",
+		Message:        "User report was already resolved",
+	}
+	ErrRequestNotPending = &dberr{
+		Id:             "48e45018ac73723d99ce6d0591bacaea",
+		HttpStatusCode: 409,
+		GrpcStatusCode: 9,
+		ErrorCode:      "ErrRequestNotPending",
+		Message:        "Request is no longer pending",
+	}
+	ErrRequestNotOffered = &dberr{
+		Id:             "a7562b7d34b8714afe3c8fea9f81fb54",
+		HttpStatusCode: 409,
+		GrpcStatusCode: 9,
+		ErrorCode:      "ErrRequestNotOffered",
+		Message:        "Request has no seat offer to confirm",
+	}
+	ErrRequestNotCancellable = &dberr{
+		Id:             "ad1e50820321cc616845778fcec45e9b",
+		HttpStatusCode: 409,
+		GrpcStatusCode: 9,
+		ErrorCode:      "ErrRequestNotCancellable",
+		Message:        "Request was already completed or cancelled",
+	}
+	ErrRouteSeatsUnavailable = &dberr{
+		Id:             "80a87a2d085bde105e24d4f687673306",
+		HttpStatusCode: 409,
+		GrpcStatusCode: 9,
+		ErrorCode:      "ErrRouteSeatsUnavailable",
+		Message:        "Route does not have enough free seats",
+	}
+	ErrNotificationNotFound = &dberr{
+		Id:             "b24fa849f4b9a9aadda4bdb41aa7766b",
+		HttpStatusCode: 404,
+		GrpcStatusCode: 5,
+		ErrorCode:      "ErrNotificationNotFound",
+		Message:        "Notification not found",
+	}
+	ErrTripNotScheduled = &dberr{
+		Id:             "e61a5a1c9f1091c441aaccbac6282242",
+		HttpStatusCode: 409,
+		GrpcStatusCode: 9,
+		ErrorCode:      "ErrTripNotScheduled",
+		Message:        "Trip is no longer scheduled",
+	}
+	ErrTripNotActive = &dberr{
+		Id:             "58fd76215d01e05852dc309d59c2b6ce",
+		HttpStatusCode: 409,
+		GrpcStatusCode: 9,
+		ErrorCode:      "ErrTripNotActive",
+		Message:        "Trip was already completed or cancelled",
+	}
+	ErrRequestProposalNotOpen = &dberr{
+		Id:             "bac1ede4dde6491ef1e43eb373dd1b56",
+		HttpStatusCode: 409,
+		GrpcStatusCode: 9,
+		ErrorCode:      "ErrRequestProposalNotOpen",
+		Message:        "Request has no open counter-proposal",
+	}
+	ErrMeetingPointNotFound = &dberr{
+		Id:             "30d905ade21523b114a47d016a39ef89",
+		HttpStatusCode: 404,
+		GrpcStatusCode: 5,
+		ErrorCode:      "ErrMeetingPointNotFound",
+		Message:        "Meeting point not found",
+	}
+	ErrDriverPresenceNotFound = &dberr{
+		Id:             "d00cdc2f0180a5946606dafa8fcc1e82",
+		HttpStatusCode: 404,
+		GrpcStatusCode: 5,
+		ErrorCode:      "ErrDriverPresenceNotFound",
+		Message:        "Driver has not gone online in instant mode",
+	}
+	ErrInstantRequestNotFound = &dberr{
+		Id:             "4fe63dec078317c785bcb8e9cf2fb6dc",
+		HttpStatusCode: 404,
+		GrpcStatusCode: 5,
+		ErrorCode:      "ErrInstantRequestNotFound",
+		Message:        "Instant request not found",
+	}
+	ErrInstantOfferNotActive = &dberr{
+		Id:             "f4256ffb659014dbddf965aad0e22633",
+		HttpStatusCode: 409,
+		GrpcStatusCode: 9,
+		ErrorCode:      "ErrInstantOfferNotActive",
+		Message:        "Instant ride offer is no longer open",
+	}
+	ErrInstantRequestNotActive = &dberr{
+		Id:             "5486df0f94e575213926db2fba7d2b15",
+		HttpStatusCode: 409,
+		GrpcStatusCode: 9,
+		ErrorCode:      "ErrInstantRequestNotActive",
+		Message:        "Instant request is no longer looking for a driver",
+	}
+	ErrInstantRideNotAccepted = &dberr{
+		Id:             "4919a9074e8bf36607f955487b3b06c4",
+		HttpStatusCode: 409,
+		GrpcStatusCode: 9,
+		ErrorCode:      "ErrInstantRideNotAccepted",
+		Message:        "Instant ride is not in progress with this driver",
+	}
+	ErrAssignmentProposalNotFound = &dberr{
+		Id:             "fd304774ccf7d4f8ee0fe77c1fb0ac80",
+		HttpStatusCode: 404,
+		GrpcStatusCode: 5,
+		ErrorCode:      "ErrAssignmentProposalNotFound",
+		Message:        "Assignment proposal not found",
+	}
+	ErrAssignmentProposalNotOpen = &dberr{
+		Id:             "39546d01b9eea91ce1220f26f77626a0",
+		HttpStatusCode: 409,
+		GrpcStatusCode: 9,
+		ErrorCode:      "ErrAssignmentProposalNotOpen",
+		Message:        "Assignment proposal is no longer open",
+	}
+	ErrJourneyNotFound = &dberr{
+		Id:             "1b0cd807f5874ede3cf55dc43faac49f",
+		HttpStatusCode: 404,
+		GrpcStatusCode: 5,
+		ErrorCode:      "ErrJourneyNotFound",
+		Message:        "Journey not found",
+	}
+	ErrJourneyNotActive = &dberr{
+		Id:             "aef83badbab6f96ddd7c2bdf74317e3f",
+		HttpStatusCode: 409,
+		GrpcStatusCode: 9,
+		ErrorCode:      "ErrJourneyNotActive",
+		Message:        "Journey is already cancelled",
+	}
 )
 
 var (
@@ -42,6 +273,39 @@ var (
 	_ Error = ErrRouteNotFound
 	_ Error = ErrTripNotFound
 	_ Error = ErrUserNotFound
+	_ Error = ErrSessionNotFound
+	_ Error = ErrSessionRevoked
+	_ Error = ErrUserIdentityNotFound
+	_ Error = ErrUserIdentityAlreadyLinked
+	_ Error = ErrEmailLoginThrottled
+	_ Error = ErrUserIdentityProviderLinked
+	_ Error = ErrEmailLoginTokenNotFound
+	_ Error = ErrUserDeleted
+	_ Error = ErrVehicleNotFound
+	_ Error = ErrVehicleSeatsTaken
+	_ Error = ErrDriverDocumentNotFound
+	_ Error = ErrDriverDocumentAlreadyReviewed
+	_ Error = ErrUserBlockNotFound
+	_ Error = ErrUserReportNotFound
+	_ Error = ErrUserReportAlreadyResolved
+	_ Error = ErrRequestNotPending
+	_ Error = ErrRequestNotOffered
+	_ Error = ErrRequestNotCancellable
+	_ Error = ErrRouteSeatsUnavailable
+	_ Error = ErrNotificationNotFound
+	_ Error = ErrTripNotScheduled
+	_ Error = ErrTripNotActive
+	_ Error = ErrRequestProposalNotOpen
+	_ Error = ErrMeetingPointNotFound
+	_ Error = ErrDriverPresenceNotFound
+	_ Error = ErrInstantRequestNotFound
+	_ Error = ErrInstantOfferNotActive
+	_ Error = ErrInstantRequestNotActive
+	_ Error = ErrInstantRideNotAccepted
+	_ Error = ErrAssignmentProposalNotFound
+	_ Error = ErrAssignmentProposalNotOpen
+	_ Error = ErrJourneyNotFound
+	_ Error = ErrJourneyNotActive
 )
 
 type dberr struct {
@@ -35,6 +35,195 @@ var (
 		ErrorCode:      "ErrUserIdQueryParamMissing",
 		Message:        "User ID query parameter is missing",
 	}
+	ErrRefreshTokenInvalid = &svcerr{
+		Id:             "f7f78409f9dcaaef2ed028e58e9eb16d",
+		HttpStatusCode: 401,
+		GrpcStatusCode: 16,
+		ErrorCode:      "ErrRefreshTokenInvalid",
+		Message:        "Refresh token is invalid",
+	}
+	ErrRefreshTokenExpired = &svcerr{
+		Id:             "c73d6133d3bb40360874abb032a5479d",
+		HttpStatusCode: 401,
+		GrpcStatusCode: 16,
+		ErrorCode:      "ErrRefreshTokenExpired",
+		Message:        "Refresh token is expired",
+	}
+	ErrRefreshTokenReused = &svcerr{
+		Id:             "282a361eae0c1089c01f943d2f344059",
+		HttpStatusCode: 401,
+		GrpcStatusCode: 16,
+		ErrorCode:      "ErrRefreshTokenReused",
+		Message:        "Refresh token reuse detected, session revoked",
+	}
+	ErrIdentityProviderUnsupported = &svcerr{
+		Id:             "54272197bb8637b25632c4ffd53f59c2",
+		HttpStatusCode: 400,
+		GrpcStatusCode: 3,
+		ErrorCode:      "ErrIdentityProviderUnsupported",
+		Message:        "Identity provider is not supported",
+	}
+	ErrLastIdentityUnlink = &svcerr{
+		Id:             "ab70e289ddc35794b4f933fbef92248e",
+		HttpStatusCode: 400,
+		GrpcStatusCode: 9,
+		ErrorCode:      "ErrLastIdentityUnlink",
+		Message:        "Cannot unlink the last identity of a user",
+	}
+	ErrUserSuspended = &svcerr{
+		Id:             "7a3bd980ca1aa5c0c3169416e191452c",
+		HttpStatusCode: 403,
+		GrpcStatusCode: 7,
+		ErrorCode:      "ErrUserSuspended",
+		Message:        "User is suspended",
+	}
+	ErrVehicleNotOwned = &svcerr{
+		Id:             "aae9898aa5f748233423d5d5d8d94acf",
+		HttpStatusCode: 403,
+		GrpcStatusCode: 7,
+		ErrorCode:      "ErrVehicleNotOwned",
+		Message:        "Vehicle does not belong to the driver",
+	}
+	ErrRouteCapacityExceedsSeats = &svcerr{
+		Id:             "602dbd5595a3ecd06ed17e2e5338b48b",
+		HttpStatusCode: 400,
+		GrpcStatusCode: 3,
+		ErrorCode:      "ErrRouteCapacityExceedsSeats",
+		Message:        "Route capacity exceeds the seat count of the vehicle",
+	}
+	ErrDriverDocumentKindInvalid = &svcerr{
+		Id:             "4053dd34be2c38e4598c7b54de489eb9",
+		HttpStatusCode: 400,
+		GrpcStatusCode: 3,
+		ErrorCode:      "ErrDriverDocumentKindInvalid",
+		Message:        "Driver document kind must be license or vehicle_registration",
+	}
+	ErrDriverDocumentTooLarge = &svcerr{
+		Id:             "24659abe230461693e47ba99fb4f7d46",
+		HttpStatusCode: 413,
+		GrpcStatusCode: 3,
+		ErrorCode:      "ErrDriverDocumentTooLarge",
+		Message:        "Driver document is too large",
+	}
+	ErrDriverDocumentTypeUnsupported = &svcerr{
+		Id:             "75d4139e91fdf8dd5e28b355c1dbfc6a",
+		HttpStatusCode: 415,
+		GrpcStatusCode: 3,
+		ErrorCode:      "ErrDriverDocumentTypeUnsupported",
+		Message:        "Driver document must be a JPEG, PNG or PDF file",
+	}
+	ErrUserBlocked = &svcerr{
+		Id:             "105b3de164be1e313742e8225ef24a82",
+		HttpStatusCode: 403,
+		GrpcStatusCode: 7,
+		ErrorCode:      "ErrUserBlocked",
+		Message:        "User is blocked",
+	}
+	ErrTargetIsSelf = &svcerr{
+		Id:             "f95d5a6833be48c24533dc76c4694e23",
+		HttpStatusCode: 400,
+		GrpcStatusCode: 3,
+		ErrorCode:      "ErrTargetIsSelf",
+		Message:        "Cannot block or report yourself",
+	}
+	ErrUserReportCategoryInvalid = &svcerr{
+		Id:             "08d8318cea30c8e694a9b8ccd326e810",
+		HttpStatusCode: 400,
+		GrpcStatusCode: 3,
+		ErrorCode:      "ErrUserReportCategoryInvalid",
+		Message:        "Report category is not supported",
+	}
+	ErrUserReportTripInvalid = &svcerr{
+		Id:             "a2398c7bdc00149b1197454e168b52d6",
+		HttpStatusCode: 400,
+		GrpcStatusCode: 3,
+		ErrorCode:      "ErrUserReportTripInvalid",
+		Message:        "Report trip must be a trip between you and the reported user",
+	}
+	ErrRidePreferenceInvalid = &svcerr{
+		Id:             "0aa8b42765f50b3b59178211589c7b84",
+		HttpStatusCode: 400,
+		GrpcStatusCode: 3,
+		ErrorCode:      "ErrRidePreferenceInvalid",
+		Message:        "Gender or luggage size is not supported",
+	}
+	ErrWomenOnlyRouteDriver = &svcerr{
+		Id:             "42495fd6889e9623523901146382a1ba",
+		HttpStatusCode: 403,
+		GrpcStatusCode: 7,
+		ErrorCode:      "ErrWomenOnlyRouteDriver",
+		Message:        "Only female drivers can offer women-only routes",
+	}
+	ErrRideRequirementsNotMet = &svcerr{
+		Id:             "3cfb186c8277800773f06fde831468d8",
+		HttpStatusCode: 409,
+		GrpcStatusCode: 9,
+		ErrorCode:      "ErrRideRequirementsNotMet",
+		Message:        "Route does not meet the ride requirements",
+	}
+	ErrSeatCountInvalid = &svcerr{
+		Id:             "3abfc9991a036355f8d0dd5b902233b2",
+		HttpStatusCode: 400,
+		GrpcStatusCode: 3,
+		ErrorCode:      "ErrSeatCountInvalid",
+		Message:        "Seat count is out of range",
+	}
+	ErrRequestProposalInvalid = &svcerr{
+		Id:             "ad3d399f1c7c586ee5ddd70896f203d8",
+		HttpStatusCode: 400,
+		GrpcStatusCode: 3,
+		ErrorCode:      "ErrRequestProposalInvalid",
+		Message:        "Counter-proposal must change the pickup window, meeting point or tips",
+	}
+	ErrMeetingPointTooFar = &svcerr{
+		Id:             "3e0558420cda99b773b386f5f3c577e0",
+		HttpStatusCode: 400,
+		GrpcStatusCode: 3,
+		ErrorCode:      "ErrMeetingPointTooFar",
+		Message:        "Meeting point is not within walking distance of the pickup",
+	}
+	ErrMeetingPointOffRoute = &svcerr{
+		Id:             "8f55317dbad9e058161e935f839b572b",
+		HttpStatusCode: 400,
+		GrpcStatusCode: 3,
+		ErrorCode:      "ErrMeetingPointOffRoute",
+		Message:        "Meeting point is not within walking distance of the route",
+	}
+	ErrMeetingPointInvalid = &svcerr{
+		Id:             "005f3c014c9f2e4d4c4e594d77be6d2e",
+		HttpStatusCode: 400,
+		GrpcStatusCode: 3,
+		ErrorCode:      "ErrMeetingPointInvalid",
+		Message:        "Meeting point needs a name and valid coordinates",
+	}
+	ErrInstantRideInvalid = &svcerr{
+		Id:             "52357e306ce5e74befb15a85d2195344",
+		HttpStatusCode: 400,
+		GrpcStatusCode: 3,
+		ErrorCode:      "ErrInstantRideInvalid",
+		Message:        "Instant ride needs valid coordinates and seats",
+	}
+	ErrJourneyInvalid = &svcerr{
+		Id:             "67d537ce4d35cc73c5e2a249518d6d9d",
+		HttpStatusCode: 400,
+		GrpcStatusCode: 3,
+		ErrorCode:      "ErrJourneyInvalid",
+		Message:        "Journey needs two legs on different routes that connect in time",
+	}
+	ErrTrackInvalid = &svcerr{
+		Id:             "c0e3bfdfde6bb25f02388c34eb53338c",
+		HttpStatusCode: 400,
+		GrpcStatusCode: 3,
+		ErrorCode:      "ErrTrackInvalid",
+		Message:        "Track must be a GPX track or a GeoJSON LineString of at least two valid coordinates",
+	}
+	ErrTrackTooLarge = &svcerr{
+		Id:             "859f369073030acd87c5aa3bc447ca51",
+		HttpStatusCode: 413,
+		GrpcStatusCode: 3,
+		ErrorCode:      "ErrTrackTooLarge",
+		Message:        "Track file is too large",
+	}
 )
 
 var (
@@ -42,6 +231,33 @@ var (
 	_ Error = ErrPlaceQueryParamMissing
 	_ Error = ErrIdPathParamMissing
 	_ Error = ErrUserIdQueryParamMissing
+	_ Error = ErrRefreshTokenInvalid
+	_ Error = ErrRefreshTokenExpired
+	_ Error = ErrRefreshTokenReused
+	_ Error = ErrIdentityProviderUnsupported
+	_ Error = ErrLastIdentityUnlink
+	_ Error = ErrUserSuspended
+	_ Error = ErrVehicleNotOwned
+	_ Error = ErrRouteCapacityExceedsSeats
+	_ Error = ErrDriverDocumentKindInvalid
+	_ Error = ErrDriverDocumentTooLarge
+	_ Error = ErrDriverDocumentTypeUnsupported
+	_ Error = ErrUserBlocked
+	_ Error = ErrTargetIsSelf
+	_ Error = ErrUserReportCategoryInvalid
+	_ Error = ErrUserReportTripInvalid
+	_ Error = ErrRidePreferenceInvalid
+	_ Error = ErrWomenOnlyRouteDriver
+	_ Error = ErrRideRequirementsNotMet
+	_ Error = ErrSeatCountInvalid
+	_ Error = ErrRequestProposalInvalid
+	_ Error = ErrMeetingPointTooFar
+	_ Error = ErrMeetingPointOffRoute
+	_ Error = ErrMeetingPointInvalid
+	_ Error = ErrInstantRideInvalid
+	_ Error = ErrJourneyInvalid
+	_ Error = ErrTrackInvalid
+	_ Error = ErrTrackTooLarge
 )
 
 type svcerr struct {
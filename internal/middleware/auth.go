@@ -5,12 +5,13 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/CoRide-tw/backend/internal/config"
 	"github.com/CoRide-tw/backend/internal/model"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt"
 )
 
-func Auth(secret string) gin.HandlerFunc {
+func Auth(keys *config.JwtKeySet) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.Request.Header.Get("Authorization")
 
@@ -33,15 +34,11 @@ func Auth(secret string) gin.HandlerFunc {
 
 		var parsedClaims model.Claims
 		token := strings.Split(authHeader, " ")[1]
-		tokenClaims, err := jwt.ParseWithClaims(
-			token, &parsedClaims,
-			func(token *jwt.Token) (i interface{}, err error) {
-				return []byte(secret), nil
-			},
-		)
+		tokenClaims, err := jwt.ParseWithClaims(token, &parsedClaims, keys.Keyfunc)
 
-		// add user id into context
+		// add user id and roles into context
 		c.Set("userId", parsedClaims.ID)
+		c.Set("roles", parsedClaims.Roles)
 
 		if err != nil {
 			var message string
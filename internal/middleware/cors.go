@@ -10,7 +10,8 @@ func Cors() gin.HandlerFunc {
 
 	cors_config.AllowOrigins = []string{"*"}
 	cors_config.AllowCredentials = true
-	cors_config.AddAllowHeaders("Authorization")
+	cors_config.AddAllowHeaders("Authorization", RequestIdHeader)
+	cors_config.AddExposeHeaders(RequestIdHeader)
 
 	return cors.New(cors_config)
 }
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"github.com/CoRide-tw/backend/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+const RequestIdHeader = "X-Request-Id"
+
+// maxRequestIdLength bounds ids forwarded by clients or proxies, longer ones are replaced
+const maxRequestIdLength = 100
+
+// RequestId reuses the caller's X-Request-Id or generates one, and echoes it back in the response
+func RequestId() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestId := c.GetHeader(RequestIdHeader)
+		if requestId == "" || len(requestId) > maxRequestIdLength {
+			var err error
+			if requestId, err = util.GenerateRandomToken(16); err != nil {
+				requestId = ""
+			}
+		}
+
+		c.Set("requestId", requestId)
+		c.Header(RequestIdHeader, requestId)
+		c.Next()
+	}
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole only lets through users holding at least one of the given roles, it must run after Auth
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userRoles, _ := c.Get("roles")
+		granted, _ := userRoles.([]string)
+
+		for _, role := range roles {
+			for _, grantedRole := range granted {
+				if role == grantedRole {
+					c.Next()
+					return
+				}
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+		c.Abort()
+	}
+}
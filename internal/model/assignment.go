@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// AssignmentProposal suggests moving a pending request onto a route, it books the ride once both the
+// rider and the driver confirmed it
+type AssignmentProposal struct {
+	Id                int32      `json:"id"`
+	RequestId         int32      `json:"requestId"`
+	RouteId           int32      `json:"routeId"`
+	RiderId           int32      `json:"riderId"`
+	DriverId          int32      `json:"driverId"`
+	Detour            float64    `json:"detour"`
+	Status            string     `json:"status"`
+	RiderConfirmedAt  *time.Time `json:"riderConfirmedAt,omitempty"`
+	DriverConfirmedAt *time.Time `json:"driverConfirmedAt,omitempty"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	ResolvedAt        *time.Time `json:"resolvedAt,omitempty"`
+}
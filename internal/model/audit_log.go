@@ -0,0 +1,32 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type AuditLog struct {
+	Id         int64           `json:"id"`
+	ActorId    int32           `json:"actorId"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entityType"`
+	EntityId   int32           `json:"entityId"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	Detail     json.RawMessage `json:"detail,omitempty"`
+	RequestId  string          `json:"requestId"`
+	CreatedAt  time.Time       `json:"createdAt"`
+}
+
+// AuditLogFilter narrows an audit log query, zero values match everything
+type AuditLogFilter struct {
+	ActorId    int32
+	Action     string
+	EntityType string
+	EntityId   int32
+	RequestId  string
+	Since      *time.Time
+	Until      *time.Time
+	Limit      int32
+	Offset     int32
+}
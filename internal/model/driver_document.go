@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+type DriverDocument struct {
+	Id          int32      `json:"id"`
+	UserId      int32      `json:"userId"`
+	Kind        string     `json:"kind"`
+	BlobKey     string     `json:"-"`
+	ContentType string     `json:"contentType"`
+	SizeBytes   int64      `json:"sizeBytes"`
+	Status      string     `json:"status"`
+	ReviewNote  string     `json:"reviewNote"`
+	ReviewerId  *int32     `json:"reviewerId,omitempty"`
+	ReviewedAt  *time.Time `json:"reviewedAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	DeletedAt   *time.Time `json:"deletedAt,omitempty"`
+}
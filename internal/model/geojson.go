@@ -0,0 +1,29 @@
+package model
+
+import "encoding/json"
+
+const GeoJsonContentType = "application/geo+json"
+
+const (
+	GeoJsonFeatureCollection = "FeatureCollection"
+	GeoJsonFeature           = "Feature"
+	GeoJsonLineString        = "LineString"
+	GeoJsonPolygon           = "Polygon"
+)
+
+type GeoJsonFeatureCollectionObject struct {
+	Type     string                  `json:"type"`
+	Features []*GeoJsonFeatureObject `json:"features"`
+}
+
+type GeoJsonFeatureObject struct {
+	Type       string                 `json:"type"`
+	Geometry   *GeoJsonGeometry       `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// GeoJsonGeometry keeps the coordinates raw, their nesting depends on the geometry type
+type GeoJsonGeometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
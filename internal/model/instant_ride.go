@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// DriverPresence is where an instant mode driver is and where they are heading while online
+type DriverPresence struct {
+	UserId          int32     `json:"userId"`
+	Online          bool      `json:"online"`
+	Long            float64   `json:"long"`
+	Lat             float64   `json:"lat"`
+	DestinationLong float64   `json:"destinationLong"`
+	DestinationLat  float64   `json:"destinationLat"`
+	Seats           int32     `json:"seats"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
+
+// InstantRequest is a ride wanted right away, it is offered to one nearby online driver at a time
+type InstantRequest struct {
+	Id             int32      `json:"id"`
+	RiderId        int32      `json:"riderId"`
+	DriverId       *int32     `json:"driverId,omitempty"`
+	PickupLong     float64    `json:"pickupLong"`
+	PickupLat      float64    `json:"pickupLat"`
+	DropoffLong    float64    `json:"dropoffLong"`
+	DropoffLat     float64    `json:"dropoffLat"`
+	SeatCount      int32      `json:"seatCount"`
+	Status         string     `json:"status"`
+	OfferExpiresAt *time.Time `json:"offerExpiresAt,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// Journey books a ride that changes drivers on the way, each leg is an ordinary request on one route
+type Journey struct {
+	Id        int32      `json:"id"`
+	RiderId   int32      `json:"riderId"`
+	Status    string     `json:"status"`
+	Legs      []*Request `json:"legs"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
@@ -3,6 +3,8 @@ package model
 import "github.com/golang-jwt/jwt"
 
 type Claims struct {
-	ID int32
+	ID        int32
+	SessionId int32    `json:"sid,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
 	jwt.StandardClaims
 }
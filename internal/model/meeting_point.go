@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// MeetingPoint is a curated spot where drivers can stop safely to pick riders up or drop them off
+type MeetingPoint struct {
+	Id        int32      `json:"id"`
+	Name      string     `json:"name"`
+	Long      float64    `json:"long"`
+	Lat       float64    `json:"lat"`
+	CreatedAt time.Time  `json:"createdAt"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+}
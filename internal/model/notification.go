@@ -0,0 +1,17 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Notification struct {
+	Id        int32           `json:"id"`
+	UserId    int32           `json:"userId"`
+	Kind      string          `json:"kind"`
+	Title     string          `json:"title"`
+	Body      string          `json:"body"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	ReadAt    *time.Time      `json:"readAt,omitempty"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
@@ -14,7 +14,11 @@ type Request struct {
 	PickupEndTime   time.Time  `json:"pickupEndTime"`
 	Tips            int32      `json:"tips"`
 	Status          string     `json:"status"`
+	SeatCount       int32      `json:"seatCount"`
+	AcceptedSeats   int32      `json:"acceptedSeats"`
+	MeetingPointId  *int32     `json:"meetingPointId,omitempty"`
 	CreatedAt       time.Time  `json:"createdAt"`
 	UpdatedAt       time.Time  `json:"updatedAt"`
 	DeletedAt       *time.Time `json:"deletedAt,omitempty"`
+	RideRequirements
 }
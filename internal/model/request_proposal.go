@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// RequestProposal is a driver's counter to a request, nil fields keep what the rider asked for
+type RequestProposal struct {
+	Id              int32      `json:"id"`
+	RequestId       int32      `json:"requestId"`
+	Round           int32      `json:"round"`
+	ProposerId      int32      `json:"proposerId"`
+	PickupStartTime *time.Time `json:"pickupStartTime,omitempty"`
+	PickupEndTime   *time.Time `json:"pickupEndTime,omitempty"`
+	PickupLong      *float64   `json:"pickupLong,omitempty"`
+	PickupLat       *float64   `json:"pickupLat,omitempty"`
+	Tips            *int32     `json:"tips,omitempty"`
+	Status          string     `json:"status"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	ResolvedAt      *time.Time `json:"resolvedAt,omitempty"`
+}
@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// RideRequirements are what a rider needs from a ride
+type RideRequirements struct {
+	WomenOnly bool   `json:"womenOnly"`
+	Smoking   bool   `json:"smoking"`
+	Pets      bool   `json:"pets"`
+	Luggage   string `json:"luggage"`
+	Quiet     bool   `json:"quiet"`
+}
+
+// RidePreferences are saved per user, riders search with them unless the query says otherwise
+type RidePreferences struct {
+	UserId    int32     `json:"userId"`
+	Gender    string    `json:"gender"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	RideRequirements
+}
+
+// RouteRules are set by the driver for each route
+type RouteRules struct {
+	WomenOnly      bool   `json:"womenOnly"`
+	SmokingAllowed bool   `json:"smokingAllowed"`
+	PetsAllowed    bool   `json:"petsAllowed"`
+	MaxLuggage     string `json:"maxLuggage"`
+	Music          bool   `json:"music"`
+}
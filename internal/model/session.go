@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+type Session struct {
+	Id               int32      `json:"id"`
+	UserId           int32      `json:"userId"`
+	FamilyId         string     `json:"familyId"`
+	RefreshTokenHash string     `json:"-"`
+	UserAgent        string     `json:"userAgent"`
+	ExpiresAt        time.Time  `json:"expiresAt"`
+	RevokedAt        *time.Time `json:"revokedAt,omitempty"`
+	CreatedAt        time.Time  `json:"createdAt"`
+}
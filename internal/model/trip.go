@@ -8,6 +8,7 @@ type Trip struct {
 	DriverId  int32      `json:"driverId"`
 	RequestId int32      `json:"requestId"`
 	RouteId   int32      `json:"routeId"`
+	Status    string     `json:"status"`
 	CreatedAt time.Time  `json:"createdAt"`
 	DeletedAt *time.Time `json:"deletedAt,omitempty"`
 }
@@ -0,0 +1,9 @@
+package model
+
+import "time"
+
+type UserBlock struct {
+	BlockerId int32     `json:"blockerId"`
+	BlockedId int32     `json:"blockedId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// UserExport is every piece of personal data kept about a user, soft deleted rows included
+type UserExport struct {
+	User          *User             `json:"user"`
+	Preferences   *RidePreferences  `json:"preferences"`
+	Identities    []*UserIdentity   `json:"identities"`
+	Sessions      []*Session        `json:"sessions"`
+	Vehicles      []*Vehicle        `json:"vehicles"`
+	Documents     []*DriverDocument `json:"driverDocuments"`
+	Blocks        []*UserBlock      `json:"blocks"`
+	Reports       []*UserReport     `json:"reports"`
+	Notifications []*Notification   `json:"notifications"`
+	Routes        []*Route          `json:"routes"`
+	Requests      []*Request        `json:"requests"`
+	Trips         []*Trip           `json:"trips"`
+	ExportedAt    time.Time         `json:"exportedAt"`
+}
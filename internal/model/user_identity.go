@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+type UserIdentity struct {
+	Id        int32     `json:"id"`
+	UserId    int32     `json:"userId"`
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+// PrivateUser is what a user sees of their own account, new fields on User stay hidden until added here
+type PrivateUser struct {
+	Id               int32      `json:"id"`
+	Name             string     `json:"name"`
+	Email            string     `json:"email"`
+	GoogleId         string     `json:"googleId"`
+	PictureUrl       string     `json:"pictureUrl"`
+	CarType          *string    `json:"carType"`
+	CarPlate         *string    `json:"carPlate"`
+	Roles            []string   `json:"roles"`
+	DriverVerifiedAt *time.Time `json:"driverVerifiedAt,omitempty"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	UpdatedAt        time.Time  `json:"updatedAt"`
+}
+
+func (u *User) Private() *PrivateUser {
+	return &PrivateUser{
+		Id:               u.Id,
+		Name:             u.Name,
+		Email:            u.Email,
+		GoogleId:         u.GoogleId,
+		PictureUrl:       u.PictureUrl,
+		CarType:          u.CarType,
+		CarPlate:         u.CarPlate,
+		Roles:            u.Roles,
+		DriverVerifiedAt: u.DriverVerifiedAt,
+		CreatedAt:        u.CreatedAt,
+		UpdatedAt:        u.UpdatedAt,
+	}
+}
+
+// PublicProfile is what any signed in user may see about another one
+type PublicProfile struct {
+	Id          int32     `json:"id"`
+	Name        string    `json:"name"`
+	PictureUrl  string    `json:"pictureUrl"`
+	TripCount   int32     `json:"tripCount"`
+	Badges      []string  `json:"badges"`
+	MemberSince time.Time `json:"memberSince"`
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+type UserReport struct {
+	Id          int32      `json:"id"`
+	ReporterId  int32      `json:"reporterId"`
+	ReportedId  int32      `json:"reportedId"`
+	TripId      *int32     `json:"tripId,omitempty"`
+	Category    string     `json:"category"`
+	Description string     `json:"description"`
+	Status      string     `json:"status"`
+	ReviewerId  *int32     `json:"reviewerId,omitempty"`
+	ReviewNote  string     `json:"reviewNote"`
+	ResolvedAt  *time.Time `json:"resolvedAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
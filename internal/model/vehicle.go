@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+type Vehicle struct {
+	Id        int32      `json:"id"`
+	UserId    int32      `json:"userId"`
+	Make      string     `json:"make"`
+	Model     string     `json:"model"`
+	Color     string     `json:"color"`
+	Plate     string     `json:"plate"`
+	SeatCount int32      `json:"seatCount"`
+	PhotoUrl  string     `json:"photoUrl"`
+	Amenities []string   `json:"amenities"`
+	IsDefault bool       `json:"isDefault"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+}
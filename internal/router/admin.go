@@ -0,0 +1,33 @@
+package router
+
+import (
+	"github.com/CoRide-tw/backend/internal/constants"
+	"github.com/CoRide-tw/backend/internal/middleware"
+)
+
+func (r *router) setAdminRoutes() {
+	adminRouter := r.Engine.Group("/admin", middleware.RequireRole(constants.RoleAdmin, constants.RoleSupport))
+	adminOnly := middleware.RequireRole(constants.RoleAdmin)
+
+	adminRouter.GET("/users", r.Service.Admin.ListUsers)
+	adminRouter.GET("/routes", r.Service.Admin.ListRoutes)
+	adminRouter.GET("/requests", r.Service.Admin.ListRequests)
+	adminRouter.GET("/trips", r.Service.Admin.ListTrips)
+	adminRouter.POST("/users/:id/suspend", r.Service.Admin.SuspendUser)
+	adminRouter.POST("/users/:id/unsuspend", r.Service.Admin.UnsuspendUser)
+	adminRouter.PATCH("/users/:id/roles", adminOnly, r.Service.Admin.SetUserRoles)
+	adminRouter.POST("/users/:id/revoke-verification", r.Service.Admin.RevokeDriverVerification)
+	adminRouter.POST("/trips/:id/cancel", r.Service.Admin.CancelTrip)
+	adminRouter.GET("/audit-logs", r.Service.Admin.ListAuditLogs)
+	adminRouter.GET("/driver-documents", r.Service.Admin.ListDriverDocumentQueue)
+	adminRouter.GET("/driver-documents/:id/file", r.Service.Admin.GetDriverDocumentFile)
+	adminRouter.POST("/driver-documents/:id/approve", r.Service.Admin.ApproveDriverDocument)
+	adminRouter.POST("/driver-documents/:id/reject", r.Service.Admin.RejectDriverDocument)
+	adminRouter.GET("/reports", r.Service.Admin.ListUserReports)
+	adminRouter.POST("/reports/:id/dismiss", r.Service.Admin.DismissUserReport)
+	adminRouter.POST("/reports/:id/action", r.Service.Admin.ActionUserReport)
+	adminRouter.GET("/meeting-points", r.Service.Admin.ListMeetingPoints)
+	adminRouter.POST("/meeting-points", adminOnly, r.Service.Admin.CreateMeetingPoint)
+	adminRouter.DELETE("/meeting-points/:id", adminOnly, r.Service.Admin.DeleteMeetingPoint)
+	adminRouter.POST("/assignments/run", adminOnly, r.Service.Admin.RunAssignment)
+}
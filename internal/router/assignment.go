@@ -0,0 +1,9 @@
+package router
+
+func (r *router) setAssignmentRoutes() {
+	assignmentRouter := r.Engine.Group("/assignment")
+
+	assignmentRouter.GET("", r.Service.Assignment.List)
+	assignmentRouter.POST("/:id/confirm", r.Service.Assignment.Confirm)
+	assignmentRouter.POST("/:id/reject", r.Service.Assignment.Reject)
+}
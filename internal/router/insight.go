@@ -0,0 +1,7 @@
+package router
+
+func (r *router) setInsightRoutes() {
+	insightRouter := r.Engine.Group("/insights")
+
+	insightRouter.GET("/demand", r.Service.Insight.Demand)
+}
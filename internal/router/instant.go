@@ -0,0 +1,16 @@
+package router
+
+func (r *router) setInstantRoutes() {
+	instantRouter := r.Engine.Group("/instant")
+
+	instantRouter.GET("/driver", r.Service.Instant.GetPresence)
+	instantRouter.PUT("/driver", r.Service.Instant.GoOnline)
+	instantRouter.DELETE("/driver", r.Service.Instant.GoOffline)
+	instantRouter.POST("/request", r.Service.Instant.CreateRequest)
+	instantRouter.GET("/request/:id", r.Service.Instant.GetRequest)
+	instantRouter.POST("/request/:id/accept", r.Service.Instant.AcceptRequest)
+	instantRouter.POST("/request/:id/decline", r.Service.Instant.DeclineRequest)
+	instantRouter.POST("/request/:id/complete", r.Service.Instant.CompleteRequest)
+	instantRouter.POST("/request/:id/withdraw", r.Service.Instant.WithdrawRequest)
+	instantRouter.DELETE("/request/:id", r.Service.Instant.CancelRequest)
+}
@@ -0,0 +1,9 @@
+package router
+
+func (r *router) setJourneyRoutes() {
+	journeyRouter := r.Engine.Group("/journey")
+
+	journeyRouter.POST("", r.Service.Request.CreateJourney)
+	journeyRouter.GET("/:id", r.Service.Request.GetJourney)
+	journeyRouter.DELETE("/:id", r.Service.Request.CancelJourney)
+}
@@ -3,4 +3,12 @@ package router
 func (r *router) setLoginRoutes() {
 	r.Engine.GET("/oauthUrl", r.Service.User.OauthUrl)
 	r.Engine.POST("/user/login", r.Service.User.OAuthLogin)
+	r.Engine.POST("/user/login/id_token", r.Service.User.IdTokenLogin)
+	r.Engine.POST("/user/login/email", r.Service.User.StartEmailLogin)
+
+	authRouter := r.Engine.Group("/auth")
+	authRouter.POST("/refresh", r.Service.Auth.Refresh)
+	authRouter.POST("/logout", r.Service.Auth.Logout)
+
+	r.Engine.GET("/.well-known/jwks.json", r.Service.Auth.Jwks)
 }
@@ -18,6 +18,9 @@ func NewRouterEngine(engine *gin.Engine, service *service.Service) *gin.Engine {
 		Service: service,
 	}
 
+	// use request id middleware
+	router.useRequestIdMiddleware()
+
 	// use CORS middleware
 	router.useCorsMiddleware()
 
@@ -31,16 +34,26 @@ func NewRouterEngine(engine *gin.Engine, service *service.Service) *gin.Engine {
 	router.setUserRoutes()
 	router.setRouteRoutes()
 	router.setRequestRoutes()
+	router.setJourneyRoutes()
 	router.setTripRoutes()
+	router.setReportRoutes()
+	router.setInstantRoutes()
+	router.setAssignmentRoutes()
+	router.setInsightRoutes()
 	router.setGoogleApiRoutes()
+	router.setAdminRoutes()
 
 	return router.Engine
 }
 
+func (r *router) useRequestIdMiddleware() {
+	r.Engine.Use(middleware.RequestId())
+}
+
 func (r *router) useCorsMiddleware() {
 	r.Engine.Use(middleware.Cors())
 }
 
 func (r *router) useAuthMiddleware() {
-	r.Engine.Use(middleware.Auth(config.Env.CoRideJwtSecret))
+	r.Engine.Use(middleware.Auth(config.JwtKeys))
 }
@@ -0,0 +1,7 @@
+package router
+
+func (r *router) setReportRoutes() {
+	reportRouter := r.Engine.Group("/report")
+
+	reportRouter.POST("", r.Service.Report.Create)
+}
@@ -7,5 +7,10 @@ func (r *router) setRequestRoutes() {
 	requestRouter.GET("/:id", r.Service.Request.Get)
 	requestRouter.POST("", r.Service.Request.Create)
 	requestRouter.PATCH("/:id/status", r.Service.Request.Deny)
+	requestRouter.POST("/:id/accept", r.Service.Request.Accept)
+	requestRouter.POST("/:id/confirm", r.Service.Request.Confirm)
+	requestRouter.POST("/:id/counter", r.Service.Request.Counter)
+	requestRouter.POST("/:id/counter/accept", r.Service.Request.AcceptCounter)
+	requestRouter.POST("/:id/counter/reject", r.Service.Request.RejectCounter)
 	requestRouter.DELETE("/:id", r.Service.Request.Delete)
 }
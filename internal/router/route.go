@@ -4,7 +4,13 @@ func (r *router) setRouteRoutes() {
 	routeRouter := r.Engine.Group("/route")
 
 	routeRouter.GET("/ranking", r.Service.Route.ListNearestRoutes)
+	routeRouter.GET("/ranking/multi-leg", r.Service.Route.ListMultiLegRoutes)
 	routeRouter.GET("/:id", r.Service.Route.Get)
+	routeRouter.GET("/:id/waitlist", r.Service.Route.ListWaitlist)
+	routeRouter.GET("/:id/meeting-points", r.Service.Route.SuggestMeetingPoints)
+	routeRouter.GET("/:id/itinerary", r.Service.Route.GetItinerary)
+	routeRouter.GET("/:id/demand", r.Service.Route.SuggestDemand)
 	routeRouter.POST("", r.Service.Route.Create)
+	routeRouter.POST("/import", r.Service.Route.Import)
 	routeRouter.DELETE("/:id", r.Service.Route.Delete)
 }
@@ -6,4 +6,5 @@ func (r *router) setTripRoutes() {
 	tripRouter.GET("", r.Service.Trip.List)
 	tripRouter.GET("/:id", r.Service.Trip.Get)
 	tripRouter.POST("", r.Service.Trip.Create)
+	tripRouter.POST("/:id/depart", r.Service.Trip.Depart)
 }
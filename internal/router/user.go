@@ -5,4 +5,26 @@ func (r *router) setUserRoutes() {
 
 	userRouter.GET("/:id", r.Service.User.Get)
 	userRouter.PATCH("/:id", r.Service.User.Update)
+	userRouter.DELETE("/:id", r.Service.User.Delete)
+	userRouter.GET("/:id/export", r.Service.User.Export)
+	userRouter.GET("/:id/trips.geojson", r.Service.User.ExportTrips)
+	userRouter.GET("/:id/profile", r.Service.User.Profile)
+	userRouter.DELETE("/:id/sessions", r.Service.Auth.LogoutAll)
+	userRouter.GET("/:id/identities", r.Service.User.ListIdentities)
+	userRouter.POST("/:id/identities", r.Service.User.LinkIdentity)
+	userRouter.DELETE("/:id/identities/:provider", r.Service.User.UnlinkIdentity)
+	userRouter.GET("/:id/vehicles", r.Service.User.ListVehicles)
+	userRouter.POST("/:id/vehicles", r.Service.User.CreateVehicle)
+	userRouter.PATCH("/:id/vehicles/:vehicleId", r.Service.User.UpdateVehicle)
+	userRouter.DELETE("/:id/vehicles/:vehicleId", r.Service.User.DeleteVehicle)
+	userRouter.POST("/:id/vehicles/:vehicleId/default", r.Service.User.SetDefaultVehicle)
+	userRouter.GET("/:id/driver-documents", r.Service.User.ListDriverDocuments)
+	userRouter.POST("/:id/driver-documents", r.Service.User.UploadDriverDocument)
+	userRouter.GET("/:id/preferences", r.Service.User.GetPreferences)
+	userRouter.PUT("/:id/preferences", r.Service.User.UpdatePreferences)
+	userRouter.GET("/:id/notifications", r.Service.User.ListNotifications)
+	userRouter.POST("/:id/notifications/:notificationId/read", r.Service.User.ReadNotification)
+	userRouter.GET("/:id/blocks", r.Service.User.ListBlocks)
+	userRouter.POST("/:id/blocks", r.Service.User.BlockUser)
+	userRouter.DELETE("/:id/blocks/:blockedId", r.Service.User.UnblockUser)
 }
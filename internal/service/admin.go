@@ -0,0 +1,488 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	"github.com/CoRide-tw/backend/internal/db"
+	"github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	"github.com/CoRide-tw/backend/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+type adminSvc struct {
+	Logger   *zap.SugaredLogger
+	Blobs    blobStore
+	Notifier *notifier
+}
+
+func (s *adminSvc) ListUsers(c *gin.Context) {
+	parsedQuery, err := util.ParseAdminListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	users, err := db.AdminListUsers(parsedQuery.Search, parsedQuery.Limit, parsedQuery.Offset)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+func (s *adminSvc) ListRoutes(c *gin.Context) {
+	parsedQuery, err := util.ParseAdminListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	routes, err := db.AdminListRoutes(parsedQuery.DriverId, parsedQuery.Limit, parsedQuery.Offset)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, routes)
+}
+
+func (s *adminSvc) ListRequests(c *gin.Context) {
+	parsedQuery, err := util.ParseAdminListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	requests, err := db.AdminListRequests(parsedQuery.RiderId, parsedQuery.RouteId, parsedQuery.Status, parsedQuery.Limit, parsedQuery.Offset)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, requests)
+}
+
+func (s *adminSvc) ListTrips(c *gin.Context) {
+	parsedQuery, err := util.ParseAdminListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trips, err := db.AdminListTrips(parsedQuery.UserId, parsedQuery.Status, parsedQuery.Limit, parsedQuery.Offset)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, trips)
+}
+
+type adminReasonReq struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+func (s *adminSvc) SuspendUser(c *gin.Context) {
+	s.setUserSuspended(c, true)
+}
+
+func (s *adminSvc) UnsuspendUser(c *gin.Context) {
+	s.setUserSuspended(c, false)
+}
+
+func (s *adminSvc) setUserSuspended(c *gin.Context, suspended bool) {
+	userId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id must be integer"})
+		return
+	}
+
+	var req adminReasonReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := db.SetUserSuspended(int32(userId), suspended); err != nil {
+		if errors.Is(err, dberr.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	action := constants.AuditActionAdminUnsuspendUser
+	if suspended {
+		// end every session right away, access tokens still live until they expire
+		if err := db.RevokeUserSessions(int32(userId)); err != nil {
+			s.Logger.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		action = constants.AuditActionAdminSuspendUser
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     action,
+		EntityType: constants.AuditEntityUser,
+		EntityId:   int32(userId),
+		Detail:     gin.H{"reason": req.Reason},
+	})
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+type adminSetRolesReq struct {
+	Roles []string `json:"roles" binding:"required"`
+}
+
+func (s *adminSvc) SetUserRoles(c *gin.Context) {
+	userId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id must be integer"})
+		return
+	}
+
+	var req adminSetRolesReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for _, role := range req.Roles {
+		if !isKnownRole(role) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown role " + role})
+			return
+		}
+	}
+
+	before, err := db.GetUser(int32(userId))
+	if err != nil {
+		if errors.Is(err, dberr.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := db.SetUserRoles(int32(userId), req.Roles); err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionAdminSetUserRoles,
+		EntityType: constants.AuditEntityUser,
+		EntityId:   int32(userId),
+		Before:     gin.H{"roles": before.Roles},
+		After:      gin.H{"roles": req.Roles},
+	})
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+func isKnownRole(role string) bool {
+	for _, knownRole := range constants.Roles {
+		if role == knownRole {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *adminSvc) CancelTrip(c *gin.Context) {
+	tripId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id must be integer"})
+		return
+	}
+
+	var req adminReasonReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	before, err := db.GetTrip(int32(tripId))
+	if err != nil {
+		if errors.Is(err, dberr.ErrTripNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := db.CancelTrip(int32(tripId)); err != nil {
+		if errors.Is(err, dberr.ErrTripNotActive) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionAdminCancelTrip,
+		EntityType: constants.AuditEntityTrip,
+		EntityId:   int32(tripId),
+		Before:     before,
+		After:      gin.H{"status": constants.TripStatusCancelled},
+		Detail:     gin.H{"reason": req.Reason},
+	})
+	promoteWaitlist(s.Logger, s.Notifier, before.RouteId)
+	cancelJourneyOf(s.Logger, s.Notifier, c, before.RequestId, before.RiderId, constants.RequestStatusCancelled)
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+func (s *adminSvc) ListAuditLogs(c *gin.Context) {
+	filter, err := util.ParseAuditLogQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	auditLogs, err := db.ListAuditLogs(filter)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, auditLogs)
+}
+
+// ListDriverDocumentQueue lists documents waiting for review, oldest first
+func (s *adminSvc) ListDriverDocumentQueue(c *gin.Context) {
+	parsedQuery, err := util.ParseAdminListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	documents, err := db.ListPendingDriverDocuments(parsedQuery.Limit, parsedQuery.Offset)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, documents)
+}
+
+func (s *adminSvc) GetDriverDocumentFile(c *gin.Context) {
+	document, ok := s.driverDocument(c)
+	if !ok {
+		return
+	}
+
+	file, err := s.Blobs.Open(document.BlobKey)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	c.DataFromReader(http.StatusOK, document.SizeBytes, document.ContentType, file, nil)
+}
+
+type adminReviewReq struct {
+	Reason string `json:"reason"`
+}
+
+func (s *adminSvc) ApproveDriverDocument(c *gin.Context) {
+	s.reviewDriverDocument(c, constants.DriverDocumentStatusApproved)
+}
+
+func (s *adminSvc) RejectDriverDocument(c *gin.Context) {
+	s.reviewDriverDocument(c, constants.DriverDocumentStatusRejected)
+}
+
+func (s *adminSvc) reviewDriverDocument(c *gin.Context, status string) {
+	document, ok := s.driverDocument(c)
+	if !ok {
+		return
+	}
+
+	var req adminReviewReq
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// the driver is shown the reason, so a rejection must have one
+	if status == constants.DriverDocumentStatusRejected && req.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason is required"})
+		return
+	}
+
+	reviewerId, _ := c.Get("userId")
+	if err := db.ReviewDriverDocument(document.Id, reviewerId.(int32), status, req.Reason); err != nil {
+		if errors.Is(err, dberr.ErrDriverDocumentAlreadyReviewed) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	action := constants.AuditActionAdminApproveDriverDocument
+	if status == constants.DriverDocumentStatusRejected {
+		action = constants.AuditActionAdminRejectDriverDocument
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     action,
+		EntityType: constants.AuditEntityDriverDocument,
+		EntityId:   document.Id,
+		Before:     gin.H{"status": document.Status},
+		After:      gin.H{"status": status},
+		Detail:     gin.H{"reason": req.Reason, "userId": document.UserId},
+	})
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// RevokeDriverVerification takes a driver's verification back, e.g. when a document turned out to be forged
+// or expired, the driver has to upload a new document to be verified again
+func (s *adminSvc) RevokeDriverVerification(c *gin.Context) {
+	userId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id must be integer"})
+		return
+	}
+
+	var req adminReasonReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := db.RevokeDriverVerification(int32(userId)); err != nil {
+		if errors.Is(err, dberr.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionAdminRevokeVerification,
+		EntityType: constants.AuditEntityUser,
+		EntityId:   int32(userId),
+		Detail:     gin.H{"reason": req.Reason},
+	})
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// driverDocument loads the :id path param, otherwise the error response is already written
+func (s *adminSvc) driverDocument(c *gin.Context) (*model.DriverDocument, bool) {
+	documentId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id must be integer"})
+		return nil, false
+	}
+
+	document, err := db.GetDriverDocument(int32(documentId))
+	if err != nil {
+		if errors.Is(err, dberr.ErrDriverDocumentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return document, true
+}
+
+// ListUserReports lists the moderation queue, open reports unless another status is asked for
+func (s *adminSvc) ListUserReports(c *gin.Context) {
+	parsedQuery, err := util.ParseAdminListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, exist := c.GetQuery("status"); !exist {
+		parsedQuery.Status = constants.UserReportStatusOpen
+	}
+
+	reports, err := db.ListUserReports(parsedQuery.Status, parsedQuery.UserId, parsedQuery.Limit, parsedQuery.Offset)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, reports)
+}
+
+func (s *adminSvc) DismissUserReport(c *gin.Context) {
+	s.resolveUserReport(c, constants.UserReportStatusDismissed, constants.AuditActionAdminDismissUserReport)
+}
+
+func (s *adminSvc) ActionUserReport(c *gin.Context) {
+	s.resolveUserReport(c, constants.UserReportStatusActioned, constants.AuditActionAdminActionUserReport)
+}
+
+func (s *adminSvc) resolveUserReport(c *gin.Context, status, action string) {
+	reportId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id must be integer"})
+		return
+	}
+
+	var req adminReviewReq
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := db.GetUserReport(int32(reportId))
+	if err != nil {
+		if errors.Is(err, dberr.ErrUserReportNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	reviewerId, _ := c.Get("userId")
+	if err := db.ResolveUserReport(report.Id, reviewerId.(int32), status, req.Reason); err != nil {
+		if errors.Is(err, dberr.ErrUserReportAlreadyResolved) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     action,
+		EntityType: constants.AuditEntityUserReport,
+		EntityId:   report.Id,
+		Before:     gin.H{"status": report.Status},
+		After:      gin.H{"status": status},
+		Detail:     gin.H{"reason": req.Reason},
+	})
+
+	c.JSON(http.StatusOK, gin.H{})
+}
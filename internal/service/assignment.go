@@ -0,0 +1,325 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/config"
+	"github.com/CoRide-tw/backend/internal/constants"
+	"github.com/CoRide-tw/backend/internal/db"
+	"github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// maxAssignmentPasses bounds the local search after the first assignment, each pass is quadratic in the
+// number of assigned requests
+const maxAssignmentPasses = 20
+
+type assignmentSvc struct {
+	Logger   *zap.SugaredLogger
+	Notifier *notifier
+}
+
+// List shows the open proposals the user still has to answer as rider or driver
+func (s *assignmentSvc) List(c *gin.Context) {
+	userId, _ := c.Get("userId")
+	proposals, err := db.ListOpenAssignmentProposalsByUserId(userId.(int32))
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, proposals)
+}
+
+// Confirm records the rider's or driver's agreement, the ride is booked once both agreed
+func (s *assignmentSvc) Confirm(c *gin.Context) {
+	proposal, ok := s.getForParticipant(c)
+	if !ok {
+		return
+	}
+
+	userId, _ := c.Get("userId")
+	confirmed, trip, err := db.ConfirmAssignmentProposal(proposal.Id, userId.(int32))
+	if err != nil {
+		if errors.Is(err, dberr.ErrAssignmentProposalNotOpen) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		writeAcceptRequestError(s.Logger, c, err)
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionAssignmentConfirm,
+		EntityType: constants.AuditEntityAssignmentProposal,
+		EntityId:   confirmed.Id,
+		Before:     proposal,
+		After:      confirmed,
+	})
+	if trip != nil {
+		data := gin.H{"assignmentProposalId": confirmed.Id, "requestId": confirmed.RequestId, "routeId": confirmed.RouteId, "tripId": trip.Id}
+		body := "Rider and driver both confirmed the suggested match, the ride is booked."
+		s.Notifier.Notify(confirmed.RiderId, constants.NotificationKindAssignmentConfirmed, "Your ride is booked", body, data)
+		s.Notifier.Notify(confirmed.DriverId, constants.NotificationKindAssignmentConfirmed, "Your ride is booked", body, data)
+	}
+
+	c.JSON(http.StatusOK, confirmed)
+}
+
+// Reject lets either side turn the proposal down, the request stays pending and is matched again on a
+// later run
+func (s *assignmentSvc) Reject(c *gin.Context) {
+	proposal, ok := s.getForParticipant(c)
+	if !ok {
+		return
+	}
+
+	rejected, err := db.RejectAssignmentProposal(proposal.Id)
+	if err != nil {
+		if errors.Is(err, dberr.ErrAssignmentProposalNotOpen) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionAssignmentReject,
+		EntityType: constants.AuditEntityAssignmentProposal,
+		EntityId:   rejected.Id,
+		Before:     gin.H{"status": proposal.Status},
+		After:      gin.H{"status": rejected.Status},
+	})
+
+	c.JSON(http.StatusOK, rejected)
+}
+
+// getForParticipant loads the :id proposal and only lets its rider or driver through, otherwise the error
+// response is already written
+func (s *assignmentSvc) getForParticipant(c *gin.Context) (*model.AssignmentProposal, bool) {
+	proposalId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id must be integer"})
+		return nil, false
+	}
+
+	proposal, err := db.GetAssignmentProposal(int32(proposalId))
+	if err != nil {
+		if errors.Is(err, dberr.ErrAssignmentProposalNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if authUid, _ := c.Get("userId"); authUid != proposal.RiderId && authUid != proposal.DriverId {
+		c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+		return nil, false
+	}
+	return proposal, true
+}
+
+// RunAssignment runs the batch assignment right away instead of waiting for the job
+func (s *adminSvc) RunAssignment(c *gin.Context) {
+	proposed, err := assignPendingRequests(s.Notifier)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionAdminRunAssignment,
+		EntityType: constants.AuditEntityAssignmentProposal,
+		Detail:     gin.H{"proposed": proposed},
+	})
+
+	c.JSON(http.StatusOK, gin.H{"proposed": proposed})
+}
+
+func assignmentJobs(n *notifier) []job {
+	return []job{
+		{Name: "assign_pending_requests", Run: func() (int64, error) { return assignPendingRequests(n) }},
+	}
+}
+
+// assignPendingRequests matches the pending requests against the routes starting within the horizon and
+// proposes the result to both sides. Stale proposals are expired first so their seats are free again,
+// rejected pairs are not proposed again and expired ones only after the retry cooldown.
+func assignPendingRequests(n *notifier) (int64, error) {
+	now := time.Now()
+	if _, err := db.ExpireAssignmentProposals(now.Add(-config.Env.AssignmentProposalTtl)); err != nil {
+		return 0, err
+	}
+
+	candidates, err := db.ListAssignmentCandidates(now, now.Add(config.Env.AssignmentHorizon), float64(config.Env.AssignmentMaxDetour), now.Add(-config.Env.AssignmentRetryCooldown))
+	if err != nil {
+		return 0, err
+	}
+	proposals, err := db.CreateAssignmentProposals(solveAssignment(candidates))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, proposal := range proposals {
+		data := gin.H{"assignmentProposalId": proposal.Id, "requestId": proposal.RequestId, "routeId": proposal.RouteId}
+		if proposal.RiderConfirmedAt == nil {
+			n.Notify(proposal.RiderId, constants.NotificationKindAssignmentProposed,
+				"We found a ride for you",
+				"A driver's route fits your request, please confirm or reject the match.",
+				data,
+			)
+		}
+		n.Notify(proposal.DriverId, constants.NotificationKindAssignmentProposed,
+			"We found a rider for you",
+			"A rider's request fits your route with a small detour, please confirm or reject the match.",
+			data,
+		)
+	}
+	return int64(len(proposals)), nil
+}
+
+// solveAssignment picks at most one route per request so that route seats are not exceeded, serving as
+// many requests as it can and then keeping the total detour low. Finding the optimum is NP-hard with seat
+// counts, so the requests are first placed by regret, the request that loses most by not getting its best
+// route goes first, and the result is then improved by moving, swapping and inserting requests. The result
+// only depends on the candidates, not on their order.
+func solveAssignment(candidates []*db.AssignmentCandidate) []*db.AssignmentCandidate {
+	sorted := make([]*db.AssignmentCandidate, len(candidates))
+	copy(sorted, candidates)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].RequestId != sorted[j].RequestId {
+			return sorted[i].RequestId < sorted[j].RequestId
+		}
+		return sorted[i].RouteId < sorted[j].RouteId
+	})
+
+	var requestIds []int32
+	options := make(map[int32][]*db.AssignmentCandidate)
+	free := make(map[int32]int32)
+	for _, candidate := range sorted {
+		if _, ok := options[candidate.RequestId]; !ok {
+			requestIds = append(requestIds, candidate.RequestId)
+		}
+		options[candidate.RequestId] = append(options[candidate.RequestId], candidate)
+		free[candidate.RouteId] = candidate.FreeSeats
+	}
+
+	assigned := make(map[int32]*db.AssignmentCandidate)
+	fits := func(option *db.AssignmentCandidate) bool {
+		return free[option.RouteId] >= option.SeatCount
+	}
+	assign := func(option *db.AssignmentCandidate) {
+		if current := assigned[option.RequestId]; current != nil {
+			free[current.RouteId] += current.SeatCount
+		}
+		assigned[option.RequestId] = option
+		free[option.RouteId] -= option.SeatCount
+	}
+
+	// regret insertion
+	for {
+		var (
+			next       *db.AssignmentCandidate
+			nextRegret float64
+		)
+		for _, requestId := range requestIds {
+			if assigned[requestId] != nil {
+				continue
+			}
+			best, second := (*db.AssignmentCandidate)(nil), math.Inf(1)
+			for _, option := range options[requestId] {
+				if !fits(option) {
+					continue
+				}
+				if best == nil || option.Detour < best.Detour {
+					if best != nil {
+						second = best.Detour
+					}
+					best = option
+				} else if option.Detour < second {
+					second = option.Detour
+				}
+			}
+			if best == nil {
+				continue
+			}
+			regret := second - best.Detour
+			if next == nil || regret > nextRegret || (regret == nextRegret && best.Detour < next.Detour) {
+				next, nextRegret = best, regret
+			}
+		}
+		if next == nil {
+			break
+		}
+		assign(next)
+	}
+
+	for pass := 0; pass < maxAssignmentPasses; pass++ {
+		improved := false
+
+		// relocate a request to a cheaper route, or give an unassigned one a seat freed by an earlier move
+		for _, requestId := range requestIds {
+			for _, option := range options[requestId] {
+				current := assigned[requestId]
+				if option == current || !fits(option) {
+					continue
+				}
+				if current == nil || option.Detour < current.Detour {
+					assign(option)
+					improved = true
+				}
+			}
+		}
+
+		// swap the routes of two requests when both fit and the total detour drops
+		for i, first := range requestIds {
+			for _, second := range requestIds[i+1:] {
+				a, b := assigned[first], assigned[second]
+				if a == nil || b == nil || a.RouteId == b.RouteId {
+					continue
+				}
+				aOnB, bOnA := optionOn(options[first], b.RouteId), optionOn(options[second], a.RouteId)
+				if aOnB == nil || bOnA == nil || aOnB.Detour+bOnA.Detour >= a.Detour+b.Detour {
+					continue
+				}
+				if free[b.RouteId]+b.SeatCount < a.SeatCount || free[a.RouteId]+a.SeatCount < b.SeatCount {
+					continue
+				}
+				free[a.RouteId] += a.SeatCount - b.SeatCount
+				free[b.RouteId] += b.SeatCount - a.SeatCount
+				assigned[first], assigned[second] = aOnB, bOnA
+				improved = true
+			}
+		}
+
+		if !improved {
+			break
+		}
+	}
+
+	var result []*db.AssignmentCandidate
+	for _, requestId := range requestIds {
+		if option := assigned[requestId]; option != nil {
+			result = append(result, option)
+		}
+	}
+	return result
+}
+
+func optionOn(options []*db.AssignmentCandidate, routeId int32) *db.AssignmentCandidate {
+	for _, option := range options {
+		if option.RouteId == routeId {
+			return option
+		}
+	}
+	return nil
+}
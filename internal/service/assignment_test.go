@@ -0,0 +1,112 @@
+package service
+
+import (
+	"math/rand"
+
+	"github.com/CoRide-tw/backend/internal/db"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func testCandidate(requestId, routeId, seats, freeSeats int32, detour float64) *db.AssignmentCandidate {
+	return &db.AssignmentCandidate{
+		RequestId: requestId,
+		RiderId:   -requestId,
+		SeatCount: seats,
+		RouteId:   routeId,
+		DriverId:  -100 - routeId,
+		FreeSeats: freeSeats,
+		Detour:    detour,
+	}
+}
+
+func assignedRoutes(assignment []*db.AssignmentCandidate) map[int32]int32 {
+	routes := make(map[int32]int32)
+	for _, candidate := range assignment {
+		routes[candidate.RequestId] = candidate.RouteId
+	}
+	return routes
+}
+
+var _ = Describe("SolveAssignment", func() {
+	It("should give the route to the request without an alternative", func() {
+		assignment := solveAssignment([]*db.AssignmentCandidate{
+			testCandidate(1, 1, 1, 1, 1000),
+			testCandidate(1, 2, 1, 1, 2000),
+			testCandidate(2, 1, 1, 1, 1500),
+		})
+
+		Expect(assignedRoutes(assignment)).To(Equal(map[int32]int32{1: 2, 2: 1}))
+	})
+
+	It("should prefer the lower total detour over the cheapest single match", func() {
+		// handing route 1 to the cheapest match first would cost 2100 in total instead of 700
+		assignment := solveAssignment([]*db.AssignmentCandidate{
+			testCandidate(1, 1, 1, 1, 100),
+			testCandidate(1, 2, 1, 1, 500),
+			testCandidate(2, 1, 1, 1, 200),
+			testCandidate(2, 2, 1, 1, 2000),
+		})
+
+		Expect(assignedRoutes(assignment)).To(Equal(map[int32]int32{1: 2, 2: 1}))
+	})
+
+	It("should not exceed the free seats of a route", func() {
+		assignment := solveAssignment([]*db.AssignmentCandidate{
+			testCandidate(1, 1, 2, 3, 100),
+			testCandidate(2, 1, 2, 3, 200),
+			testCandidate(3, 1, 1, 3, 300),
+		})
+
+		Expect(assignedRoutes(assignment)).To(Equal(map[int32]int32{1: 1, 3: 1}))
+	})
+
+	It("should not depend on the order of the candidates", func() {
+		candidates := []*db.AssignmentCandidate{
+			testCandidate(1, 1, 1, 1, 300),
+			testCandidate(1, 2, 1, 1, 300),
+			testCandidate(2, 1, 1, 1, 300),
+			testCandidate(2, 2, 1, 1, 300),
+		}
+		reversed := []*db.AssignmentCandidate{candidates[3], candidates[2], candidates[1], candidates[0]}
+
+		Expect(assignedRoutes(solveAssignment(reversed))).To(Equal(assignedRoutes(solveAssignment(candidates))))
+	})
+
+	It("should return a feasible assignment for random instances", func() {
+		random := rand.New(rand.NewSource(46))
+		for instance := 0; instance < 50; instance++ {
+			routeCount, requestCount := random.Intn(8)+1, random.Intn(30)+1
+			freeSeats := make(map[int32]int32)
+			for routeId := int32(1); routeId <= int32(routeCount); routeId++ {
+				freeSeats[routeId] = int32(random.Intn(4) + 1)
+			}
+
+			var candidates []*db.AssignmentCandidate
+			feasible := make(map[[2]int32]bool)
+			for requestId := int32(1); requestId <= int32(requestCount); requestId++ {
+				seats := int32(random.Intn(2) + 1)
+				for routeId := int32(1); routeId <= int32(routeCount); routeId++ {
+					if random.Intn(2) == 0 || freeSeats[routeId] < seats {
+						continue
+					}
+					candidates = append(candidates, testCandidate(requestId, routeId, seats, freeSeats[routeId], random.Float64()*5000))
+					feasible[[2]int32{requestId, routeId}] = true
+				}
+			}
+
+			assignment := solveAssignment(candidates)
+			used := make(map[int32]int32)
+			seen := make(map[int32]bool)
+			for _, candidate := range assignment {
+				Expect(feasible[[2]int32{candidate.RequestId, candidate.RouteId}]).To(BeTrue())
+				Expect(seen[candidate.RequestId]).To(BeFalse())
+				seen[candidate.RequestId] = true
+				used[candidate.RouteId] += candidate.SeatCount
+			}
+			for routeId, seats := range used {
+				Expect(seats).To(BeNumerically("<=", freeSeats[routeId]))
+			}
+		}
+	})
+})
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"encoding/json"
+
+	"go.uber.org/zap"
+
+	"github.com/CoRide-tw/backend/internal/db"
+	"github.com/CoRide-tw/backend/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+type auditEntry struct {
+	Action     string
+	EntityType string
+	EntityId   int32
+	Before     interface{}
+	After      interface{}
+	Detail     interface{}
+}
+
+// recordAudit appends an entry attributed to the authenticated user and the current request id.
+// The audited change has already been made by then, so a failure is logged rather than returned.
+func recordAudit(logger *zap.SugaredLogger, c *gin.Context, entry auditEntry) {
+	authUid, _ := c.Get("userId")
+	actorId, _ := authUid.(int32)
+
+	auditLog := &model.AuditLog{
+		ActorId:    actorId,
+		Action:     entry.Action,
+		EntityType: entry.EntityType,
+		EntityId:   entry.EntityId,
+		RequestId:  c.GetString("requestId"),
+	}
+
+	for target, value := range map[*json.RawMessage]interface{}{
+		&auditLog.Before: entry.Before,
+		&auditLog.After:  entry.After,
+		&auditLog.Detail: entry.Detail,
+	} {
+		if value == nil {
+			continue
+		}
+		raw, err := json.Marshal(value)
+		if err != nil {
+			logger.Errorw("failed to encode audit log", "action", entry.Action, "error", err)
+			return
+		}
+		*target = raw
+	}
+
+	if _, err := db.CreateAuditLog(auditLog); err != nil {
+		logger.Errorw("failed to record audit log", "action", entry.Action, "entityId", entry.EntityId, "error", err)
+	}
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/CoRide-tw/backend/internal/config"
+	"github.com/CoRide-tw/backend/internal/constants"
+	"github.com/CoRide-tw/backend/internal/db"
+	"github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/errors/generated/svcerr"
+	"github.com/CoRide-tw/backend/internal/model"
+	"github.com/CoRide-tw/backend/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+type authSvc struct {
+	Logger *zap.SugaredLogger
+}
+
+type tokenPair struct {
+	AccessToken  string    `json:"token"`
+	RefreshToken string    `json:"refreshToken"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
+
+// issueTokens starts a new session (or continues familyId when rotating) and signs its access token
+func issueTokens(userId int32, familyId string, userAgent string, rotateFrom *model.Session) (*tokenPair, error) {
+	// roles are read on every issue so that role changes apply from the next refresh on
+	user, err := db.GetUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	if user.SuspendedAt != nil {
+		return nil, svcerr.ErrUserSuspended
+	}
+
+	refreshToken, err := util.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	if familyId == "" {
+		if familyId, err = util.GenerateRandomToken(16); err != nil {
+			return nil, err
+		}
+	}
+
+	session := &model.Session{
+		UserId:           userId,
+		FamilyId:         familyId,
+		RefreshTokenHash: util.HashToken(refreshToken),
+		UserAgent:        userAgent,
+		ExpiresAt:        time.Now().Add(config.Env.CoRideRefreshTokenTtl),
+	}
+	if rotateFrom != nil {
+		session, err = db.RotateSession(rotateFrom.Id, session)
+	} else {
+		session, err = db.CreateSession(session)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := util.GenerateJWT(userId, session.Id, user.Roles, config.JwtKeys, config.Env.CoRideAccessTokenTtl)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tokenPair{
+		AccessToken:  *accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    time.Now().Add(config.Env.CoRideAccessTokenTtl),
+	}, nil
+}
+
+type refreshTokenReq struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+func (s *authSvc) Refresh(c *gin.Context) {
+	var req refreshTokenReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := db.GetSessionByRefreshTokenHash(util.HashToken(req.RefreshToken))
+	if err != nil {
+		if errors.Is(err, dberr.ErrSessionNotFound) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": svcerr.ErrRefreshTokenInvalid.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// a rotated token presented again means it leaked, so revoke every session descended from the same login
+	if session.RevokedAt != nil {
+		s.revokeFamily(c, session.FamilyId)
+		return
+	}
+	if session.ExpiresAt.Before(time.Now()) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": svcerr.ErrRefreshTokenExpired.Error()})
+		return
+	}
+
+	tokens, err := issueTokens(session.UserId, session.FamilyId, c.Request.UserAgent(), session)
+	if err != nil {
+		if errors.Is(err, dberr.ErrSessionRevoked) {
+			s.revokeFamily(c, session.FamilyId)
+			return
+		}
+		if errors.Is(err, svcerr.ErrUserSuspended) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+func (s *authSvc) revokeFamily(c *gin.Context, familyId string) {
+	s.Logger.Warnw("refresh token reuse detected", "familyId", familyId)
+	if err := db.RevokeSessionFamily(familyId); err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{"error": svcerr.ErrRefreshTokenReused.Error()})
+}
+
+func (s *authSvc) Logout(c *gin.Context) {
+	var req refreshTokenReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := db.RevokeSessionByRefreshTokenHash(util.HashToken(req.RefreshToken)); err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+func (s *authSvc) LogoutAll(c *gin.Context) {
+	userId, ok := selfUserId(s.Logger, c)
+	if !ok {
+		return
+	}
+
+	if err := db.RevokeUserSessions(userId); err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionUserRevokeSessions,
+		EntityType: constants.AuditEntityUser,
+		EntityId:   userId,
+	})
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+func (s *authSvc) Jwks(c *gin.Context) {
+	c.JSON(http.StatusOK, config.JwtKeys.Jwks())
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/CoRide-tw/backend/internal/config"
+)
+
+// blobStore keeps uploaded files such as driver documents, keys are slash separated paths
+type blobStore interface {
+	Put(key string, content io.Reader) error
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+var errBlobKeyInvalid = errors.New("invalid blob key")
+
+func newBlobStore() (blobStore, error) {
+	switch config.Env.BlobStorageBackend {
+	case config.BlobStorageLocal:
+		return &localBlobStore{Dir: config.Env.BlobStorageDir}, nil
+	default:
+		return nil, fmt.Errorf("unsupported blob storage backend %q", config.Env.BlobStorageBackend)
+	}
+}
+
+// localBlobStore keeps blobs as files under Dir, suitable for a single instance or a shared volume
+type localBlobStore struct {
+	Dir string
+}
+
+func (s *localBlobStore) path(key string) (string, error) {
+	path := filepath.Join(s.Dir, filepath.FromSlash(key))
+	rel, err := filepath.Rel(s.Dir, path)
+	if err != nil || key == "" || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errBlobKeyInvalid
+	}
+	return path, nil
+}
+
+func (s *localBlobStore) Put(key string, content io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	// write aside and rename, so a failed upload never leaves a truncated blob behind
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *localBlobStore) Open(key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+func (s *localBlobStore) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LocalBlobStore", func() {
+	var store *localBlobStore
+
+	BeforeEach(func() {
+		store = &localBlobStore{Dir: GinkgoT().TempDir()}
+	})
+
+	It("should read back what was put", func() {
+		Expect(store.Put("driver-documents/1/test.png", strings.NewReader("content"))).To(Succeed())
+
+		file, err := store.Open("driver-documents/1/test.png")
+		Expect(err).NotTo(HaveOccurred())
+		defer file.Close()
+		content, err := io.ReadAll(file)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal("content"))
+	})
+
+	It("should delete blobs and ignore missing ones", func() {
+		Expect(store.Put("test", strings.NewReader("content"))).To(Succeed())
+		Expect(store.Delete("test")).To(Succeed())
+		Expect(store.Delete("test")).To(Succeed())
+
+		_, err := store.Open("test")
+		Expect(err).To(MatchError(os.ErrNotExist))
+	})
+
+	It("should refuse keys escaping the directory", func() {
+		Expect(store.Put("../escape", strings.NewReader("content"))).To(MatchError(errBlobKeyInvalid))
+		_, err := store.Open("a/../../escape")
+		Expect(err).To(MatchError(errBlobKeyInvalid))
+	})
+})
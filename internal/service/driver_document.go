@@ -0,0 +1,145 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	"github.com/CoRide-tw/backend/internal/db"
+	"github.com/CoRide-tw/backend/internal/errors/generated/svcerr"
+	"github.com/CoRide-tw/backend/internal/model"
+	"github.com/CoRide-tw/backend/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+const maxDriverDocumentSize = 10 << 20
+
+// driverDocumentExtensions lists the accepted content types, detected from the file itself rather than trusted from the client
+var driverDocumentExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"application/pdf": ".pdf",
+}
+
+func (s *userSvc) ListDriverDocuments(c *gin.Context) {
+	userId, ok := selfUserId(s.Logger, c)
+	if !ok {
+		return
+	}
+
+	documents, err := db.ListDriverDocumentsByUserId(userId)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, documents)
+}
+
+// UploadDriverDocument takes a multipart form with the document kind and the file, which then waits for an admin review
+func (s *userSvc) UploadDriverDocument(c *gin.Context) {
+	userId, ok := selfUserId(s.Logger, c)
+	if !ok {
+		return
+	}
+
+	// leave room for the other form fields around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxDriverDocumentSize+1<<20)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": svcerr.ErrDriverDocumentTooLarge.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if fileHeader.Size > maxDriverDocumentSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": svcerr.ErrDriverDocumentTooLarge.Error()})
+		return
+	}
+
+	kind := c.PostForm("kind")
+	if !isDriverDocumentKind(kind) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": svcerr.ErrDriverDocumentKindInvalid.Error()})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		s.Logger.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	contentType := http.DetectContentType(head[:n])
+	extension, ok := driverDocumentExtensions[contentType]
+	if !ok {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": svcerr.ErrDriverDocumentTypeUnsupported.Error()})
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, err := util.GenerateRandomToken(16)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	blobKey := fmt.Sprintf("driver-documents/%d/%s%s", userId, token, extension)
+	if err := s.Blobs.Put(blobKey, file); err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	document, err := db.CreateDriverDocument(&model.DriverDocument{
+		UserId:      userId,
+		Kind:        kind,
+		BlobKey:     blobKey,
+		ContentType: contentType,
+		SizeBytes:   fileHeader.Size,
+	})
+	if err != nil {
+		s.Logger.Error(err)
+		if deleteErr := s.Blobs.Delete(blobKey); deleteErr != nil {
+			s.Logger.Error(deleteErr)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionDriverDocumentUpload,
+		EntityType: constants.AuditEntityDriverDocument,
+		EntityId:   document.Id,
+		After:      document,
+	})
+
+	c.JSON(http.StatusOK, document)
+}
+
+func isDriverDocumentKind(kind string) bool {
+	for _, knownKind := range constants.DriverDocumentKinds {
+		if kind == knownKind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,312 @@
+package service
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/CoRide-tw/backend/internal/config"
+	"github.com/CoRide-tw/backend/internal/constants"
+	"github.com/CoRide-tw/backend/internal/db"
+	"github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/errors/generated/svcerr"
+	"github.com/CoRide-tw/backend/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	geoJsonSuffix  = ".geojson"
+	gpxSuffix      = ".gpx"
+	gpxContentType = "application/gpx+xml"
+)
+
+// gpxExport is a GPX 1.1 file holding a single track
+type gpxExport struct {
+	XMLName xml.Name `xml:"http://www.topografix.com/GPX/1/1 gpx"`
+	Version string   `xml:"version,attr"`
+	Creator string   `xml:"creator,attr"`
+	Track   struct {
+		Name    string `xml:"name"`
+		Segment struct {
+			Points []gpxPoint `xml:"trkpt"`
+		} `xml:"trkseg"`
+	} `xml:"trk"`
+}
+
+// Import creates a route from an uploaded GPX track or GeoJSON LineString in the file form field, the route
+// starts and ends where the track does. The other route fields come as JSON in the route form field.
+func (s *routeSvc) Import(c *gin.Context) {
+	// leave room for the route form field next to the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxTrackSize+1<<20)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": svcerr.ErrTrackTooLarge.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if fileHeader.Size > maxTrackSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": svcerr.ErrTrackTooLarge.Error()})
+		return
+	}
+
+	var route model.Route
+	if err := json.Unmarshal([]byte(c.PostForm("route")), &route); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	points, ok := parseTrack(data)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": svcerr.ErrTrackInvalid.Error()})
+		return
+	}
+	points = simplifyTrack(points, float64(config.Env.TrackSimplifyTolerance), config.Env.TrackMaxPoints)
+
+	driverId, _ := c.Get("userId")
+	route.DriverId = driverId.(int32)
+	route.StartLong, route.StartLat = points[0][0], points[0][1]
+	route.EndLong, route.EndLat = points[len(points)-1][0], points[len(points)-1][1]
+	if !s.applyVehicle(c, &route) {
+		return
+	}
+	if !s.applyRules(c, &route) {
+		return
+	}
+
+	path, _ := json.Marshal(lineStringGeometry(points))
+	routeResp, err := db.CreateRouteWithPath(&route, string(path))
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionRouteCreate,
+		EntityType: constants.AuditEntityRoute,
+		EntityId:   routeResp.Id,
+		After:      routeResp,
+		Detail:     gin.H{"trackPoints": len(points)},
+	})
+
+	c.JSON(http.StatusOK, routeResp)
+}
+
+// getExport answers GET /route/:id.geojson and /route/:id.gpx, gin cannot tell them apart from /route/:id
+// so Get hands them over. False means the id has no export suffix.
+func (s *routeSvc) getExport(c *gin.Context) bool {
+	stringId := c.Param("id")
+	format := ""
+	for _, suffix := range []string{geoJsonSuffix, gpxSuffix} {
+		if strings.HasSuffix(stringId, suffix) {
+			stringId, format = strings.TrimSuffix(stringId, suffix), suffix
+		}
+	}
+	if format == "" {
+		return false
+	}
+
+	routeId, err := strconv.Atoi(stringId)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id must be integer"})
+		return true
+	}
+	route, err := db.GetRoute(int32(routeId))
+	if err != nil {
+		if errors.Is(err, dberr.ErrRouteNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return true
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return true
+	}
+	geometry, err := db.GetRouteGeometry(route.Id)
+	if err != nil {
+		if errors.Is(err, dberr.ErrRouteNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return true
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return true
+	}
+
+	s.writeRouteExport(c, route, geometry, format)
+	return true
+}
+
+func (s *routeSvc) writeRouteExport(c *gin.Context, route *model.Route, geometry string, format string) {
+	feature, err := geoJsonFeature(geometry, map[string]interface{}{
+		"id":        route.Id,
+		"driverId":  route.DriverId,
+		"startTime": route.StartTime,
+		"endTime":   route.EndTime,
+		"capacity":  route.Capacity,
+	})
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if format == geoJsonSuffix {
+		writeGeoJson(c, feature)
+		return
+	}
+
+	body, err := gpxTrack(fmt.Sprintf("CoRide route %d", route.Id), feature.Geometry)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, gpxContentType, body)
+}
+
+// getGeoJson answers GET /trip/:id.geojson for the rider and driver of the trip, false means the id has no
+// GeoJSON suffix
+func (s *tripSvc) getGeoJson(c *gin.Context) bool {
+	stringId := c.Param("id")
+	if !strings.HasSuffix(stringId, geoJsonSuffix) {
+		return false
+	}
+
+	tripId, err := strconv.Atoi(strings.TrimSuffix(stringId, geoJsonSuffix))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id must be integer"})
+		return true
+	}
+	trip, err := db.GetTripGeometry(int32(tripId))
+	if err != nil {
+		if errors.Is(err, dberr.ErrTripNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return true
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return true
+	}
+	if authUid, _ := c.Get("userId"); authUid != trip.RiderId && authUid != trip.DriverId {
+		c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+		return true
+	}
+
+	feature, err := tripFeature(trip)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return true
+	}
+	writeGeoJson(c, feature)
+	return true
+}
+
+// ExportTrips responds with every trip of the user, as rider or driver, as a GeoJSON FeatureCollection
+func (s *userSvc) ExportTrips(c *gin.Context) {
+	userId, ok := selfUserId(s.Logger, c)
+	if !ok {
+		return
+	}
+
+	trips, err := db.ListTripGeometriesByUserId(userId)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	features := make([]*model.GeoJsonFeatureObject, 0, len(trips))
+	for _, trip := range trips {
+		feature, err := tripFeature(trip)
+		if err != nil {
+			s.Logger.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		features = append(features, feature)
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"coride-user-%d-trips.geojson\"", userId))
+	writeGeoJson(c, &model.GeoJsonFeatureCollectionObject{Type: model.GeoJsonFeatureCollection, Features: features})
+}
+
+func tripFeature(trip *db.TripGeometry) (*model.GeoJsonFeatureObject, error) {
+	return geoJsonFeature(trip.Geometry, map[string]interface{}{
+		"id":              trip.TripId,
+		"riderId":         trip.RiderId,
+		"driverId":        trip.DriverId,
+		"requestId":       trip.RequestId,
+		"routeId":         trip.RouteId,
+		"status":          trip.Status,
+		"pickupStartTime": trip.PickupStartTime,
+		"pickupEndTime":   trip.PickupEndTime,
+	})
+}
+
+// geoJsonFeature wraps a geometry as returned by PostGIS into a feature
+func geoJsonFeature(geometry string, properties map[string]interface{}) (*model.GeoJsonFeatureObject, error) {
+	var parsed model.GeoJsonGeometry
+	if err := json.Unmarshal([]byte(geometry), &parsed); err != nil {
+		return nil, err
+	}
+	return &model.GeoJsonFeatureObject{Type: model.GeoJsonFeature, Geometry: &parsed, Properties: properties}, nil
+}
+
+func lineStringGeometry(points [][2]float64) *model.GeoJsonGeometry {
+	// plain finite numbers always marshal
+	coordinates, _ := json.Marshal(points)
+	return &model.GeoJsonGeometry{Type: model.GeoJsonLineString, Coordinates: coordinates}
+}
+
+// gpxTrack writes a LineString as a GPX file with a single track
+func gpxTrack(name string, geometry *model.GeoJsonGeometry) ([]byte, error) {
+	var positions [][]float64
+	if err := json.Unmarshal(geometry.Coordinates, &positions); err != nil {
+		return nil, err
+	}
+
+	document := gpxExport{Version: "1.1", Creator: "CoRide"}
+	document.Track.Name = name
+	for _, position := range positions {
+		if len(position) < 2 {
+			return nil, errors.New("position needs a longitude and a latitude")
+		}
+		long, lat := position[0], position[1]
+		document.Track.Segment.Points = append(document.Track.Segment.Points, gpxPoint{Lat: &lat, Lon: &long})
+	}
+
+	body, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+func writeGeoJson(c *gin.Context, obj interface{}) {
+	// gin keeps a content type that is already set
+	c.Header("Content-Type", model.GeoJsonContentType)
+	c.JSON(http.StatusOK, obj)
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/CoRide-tw/backend/internal/config"
+	"github.com/CoRide-tw/backend/internal/constants"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+const googleCertsUrl = "https://www.googleapis.com/oauth2/v3/certs"
+
+type googleIdTokenClaims struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	Picture       string `json:"picture"`
+	jwt.StandardClaims
+}
+
+var errGoogleIdTokenInvalid = errors.New("invalid google id token")
+
+// verifyGoogleIdToken checks signature, issuer, audience and expiry of an ID token issued to one of our clients
+func (s *userSvc) verifyGoogleIdToken(idToken string) (*googleIdTokenClaims, error) {
+	var claims googleIdTokenClaims
+	if _, err := jwt.ParseWithClaims(idToken, &claims, func(token *jwt.Token) (interface{}, error) {
+		if token.Method.Alg() != jwt.SigningMethodRS256.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		kid, _ := token.Header["kid"].(string)
+		return s.GoogleKeys.PublicKey(kid)
+	}); err != nil {
+		return nil, fmt.Errorf("%w: %s", errGoogleIdTokenInvalid, err.Error())
+	}
+
+	if claims.Issuer != "accounts.google.com" && claims.Issuer != "https://accounts.google.com" {
+		return nil, fmt.Errorf("%w: unexpected issuer %s", errGoogleIdTokenInvalid, claims.Issuer)
+	}
+	if !googleAudienceAllowed(claims.Audience) {
+		return nil, fmt.Errorf("%w: unexpected audience %s", errGoogleIdTokenInvalid, claims.Audience)
+	}
+	if claims.ExpiresAt == 0 {
+		return nil, fmt.Errorf("%w: missing expiry", errGoogleIdTokenInvalid)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", errGoogleIdTokenInvalid)
+	}
+	return &claims, nil
+}
+
+// googleAudienceAllowed accepts the web client id and the native (Android/iOS) client ids
+func googleAudienceAllowed(aud string) bool {
+	if aud == "" {
+		return false
+	}
+	if aud == config.Env.GoogleOAuthClientId {
+		return true
+	}
+	for _, clientId := range config.Env.GoogleMobileClientIds {
+		if aud == clientId {
+			return true
+		}
+	}
+	return false
+}
+
+type idTokenReq struct {
+	IdToken  string `json:"idToken" binding:"required"`
+	Register bool   `json:"register"`
+}
+
+func (s *userSvc) IdTokenLogin(c *gin.Context) {
+	var req idTokenReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	claims, err := s.verifyGoogleIdToken(req.IdToken)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.login(c, &externalIdentity{
+		Provider:   constants.IdentityProviderGoogle,
+		Subject:    claims.Subject,
+		Email:      claims.Email,
+		Name:       claims.Name,
+		PictureUrl: claims.Picture,
+	}, req.Register)
+}
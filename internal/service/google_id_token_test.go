@@ -0,0 +1,123 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/config"
+	"github.com/golang-jwt/jwt"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+)
+
+type staticKeySource map[string]*rsa.PublicKey
+
+func (k staticKeySource) PublicKey(kid string) (*rsa.PublicKey, error) {
+	if key, ok := k[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown google key id %q", kid)
+}
+
+var _ = Describe("GoogleIdToken", func() {
+	var (
+		privateKey *rsa.PrivateKey
+		svc        *userSvc
+		claims     googleIdTokenClaims
+		kid        string
+	)
+
+	BeforeEach(func() {
+		var err error
+		privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).NotTo(HaveOccurred())
+
+		config.Env.GoogleOAuthClientId = "web-client"
+		config.Env.GoogleMobileClientIds = []string{"android-client"}
+
+		svc = &userSvc{
+			Logger:     zap.NewNop().Sugar(),
+			GoogleKeys: staticKeySource{"google-key": &privateKey.PublicKey},
+		}
+		kid = "google-key"
+		claims = googleIdTokenClaims{
+			Email:   "rider@example.com",
+			Name:    "rider",
+			Picture: "https://example.com/rider.png",
+			StandardClaims: jwt.StandardClaims{
+				Audience:  "android-client",
+				ExpiresAt: time.Now().Add(time.Hour).Unix(),
+				IssuedAt:  time.Now().Unix(),
+				Issuer:    "https://accounts.google.com",
+				Subject:   "google-sub",
+			},
+		}
+	})
+
+	Describe("verifyGoogleIdToken", func() {
+		var (
+			verified *googleIdTokenClaims
+			err      error
+		)
+
+		JustBeforeEach(func() {
+			token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+			token.Header["kid"] = kid
+			signed, signErr := token.SignedString(privateKey)
+			Expect(signErr).NotTo(HaveOccurred())
+
+			verified, err = svc.verifyGoogleIdToken(signed)
+		})
+
+		When("token is valid", func() {
+			It("should return claims", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(verified.Subject).To(Equal("google-sub"))
+				Expect(verified.Email).To(Equal("rider@example.com"))
+			})
+		})
+
+		When("token is expired", func() {
+			BeforeEach(func() {
+				claims.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+			})
+
+			It("should return error", func() {
+				Expect(err).To(MatchError(errGoogleIdTokenInvalid))
+			})
+		})
+
+		When("audience is another client", func() {
+			BeforeEach(func() {
+				claims.Audience = "someone-else"
+			})
+
+			It("should return error", func() {
+				Expect(err).To(MatchError(errGoogleIdTokenInvalid))
+			})
+		})
+
+		When("issuer is not google", func() {
+			BeforeEach(func() {
+				claims.Issuer = "https://evil.example.com"
+			})
+
+			It("should return error", func() {
+				Expect(err).To(MatchError(errGoogleIdTokenInvalid))
+			})
+		})
+
+		When("key id is unknown", func() {
+			BeforeEach(func() {
+				kid = "rotated-away"
+			})
+
+			It("should return error", func() {
+				Expect(err).To(MatchError(errGoogleIdTokenInvalid))
+			})
+		})
+	})
+})
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/config"
+	"github.com/CoRide-tw/backend/internal/constants"
+	"github.com/CoRide-tw/backend/internal/db"
+)
+
+func housekeepingJobs(n *notifier) []job {
+	return []job{
+		{Name: "expire_stale_requests", Run: func() (int64, error) { return expireStaleRequests(n) }},
+		{Name: "complete_finished_trips", Run: db.CompleteFinishedTrips},
+		{Name: "purge_soft_deleted_rows", Run: purgeSoftDeletedRows},
+		{Name: "purge_old_route_searches", Run: purgeOldRouteSearches},
+		{Name: "purge_sent_reminders", Run: purgeSentReminders},
+	}
+}
+
+// expireStaleRequests expires the requests whose pickup window closed unanswered, seats held by an offer go
+// to the waitlist and a journey the request belonged to is cancelled as a whole
+func expireStaleRequests(n *notifier) (int64, error) {
+	expired, err := db.ExpireStaleRequests()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, request := range expired {
+		if holdsSeats(request.Status) {
+			promoteWaitlist(n.Logger, n, request.RouteId)
+		}
+		cancelJourneyOf(n.Logger, n, nil, request.RequestId, request.RiderId, constants.RequestStatusExpired)
+	}
+	return int64(len(expired)), nil
+}
+
+// purgeSoftDeletedRows keeps soft deleted rows around for the retention period, a non positive retention
+// disables purging
+func purgeSoftDeletedRows() (int64, error) {
+	if config.Env.SoftDeleteRetention <= 0 {
+		return 0, nil
+	}
+	return db.PurgeSoftDeletedRows(time.Now().Add(-config.Env.SoftDeleteRetention))
+}
+
+// purgeOldRouteSearches drops the searches that fell out of the demand lookback
+func purgeOldRouteSearches() (int64, error) {
+	return db.PurgeRouteSearches(time.Now().Add(-config.Env.DemandLookback))
+}
+
+// purgeSentReminders forgets the reminders of trips and routes that ended longer than the retention period
+// ago, a non positive retention disables purging
+func purgeSentReminders() (int64, error) {
+	if config.Env.SoftDeleteRetention <= 0 {
+		return 0, nil
+	}
+	return db.PurgeSentReminders(time.Now().Add(-config.Env.SoftDeleteRetention))
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/config"
+	"github.com/CoRide-tw/backend/internal/constants"
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	appleIssuer       = "https://appleid.apple.com"
+	appleAuthorizeUrl = "https://appleid.apple.com/auth/authorize"
+	appleTokenUrl     = "https://appleid.apple.com/auth/token"
+	appleKeysUrl      = "https://appleid.apple.com/auth/keys"
+)
+
+// appleProvider implements Sign in with Apple, its id token is verified against Apple's published keys
+type appleProvider struct {
+	Keys publicKeySource
+}
+
+func (p *appleProvider) AuthUrl(state string) (string, error) {
+	baseUrl, err := url.Parse(appleAuthorizeUrl)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Add("response_type", "code")
+	params.Add("response_mode", "form_post")
+	params.Add("client_id", config.Env.AppleClientId)
+	params.Add("redirect_uri", config.Env.AppleRedirectUrl)
+	params.Add("scope", "name email")
+	params.Add("state", state)
+	baseUrl.RawQuery = params.Encode()
+
+	return baseUrl.String(), nil
+}
+
+type appleTokenRes struct {
+	IdToken string `json:"id_token"`
+}
+
+type appleIdTokenClaims struct {
+	Email string `json:"email"`
+	jwt.StandardClaims
+}
+
+func (p *appleProvider) Exchange(code string) (*externalIdentity, error) {
+	clientSecret, err := p.clientSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	var token appleTokenRes
+	if err := postForm(appleTokenUrl, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {config.Env.AppleRedirectUrl},
+		"client_id":     {config.Env.AppleClientId},
+		"client_secret": {clientSecret},
+	}, &token); err != nil {
+		return nil, err
+	}
+
+	var claims appleIdTokenClaims
+	if _, err := jwt.ParseWithClaims(token.IdToken, &claims, func(token *jwt.Token) (interface{}, error) {
+		if token.Method.Alg() != jwt.SigningMethodRS256.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		kid, _ := token.Header["kid"].(string)
+		return p.Keys.PublicKey(kid)
+	}); err != nil {
+		return nil, err
+	}
+	if claims.Issuer != appleIssuer || claims.Audience != config.Env.AppleClientId || claims.Subject == "" {
+		return nil, fmt.Errorf("invalid apple id token")
+	}
+
+	return &externalIdentity{
+		Provider: constants.IdentityProviderApple,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}, nil
+}
+
+// clientSecret is a short lived ES256 jwt signed with the team's Sign in with Apple key
+func (p *appleProvider) clientSecret() (string, error) {
+	pemBytes, err := os.ReadFile(config.Env.ApplePrivateKeyPath)
+	if err != nil {
+		return "", err
+	}
+	key, err := jwt.ParseECPrivateKeyFromPEM(pemBytes)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.StandardClaims{
+		Issuer:    config.Env.AppleTeamId,
+		Subject:   config.Env.AppleClientId,
+		Audience:  appleIssuer,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(5 * time.Minute).Unix(),
+	})
+	token.Header["kid"] = config.Env.AppleKeyId
+	return token.SignedString(key)
+}
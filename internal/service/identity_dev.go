@@ -0,0 +1,59 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/CoRide-tw/backend/internal/config"
+	"github.com/CoRide-tw/backend/internal/constants"
+)
+
+// devUsers are the seeded accounts dev login can sign in as, keyed by the code to exchange
+var devUsers = map[string]externalIdentity{
+	"rider": {
+		Provider: constants.IdentityProviderDev,
+		Subject:  "rider",
+		Email:    "rider@dev.coride.tw",
+		Name:     "Dev Rider",
+	},
+	"rider2": {
+		Provider: constants.IdentityProviderDev,
+		Subject:  "rider2",
+		Email:    "rider2@dev.coride.tw",
+		Name:     "Dev Rider 2",
+	},
+	"driver": {
+		Provider: constants.IdentityProviderDev,
+		Subject:  "driver",
+		Email:    "driver@dev.coride.tw",
+		Name:     "Dev Driver",
+	},
+}
+
+// devProvider logs in as a seeded user without talking to any identity provider.
+// It is only registered when CORIDE_DEV_LOGIN is enabled outside production.
+type devProvider struct{}
+
+// AuthUrl skips the consent screen and sends the client straight back to its callback
+func (p *devProvider) AuthUrl(state string) (string, error) {
+	callbackUrl, err := url.Parse(config.Env.GoogleOAuthRedirectUrl)
+	if err != nil {
+		return "", err
+	}
+
+	query := callbackUrl.Query()
+	query.Set("provider", constants.IdentityProviderDev)
+	query.Set("code", "rider")
+	query.Set("state", state)
+	callbackUrl.RawQuery = query.Encode()
+
+	return callbackUrl.String(), nil
+}
+
+func (p *devProvider) Exchange(code string) (*externalIdentity, error) {
+	identity, ok := devUsers[code]
+	if !ok {
+		return nil, fmt.Errorf("unknown dev user %q", code)
+	}
+	return &identity, nil
+}
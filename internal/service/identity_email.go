@@ -0,0 +1,107 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/config"
+	"github.com/CoRide-tw/backend/internal/constants"
+	"github.com/CoRide-tw/backend/internal/db"
+	"github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+const emailLoginTokenTtl = 15 * time.Minute
+
+var errEmailLoginLinkInvalid = errors.New("login link is invalid or expired")
+
+// emailLoginProvider proves ownership of an email address with a single use link mailed to it
+type emailLoginProvider struct {
+	Mailer mailer
+}
+
+func (p *emailLoginProvider) AuthUrl(state string) (string, error) {
+	return "", fmt.Errorf("email login has no redirect url, request a login link instead")
+}
+
+// Send mails a login link carrying a fresh token, only the token hash is stored. Links are throttled per
+// address and per requesting ip so that the endpoint cannot be used to flood inboxes.
+func (p *emailLoginProvider) Send(email, requestedIp string) error {
+	token, err := util.GenerateRandomToken(32)
+	if err != nil {
+		return err
+	}
+	if err := db.CreateEmailLoginToken(email, requestedIp, util.HashToken(token), time.Now().Add(emailLoginTokenTtl), &db.EmailLoginThrottle{
+		Window:        config.Env.EmailLoginThrottleWindow,
+		MaxPerAddress: config.Env.EmailLoginMaxPerAddress,
+		MaxPerIp:      config.Env.EmailLoginMaxPerIp,
+	}); err != nil {
+		return err
+	}
+
+	link, err := url.Parse(config.Env.EmailLoginUrl)
+	if err != nil {
+		return err
+	}
+	query := link.Query()
+	query.Set("code", token)
+	link.RawQuery = query.Encode()
+
+	return p.Mailer.Send(email, "Sign in to CoRide", fmt.Sprintf(
+		"Open this link within %d minutes to sign in to CoRide:\n\n%s\n\nIf you did not ask for it, you can ignore this email.",
+		int(emailLoginTokenTtl.Minutes()), link.String(),
+	))
+}
+
+func (p *emailLoginProvider) Exchange(code string) (*externalIdentity, error) {
+	email, err := db.ConsumeEmailLoginToken(util.HashToken(code))
+	if err != nil {
+		if errors.Is(err, dberr.ErrEmailLoginTokenNotFound) {
+			return nil, errEmailLoginLinkInvalid
+		}
+		return nil, err
+	}
+
+	return &externalIdentity{
+		Provider: constants.IdentityProviderEmail,
+		Subject:  email,
+		Email:    email,
+	}, nil
+}
+
+type emailLoginReq struct {
+	Email string `json:"email" binding:"required"`
+}
+
+func (s *userSvc) StartEmailLogin(c *gin.Context) {
+	var req emailLoginReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	address, err := mail.ParseAddress(req.Email)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid email"})
+		return
+	}
+
+	if err := s.EmailLogin.Send(strings.ToLower(address.Address), c.ClientIP()); err != nil {
+		if errors.Is(err, dberr.ErrEmailLoginThrottled) {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/config"
+	"github.com/CoRide-tw/backend/internal/constants"
+	"go.uber.org/zap"
+)
+
+const (
+	googleAuthUrl     = "https://accounts.google.com/o/oauth2/v2/auth"
+	googleTokenUrl    = "https://oauth2.googleapis.com/token"
+	googleUserInfoUrl = "https://www.googleapis.com/oauth2/v3/userinfo"
+)
+
+// googleProvider talks to Google's OAuth endpoints, which tests can point at an httptest server
+type googleProvider struct {
+	Logger      *zap.SugaredLogger
+	Client      *http.Client
+	TokenUrl    string
+	UserInfoUrl string
+}
+
+func newGoogleProvider(logger *zap.SugaredLogger) *googleProvider {
+	return &googleProvider{
+		Logger:      logger,
+		Client:      &http.Client{Timeout: 10 * time.Second},
+		TokenUrl:    googleTokenUrl,
+		UserInfoUrl: googleUserInfoUrl,
+	}
+}
+
+func (p *googleProvider) AuthUrl(state string) (string, error) {
+	baseUrl, err := url.Parse(googleAuthUrl)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Add("client_id", config.Env.GoogleOAuthClientId)
+	params.Add("redirect_uri", config.Env.GoogleOAuthRedirectUrl)
+	params.Add("response_type", "code")
+	params.Add("scope", config.Env.GoogleOauthScope)
+	params.Add("access_type", "offline")
+	params.Add("include_granted_scopes", "true")
+	params.Add("state", state)
+	baseUrl.RawQuery = params.Encode()
+
+	return baseUrl.String(), nil
+}
+
+func (p *googleProvider) Exchange(code string) (*externalIdentity, error) {
+	token, err := p.getAccessToken(code)
+	if err != nil {
+		return nil, err
+	}
+
+	userData, err := p.getUserData(token.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &externalIdentity{
+		Provider:   constants.IdentityProviderGoogle,
+		Subject:    userData.GoogleId,
+		Email:      userData.Email,
+		Name:       userData.Name,
+		PictureUrl: userData.PictureUrl,
+	}, nil
+}
+
+type oauthExchangeRes struct {
+	AccessToken  string `json:"access_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+	TokenType    string `json:"token_type"`
+}
+
+func (p *googleProvider) getAccessToken(code string) (*oauthExchangeRes, error) {
+	baseUrl, err := url.Parse(p.TokenUrl)
+	if err != nil {
+		p.Logger.Error(err)
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Add("code", code)
+	params.Add("client_id", config.Env.GoogleOAuthClientId)
+	params.Add("client_secret", config.Env.GoogleOAuthClientSecret)
+	params.Add("redirect_uri", config.Env.GoogleOAuthRedirectUrl)
+	params.Add("grant_type", "authorization_code")
+	baseUrl.RawQuery = params.Encode()
+
+	req, err := http.NewRequest(
+		"POST",
+		baseUrl.String(),
+		nil,
+	)
+	if err != nil {
+		p.Logger.Error(err)
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		p.Logger.Error(err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("google token exchange failed with status %d", resp.StatusCode)
+	}
+
+	var res oauthExchangeRes
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		p.Logger.Error(err)
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+type userData struct {
+	Name       string `json:"name"`
+	Email      string `json:"email"`
+	GoogleId   string `json:"sub"`
+	PictureUrl string `json:"picture"`
+}
+
+func (p *googleProvider) getUserData(accessToken string) (*userData, error) {
+	baseUrl, err := url.Parse(p.UserInfoUrl)
+	if err != nil {
+		p.Logger.Error(err)
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Add("access_token", accessToken)
+	baseUrl.RawQuery = params.Encode()
+
+	req, err := http.NewRequest(
+		"GET",
+		baseUrl.String(),
+		nil,
+	)
+	if err != nil {
+		p.Logger.Error(err)
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		p.Logger.Error(err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("google userinfo failed with status %d", resp.StatusCode)
+	}
+
+	// Read the response as a byte slice
+	var user userData
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		p.Logger.Error(err)
+		return nil, err
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("GoogleProvider", func() {
+	var (
+		server   *httptest.Server
+		provider *googleProvider
+		status   int
+	)
+
+	BeforeEach(func() {
+		status = http.StatusOK
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("code") != "valid-code" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(status)
+			_ = json.NewEncoder(w).Encode(oauthExchangeRes{AccessToken: "access-token", TokenType: "Bearer"})
+		})
+		mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("access_token") != "access-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(userData{
+				Name:       "rider",
+				Email:      "rider@example.com",
+				GoogleId:   "google-sub",
+				PictureUrl: "https://example.com/rider.png",
+			})
+		})
+		server = httptest.NewServer(mux)
+
+		provider = newGoogleProvider(zap.NewNop().Sugar())
+		provider.TokenUrl = server.URL + "/token"
+		provider.UserInfoUrl = server.URL + "/userinfo"
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("Exchange", func() {
+		var (
+			code     string
+			identity *externalIdentity
+			err      error
+		)
+
+		JustBeforeEach(func() {
+			identity, err = provider.Exchange(code)
+		})
+
+		When("code is valid", func() {
+			BeforeEach(func() {
+				code = "valid-code"
+			})
+
+			It("should return the google identity", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(identity.Provider).To(Equal(constants.IdentityProviderGoogle))
+				Expect(identity.Subject).To(Equal("google-sub"))
+				Expect(identity.Email).To(Equal("rider@example.com"))
+			})
+		})
+
+		When("code is rejected", func() {
+			BeforeEach(func() {
+				code = "invalid-code"
+			})
+
+			It("should return error", func() {
+				Expect(err).To(HaveOccurred())
+				Expect(identity).To(BeNil())
+			})
+		})
+	})
+})
+
+var _ = Describe("DevProvider", func() {
+	provider := &devProvider{}
+
+	It("should sign in as a seeded user", func() {
+		identity, err := provider.Exchange("driver")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(identity.Provider).To(Equal(constants.IdentityProviderDev))
+		Expect(identity.Subject).To(Equal("driver"))
+	})
+
+	It("should refuse unknown users", func() {
+		_, err := provider.Exchange("someone")
+		Expect(err).To(HaveOccurred())
+	})
+})
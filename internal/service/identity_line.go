@@ -0,0 +1,101 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/CoRide-tw/backend/internal/config"
+	"github.com/CoRide-tw/backend/internal/constants"
+)
+
+const (
+	lineAuthorizeUrl = "https://access.line.me/oauth2/v2.1/authorize"
+	lineTokenUrl     = "https://api.line.me/oauth2/v2.1/token"
+	lineVerifyUrl    = "https://api.line.me/oauth2/v2.1/verify"
+)
+
+// lineProvider implements LINE Login v2.1, the id token is checked by LINE's verify endpoint
+type lineProvider struct{}
+
+func (p *lineProvider) AuthUrl(state string) (string, error) {
+	baseUrl, err := url.Parse(lineAuthorizeUrl)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Add("response_type", "code")
+	params.Add("client_id", config.Env.LineChannelId)
+	params.Add("redirect_uri", config.Env.LineRedirectUrl)
+	params.Add("scope", "profile openid email")
+	params.Add("state", state)
+	baseUrl.RawQuery = params.Encode()
+
+	return baseUrl.String(), nil
+}
+
+type lineTokenRes struct {
+	AccessToken string `json:"access_token"`
+	IdToken     string `json:"id_token"`
+}
+
+type lineVerifyRes struct {
+	Sub     string `json:"sub"`
+	Name    string `json:"name"`
+	Picture string `json:"picture"`
+	Email   string `json:"email"`
+}
+
+func (p *lineProvider) Exchange(code string) (*externalIdentity, error) {
+	var token lineTokenRes
+	if err := postForm(lineTokenUrl, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {config.Env.LineRedirectUrl},
+		"client_id":     {config.Env.LineChannelId},
+		"client_secret": {config.Env.LineChannelSecret},
+	}, &token); err != nil {
+		return nil, err
+	}
+
+	// verify checks signature, audience and expiry of the id token on LINE's side
+	var verified lineVerifyRes
+	if err := postForm(lineVerifyUrl, url.Values{
+		"id_token":  {token.IdToken},
+		"client_id": {config.Env.LineChannelId},
+	}, &verified); err != nil {
+		return nil, err
+	}
+
+	return &externalIdentity{
+		Provider:   constants.IdentityProviderLine,
+		Subject:    verified.Sub,
+		Email:      verified.Email,
+		Name:       verified.Name,
+		PictureUrl: verified.Picture,
+	}, nil
+}
+
+// postForm posts a form encoded body and decodes the json response into res
+func postForm(endpoint string, form url.Values, res interface{}) error {
+	req, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s responded with status %d", endpoint, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(res)
+}
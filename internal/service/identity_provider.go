@@ -0,0 +1,67 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/CoRide-tw/backend/internal/config"
+	"github.com/CoRide-tw/backend/internal/constants"
+	"github.com/CoRide-tw/backend/internal/model"
+	"go.uber.org/zap"
+)
+
+// identityProvider is an external login method, such as an OAuth provider or an emailed magic link
+type identityProvider interface {
+	// AuthUrl builds the url the client redirects to, state is echoed back on the callback
+	AuthUrl(state string) (string, error)
+	// Exchange verifies the credential handed back to the client and resolves the identity it proves
+	Exchange(credential string) (*externalIdentity, error)
+}
+
+type externalIdentity struct {
+	Provider   string
+	Subject    string
+	Email      string
+	Name       string
+	PictureUrl string
+}
+
+func (i *externalIdentity) userIdentity() *model.UserIdentity {
+	return &model.UserIdentity{
+		Provider: i.Provider,
+		Subject:  i.Subject,
+		Email:    i.Email,
+	}
+}
+
+func (i *externalIdentity) user() *model.User {
+	name := i.Name
+	if name == "" {
+		// providers like apple and email share no display name, so start from the email local part
+		name = strings.Split(i.Email, "@")[0]
+	}
+	return &model.User{
+		Name:       name,
+		Email:      i.Email,
+		PictureUrl: i.PictureUrl,
+	}
+}
+
+// newIdentityProviders registers google and email login, plus line, apple and dev login when they are configured
+func newIdentityProviders(logger *zap.SugaredLogger, emailLogin *emailLoginProvider) map[string]identityProvider {
+	providers := map[string]identityProvider{
+		constants.IdentityProviderGoogle: newGoogleProvider(logger),
+		constants.IdentityProviderEmail:  emailLogin,
+	}
+	if config.Env.LineChannelId != "" {
+		providers[constants.IdentityProviderLine] = &lineProvider{}
+	}
+	if config.Env.DevLoginEnabled && config.Env.AppEnv != config.AppEnvProduction {
+		providers[constants.IdentityProviderDev] = &devProvider{}
+	}
+	if config.Env.AppleClientId != "" {
+		providers[constants.IdentityProviderApple] = &appleProvider{
+			Keys: newJwksKeySource(appleKeysUrl),
+		}
+	}
+	return providers
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/config"
+	"github.com/CoRide-tw/backend/internal/constants"
+	"github.com/CoRide-tw/backend/internal/db"
+	"github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	"github.com/CoRide-tw/backend/internal/util"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// maxRouteDemandSuggestions is how many busy cells are suggested for a single route
+const maxRouteDemandSuggestions = 3
+
+type insightSvc struct {
+	Logger *zap.SugaredLogger
+}
+
+// Demand shows where riders wanted rides over the lookback period as GeoJSON cells, so that drivers can
+// plan routes where they are needed
+func (s *insightSvc) Demand(c *gin.Context) {
+	parsedQuery, err := util.ParseDemandQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cells, err := db.ListDemandCells(parsedQuery.HourOfWeek, demandParams(), parsedQuery.Limit)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	features := make([]*model.GeoJsonFeatureObject, 0, len(cells))
+	for _, cell := range cells {
+		features = append(features, demandCellFeature(cell))
+	}
+	writeGeoJson(c, &model.GeoJsonFeatureCollectionObject{Type: model.GeoJsonFeatureCollection, Features: features})
+}
+
+// SuggestDemand shows the driver the busy cells close to the route at the time it runs, with the detour
+// passing through each of them would take
+func (s *routeSvc) SuggestDemand(c *gin.Context) {
+	routeId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id must be integer"})
+		return
+	}
+
+	route, err := db.GetRoute(int32(routeId))
+	if err != nil {
+		if errors.Is(err, dberr.ErrRouteNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if authUid, _ := c.Get("userId"); authUid != route.DriverId {
+		c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+		return
+	}
+
+	suggestions, err := db.ListRouteDemandSuggestions(route.Id, demandParams(), float64(config.Env.DemandSuggestionRadius), maxRouteDemandSuggestions)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	features := make([]*model.GeoJsonFeatureObject, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		feature := demandCellFeature(&suggestion.DemandCell)
+		feature.Properties["detour"] = suggestion.Detour
+		features = append(features, feature)
+	}
+	writeGeoJson(c, &model.GeoJsonFeatureCollectionObject{Type: model.GeoJsonFeatureCollection, Features: features})
+}
+
+func demandParams() *db.DemandParams {
+	return &db.DemandParams{
+		Since:     time.Now().Add(-config.Env.DemandLookback),
+		Precision: config.Env.DemandGeohashPrecision,
+		TimeZone:  config.Env.DemandTimeZone,
+		MinCount:  config.Env.DemandMinCount,
+		Salt:      config.Env.DemandSearcherSalt,
+	}
+}
+
+// demandCellFeature draws the geohash cell as a polygon, the counts go into the properties
+func demandCellFeature(cell *db.DemandCell) *model.GeoJsonFeatureObject {
+	ring := [][][2]float64{{
+		{cell.MinLong, cell.MinLat},
+		{cell.MaxLong, cell.MinLat},
+		{cell.MaxLong, cell.MaxLat},
+		{cell.MinLong, cell.MaxLat},
+		{cell.MinLong, cell.MinLat},
+	}}
+	// plain finite numbers always marshal
+	coordinates, _ := json.Marshal(ring)
+
+	return &model.GeoJsonFeatureObject{
+		Type:     model.GeoJsonFeature,
+		Geometry: &model.GeoJsonGeometry{Type: model.GeoJsonPolygon, Coordinates: coordinates},
+		Properties: map[string]interface{}{
+			"geohash":       cell.Geohash,
+			"hourOfWeek":    cell.HourOfWeek,
+			"requests":      cell.Requests,
+			"unmetSearches": cell.UnmetSearches,
+			"demand":        cell.Requests + cell.UnmetSearches,
+		},
+	}
+}
+
+func demandJobs(n *notifier) []job {
+	return []job{
+		{Name: "suggest_route_tweaks", Run: func() (int64, error) { return suggestRouteTweaks(n) }},
+	}
+}
+
+// suggestRouteTweaks tells each driver once per route about the busiest cell close to it as the route
+// start draws near. Every route is looked at once, a route with nothing busy nearby is marked handled as
+// well, the demand is weeks of history and barely moves before the route starts.
+func suggestRouteTweaks(n *notifier) (int64, error) {
+	if config.Env.DemandSuggestionLead <= 0 {
+		return 0, nil
+	}
+
+	routes, err := db.ListUndemandedUpcomingRoutes(time.Now().Add(config.Env.DemandSuggestionLead))
+	if err != nil {
+		return 0, err
+	}
+
+	params := demandParams()
+	var sent int64
+	for _, route := range routes {
+		suggestions, err := db.ListRouteDemandSuggestions(route.RouteId, params, float64(config.Env.DemandSuggestionRadius), 1)
+		if err != nil {
+			return sent, err
+		}
+		claimed, err := db.ClaimReminder(constants.NotificationKindRouteDemand, route.RouteId, "before_start")
+		if err != nil {
+			return sent, err
+		}
+		if !claimed || len(suggestions) == 0 {
+			continue
+		}
+
+		title, body := routeTweakMessage(suggestions[0])
+		n.Notify(route.DriverId, constants.NotificationKindRouteDemand, title, body, gin.H{
+			"routeId":   route.RouteId,
+			"startTime": route.StartTime,
+			"geohash":   suggestions[0].Geohash,
+			"long":      (suggestions[0].MinLong + suggestions[0].MaxLong) / 2,
+			"lat":       (suggestions[0].MinLat + suggestions[0].MaxLat) / 2,
+			"detour":    suggestions[0].Detour,
+		})
+		sent++
+	}
+	return sent, nil
+}
+
+func routeTweakMessage(suggestion *db.RouteDemandSuggestion) (string, string) {
+	demand := suggestion.Requests + suggestion.UnmetSearches
+	return "Riders are looking for rides near your route",
+		fmt.Sprintf("%d riders usually look for a ride close to your route at this time, a detour of about %.1f km would pass by them.",
+			demand, suggestion.Detour/1000)
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"encoding/json"
+
+	"github.com/CoRide-tw/backend/internal/db"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DemandCellFeature", func() {
+	It("should draw the cell as a closed polygon with its counts", func() {
+		feature := demandCellFeature(&db.DemandCell{
+			Geohash:       "wsqqqq",
+			HourOfWeek:    8,
+			Requests:      2,
+			UnmetSearches: 3,
+			MinLong:       121.5,
+			MinLat:        25,
+			MaxLong:       121.6,
+			MaxLat:        25.1,
+		})
+
+		Expect(feature.Type).To(Equal("Feature"))
+		Expect(feature.Geometry.Type).To(Equal("Polygon"))
+		var rings [][][2]float64
+		Expect(json.Unmarshal(feature.Geometry.Coordinates, &rings)).To(Succeed())
+		Expect(rings).To(HaveLen(1))
+		Expect(rings[0]).To(HaveLen(5))
+		Expect(rings[0][0]).To(Equal(rings[0][4]))
+		Expect(rings[0][2]).To(Equal([2]float64{121.6, 25.1}))
+		Expect(feature.Properties).To(HaveKeyWithValue("demand", int32(5)))
+		Expect(feature.Properties).To(HaveKeyWithValue("geohash", "wsqqqq"))
+	})
+})
+
+var _ = Describe("RouteTweakMessage", func() {
+	It("should mention the demand and the detour in kilometers", func() {
+		_, body := routeTweakMessage(&db.RouteDemandSuggestion{
+			DemandCell: db.DemandCell{Requests: 1, UnmetSearches: 4},
+			Detour:     1250,
+		})
+
+		Expect(body).To(ContainSubstring("5 riders"))
+		Expect(body).To(ContainSubstring("1.2 km"))
+	})
+})
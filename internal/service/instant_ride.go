@@ -0,0 +1,410 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/config"
+	"github.com/CoRide-tw/backend/internal/constants"
+	"github.com/CoRide-tw/backend/internal/db"
+	"github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/errors/generated/svcerr"
+	"github.com/CoRide-tw/backend/internal/model"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// instantHeadingTolerance is how far in degrees a driver's heading may be off the ride's direction
+const instantHeadingTolerance = 60
+
+const maxInstantSeats = 8
+
+type instantSvc struct {
+	Logger   *zap.SugaredLogger
+	Notifier *notifier
+}
+
+func (s *instantSvc) GetPresence(c *gin.Context) {
+	userId, _ := c.Get("userId")
+	presence, err := db.GetDriverPresence(userId.(int32))
+	if err != nil {
+		if errors.Is(err, dberr.ErrDriverPresenceNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, presence)
+}
+
+// GoOnline puts the driver into instant mode, drivers keep calling it with their current location
+func (s *instantSvc) GoOnline(c *gin.Context) {
+	var presence model.DriverPresence
+	if err := c.ShouldBindJSON(&presence); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !isValidCoordinate(presence.Long, presence.Lat) || !isValidCoordinate(presence.DestinationLong, presence.DestinationLat) ||
+		presence.Seats < 1 || presence.Seats > maxInstantSeats {
+		c.JSON(http.StatusBadRequest, gin.H{"error": svcerr.ErrInstantRideInvalid.Error()})
+		return
+	}
+
+	userId, _ := c.Get("userId")
+	presence.UserId = userId.(int32)
+	updated, err := db.UpsertDriverPresence(&presence)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionDriverPresenceOnline,
+		EntityType: constants.AuditEntityDriverPresence,
+		EntityId:   updated.UserId,
+		After:      updated,
+	})
+
+	c.JSON(http.StatusOK, updated)
+}
+
+func (s *instantSvc) GoOffline(c *gin.Context) {
+	userId, _ := c.Get("userId")
+	if err := db.SetDriverOffline(userId.(int32)); err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionDriverPresenceOffline,
+		EntityType: constants.AuditEntityDriverPresence,
+		EntityId:   userId.(int32),
+	})
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// CreateRequest posts a ride wanted right away and offers it to the first candidate driver
+func (s *instantSvc) CreateRequest(c *gin.Context) {
+	var request model.InstantRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.SeatCount == 0 {
+		request.SeatCount = 1
+	}
+	if !isValidCoordinate(request.PickupLong, request.PickupLat) || !isValidCoordinate(request.DropoffLong, request.DropoffLat) ||
+		request.SeatCount < 1 || request.SeatCount > maxInstantSeats {
+		c.JSON(http.StatusBadRequest, gin.H{"error": svcerr.ErrInstantRideInvalid.Error()})
+		return
+	}
+
+	userId, _ := c.Get("userId")
+	request.RiderId = userId.(int32)
+	created, err := db.CreateInstantRequest(&request)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionInstantRequestCreate,
+		EntityType: constants.AuditEntityInstantRequest,
+		EntityId:   created.Id,
+		After:      created,
+	})
+
+	c.JSON(http.StatusOK, dispatchInstantRequest(s.Logger, s.Notifier, created))
+}
+
+// GetRequest shows the rider or the offered driver where the request stands, an offer that timed out
+// falls back to the next driver right away instead of waiting for the job
+func (s *instantSvc) GetRequest(c *gin.Context) {
+	request, ok := s.getRequest(c)
+	if !ok {
+		return
+	}
+	authUid, _ := c.Get("userId")
+	if authUid != request.RiderId && (request.DriverId == nil || authUid != *request.DriverId) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dispatchInstantRequest(s.Logger, s.Notifier, request))
+}
+
+func (s *instantSvc) AcceptRequest(c *gin.Context) {
+	request, ok := s.getRequest(c)
+	if !ok {
+		return
+	}
+
+	userId, _ := c.Get("userId")
+	accepted, err := db.AcceptInstantRequest(request.Id, userId.(int32))
+	if err != nil {
+		if errors.Is(err, dberr.ErrInstantOfferNotActive) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionInstantRequestAccept,
+		EntityType: constants.AuditEntityInstantRequest,
+		EntityId:   accepted.Id,
+		Before:     gin.H{"status": request.Status},
+		After:      gin.H{"status": accepted.Status, "driverId": accepted.DriverId},
+	})
+	s.Notifier.Notify(accepted.RiderId, constants.NotificationKindInstantAccepted,
+		"A driver is on the way",
+		"A nearby driver accepted your ride and is heading to your pickup.",
+		gin.H{"instantRequestId": accepted.Id, "driverId": accepted.DriverId},
+	)
+
+	c.JSON(http.StatusOK, accepted)
+}
+
+func (s *instantSvc) DeclineRequest(c *gin.Context) {
+	request, ok := s.getRequest(c)
+	if !ok {
+		return
+	}
+
+	userId, _ := c.Get("userId")
+	if err := db.DeclineInstantRequest(request.Id, userId.(int32)); err != nil {
+		if errors.Is(err, dberr.ErrInstantOfferNotActive) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionInstantRequestDecline,
+		EntityType: constants.AuditEntityInstantRequest,
+		EntityId:   request.Id,
+		Before:     gin.H{"status": request.Status},
+		After:      gin.H{"status": constants.InstantRequestStatusSearching},
+	})
+	request.Status, request.DriverId, request.OfferExpiresAt = constants.InstantRequestStatusSearching, nil, nil
+	dispatchInstantRequest(s.Logger, s.Notifier, request)
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// CompleteRequest lets the driver end the accepted ride, they are back online for the next one
+func (s *instantSvc) CompleteRequest(c *gin.Context) {
+	request, ok := s.getRequest(c)
+	if !ok {
+		return
+	}
+
+	userId, _ := c.Get("userId")
+	completed, err := db.CompleteInstantRequest(request.Id, userId.(int32))
+	if err != nil {
+		if errors.Is(err, dberr.ErrInstantRideNotAccepted) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionInstantRequestComplete,
+		EntityType: constants.AuditEntityInstantRequest,
+		EntityId:   completed.Id,
+		Before:     gin.H{"status": request.Status},
+		After:      gin.H{"status": completed.Status},
+	})
+
+	c.JSON(http.StatusOK, completed)
+}
+
+// WithdrawRequest lets the driver back out of the accepted ride, the rider is told and the request goes
+// back to dispatch without them
+func (s *instantSvc) WithdrawRequest(c *gin.Context) {
+	request, ok := s.getRequest(c)
+	if !ok {
+		return
+	}
+
+	userId, _ := c.Get("userId")
+	withdrawn, err := db.WithdrawInstantRequest(request.Id, userId.(int32))
+	if err != nil {
+		if errors.Is(err, dberr.ErrInstantRideNotAccepted) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionInstantRequestWithdraw,
+		EntityType: constants.AuditEntityInstantRequest,
+		EntityId:   withdrawn.Id,
+		Before:     gin.H{"status": request.Status, "driverId": request.DriverId},
+		After:      gin.H{"status": withdrawn.Status},
+	})
+	s.Notifier.Notify(withdrawn.RiderId, constants.NotificationKindInstantWithdrawn,
+		"Your driver cancelled",
+		"The driver who accepted your ride can no longer make it, we are looking for another one nearby.",
+		gin.H{"instantRequestId": withdrawn.Id},
+	)
+	dispatchInstantRequest(s.Logger, s.Notifier, withdrawn)
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+func (s *instantSvc) CancelRequest(c *gin.Context) {
+	request, ok := s.getRequest(c)
+	if !ok {
+		return
+	}
+
+	userId, _ := c.Get("userId")
+	if err := db.CancelInstantRequest(request.Id, userId.(int32)); err != nil {
+		if errors.Is(err, dberr.ErrInstantRequestNotActive) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionInstantRequestCancel,
+		EntityType: constants.AuditEntityInstantRequest,
+		EntityId:   request.Id,
+		Before:     gin.H{"status": request.Status},
+		After:      gin.H{"status": constants.InstantRequestStatusCancelled},
+	})
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// getRequest loads the :id instant request, otherwise the error response is already written
+func (s *instantSvc) getRequest(c *gin.Context) (*model.InstantRequest, bool) {
+	requestId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id must be integer"})
+		return nil, false
+	}
+
+	request, err := db.GetInstantRequest(int32(requestId))
+	if err != nil {
+		if errors.Is(err, dberr.ErrInstantRequestNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return request, true
+}
+
+func instantDispatchParams() *db.InstantDispatchParams {
+	return &db.InstantDispatchParams{
+		Radius:        float64(config.Env.InstantDispatchRadius),
+		MinHeadingCos: math.Cos(instantHeadingTolerance * math.Pi / 180),
+		OfferTimeout:  config.Env.InstantOfferTimeout,
+		SearchTimeout: config.Env.InstantSearchTimeout,
+		PresenceTtl:   config.Env.InstantPresenceTtl,
+	}
+}
+
+// dispatchInstantRequest offers the request to the next driver when it needs one and tells whoever is
+// affected. The request was already stored, so a failure is logged and the job retries it.
+func dispatchInstantRequest(logger *zap.SugaredLogger, n *notifier, request *model.InstantRequest) *model.InstantRequest {
+	if !needsDispatch(request) {
+		return request
+	}
+
+	dispatched, changed, err := db.DispatchInstantRequest(request.Id, instantDispatchParams())
+	if err != nil {
+		logger.Errorw("failed to dispatch instant request", "instantRequestId", request.Id, "error", err)
+		return request
+	}
+	if changed {
+		notifyInstantDispatch(n, dispatched)
+	}
+	return dispatched
+}
+
+// notifyInstantDispatch tells the driver about a new offer or the rider that nobody could be found
+func notifyInstantDispatch(n *notifier, request *model.InstantRequest) {
+	switch request.Status {
+	case constants.InstantRequestStatusOffered:
+		n.Notify(*request.DriverId, constants.NotificationKindInstantOffer,
+			"A rider nearby needs a ride",
+			"A rider close to you is heading your way, accept the ride before the offer runs out.",
+			gin.H{"instantRequestId": request.Id, "offerExpiresAt": request.OfferExpiresAt},
+		)
+	case constants.InstantRequestStatusUnmatched:
+		n.Notify(request.RiderId, constants.NotificationKindInstantUnmatched,
+			"No driver found",
+			"No driver nearby could take your ride right now, please try again later or look for a scheduled route.",
+			gin.H{"instantRequestId": request.Id},
+		)
+	}
+}
+
+func needsDispatch(request *model.InstantRequest) bool {
+	switch request.Status {
+	case constants.InstantRequestStatusSearching:
+		return true
+	case constants.InstantRequestStatusOffered:
+		return request.OfferExpiresAt != nil && !request.OfferExpiresAt.After(time.Now())
+	default:
+		return false
+	}
+}
+
+func instantJobs(n *notifier) []job {
+	return []job{
+		// offers time out quicker than the other jobs run, so this one runs as often as an offer lasts
+		{
+			Name:     "dispatch_instant_requests",
+			Interval: config.Env.InstantOfferTimeout,
+			Run:      func() (int64, error) { return dispatchPendingInstantRequests(n) },
+		},
+	}
+}
+
+// dispatchPendingInstantRequests moves along every request whose offer timed out or that still has no
+// driver, riders nobody polls for would otherwise wait forever
+func dispatchPendingInstantRequests(n *notifier) (int64, error) {
+	ids, err := db.ListDispatchableInstantRequests()
+	if err != nil {
+		return 0, err
+	}
+
+	var changed int64
+	for _, id := range ids {
+		request, moved, err := db.DispatchInstantRequest(id, instantDispatchParams())
+		if err != nil {
+			return changed, err
+		}
+		if moved {
+			notifyInstantDispatch(n, request)
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+func isValidCoordinate(long, lat float64) bool {
+	return math.Abs(long) <= 180 && math.Abs(lat) <= 90
+}
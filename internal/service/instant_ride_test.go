@@ -0,0 +1,27 @@
+package service
+
+import (
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NeedsDispatch", func() {
+	It("should dispatch requests still searching", func() {
+		Expect(needsDispatch(&model.InstantRequest{Status: constants.InstantRequestStatusSearching})).To(BeTrue())
+	})
+
+	It("should only dispatch offers that timed out", func() {
+		past, future := time.Now().Add(-time.Second), time.Now().Add(time.Minute)
+		Expect(needsDispatch(&model.InstantRequest{Status: constants.InstantRequestStatusOffered, OfferExpiresAt: &past})).To(BeTrue())
+		Expect(needsDispatch(&model.InstantRequest{Status: constants.InstantRequestStatusOffered, OfferExpiresAt: &future})).To(BeFalse())
+	})
+
+	It("should leave settled requests alone", func() {
+		Expect(needsDispatch(&model.InstantRequest{Status: constants.InstantRequestStatusAccepted})).To(BeFalse())
+		Expect(needsDispatch(&model.InstantRequest{Status: constants.InstantRequestStatusCancelled})).To(BeFalse())
+	})
+})
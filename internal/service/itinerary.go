@@ -0,0 +1,290 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/config"
+	"github.com/CoRide-tw/backend/internal/constants"
+	"github.com/CoRide-tw/backend/internal/db"
+	"github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+// maxExactItineraryRiders is the most riders whose stop order is searched exhaustively, larger groups are
+// planned greedily
+const maxExactItineraryRiders = 6
+
+const earthRadius = 6371000
+
+type itineraryStop struct {
+	Kind            string     `json:"kind"`
+	RequestId       *int32     `json:"requestId,omitempty"`
+	RiderId         *int32     `json:"riderId,omitempty"`
+	RiderName       string     `json:"riderName,omitempty"`
+	Long            float64    `json:"long"`
+	Lat             float64    `json:"lat"`
+	Seats           int32      `json:"seats,omitempty"`
+	Occupied        int32      `json:"occupied"`
+	PickupStartTime *time.Time `json:"pickupStartTime,omitempty"`
+	PickupEndTime   *time.Time `json:"pickupEndTime,omitempty"`
+	Eta             time.Time  `json:"eta"`
+	Departure       time.Time  `json:"departure"`
+	Late            bool       `json:"late"`
+}
+
+type itinerary struct {
+	RouteId  int32            `json:"routeId"`
+	Stops    []*itineraryStop `json:"stops"`
+	Distance float64          `json:"distance"`
+	OnTime   bool             `json:"onTime"`
+}
+
+// GetItinerary shows the driver in which order to pick up and drop off the accepted riders and when each
+// stop is reached. The plan is worked out from the current accepted requests on every call, so it follows
+// riders joining or cancelling right away.
+func (s *routeSvc) GetItinerary(c *gin.Context) {
+	routeId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id must be integer"})
+		return
+	}
+
+	route, err := db.GetRoute(int32(routeId))
+	if err != nil {
+		if errors.Is(err, dberr.ErrRouteNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if authUid, _ := c.Get("userId"); authUid != route.DriverId {
+		c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+		return
+	}
+
+	requests, err := db.ListItineraryRequests(route.Id)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	speed := float64(config.Env.ItinerarySpeed) * 1000 / 3600
+	c.JSON(http.StatusOK, planItinerary(route, requests, speed, config.Env.ItineraryStopDuration))
+}
+
+// itineraryState is where the driver stands after a partial stop order
+type itineraryState struct {
+	Long, Lat float64
+	At        time.Time
+	Occupied  int32
+	Late      time.Duration
+	Distance  float64
+	Visited   []bool
+}
+
+type itineraryPlanner struct {
+	Route    *model.Route
+	Requests []*db.ItineraryRequest
+	Speed    float64
+	Dwell    time.Duration
+
+	best      []int
+	bestLate  time.Duration
+	bestEnd   time.Time
+	bestFound bool
+}
+
+// planItinerary orders the pickups and dropoffs so that every rider is picked up within their window, the
+// car never carries more than the route capacity, and the driver reaches the route end as early as
+// possible. When no order keeps every window, the one with the least total lateness is returned and the
+// late pickups are flagged. Speed is in meters per second, dwell is spent at every rider stop.
+func planItinerary(route *model.Route, requests []*db.ItineraryRequest, speed float64, dwell time.Duration) *itinerary {
+	p := &itineraryPlanner{Route: route, Requests: requests, Speed: speed, Dwell: dwell}
+	start := &itineraryState{Long: route.StartLong, Lat: route.StartLat, At: route.StartTime, Visited: make([]bool, 2*len(requests))}
+	if len(requests) <= maxExactItineraryRiders {
+		p.search(start, make([]int, 0, 2*len(requests)))
+	} else {
+		p.greedy(start)
+	}
+	return p.build()
+}
+
+// stops are numbered 2i for the pickup and 2i+1 for the dropoff of request i
+func (p *itineraryPlanner) location(stop int) (float64, float64) {
+	request := p.Requests[stop/2]
+	if stop%2 == 0 {
+		return request.PickupLong, request.PickupLat
+	}
+	return request.DropoffLong, request.DropoffLat
+}
+
+func (p *itineraryPlanner) canVisit(state *itineraryState, stop int) bool {
+	if state.Visited[stop] {
+		return false
+	}
+	if stop%2 == 1 {
+		return state.Visited[stop-1]
+	}
+	return state.Occupied+p.Requests[stop/2].Seats <= p.Route.Capacity
+}
+
+func (p *itineraryPlanner) travel(fromLong, fromLat, toLong, toLat float64) (float64, time.Duration) {
+	distance := haversineDistance(fromLong, fromLat, toLong, toLat)
+	return distance, time.Duration(distance / p.Speed * float64(time.Second))
+}
+
+// visit drives to the stop, waits for the pickup window to open if early and spends the dwell time there.
+// It returns the arrival time next to the state after leaving the stop.
+func (p *itineraryPlanner) visit(state *itineraryState, stop int) (time.Time, *itineraryState) {
+	request := p.Requests[stop/2]
+	long, lat := p.location(stop)
+	distance, duration := p.travel(state.Long, state.Lat, long, lat)
+	arrival := state.At.Add(duration)
+
+	next := &itineraryState{
+		Long:     long,
+		Lat:      lat,
+		At:       arrival.Add(p.Dwell),
+		Occupied: state.Occupied - request.Seats,
+		Late:     state.Late,
+		Distance: state.Distance + distance,
+		Visited:  make([]bool, len(state.Visited)),
+	}
+	copy(next.Visited, state.Visited)
+	next.Visited[stop] = true
+	if stop%2 == 0 {
+		next.Occupied = state.Occupied + request.Seats
+		if arrival.After(request.PickupEndTime) {
+			next.Late += arrival.Sub(request.PickupEndTime)
+		}
+		if arrival.Before(request.PickupStartTime) {
+			next.At = request.PickupStartTime.Add(p.Dwell)
+		}
+	}
+	return arrival, next
+}
+
+// finish is when the driver reaches the route end from the state, it never decreases along an order so it
+// also bounds every order that continues from the state
+func (p *itineraryPlanner) finish(state *itineraryState) time.Time {
+	_, duration := p.travel(state.Long, state.Lat, p.Route.EndLong, p.Route.EndLat)
+	return state.At.Add(duration)
+}
+
+func (p *itineraryPlanner) better(late time.Duration, end time.Time) bool {
+	return !p.bestFound || late < p.bestLate || (late == p.bestLate && end.Before(p.bestEnd))
+}
+
+// search tries every order depth first and drops a branch once it cannot beat the best order found
+func (p *itineraryPlanner) search(state *itineraryState, order []int) {
+	end := p.finish(state)
+	if !p.better(state.Late, end) {
+		return
+	}
+	if len(order) == len(state.Visited) {
+		p.best = append([]int(nil), order...)
+		p.bestLate, p.bestEnd, p.bestFound = state.Late, end, true
+		return
+	}
+
+	for stop := range state.Visited {
+		if !p.canVisit(state, stop) {
+			continue
+		}
+		_, next := p.visit(state, stop)
+		p.search(next, append(order, stop))
+	}
+}
+
+// greedy always heads for the stop it can reach with the least lateness, then the earliest
+func (p *itineraryPlanner) greedy(state *itineraryState) {
+	var order []int
+	for len(order) < len(state.Visited) {
+		chosen, chosenArrival := -1, time.Time{}
+		var chosenState *itineraryState
+		for stop := range state.Visited {
+			if !p.canVisit(state, stop) {
+				continue
+			}
+			arrival, next := p.visit(state, stop)
+			if chosen < 0 || next.Late < chosenState.Late || (next.Late == chosenState.Late && arrival.Before(chosenArrival)) {
+				chosen, chosenArrival, chosenState = stop, arrival, next
+			}
+		}
+		order = append(order, chosen)
+		state = chosenState
+	}
+	p.best = order
+}
+
+// build replays the chosen order into the stops shown to the driver
+func (p *itineraryPlanner) build() *itinerary {
+	state := &itineraryState{Long: p.Route.StartLong, Lat: p.Route.StartLat, At: p.Route.StartTime, Visited: make([]bool, 2*len(p.Requests))}
+	result := &itinerary{
+		RouteId: p.Route.Id,
+		Stops: []*itineraryStop{{
+			Kind:      constants.ItineraryStopStart,
+			Long:      state.Long,
+			Lat:       state.Lat,
+			Eta:       state.At,
+			Departure: state.At,
+		}},
+		OnTime: true,
+	}
+
+	for _, stop := range p.best {
+		request := p.Requests[stop/2]
+		arrival, next := p.visit(state, stop)
+		entry := &itineraryStop{
+			Kind:      constants.ItineraryStopDropoff,
+			RequestId: &request.Id,
+			RiderId:   &request.RiderId,
+			RiderName: request.RiderName,
+			Long:      next.Long,
+			Lat:       next.Lat,
+			Seats:     request.Seats,
+			Occupied:  next.Occupied,
+			Eta:       arrival,
+			Departure: next.At,
+		}
+		if stop%2 == 0 {
+			entry.Kind = constants.ItineraryStopPickup
+			entry.PickupStartTime = &request.PickupStartTime
+			entry.PickupEndTime = &request.PickupEndTime
+			entry.Late = arrival.After(request.PickupEndTime)
+			result.OnTime = result.OnTime && !entry.Late
+		}
+		result.Stops = append(result.Stops, entry)
+		state = next
+	}
+
+	distance, _ := p.travel(state.Long, state.Lat, p.Route.EndLong, p.Route.EndLat)
+	end := p.finish(state)
+	result.Stops = append(result.Stops, &itineraryStop{
+		Kind:      constants.ItineraryStopEnd,
+		Long:      p.Route.EndLong,
+		Lat:       p.Route.EndLat,
+		Eta:       end,
+		Departure: end,
+	})
+	result.Distance = state.Distance + distance
+	return result
+}
+
+// haversineDistance is the great circle distance in meters between two points
+func haversineDistance(fromLong, fromLat, toLong, toLat float64) float64 {
+	toRadians := math.Pi / 180
+	dLat := (toLat - fromLat) * toRadians
+	dLong := (toLong - fromLong) * toRadians
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(fromLat*toRadians)*math.Cos(toLat*toRadians)*math.Sin(dLong/2)*math.Sin(dLong/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	"github.com/CoRide-tw/backend/internal/db"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// 0.01 degrees of longitude on the equator take about 67 seconds at 60 km/h
+const testItinerarySpeed = 60 * 1000 / 3600.0
+
+func testItineraryRequest(id int32, pickupLong, dropoffLong float64, windowStart, windowEnd time.Time) *db.ItineraryRequest {
+	return &db.ItineraryRequest{
+		Id:              id,
+		RiderId:         -id,
+		PickupLong:      pickupLong,
+		DropoffLong:     dropoffLong,
+		PickupStartTime: windowStart,
+		PickupEndTime:   windowEnd,
+		Seats:           1,
+	}
+}
+
+func stopOrder(plan *itinerary) []string {
+	var order []string
+	for _, stop := range plan.Stops {
+		name := stop.Kind
+		if stop.RequestId != nil {
+			name += string(rune('0' + *stop.RequestId))
+		}
+		order = append(order, name)
+	}
+	return order
+}
+
+var _ = Describe("PlanItinerary", func() {
+	var (
+		start time.Time
+		route *model.Route
+	)
+
+	BeforeEach(func() {
+		start = time.Date(2023, 5, 1, 8, 0, 0, 0, time.UTC)
+		route = &model.Route{Id: 1, StartLong: 0, EndLong: 0.1, StartTime: start, Capacity: 3}
+	})
+
+	It("should pick up and drop off along the way", func() {
+		plan := planItinerary(route, []*db.ItineraryRequest{
+			testItineraryRequest(1, 0.02, 0.06, start, start.Add(time.Hour)),
+			testItineraryRequest(2, 0.04, 0.08, start, start.Add(time.Hour)),
+		}, testItinerarySpeed, 0)
+
+		Expect(stopOrder(plan)).To(Equal([]string{"start", "pickup1", "pickup2", "dropoff1", "dropoff2", "end"}))
+		Expect(plan.OnTime).To(BeTrue())
+		Expect(plan.Stops[2].Occupied).To(Equal(int32(2)))
+		Expect(plan.Distance).To(BeNumerically("~", 11120, 10))
+		Expect(plan.Stops[5].Eta).To(BeTemporally("~", start.Add(667*time.Second), time.Second))
+	})
+
+	It("should respect pickup windows even when it means driving back and waiting", func() {
+		plan := planItinerary(route, []*db.ItineraryRequest{
+			testItineraryRequest(1, 0.02, 0.08, start.Add(30*time.Minute), start.Add(40*time.Minute)),
+			testItineraryRequest(2, 0.04, 0.06, start, start.Add(5*time.Minute)),
+		}, testItinerarySpeed, 0)
+
+		Expect(stopOrder(plan)[1:3]).To(Equal([]string{"pickup2", "pickup1"}))
+		Expect(plan.OnTime).To(BeTrue())
+		Expect(plan.Stops[2].Eta.Before(start.Add(30 * time.Minute))).To(BeTrue())
+		Expect(plan.Stops[2].Departure).To(Equal(start.Add(30 * time.Minute)))
+	})
+
+	It("should never carry more riders than the capacity", func() {
+		route.Capacity = 1
+		plan := planItinerary(route, []*db.ItineraryRequest{
+			testItineraryRequest(1, 0.02, 0.06, start, start.Add(time.Hour)),
+			testItineraryRequest(2, 0.04, 0.08, start, start.Add(time.Hour)),
+		}, testItinerarySpeed, time.Minute)
+
+		Expect(stopOrder(plan)).To(Equal([]string{"start", "pickup1", "dropoff1", "pickup2", "dropoff2", "end"}))
+		for _, stop := range plan.Stops {
+			Expect(stop.Occupied).To(BeNumerically("<=", 1))
+		}
+	})
+
+	It("should flag pickups that cannot be reached in time", func() {
+		plan := planItinerary(route, []*db.ItineraryRequest{
+			testItineraryRequest(1, 0.02, 0.06, start, start.Add(time.Second)),
+		}, testItinerarySpeed, 0)
+
+		Expect(plan.OnTime).To(BeFalse())
+		Expect(plan.Stops[1].Late).To(BeTrue())
+	})
+
+	It("should plan every stop of a large group", func() {
+		route.Capacity = 4
+		var requests []*db.ItineraryRequest
+		for id := int32(1); id <= maxExactItineraryRiders+2; id++ {
+			pickup := float64(id) / 100
+			requests = append(requests, testItineraryRequest(id, pickup, pickup+0.005, start, start.Add(time.Hour)))
+		}
+		plan := planItinerary(route, requests, testItinerarySpeed, 0)
+
+		Expect(plan.Stops).To(HaveLen(2*len(requests) + 2))
+		Expect(plan.OnTime).To(BeTrue())
+		pickedUp := make(map[int32]bool)
+		for _, stop := range plan.Stops {
+			Expect(stop.Occupied).To(BeNumerically("<=", 4))
+			switch stop.Kind {
+			case constants.ItineraryStopPickup:
+				pickedUp[*stop.RequestId] = true
+			case constants.ItineraryStopDropoff:
+				Expect(pickedUp[*stop.RequestId]).To(BeTrue())
+			}
+		}
+	})
+})
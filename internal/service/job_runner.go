@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/config"
+	"github.com/CoRide-tw/backend/internal/db"
+	"go.uber.org/zap"
+)
+
+// jobLeaderLockKey is the advisory lock that elects the single instance running background jobs
+const jobLeaderLockKey int64 = 0x436f52696465
+
+// job is a periodic background task, Run returns how many rows it touched. Interval is how often it
+// runs, zero runs it every job interval.
+type job struct {
+	Name     string
+	Interval time.Duration
+	Run      func() (int64, error)
+}
+
+// JobRunner ticks at the shortest job interval and runs the jobs that are due, several runners may be
+// started across servers and workers but only the one holding the leader lock does any work
+type JobRunner struct {
+	Logger   *zap.SugaredLogger
+	Interval time.Duration
+	Jobs     []job
+	lastRun  map[string]time.Time
+}
+
+func NewJobRunner(logger *zap.SugaredLogger) *JobRunner {
+	notify := &notifier{Logger: logger, Mailer: newMailer(logger)}
+	var jobs []job
+	for _, group := range []func(*notifier) []job{housekeepingJobs, reminderJobs, instantJobs, assignmentJobs, demandJobs} {
+		jobs = append(jobs, group(notify)...)
+	}
+
+	interval := config.Env.JobInterval
+	for i := range jobs {
+		if jobs[i].Interval <= 0 {
+			jobs[i].Interval = config.Env.JobInterval
+		}
+		if jobs[i].Interval < interval {
+			interval = jobs[i].Interval
+		}
+	}
+	return &JobRunner{
+		Logger:   logger,
+		Interval: interval,
+		Jobs:     jobs,
+		lastRun:  map[string]time.Time{},
+	}
+}
+
+// Run blocks until the context is done, leadership is given up on return
+func (r *JobRunner) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	var lock *db.AdvisoryLock
+	defer func() {
+		if lock != nil {
+			lock.Release()
+		}
+	}()
+
+	now := time.Now()
+	for {
+		lock = r.elect(lock)
+		if lock != nil {
+			r.runJobs(now)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case now = <-ticker.C:
+		}
+	}
+}
+
+// elect keeps the current leadership while its connection is alive, otherwise it tries to take over
+func (r *JobRunner) elect(lock *db.AdvisoryLock) *db.AdvisoryLock {
+	if lock != nil {
+		if lock.Alive() {
+			return lock
+		}
+		r.Logger.Warn("lost background job leadership")
+		lock.Release()
+	}
+
+	lock, err := db.TryAdvisoryLock(jobLeaderLockKey)
+	if err != nil {
+		r.Logger.Errorw("failed to elect background job leader", "error", err)
+		return nil
+	}
+	if lock != nil {
+		r.Logger.Info("acquired background job leadership")
+	}
+	return lock
+}
+
+// runJobs runs every job that is due, a failing job does not keep the others from running
+func (r *JobRunner) runJobs(now time.Time) {
+	for _, job := range r.Jobs {
+		if !r.isDue(job, now) {
+			continue
+		}
+		r.lastRun[job.Name] = now
+		affected, err := job.Run()
+		if err != nil {
+			r.Logger.Errorw("background job failed", "job", job.Name, "error", err)
+			continue
+		}
+		if affected > 0 {
+			r.Logger.Infow("background job finished", "job", job.Name, "affected", affected)
+		}
+	}
+}
+
+// isDue tells whether the job's interval passed since it last ran, half a tick of slack keeps a job from
+// slipping a whole tick when the ticker fires a little early
+func (r *JobRunner) isDue(job job, now time.Time) bool {
+	last, ok := r.lastRun[job.Name]
+	return !ok || now.Sub(last) >= job.Interval-r.Interval/2
+}
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("JobRunnerIsDue", func() {
+	It("should run each job at its own interval", func() {
+		runner := &JobRunner{Interval: 30 * time.Second, lastRun: map[string]time.Time{}}
+		dispatch := job{Name: "dispatch", Interval: 30 * time.Second}
+		housekeeping := job{Name: "housekeeping", Interval: time.Minute}
+
+		start := time.Now()
+		Expect(runner.isDue(dispatch, start)).To(BeTrue())
+		Expect(runner.isDue(housekeeping, start)).To(BeTrue())
+		runner.lastRun[dispatch.Name], runner.lastRun[housekeeping.Name] = start, start
+
+		tick := start.Add(30*time.Second - time.Millisecond)
+		Expect(runner.isDue(dispatch, tick)).To(BeTrue())
+		Expect(runner.isDue(housekeeping, tick)).To(BeFalse())
+		Expect(runner.isDue(housekeeping, start.Add(time.Minute-time.Millisecond))).To(BeTrue())
+	})
+})
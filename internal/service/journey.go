@@ -0,0 +1,290 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/CoRide-tw/backend/internal/config"
+	"github.com/CoRide-tw/backend/internal/constants"
+	"github.com/CoRide-tw/backend/internal/db"
+	"github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/errors/generated/svcerr"
+	"github.com/CoRide-tw/backend/internal/model"
+	"github.com/CoRide-tw/backend/internal/util"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ListMultiLegRoutes takes the same query as the ranking and suggests pairs of routes for trips no single
+// driver covers, the rider changes cars at a transfer point in between
+func (s *routeSvc) ListMultiLegRoutes(c *gin.Context) {
+	parsedQuery, err := util.ParseListNearestRoutesQuery(c)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	riderId, _ := c.Get("userId")
+	preferences, err := db.GetRidePreferences(riderId.(int32))
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	requirements, ok := searchRequirements(c, preferences, parsedQuery)
+	if !ok {
+		return
+	}
+
+	options, err := db.ListMultiLegRoutes(parsedQuery.StartLong, parsedQuery.StartLat, parsedQuery.EndLong, parsedQuery.EndLat, parsedQuery.PickupStartTime, parsedQuery.PickupEndTime, parsedQuery.VerifiedOnly, riderId.(int32), preferences.Gender, requirements, parsedQuery.Seats, &db.TransferParams{
+		WalkingDistance: float64(config.Env.MeetingPointWalkingDistance),
+		AccessDistance:  float64(config.Env.TransferAccessDistance),
+		Buffer:          config.Env.TransferBuffer,
+		MaxWait:         config.Env.TransferMaxWait,
+	})
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, options)
+}
+
+// CreateJourney books every leg of a multi-leg journey at once, each leg is sent to its driver as an
+// ordinary request. The legs share the seat count and requirements of the first one.
+func (s *requestSvc) CreateJourney(c *gin.Context) {
+	var journey model.Journey
+	if err := c.ShouldBindJSON(&journey); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !isValidJourney(&journey) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": svcerr.ErrJourneyInvalid.Error()})
+		return
+	}
+
+	riderId, _ := c.Get("userId")
+	journey.RiderId = riderId.(int32)
+	if journey.Legs[0].SeatCount == 0 {
+		journey.Legs[0].SeatCount = 1
+	}
+	routes := make([]*model.Route, len(journey.Legs))
+	for i, leg := range journey.Legs {
+		leg.RiderId = journey.RiderId
+		leg.Status = constants.RequestStatusPending
+		leg.SeatCount = journey.Legs[0].SeatCount
+		leg.RideRequirements = journey.Legs[0].RideRequirements
+		leg.MeetingPointId = nil
+
+		route, ok := s.checkJourneyLeg(c, leg)
+		if !ok {
+			return
+		}
+		routes[i] = route
+	}
+
+	created, err := db.CreateJourney(&journey)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionJourneyCreate,
+		EntityType: constants.AuditEntityJourney,
+		EntityId:   created.Id,
+		After:      created,
+	})
+	for i, leg := range created.Legs {
+		notifyRequestReceived(s.Notifier, routes[i].DriverId, leg)
+	}
+
+	c.JSON(http.StatusOK, created)
+}
+
+// checkJourneyLeg applies the rules of a single request to the leg, except that a full route is refused
+// instead of waitlisted. On failure the error response is already written.
+func (s *requestSvc) checkJourneyLeg(c *gin.Context, leg *model.Request) (*model.Route, bool) {
+	route, err := db.GetRoute(leg.RouteId)
+	if err != nil {
+		if errors.Is(err, dberr.ErrRouteNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if route.DriverId == leg.RiderId {
+		c.JSON(http.StatusBadRequest, gin.H{"error": svcerr.ErrJourneyInvalid.Error()})
+		return nil, false
+	}
+	blocked, err := db.IsBlocked(leg.RiderId, route.DriverId)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if blocked {
+		c.JSON(http.StatusForbidden, gin.H{"error": svcerr.ErrUserBlocked.Error()})
+		return nil, false
+	}
+	if !s.checkRequirements(c, leg, route) {
+		return nil, false
+	}
+	if leg.SeatCount < 0 || leg.SeatCount > route.Capacity {
+		c.JSON(http.StatusBadRequest, gin.H{"error": svcerr.ErrSeatCountInvalid.Error()})
+		return nil, false
+	}
+	freeSeats, err := db.GetRouteFreeSeats(route.Id)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if leg.SeatCount > freeSeats {
+		c.JSON(http.StatusConflict, gin.H{"error": dberr.ErrRouteSeatsUnavailable.Error()})
+		return nil, false
+	}
+	return route, true
+}
+
+func (s *requestSvc) GetJourney(c *gin.Context) {
+	journey, ok := s.getJourneyForRider(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, journey)
+}
+
+// CancelJourney lets the rider give up the whole journey, every leg is cancelled together
+func (s *requestSvc) CancelJourney(c *gin.Context) {
+	journey, ok := s.getJourneyForRider(c)
+	if !ok {
+		return
+	}
+
+	legs, err := db.CancelJourney(journey.Id, 0)
+	if err != nil {
+		if errors.Is(err, dberr.ErrJourneyNotActive) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionJourneyCancel,
+		EntityType: constants.AuditEntityJourney,
+		EntityId:   journey.Id,
+		Before:     journey,
+		After:      gin.H{"status": constants.JourneyStatusCancelled},
+	})
+	afterJourneyCancelled(s.Logger, s.Notifier, journey.Id, journey.RiderId, legs, false)
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// cancelJourneyOf cancels the rest of the journey once one of its legs fell through, a single leg is of
+// no use to the rider. Plain requests are left alone. Background jobs pass no context and leave no audit
+// entry.
+func cancelJourneyOf(logger *zap.SugaredLogger, n *notifier, c *gin.Context, requestId, riderId int32, reason string) {
+	journeyId, err := db.GetRequestJourneyId(requestId)
+	if err != nil {
+		logger.Errorw("failed to look up journey of request", "requestId", requestId, "error", err)
+		return
+	}
+	if journeyId == nil {
+		return
+	}
+
+	legs, err := db.CancelJourney(*journeyId, requestId)
+	if err != nil {
+		if !errors.Is(err, dberr.ErrJourneyNotActive) {
+			logger.Errorw("failed to cancel journey", "journeyId", *journeyId, "error", err)
+		}
+		return
+	}
+	if c != nil {
+		recordAudit(logger, c, auditEntry{
+			Action:     constants.AuditActionJourneyCancel,
+			EntityType: constants.AuditEntityJourney,
+			EntityId:   *journeyId,
+			After:      gin.H{"status": constants.JourneyStatusCancelled},
+			Detail:     gin.H{"requestId": requestId, "reason": reason},
+		})
+	}
+	afterJourneyCancelled(logger, n, *journeyId, riderId, legs, true)
+}
+
+// afterJourneyCancelled hands the freed seats to the waitlists and tells the drivers of the cancelled legs,
+// and the rider when they did not cancel themselves
+func afterJourneyCancelled(logger *zap.SugaredLogger, n *notifier, journeyId, riderId int32, legs []*db.CancelledJourneyLeg, notifyRider bool) {
+	if notifyRider {
+		n.Notify(riderId, constants.NotificationKindJourneyCancelled,
+			"Your journey was cancelled",
+			"One leg of your journey could not be booked, so the other legs were cancelled as well.",
+			gin.H{"journeyId": journeyId},
+		)
+	}
+	for _, leg := range legs {
+		n.Notify(leg.DriverId, constants.NotificationKindJourneyCancelled,
+			"A ride request was cancelled",
+			"The rider's journey fell through, so their request on your route was cancelled.",
+			gin.H{"journeyId": journeyId, "requestId": leg.RequestId, "routeId": leg.RouteId},
+		)
+		if holdsSeats(leg.Status) {
+			promoteWaitlist(logger, n, leg.RouteId)
+		}
+	}
+}
+
+// getJourneyForRider loads the :id journey and only lets its rider through, otherwise the error response
+// is already written
+func (s *requestSvc) getJourneyForRider(c *gin.Context) (*model.Journey, bool) {
+	journeyId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id must be integer"})
+		return nil, false
+	}
+
+	journey, err := db.GetJourney(int32(journeyId))
+	if err != nil {
+		if errors.Is(err, dberr.ErrJourneyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if authUid, _ := c.Get("userId"); authUid != journey.RiderId {
+		c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+		return nil, false
+	}
+	return journey, true
+}
+
+// isValidJourney checks that the journey has two legs on different routes, each with a sane pickup window,
+// and that the second leg does not start before the first one
+func isValidJourney(journey *model.Journey) bool {
+	if len(journey.Legs) != 2 {
+		return false
+	}
+	first, second := journey.Legs[0], journey.Legs[1]
+	if first == nil || second == nil || first.RouteId == second.RouteId {
+		return false
+	}
+	for _, leg := range journey.Legs {
+		if !isValidCoordinate(leg.PickupLong, leg.PickupLat) || !isValidCoordinate(leg.DropoffLong, leg.DropoffLat) ||
+			leg.PickupEndTime.Before(leg.PickupStartTime) || leg.Tips < 0 {
+			return false
+		}
+	}
+	return !second.PickupStartTime.Before(first.PickupStartTime)
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("IsValidJourney", func() {
+	var journey *model.Journey
+
+	BeforeEach(func() {
+		start := time.Now()
+		journey = &model.Journey{Legs: []*model.Request{
+			{RouteId: 1, PickupStartTime: start, PickupEndTime: start.Add(30 * time.Minute)},
+			{RouteId: 2, PickupStartTime: start.Add(time.Hour), PickupEndTime: start.Add(time.Hour)},
+		}}
+	})
+
+	It("should accept two connecting legs", func() {
+		Expect(isValidJourney(journey)).To(BeTrue())
+	})
+
+	It("should need exactly two legs on different routes", func() {
+		journey.Legs[1].RouteId = 1
+		Expect(isValidJourney(journey)).To(BeFalse())
+
+		journey.Legs = journey.Legs[:1]
+		Expect(isValidJourney(journey)).To(BeFalse())
+	})
+
+	It("should reject a second leg starting before the first", func() {
+		journey.Legs[1].PickupStartTime = journey.Legs[0].PickupStartTime.Add(-time.Minute)
+		Expect(isValidJourney(journey)).To(BeFalse())
+	})
+
+	It("should reject invalid coordinates", func() {
+		journey.Legs[0].PickupLat = 91
+		Expect(isValidJourney(journey)).To(BeFalse())
+	})
+})
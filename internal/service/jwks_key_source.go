@@ -0,0 +1,152 @@
+package service
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jwksMinRefetchInterval keeps tokens with made up key ids from refetching the provider's keys on every request
+const jwksMinRefetchInterval = time.Minute
+
+// jwksMissingKidTtl is how long a key id the provider did not publish is rejected without refetching
+const jwksMissingKidTtl = 5 * time.Minute
+
+// jwksMaxMissingKids bounds the missing key ids remembered, the list starts over once it is full
+const jwksMaxMissingKids = 1024
+
+// publicKeySource resolves the public key an identity provider used to sign an ID token
+type publicKeySource interface {
+	PublicKey(kid string) (*rsa.PublicKey, error)
+}
+
+// jwksKeySource fetches a provider's JWKS and caches it for as long as Cache-Control allows
+type jwksKeySource struct {
+	CertsUrl string
+	Client   *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	expiresAt time.Time
+	fetchedAt time.Time
+	missing   map[string]time.Time
+}
+
+func newJwksKeySource(certsUrl string) *jwksKeySource {
+	return &jwksKeySource{
+		CertsUrl: certsUrl,
+		Client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (k *jwksKeySource) PublicKey(kid string) (*rsa.PublicKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := time.Now()
+	key, ok := k.keys[kid]
+	if ok && now.Before(k.expiresAt) {
+		return key, nil
+	}
+	if missingUntil, missing := k.missing[kid]; missing && now.Before(missingUntil) {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	// refetch when the cache expired or an unknown kid shows up right after the provider rotated its keys,
+	// at most once per interval so that made up kids cannot keep every request waiting on the provider
+	refetched := false
+	if now.Sub(k.fetchedAt) >= jwksMinRefetchInterval {
+		k.fetchedAt = now
+		if err := k.refresh(); err != nil {
+			return nil, err
+		}
+		refetched = true
+		key, ok = k.keys[kid]
+	}
+	if ok {
+		return key, nil
+	}
+
+	// a kid only counts as missing once the provider's current keys were checked for it, otherwise a key
+	// rotated in while refetching was throttled would stay rejected for the whole ttl
+	if !refetched {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if k.missing == nil || len(k.missing) >= jwksMaxMissingKids {
+		k.missing = map[string]time.Time{}
+	}
+	k.missing[kid] = now.Add(jwksMissingKidTtl)
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+type jwks struct {
+	Keys []struct {
+		Kid string `json:"kid"`
+		Kty string `json:"kty"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+func (k *jwksKeySource) refresh() error {
+	resp, err := k.Client.Get(k.CertsUrl)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch %s: unexpected status %d", k.CertsUrl, resp.StatusCode)
+	}
+
+	var keySet jwks
+	if err := json.NewDecoder(resp.Body).Decode(&keySet); err != nil {
+		return err
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(keySet.Keys))
+	for _, jwk := range keySet.Keys {
+		if jwk.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return err
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	k.keys = keys
+	for kid := range keys {
+		delete(k.missing, kid)
+	}
+	k.expiresAt = time.Now().Add(cacheMaxAge(resp.Header.Get("Cache-Control"), time.Hour))
+	return nil
+}
+
+func cacheMaxAge(cacheControl string, fallback time.Duration) time.Duration {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		directive = strings.TrimSpace(directive)
+		if !strings.HasPrefix(directive, "max-age=") {
+			continue
+		}
+		if seconds, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age=")); err == nil {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("JwksKeySource", func() {
+	var (
+		privateKey *rsa.PrivateKey
+		server     *httptest.Server
+		fetches    int32
+		kid        atomic.Value
+		source     *jwksKeySource
+	)
+
+	BeforeEach(func() {
+		var err error
+		privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).NotTo(HaveOccurred())
+
+		atomic.StoreInt32(&fetches, 0)
+		kid.Store("google-key")
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&fetches, 1)
+			w.Header().Set("Cache-Control", "public, max-age=3600")
+			json.NewEncoder(w).Encode(gin.H{"keys": []gin.H{{
+				"kid": kid.Load().(string),
+				"kty": "RSA",
+				"n":   base64.RawURLEncoding.EncodeToString(privateKey.PublicKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(privateKey.PublicKey.E)).Bytes()),
+			}}})
+		}))
+		source = newJwksKeySource(server.URL)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should serve known keys from the cache", func() {
+		for i := 0; i < 3; i++ {
+			key, err := source.PublicKey("google-key")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(key.N).To(Equal(privateKey.PublicKey.N))
+		}
+		Expect(atomic.LoadInt32(&fetches)).To(Equal(int32(1)))
+	})
+
+	It("should not refetch for every unknown key id", func() {
+		_, err := source.PublicKey("google-key")
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, kid := range []string{"made-up", "made-up", "another-made-up"} {
+			_, err := source.PublicKey(kid)
+			Expect(err).To(HaveOccurred())
+		}
+		Expect(atomic.LoadInt32(&fetches)).To(Equal(int32(1)))
+	})
+
+	It("should pick up a key rotated in while refetching was throttled", func() {
+		_, err := source.PublicKey("google-key")
+		Expect(err).NotTo(HaveOccurred())
+
+		kid.Store("rotated-key")
+		_, err = source.PublicKey("rotated-key")
+		Expect(err).To(HaveOccurred())
+
+		source.fetchedAt = time.Now().Add(-jwksMinRefetchInterval)
+		key, err := source.PublicKey("rotated-key")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key.N).To(Equal(privateKey.PublicKey.N))
+		Expect(atomic.LoadInt32(&fetches)).To(Equal(int32(2)))
+	})
+})
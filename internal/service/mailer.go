@@ -0,0 +1,66 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+
+	"github.com/CoRide-tw/backend/internal/config"
+	"go.uber.org/zap"
+)
+
+type mailer interface {
+	Send(to, subject, body string) error
+}
+
+// newMailer sends through SMTP when configured, otherwise mails are only logged for local development
+func newMailer(logger *zap.SugaredLogger) mailer {
+	if config.Env.SmtpAddr == "" {
+		return &logMailer{Logger: logger}
+	}
+	return &smtpMailer{
+		Addr:     config.Env.SmtpAddr,
+		Username: config.Env.SmtpUsername,
+		Password: config.Env.SmtpPassword,
+		From:     config.Env.SmtpFrom,
+	}
+}
+
+type smtpMailer struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m *smtpMailer) Send(to, subject, body string) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, err := net.SplitHostPort(m.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+
+	msg := strings.Join([]string{
+		fmt.Sprintf("From: %s", m.From),
+		fmt.Sprintf("To: %s", to),
+		fmt.Sprintf("Subject: %s", subject),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		body,
+	}, "\r\n")
+	return smtp.SendMail(m.Addr, auth, m.From, []string{to}, []byte(msg))
+}
+
+type logMailer struct {
+	Logger *zap.SugaredLogger
+}
+
+func (m *logMailer) Send(to, subject, body string) error {
+	m.Logger.Infow("mail not sent, SMTP_ADDR is unset", "to", to, "subject", subject, "body", body)
+	return nil
+}
@@ -1,21 +1,72 @@
 package service
 
-import "go.uber.org/zap"
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
 
 type Service struct {
-	User      *userSvc
-	Route     *routeSvc
-	Request   *requestSvc
-	Trip      *tripSvc
-	GoogleApi *googleApiSvc
-	Logger    *zap.SugaredLogger
+	Auth       *authSvc
+	User       *userSvc
+	Route      *routeSvc
+	Request    *requestSvc
+	Trip       *tripSvc
+	Admin      *adminSvc
+	Report     *reportSvc
+	Instant    *instantSvc
+	Assignment *assignmentSvc
+	Insight    *insightSvc
+	GoogleApi  *googleApiSvc
+	Logger     *zap.SugaredLogger
 }
 
 func NewService(logger *zap.SugaredLogger) *Service {
+	mail := newMailer(logger)
+	emailLogin := &emailLoginProvider{Mailer: mail}
+	notify := &notifier{Logger: logger, Mailer: mail}
+	blobs, err := newBlobStore()
+	if err != nil {
+		logger.Fatal(err)
+	}
+
 	return &Service{
-		User:    &userSvc{Logger: logger},
-		Route:   &routeSvc{Logger: logger},
-		Request: &requestSvc{Logger: logger},
-		Trip:    &tripSvc{Logger: logger},
+		Auth: &authSvc{Logger: logger},
+		User: &userSvc{
+			Logger:     logger,
+			GoogleKeys: newJwksKeySource(googleCertsUrl),
+			Providers:  newIdentityProviders(logger, emailLogin),
+			EmailLogin: emailLogin,
+			Blobs:      blobs,
+			Notifier:   notify,
+		},
+		Route:      &routeSvc{Logger: logger},
+		Request:    &requestSvc{Logger: logger, Notifier: notify},
+		Trip:       &tripSvc{Logger: logger, Notifier: notify},
+		Admin:      &adminSvc{Logger: logger, Blobs: blobs, Notifier: notify},
+		Report:     &reportSvc{Logger: logger},
+		Instant:    &instantSvc{Logger: logger, Notifier: notify},
+		Assignment: &assignmentSvc{Logger: logger, Notifier: notify},
+		Insight:    &insightSvc{Logger: logger},
+	}
+}
+
+// selfUserId parses the :id path param and only lets it through when it is the authenticated user,
+// otherwise the error response is already written
+func selfUserId(logger *zap.SugaredLogger, c *gin.Context) (int32, bool) {
+	userId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id must be integer"})
+		return 0, false
+	}
+
+	authUid, authUidExist := c.Get("userId")
+	if !authUidExist || authUid != int32(userId) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+		return 0, false
 	}
+	return int32(userId), true
 }
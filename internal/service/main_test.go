@@ -0,0 +1,18 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/CoRide-tw/backend/internal/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = BeforeSuite(func() {
+	config.Env = config.LoadEnv()
+})
+
+func TestService(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Service Suite")
+}
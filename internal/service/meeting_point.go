@@ -0,0 +1,159 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/CoRide-tw/backend/internal/config"
+	"github.com/CoRide-tw/backend/internal/constants"
+	"github.com/CoRide-tw/backend/internal/db"
+	"github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/errors/generated/svcerr"
+	"github.com/CoRide-tw/backend/internal/model"
+	"github.com/CoRide-tw/backend/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+const maxMeetingPointSuggestions = 5
+
+// SuggestMeetingPoints lists places near the route within walking distance of the rider's pickup
+func (s *routeSvc) SuggestMeetingPoints(c *gin.Context) {
+	routeId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id must be integer"})
+		return
+	}
+	parsedQuery, err := util.ParseMeetingPointQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	route, err := db.GetRoute(int32(routeId))
+	if err != nil {
+		if errors.Is(err, dberr.ErrRouteNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	walkingDistance := float64(config.Env.MeetingPointWalkingDistance)
+	pickup, err := db.SuggestMeetingPoints(route.Id, parsedQuery.PickupLong, parsedQuery.PickupLat, walkingDistance, maxMeetingPointSuggestions)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"pickup": pickup})
+}
+
+// applyMeetingPoint moves the pickup to the meeting point the rider chose, which must be within walking
+// distance of both where they asked to be picked up and the route. On failure the error response is
+// already written.
+func (s *requestSvc) applyMeetingPoint(c *gin.Context, request *model.Request) bool {
+	if request.MeetingPointId == nil {
+		return true
+	}
+
+	meetingPoint, distance, routeDistance, err := db.GetMeetingPointDistance(*request.MeetingPointId, request.RouteId, request.PickupLong, request.PickupLat)
+	if err != nil {
+		if errors.Is(err, dberr.ErrMeetingPointNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return false
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if distance > float64(config.Env.MeetingPointWalkingDistance) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": svcerr.ErrMeetingPointTooFar.Error()})
+		return false
+	}
+	if routeDistance > float64(config.Env.MeetingPointWalkingDistance) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": svcerr.ErrMeetingPointOffRoute.Error()})
+		return false
+	}
+
+	request.PickupLong, request.PickupLat = meetingPoint.Long, meetingPoint.Lat
+	return true
+}
+
+func (s *adminSvc) ListMeetingPoints(c *gin.Context) {
+	parsedQuery, err := util.ParseAdminListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	meetingPoints, err := db.ListMeetingPoints(parsedQuery.Limit, parsedQuery.Offset)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, meetingPoints)
+}
+
+func (s *adminSvc) CreateMeetingPoint(c *gin.Context) {
+	var meetingPoint model.MeetingPoint
+	if err := c.ShouldBindJSON(&meetingPoint); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	meetingPoint.Name = strings.TrimSpace(meetingPoint.Name)
+	if !isValidMeetingPoint(&meetingPoint) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": svcerr.ErrMeetingPointInvalid.Error()})
+		return
+	}
+
+	created, err := db.CreateMeetingPoint(&meetingPoint)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionAdminCreateMeetingPoint,
+		EntityType: constants.AuditEntityMeetingPoint,
+		EntityId:   created.Id,
+		After:      created,
+	})
+
+	c.JSON(http.StatusOK, created)
+}
+
+func (s *adminSvc) DeleteMeetingPoint(c *gin.Context) {
+	meetingPointId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id must be integer"})
+		return
+	}
+
+	if err := db.DeleteMeetingPoint(int32(meetingPointId)); err != nil {
+		if errors.Is(err, dberr.ErrMeetingPointNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionAdminDeleteMeetingPoint,
+		EntityType: constants.AuditEntityMeetingPoint,
+		EntityId:   int32(meetingPointId),
+	})
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+func isValidMeetingPoint(meetingPoint *model.MeetingPoint) bool {
+	return meetingPoint.Name != "" && len(meetingPoint.Name) <= 200 && isValidCoordinate(meetingPoint.Long, meetingPoint.Lat)
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/CoRide-tw/backend/internal/db"
+	"github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+// ListNotifications lists the newest notifications first, ?unread=true leaves out the ones already read
+func (s *userSvc) ListNotifications(c *gin.Context) {
+	userId, ok := selfUserId(s.Logger, c)
+	if !ok {
+		return
+	}
+	parsedQuery, err := util.ParseAdminListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	unreadOnly, _ := strconv.ParseBool(c.Query("unread"))
+
+	notifications, err := db.ListNotificationsByUserId(userId, unreadOnly, parsedQuery.Limit, parsedQuery.Offset)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, notifications)
+}
+
+func (s *userSvc) ReadNotification(c *gin.Context) {
+	userId, ok := selfUserId(s.Logger, c)
+	if !ok {
+		return
+	}
+	notificationId, err := strconv.Atoi(c.Param("notificationId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "notificationId must be integer"})
+		return
+	}
+
+	if err := db.MarkNotificationRead(userId, int32(notificationId)); err != nil {
+		if errors.Is(err, dberr.ErrNotificationNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
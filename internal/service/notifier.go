@@ -0,0 +1,48 @@
+package service
+
+import (
+	"encoding/json"
+
+	"github.com/CoRide-tw/backend/internal/db"
+	"github.com/CoRide-tw/backend/internal/model"
+	"go.uber.org/zap"
+)
+
+// notifier keeps an in-app notification for the user and mails a copy when they have an email address.
+// The change being notified about has already happened, so failures are logged rather than returned.
+type notifier struct {
+	Logger *zap.SugaredLogger
+	Mailer mailer
+}
+
+func (n *notifier) Notify(userId int32, kind, title, body string, data interface{}) {
+	notification := &model.Notification{
+		UserId: userId,
+		Kind:   kind,
+		Title:  title,
+		Body:   body,
+	}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			n.Logger.Errorw("failed to encode notification", "kind", kind, "error", err)
+			return
+		}
+		notification.Data = raw
+	}
+	if _, err := db.CreateNotification(notification); err != nil {
+		n.Logger.Errorw("failed to store notification", "kind", kind, "userId", userId, "error", err)
+		return
+	}
+
+	user, err := db.GetUser(userId)
+	if err != nil || user.Email == "" {
+		return
+	}
+	// a slow mail server must not hold up the request that caused the notification
+	go func() {
+		if err := n.Mailer.Send(user.Email, title, body); err != nil {
+			n.Logger.Errorw("failed to mail notification", "kind", kind, "userId", userId, "error", err)
+		}
+	}()
+}
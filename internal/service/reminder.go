@@ -0,0 +1,105 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/config"
+	"github.com/CoRide-tw/backend/internal/constants"
+	"github.com/CoRide-tw/backend/internal/db"
+	"github.com/gin-gonic/gin"
+)
+
+func reminderJobs(n *notifier) []job {
+	return []job{
+		{Name: "send_pickup_reminders", Run: func() (int64, error) { return sendPickupReminders(n) }},
+		{Name: "send_pending_request_digests", Run: func() (int64, error) { return sendPendingRequestDigests(n) }},
+	}
+}
+
+// sendPickupReminders reminds rider and driver of each upcoming pickup once per configured offset
+func sendPickupReminders(n *notifier) (int64, error) {
+	offsets := config.Env.PickupReminderOffsets
+	if len(offsets) == 0 {
+		return 0, nil
+	}
+	var furthest time.Duration
+	for _, offset := range offsets {
+		if offset > furthest {
+			furthest = offset
+		}
+	}
+
+	pickups, err := db.ListUpcomingPickups(time.Now().Add(furthest))
+	if err != nil {
+		return 0, err
+	}
+
+	var sent int64
+	for _, pickup := range pickups {
+		until := time.Until(pickup.PickupStartTime)
+		offset, due := dueReminderOffset(offsets, until)
+		if !due {
+			continue
+		}
+		claimed, err := db.ClaimReminder(constants.NotificationKindPickupReminder, pickup.TripId, offset.String())
+		if err != nil {
+			return sent, err
+		}
+		if !claimed {
+			continue
+		}
+
+		body := fmt.Sprintf("The pickup starts in about %s.", until.Round(time.Minute))
+		data := gin.H{"tripId": pickup.TripId, "routeId": pickup.RouteId, "requestId": pickup.RequestId, "pickupStartTime": pickup.PickupStartTime}
+		n.Notify(pickup.RiderId, constants.NotificationKindPickupReminder, "Upcoming pickup", body, data)
+		n.Notify(pickup.DriverId, constants.NotificationKindPickupReminder, "Upcoming pickup", body, data)
+		sent++
+	}
+	return sent, nil
+}
+
+// dueReminderOffset picks the smallest offset the pickup is already within, so that a trip booked shortly
+// before its pickup gets a single reminder instead of one for every offset it skipped
+func dueReminderOffset(offsets []time.Duration, until time.Duration) (time.Duration, bool) {
+	var due time.Duration
+	found := false
+	for _, offset := range offsets {
+		if until <= offset && (!found || offset < due) {
+			due, found = offset, true
+		}
+	}
+	return due, found
+}
+
+// sendPendingRequestDigests tells each driver once per route how many requests still wait for an answer
+// as the route start draws near
+func sendPendingRequestDigests(n *notifier) (int64, error) {
+	if config.Env.PendingRequestDigestLead <= 0 {
+		return 0, nil
+	}
+
+	digests, err := db.ListPendingRequestDigests(time.Now().Add(config.Env.PendingRequestDigestLead))
+	if err != nil {
+		return 0, err
+	}
+
+	var sent int64
+	for _, digest := range digests {
+		claimed, err := db.ClaimReminder(constants.NotificationKindPendingRequests, digest.RouteId, "before_start")
+		if err != nil {
+			return sent, err
+		}
+		if !claimed {
+			continue
+		}
+
+		n.Notify(digest.DriverId, constants.NotificationKindPendingRequests,
+			"Ride requests need your answer",
+			fmt.Sprintf("%d ride requests are still waiting for your answer before your route starts.", digest.PendingCount),
+			gin.H{"routeId": digest.RouteId, "startTime": digest.StartTime, "pendingCount": digest.PendingCount},
+		)
+		sent++
+	}
+	return sent, nil
+}
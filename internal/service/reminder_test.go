@@ -0,0 +1,29 @@
+package service
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DueReminderOffset", func() {
+	offsets := []time.Duration{time.Hour, 15 * time.Minute}
+
+	It("should not be due before the furthest offset", func() {
+		_, due := dueReminderOffset(offsets, 2*time.Hour)
+		Expect(due).To(BeFalse())
+	})
+
+	It("should pick the offset the pickup just entered", func() {
+		offset, due := dueReminderOffset(offsets, 40*time.Minute)
+		Expect(due).To(BeTrue())
+		Expect(offset).To(Equal(time.Hour))
+	})
+
+	It("should pick the smallest offset when several are due", func() {
+		offset, due := dueReminderOffset(offsets, 10*time.Minute)
+		Expect(due).To(BeTrue())
+		Expect(offset).To(Equal(15 * time.Minute))
+	})
+})
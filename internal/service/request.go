@@ -1,19 +1,24 @@
 package service
 
 import (
+	"errors"
 	"go.uber.org/zap"
+	"io"
 	"net/http"
 	"strconv"
 
 	"github.com/CoRide-tw/backend/internal/constants"
 	"github.com/CoRide-tw/backend/internal/db"
+	"github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/errors/generated/svcerr"
 	"github.com/CoRide-tw/backend/internal/model"
 	"github.com/CoRide-tw/backend/internal/util"
 	"github.com/gin-gonic/gin"
 )
 
 type requestSvc struct {
-	Logger *zap.SugaredLogger
+	Logger   *zap.SugaredLogger
+	Notifier *notifier
 }
 
 func (s *requestSvc) List(c *gin.Context) {
@@ -36,7 +41,8 @@ func (s *requestSvc) List(c *gin.Context) {
 	}
 
 	if parsedQuery.RouteId != 0 {
-		requests, err := db.ListRequestsByRouteId(parsedQuery.RouteId)
+		viewerId, _ := c.Get("userId")
+		requests, err := db.ListRequestsByRouteId(parsedQuery.RouteId, viewerId.(int32))
 		if err != nil {
 			s.Logger.Error(err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -64,8 +70,14 @@ func (s *requestSvc) Get(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	proposals, err := db.ListRequestProposals(request.Id)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
-	c.JSON(http.StatusOK, request)
+	c.JSON(http.StatusOK, requestDetail{Request: request, Proposals: proposals})
 }
 
 func (s *requestSvc) Create(c *gin.Context) {
@@ -75,6 +87,59 @@ func (s *requestSvc) Create(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	// the rider is always the caller, the checks below must not run against somebody else's id
+	riderId, _ := c.Get("userId")
+	request.RiderId = riderId.(int32)
+
+	// a blocked rider cannot ask the driver for a ride, in either direction
+	route, err := db.GetRoute(request.RouteId)
+	if err != nil {
+		if errors.Is(err, dberr.ErrRouteNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	blocked, err := db.IsBlocked(request.RiderId, route.DriverId)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if blocked {
+		c.JSON(http.StatusForbidden, gin.H{"error": svcerr.ErrUserBlocked.Error()})
+		return
+	}
+	if !s.checkRequirements(c, &request, route) {
+		return
+	}
+	if !s.applyMeetingPoint(c, &request) {
+		return
+	}
+	if request.SeatCount == 0 {
+		request.SeatCount = 1
+	}
+	if request.SeatCount < 0 || request.SeatCount > route.Capacity {
+		c.JSON(http.StatusBadRequest, gin.H{"error": svcerr.ErrSeatCountInvalid.Error()})
+		return
+	}
+	freeSeats, err := db.GetRouteFreeSeats(route.Id)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// a full route only takes the request onto its waitlist when the rider asked for it with ?waitlist=true
+	request.Status = constants.RequestStatusPending
+	if request.SeatCount > freeSeats {
+		if joinWaitlist, _ := strconv.ParseBool(c.Query("waitlist")); !joinWaitlist {
+			c.JSON(http.StatusConflict, gin.H{"error": dberr.ErrRouteSeatsUnavailable.Error()})
+			return
+		}
+		request.Status = constants.RequestStatusWaitlisted
+	}
 
 	// create route in db
 	requestResp, err := db.CreateRequest(&request)
@@ -83,10 +148,27 @@ func (s *requestSvc) Create(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionRequestCreate,
+		EntityType: constants.AuditEntityRequest,
+		EntityId:   requestResp.Id,
+		After:      requestResp,
+	})
+	if requestResp.Status == constants.RequestStatusWaitlisted {
+		s.Notifier.Notify(requestResp.RiderId, constants.NotificationKindWaitlistJoined,
+			"You are on the waitlist",
+			"The route is full for now. We will let you know as soon as seats free up.",
+			gin.H{"requestId": requestResp.Id, "routeId": requestResp.RouteId},
+		)
+	} else {
+		notifyRequestReceived(s.Notifier, route.DriverId, requestResp)
+	}
 
 	c.JSON(http.StatusOK, requestResp)
 }
 
+// Deny lets the route's driver turn down a request still waiting for an answer, seats already given away
+// are released through the trip instead
 func (s *requestSvc) Deny(c *gin.Context) {
 	stringId := c.Param("id")
 	requestId, err := strconv.Atoi(stringId)
@@ -96,15 +178,143 @@ func (s *requestSvc) Deny(c *gin.Context) {
 		return
 	}
 
-	if err := db.UpdateRequestStatus(int32(requestId), constants.RequestStatusDenied); err != nil {
+	request, ok := s.getForAudit(c, int32(requestId))
+	if !ok {
+		return
+	}
+	route, err := db.GetRoute(request.RouteId)
+	if err != nil {
 		s.Logger.Error(err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if authUid, _ := c.Get("userId"); authUid != route.DriverId {
+		c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+		return
+	}
+	if err := db.DenyRequest(request.Id); err != nil {
+		if errors.Is(err, dberr.ErrRequestNotPending) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionRequestDeny,
+		EntityType: constants.AuditEntityRequest,
+		EntityId:   request.Id,
+		Before:     gin.H{"status": request.Status},
+		After:      gin.H{"status": constants.RequestStatusDenied},
+	})
+	if holdsSeats(request.Status) {
+		promoteWaitlist(s.Logger, s.Notifier, request.RouteId)
+	}
+	cancelJourneyOf(s.Logger, s.Notifier, c, request.Id, request.RiderId, constants.RequestStatusDenied)
 
 	c.JSON(http.StatusOK, gin.H{})
 }
 
+type acceptRequestReq struct {
+	Seats int32 `json:"seats"`
+}
+
+// Accept lets the route's driver take the request, offering fewer seats than asked for leaves the rider to confirm
+func (s *requestSvc) Accept(c *gin.Context) {
+	stringId := c.Param("id")
+	requestId, err := strconv.Atoi(stringId)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req acceptRequestReq
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	request, ok := s.getForAudit(c, int32(requestId))
+	if !ok {
+		return
+	}
+	route, err := db.GetRoute(request.RouteId)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if authUid, _ := c.Get("userId"); authUid != route.DriverId {
+		c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+		return
+	}
+	if req.Seats < 0 || req.Seats > request.SeatCount {
+		c.JSON(http.StatusBadRequest, gin.H{"error": svcerr.ErrSeatCountInvalid.Error()})
+		return
+	}
+
+	trip, err := db.AcceptRequest(request.Id, req.Seats)
+	if err != nil {
+		writeAcceptRequestError(s.Logger, c, err)
+		return
+	}
+	status, seats := constants.RequestStatusAccepted, request.SeatCount
+	if trip == nil {
+		status, seats = constants.RequestStatusOffered, req.Seats
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionRequestAccept,
+		EntityType: constants.AuditEntityRequest,
+		EntityId:   request.Id,
+		Before:     gin.H{"status": request.Status},
+		After:      gin.H{"status": status, "acceptedSeats": seats},
+	})
+
+	c.JSON(http.StatusOK, gin.H{"status": status, "acceptedSeats": seats, "trip": trip})
+}
+
+// Confirm lets the rider take the seats a driver offered
+func (s *requestSvc) Confirm(c *gin.Context) {
+	stringId := c.Param("id")
+	requestId, err := strconv.Atoi(stringId)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	request, ok := s.getForAudit(c, int32(requestId))
+	if !ok {
+		return
+	}
+	if authUid, _ := c.Get("userId"); authUid != request.RiderId {
+		c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+		return
+	}
+
+	trip, err := db.ConfirmRequestOffer(request.Id)
+	if err != nil {
+		if errors.Is(err, dberr.ErrRequestNotOffered) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionRequestConfirm,
+		EntityType: constants.AuditEntityRequest,
+		EntityId:   request.Id,
+		Before:     gin.H{"status": request.Status},
+		After:      gin.H{"status": constants.RequestStatusAccepted, "acceptedSeats": request.AcceptedSeats},
+	})
+
+	c.JSON(http.StatusOK, trip)
+}
+
 func (s *requestSvc) Delete(c *gin.Context) {
 	stringId := c.Param("id")
 	requestId, err := strconv.Atoi(stringId)
@@ -114,11 +324,90 @@ func (s *requestSvc) Delete(c *gin.Context) {
 		return
 	}
 
+	request, ok := s.getForAudit(c, int32(requestId))
+	if !ok {
+		return
+	}
+	if authUid, _ := c.Get("userId"); authUid != request.RiderId {
+		c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+		return
+	}
 	if err := db.DeleteRequest(int32(requestId)); err != nil {
+		if errors.Is(err, dberr.ErrRequestNotCancellable) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		s.Logger.Error(err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionRequestDelete,
+		EntityType: constants.AuditEntityRequest,
+		EntityId:   request.Id,
+		Before:     request,
+	})
+	if holdsSeats(request.Status) {
+		promoteWaitlist(s.Logger, s.Notifier, request.RouteId)
+	}
+	cancelJourneyOf(s.Logger, s.Notifier, c, request.Id, request.RiderId, constants.RequestStatusCancelled)
 
 	c.JSON(http.StatusOK, gin.H{})
 }
+
+// getForAudit loads the request as it is before a change, otherwise the error response is already written
+func (s *requestSvc) getForAudit(c *gin.Context, requestId int32) (*model.Request, bool) {
+	request, err := db.GetRequest(requestId)
+	if err != nil {
+		if errors.Is(err, dberr.ErrRequestNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return request, true
+}
+
+// checkRequirements rejects requests the route cannot serve, the same hard rules the ranking query applies.
+// On failure the error response is already written.
+func (s *requestSvc) checkRequirements(c *gin.Context, request *model.Request, route *model.Route) bool {
+	if request.Luggage == "" {
+		request.Luggage = constants.LuggageNone
+	}
+	if !isLuggageSize(request.Luggage) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": svcerr.ErrRidePreferenceInvalid.Error()})
+		return false
+	}
+
+	riderPreferences, err := db.GetRidePreferences(request.RiderId)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	driverPreferences, err := db.GetRidePreferences(route.DriverId)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if !meetsRequirements(&route.RouteRules, driverPreferences.Gender, riderPreferences.Gender, &request.RideRequirements) {
+		c.JSON(http.StatusConflict, gin.H{"error": svcerr.ErrRideRequirementsNotMet.Error()})
+		return false
+	}
+	return true
+}
+
+func writeAcceptRequestError(logger *zap.SugaredLogger, c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, dberr.ErrRequestNotFound), errors.Is(err, dberr.ErrRouteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, dberr.ErrRequestNotPending), errors.Is(err, dberr.ErrRouteSeatsUnavailable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
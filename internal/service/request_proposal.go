@@ -0,0 +1,194 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	"github.com/CoRide-tw/backend/internal/db"
+	"github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/errors/generated/svcerr"
+	"github.com/CoRide-tw/backend/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+// requestDetail is a request together with every negotiation round on it
+type requestDetail struct {
+	*model.Request
+	Proposals []*model.RequestProposal `json:"proposals"`
+}
+
+// Counter lets the route's driver answer a pending request with other terms for the rider to accept or reject
+func (s *requestSvc) Counter(c *gin.Context) {
+	requestId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id must be integer"})
+		return
+	}
+
+	var proposal model.RequestProposal
+	if err := c.ShouldBindJSON(&proposal); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	request, ok := s.getForAudit(c, int32(requestId))
+	if !ok {
+		return
+	}
+	route, err := db.GetRoute(request.RouteId)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if authUid, _ := c.Get("userId"); authUid != route.DriverId {
+		c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+		return
+	}
+	if !isValidProposal(request, &proposal) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": svcerr.ErrRequestProposalInvalid.Error()})
+		return
+	}
+
+	proposal.RequestId, proposal.ProposerId = request.Id, route.DriverId
+	created, err := db.CreateRequestProposal(&proposal)
+	if err != nil {
+		if errors.Is(err, dberr.ErrRequestNotPending) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionRequestCounter,
+		EntityType: constants.AuditEntityRequest,
+		EntityId:   request.Id,
+		Before:     gin.H{"status": request.Status},
+		After:      gin.H{"status": constants.RequestStatusCountered},
+		Detail:     created,
+	})
+	s.Notifier.Notify(request.RiderId, constants.NotificationKindCounterProposed,
+		"The driver proposed other terms",
+		"The driver answered your ride request with a different pickup time, meeting point or tip, please accept or reject it.",
+		gin.H{"requestId": request.Id, "routeId": request.RouteId, "proposalId": created.Id, "round": created.Round},
+	)
+
+	c.JSON(http.StatusOK, created)
+}
+
+// AcceptCounter lets the rider agree to the open proposal, which books the ride with the proposed terms
+func (s *requestSvc) AcceptCounter(c *gin.Context) {
+	request, ok := s.getForRider(c)
+	if !ok {
+		return
+	}
+
+	trip, err := db.AcceptRequestProposal(request.Id)
+	if err != nil {
+		if errors.Is(err, dberr.ErrRequestProposalNotOpen) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		writeAcceptRequestError(s.Logger, c, err)
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionRequestCounterAccept,
+		EntityType: constants.AuditEntityRequest,
+		EntityId:   request.Id,
+		Before:     gin.H{"status": request.Status},
+		After:      gin.H{"status": constants.RequestStatusAccepted, "acceptedSeats": request.SeatCount},
+	})
+	s.Notifier.Notify(trip.DriverId, constants.NotificationKindCounterAccepted,
+		"The rider accepted your proposal",
+		"The rider agreed to the terms you proposed, the ride is booked.",
+		gin.H{"requestId": request.Id, "routeId": request.RouteId, "tripId": trip.Id},
+	)
+
+	c.JSON(http.StatusOK, trip)
+}
+
+// RejectCounter lets the rider turn down the open proposal, the driver may then accept, deny or counter again
+func (s *requestSvc) RejectCounter(c *gin.Context) {
+	request, ok := s.getForRider(c)
+	if !ok {
+		return
+	}
+
+	if err := db.RejectRequestProposal(request.Id); err != nil {
+		if errors.Is(err, dberr.ErrRequestProposalNotOpen) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionRequestCounterReject,
+		EntityType: constants.AuditEntityRequest,
+		EntityId:   request.Id,
+		Before:     gin.H{"status": request.Status},
+		After:      gin.H{"status": constants.RequestStatusPending},
+	})
+	route, err := db.GetRoute(request.RouteId)
+	if err != nil {
+		s.Logger.Errorw("failed to load route for counter notification", "routeId", request.RouteId, "error", err)
+	} else {
+		s.Notifier.Notify(route.DriverId, constants.NotificationKindCounterRejected,
+			"The rider rejected your proposal",
+			"The rider turned down the terms you proposed, the request is waiting for your answer again.",
+			gin.H{"requestId": request.Id, "routeId": request.RouteId},
+		)
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// getForRider loads the :id request and only lets its rider through, otherwise the error response is
+// already written
+func (s *requestSvc) getForRider(c *gin.Context) (*model.Request, bool) {
+	requestId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id must be integer"})
+		return nil, false
+	}
+
+	request, ok := s.getForAudit(c, int32(requestId))
+	if !ok {
+		return nil, false
+	}
+	if authUid, _ := c.Get("userId"); authUid != request.RiderId {
+		c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+		return nil, false
+	}
+	return request, true
+}
+
+// isValidProposal checks that the proposal changes something and still makes sense on top of the request,
+// a meeting point needs both coordinates
+func isValidProposal(request *model.Request, proposal *model.RequestProposal) bool {
+	if proposal.PickupStartTime == nil && proposal.PickupEndTime == nil && proposal.PickupLong == nil &&
+		proposal.PickupLat == nil && proposal.Tips == nil {
+		return false
+	}
+	if (proposal.PickupLong == nil) != (proposal.PickupLat == nil) {
+		return false
+	}
+	if proposal.Tips != nil && *proposal.Tips < 0 {
+		return false
+	}
+
+	start, end := request.PickupStartTime, request.PickupEndTime
+	if proposal.PickupStartTime != nil {
+		start = *proposal.PickupStartTime
+	}
+	if proposal.PickupEndTime != nil {
+		end = *proposal.PickupEndTime
+	}
+	return !end.Before(start)
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("IsValidProposal", func() {
+	now := time.Now()
+	request := &model.Request{PickupStartTime: now, PickupEndTime: now.Add(time.Hour)}
+
+	It("should reject a proposal that changes nothing", func() {
+		Expect(isValidProposal(request, &model.RequestProposal{})).To(BeFalse())
+	})
+
+	It("should reject a meeting point without both coordinates", func() {
+		long := 121.5
+		Expect(isValidProposal(request, &model.RequestProposal{PickupLong: &long})).To(BeFalse())
+	})
+
+	It("should reject negative tips", func() {
+		tips := int32(-1)
+		Expect(isValidProposal(request, &model.RequestProposal{Tips: &tips})).To(BeFalse())
+	})
+
+	It("should check the pickup window against the request", func() {
+		late := now.Add(2 * time.Hour)
+		Expect(isValidProposal(request, &model.RequestProposal{PickupStartTime: &late})).To(BeFalse())
+
+		earlier := now.Add(-time.Hour)
+		Expect(isValidProposal(request, &model.RequestProposal{PickupStartTime: &earlier})).To(BeTrue())
+	})
+})
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	"github.com/CoRide-tw/backend/internal/db"
+	"github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/errors/generated/svcerr"
+	"github.com/CoRide-tw/backend/internal/model"
+	"github.com/CoRide-tw/backend/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *userSvc) GetPreferences(c *gin.Context) {
+	userId, ok := selfUserId(s.Logger, c)
+	if !ok {
+		return
+	}
+
+	preferences, err := db.GetRidePreferences(userId)
+	if err != nil {
+		if errors.Is(err, dberr.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, preferences)
+}
+
+func (s *userSvc) UpdatePreferences(c *gin.Context) {
+	userId, ok := selfUserId(s.Logger, c)
+	if !ok {
+		return
+	}
+
+	var preferences model.RidePreferences
+	if err := c.ShouldBindJSON(&preferences); err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if preferences.Luggage == "" {
+		preferences.Luggage = constants.LuggageNone
+	}
+	if (preferences.Gender != "" && !isGender(preferences.Gender)) || !isLuggageSize(preferences.Luggage) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": svcerr.ErrRidePreferenceInvalid.Error()})
+		return
+	}
+	preferences.UserId = userId
+
+	before, err := db.GetRidePreferences(userId)
+	if err != nil {
+		if errors.Is(err, dberr.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	updated, err := db.UpsertRidePreferences(&preferences)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionRidePreferencesUpdate,
+		EntityType: constants.AuditEntityRidePreferences,
+		EntityId:   userId,
+		Before:     before,
+		After:      updated,
+	})
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// searchRequirements starts from the rider's saved preferences and applies whatever the query sets explicitly.
+// On failure the error response is already written.
+func searchRequirements(c *gin.Context, preferences *model.RidePreferences, query *util.ParsedListNearestRoutesQuery) (*model.RideRequirements, bool) {
+	requirements := preferences.RideRequirements
+	for _, override := range []struct {
+		value  *bool
+		target *bool
+	}{
+		{query.WomenOnly, &requirements.WomenOnly},
+		{query.Smoking, &requirements.Smoking},
+		{query.Pets, &requirements.Pets},
+		{query.Quiet, &requirements.Quiet},
+	} {
+		if override.value != nil {
+			*override.target = *override.value
+		}
+	}
+	if query.Luggage != "" {
+		requirements.Luggage = query.Luggage
+	}
+
+	if !isLuggageSize(requirements.Luggage) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": svcerr.ErrRidePreferenceInvalid.Error()})
+		return nil, false
+	}
+	return &requirements, true
+}
+
+// meetsRequirements mirrors the hard rules of the ranking query, soft preferences such as quiet rides never rule a route out
+func meetsRequirements(rules *model.RouteRules, driverGender, riderGender string, requirements *model.RideRequirements) bool {
+	if rules.WomenOnly && riderGender != constants.GenderFemale {
+		return false
+	}
+	if requirements.WomenOnly && driverGender != constants.GenderFemale {
+		return false
+	}
+	if requirements.Smoking && !rules.SmokingAllowed {
+		return false
+	}
+	if requirements.Pets && !rules.PetsAllowed {
+		return false
+	}
+	return luggageIndex(requirements.Luggage) <= luggageIndex(rules.MaxLuggage)
+}
+
+func isGender(gender string) bool {
+	for _, knownGender := range constants.Genders {
+		if gender == knownGender {
+			return true
+		}
+	}
+	return false
+}
+
+func isLuggageSize(size string) bool {
+	return luggageIndex(size) >= 0
+}
+
+func luggageIndex(size string) int {
+	for i, knownSize := range constants.LuggageSizes {
+		if size == knownSize {
+			return i
+		}
+	}
+	return -1
+}
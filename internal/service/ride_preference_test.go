@@ -0,0 +1,59 @@
+package service
+
+import (
+	"github.com/CoRide-tw/backend/internal/constants"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MeetsRequirements", func() {
+	var (
+		rules        *model.RouteRules
+		requirements *model.RideRequirements
+	)
+
+	BeforeEach(func() {
+		rules = &model.RouteRules{MaxLuggage: constants.LuggageSmall}
+		requirements = &model.RideRequirements{Luggage: constants.LuggageNone}
+	})
+
+	It("should accept a plain request on a plain route", func() {
+		Expect(meetsRequirements(rules, "", "", requirements)).To(BeTrue())
+	})
+
+	It("should keep women-only routes to female riders", func() {
+		rules.WomenOnly = true
+		Expect(meetsRequirements(rules, constants.GenderFemale, constants.GenderMale, requirements)).To(BeFalse())
+		Expect(meetsRequirements(rules, constants.GenderFemale, constants.GenderFemale, requirements)).To(BeTrue())
+	})
+
+	It("should keep women-only riders to female drivers", func() {
+		requirements.WomenOnly = true
+		Expect(meetsRequirements(rules, "", constants.GenderFemale, requirements)).To(BeFalse())
+		Expect(meetsRequirements(rules, constants.GenderFemale, constants.GenderFemale, requirements)).To(BeTrue())
+	})
+
+	It("should require pets and smoking to be allowed", func() {
+		requirements.Pets = true
+		Expect(meetsRequirements(rules, "", "", requirements)).To(BeFalse())
+		rules.PetsAllowed = true
+		Expect(meetsRequirements(rules, "", "", requirements)).To(BeTrue())
+
+		requirements.Smoking = true
+		Expect(meetsRequirements(rules, "", "", requirements)).To(BeFalse())
+	})
+
+	It("should compare luggage sizes", func() {
+		requirements.Luggage = constants.LuggageLarge
+		Expect(meetsRequirements(rules, "", "", requirements)).To(BeFalse())
+		rules.MaxLuggage = constants.LuggageLarge
+		Expect(meetsRequirements(rules, "", "", requirements)).To(BeTrue())
+	})
+
+	It("should not rule out a route over soft preferences", func() {
+		requirements.Quiet = true
+		rules.Music = true
+		Expect(meetsRequirements(rules, "", "", requirements)).To(BeTrue())
+	})
+})
@@ -1,11 +1,16 @@
 package service
 
 import (
+	"errors"
 	"go.uber.org/zap"
 	"net/http"
 	"strconv"
 
+	"github.com/CoRide-tw/backend/internal/config"
+	"github.com/CoRide-tw/backend/internal/constants"
 	"github.com/CoRide-tw/backend/internal/db"
+	"github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/errors/generated/svcerr"
 	"github.com/CoRide-tw/backend/internal/model"
 	"github.com/CoRide-tw/backend/internal/util"
 	"github.com/gin-gonic/gin"
@@ -23,18 +28,38 @@ func (s *routeSvc) ListNearestRoutes(c *gin.Context) {
 		return
 	}
 
-	// get nearest routes from db
-	routes, err := db.ListNearestRoutes(parsedQuery.StartLong, parsedQuery.StartLat, parsedQuery.EndLong, parsedQuery.EndLat, parsedQuery.PickupStartTime, parsedQuery.PickupEndTime)
+	riderId, _ := c.Get("userId")
+	preferences, err := db.GetRidePreferences(riderId.(int32))
 	if err != nil {
 		s.Logger.Error(err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	requirements, ok := searchRequirements(c, preferences, parsedQuery)
+	if !ok {
+		return
+	}
+
+	// get nearest routes from db, leaving out drivers the rider has a block with and routes that do not fit
+	routes, err := db.ListNearestRoutes(parsedQuery.StartLong, parsedQuery.StartLat, parsedQuery.EndLong, parsedQuery.EndLat, parsedQuery.PickupStartTime, parsedQuery.PickupEndTime, parsedQuery.VerifiedOnly, riderId.(int32), preferences.Gender, requirements, parsedQuery.Seats)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// searches are recorded for the demand insights, a failure must not cost the rider their results
+	if err := db.RecordRouteSearch(riderId.(int32), parsedQuery.StartLong, parsedQuery.StartLat, parsedQuery.EndLong, parsedQuery.EndLat, parsedQuery.PickupStartTime, int32(len(routes)), config.Env.DemandSearcherSalt); err != nil {
+		s.Logger.Errorw("failed to record route search", "error", err)
+	}
 
 	c.JSON(http.StatusOK, routes)
 }
 
 func (s *routeSvc) Get(c *gin.Context) {
+	if s.getExport(c) {
+		return
+	}
+
 	stringId := c.Param("id")
 	routeId, err := strconv.Atoi(stringId)
 	if err != nil {
@@ -62,6 +87,16 @@ func (s *routeSvc) Create(c *gin.Context) {
 		return
 	}
 
+	// routes are always posted by the signed in driver, whatever the body says
+	driverId, _ := c.Get("userId")
+	route.DriverId = driverId.(int32)
+	if !s.applyVehicle(c, &route) {
+		return
+	}
+	if !s.applyRules(c, &route) {
+		return
+	}
+
 	// create route in db
 	routeResp, err := db.CreateRoute(&route)
 	if err != nil {
@@ -69,6 +104,12 @@ func (s *routeSvc) Create(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionRouteCreate,
+		EntityType: constants.AuditEntityRoute,
+		EntityId:   routeResp.Id,
+		After:      routeResp,
+	})
 
 	c.JSON(http.StatusOK, routeResp)
 }
@@ -82,11 +123,94 @@ func (s *routeSvc) Delete(c *gin.Context) {
 		return
 	}
 
+	route, err := db.GetRoute(int32(routeId))
+	if err != nil {
+		if errors.Is(err, dberr.ErrRouteNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 	if err := db.DeleteRoute(int32(routeId)); err != nil {
 		s.Logger.Error(err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionRouteDelete,
+		EntityType: constants.AuditEntityRoute,
+		EntityId:   route.Id,
+		Before:     route,
+	})
 
 	c.JSON(http.StatusOK, gin.H{})
 }
+
+// applyVehicle attaches the named vehicle, or the driver's default one, and caps the capacity at its seat count.
+// Routes of drivers without any vehicle keep the capacity as given. On failure the error response is already written.
+func (s *routeSvc) applyVehicle(c *gin.Context, route *model.Route) bool {
+	var (
+		vehicle *model.Vehicle
+		err     error
+	)
+	if route.VehicleId != nil {
+		vehicle, err = db.GetVehicle(*route.VehicleId)
+	} else {
+		vehicle, err = db.GetDefaultVehicle(route.DriverId)
+		if errors.Is(err, dberr.ErrVehicleNotFound) {
+			return true
+		}
+	}
+	if err != nil {
+		if errors.Is(err, dberr.ErrVehicleNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return false
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+
+	if vehicle.UserId != route.DriverId {
+		c.JSON(http.StatusForbidden, gin.H{"error": svcerr.ErrVehicleNotOwned.Error()})
+		return false
+	}
+	if route.Capacity == 0 {
+		route.Capacity = vehicle.SeatCount
+	}
+	if route.Capacity > vehicle.SeatCount {
+		c.JSON(http.StatusBadRequest, gin.H{"error": svcerr.ErrRouteCapacityExceedsSeats.Error()})
+		return false
+	}
+	route.VehicleId = &vehicle.Id
+	return true
+}
+
+// applyRules defaults the luggage allowance and only lets female drivers offer women-only routes.
+// On failure the error response is already written.
+func (s *routeSvc) applyRules(c *gin.Context, route *model.Route) bool {
+	if route.MaxLuggage == "" {
+		route.MaxLuggage = constants.LuggageSmall
+	}
+	if !isLuggageSize(route.MaxLuggage) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": svcerr.ErrRidePreferenceInvalid.Error()})
+		return false
+	}
+	if !route.WomenOnly {
+		return true
+	}
+
+	preferences, err := db.GetRidePreferences(route.DriverId)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if preferences.Gender != constants.GenderFemale {
+		c.JSON(http.StatusForbidden, gin.H{"error": svcerr.ErrWomenOnlyRouteDriver.Error()})
+		return false
+	}
+	return true
+}
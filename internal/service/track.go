@@ -0,0 +1,211 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"math"
+
+	"github.com/CoRide-tw/backend/internal/model"
+)
+
+const maxTrackSize = 5 << 20
+
+// maxTrackPoints bounds the points read from an uploaded track before it is simplified
+const maxTrackPoints = 100000
+
+type gpxPoint struct {
+	Lat *float64 `xml:"lat,attr"`
+	Lon *float64 `xml:"lon,attr"`
+}
+
+// gpxDocument reads the track points of every track, or the route points when the file has no track.
+// The namespace is left open so that GPX 1.0 and 1.1 files are both read.
+type gpxDocument struct {
+	XMLName xml.Name `xml:"gpx"`
+	Tracks  []struct {
+		Segments []struct {
+			Points []gpxPoint `xml:"trkpt"`
+		} `xml:"trkseg"`
+	} `xml:"trk"`
+	Routes []struct {
+		Points []gpxPoint `xml:"rtept"`
+	} `xml:"rte"`
+}
+
+// parseTrack reads a GPX file or a GeoJSON LineString, either bare, in a Feature or as the only feature
+// of a FeatureCollection. The points are validated and repeated points dropped, false means the track is
+// unusable.
+func parseTrack(data []byte) ([][2]float64, bool) {
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	if len(data) == 0 {
+		return nil, false
+	}
+
+	var (
+		points [][2]float64
+		ok     bool
+	)
+	switch data[0] {
+	case '<':
+		points, ok = parseGpxTrack(data)
+	case '{':
+		points, ok = parseGeoJsonTrack(data)
+	}
+	if !ok {
+		return nil, false
+	}
+	return cleanTrack(points)
+}
+
+func parseGpxTrack(data []byte) ([][2]float64, bool) {
+	var document gpxDocument
+	if err := xml.Unmarshal(data, &document); err != nil {
+		return nil, false
+	}
+
+	var gpxPoints []gpxPoint
+	for _, track := range document.Tracks {
+		for _, segment := range track.Segments {
+			gpxPoints = append(gpxPoints, segment.Points...)
+		}
+	}
+	if len(gpxPoints) == 0 {
+		for _, route := range document.Routes {
+			gpxPoints = append(gpxPoints, route.Points...)
+		}
+	}
+
+	points := make([][2]float64, 0, len(gpxPoints))
+	for _, point := range gpxPoints {
+		if point.Lat == nil || point.Lon == nil {
+			return nil, false
+		}
+		points = append(points, [2]float64{*point.Lon, *point.Lat})
+	}
+	return points, true
+}
+
+func parseGeoJsonTrack(data []byte) ([][2]float64, bool) {
+	var object struct {
+		Type        string                        `json:"type"`
+		Coordinates json.RawMessage               `json:"coordinates"`
+		Geometry    *model.GeoJsonGeometry        `json:"geometry"`
+		Features    []*model.GeoJsonFeatureObject `json:"features"`
+	}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, false
+	}
+
+	geometry := &model.GeoJsonGeometry{Type: object.Type, Coordinates: object.Coordinates}
+	switch object.Type {
+	case model.GeoJsonFeatureCollection:
+		if len(object.Features) != 1 || object.Features[0] == nil {
+			return nil, false
+		}
+		geometry = object.Features[0].Geometry
+	case model.GeoJsonFeature:
+		geometry = object.Geometry
+	}
+	if geometry == nil || geometry.Type != model.GeoJsonLineString {
+		return nil, false
+	}
+
+	// positions may carry an altitude after longitude and latitude
+	var positions [][]float64
+	if err := json.Unmarshal(geometry.Coordinates, &positions); err != nil {
+		return nil, false
+	}
+	points := make([][2]float64, 0, len(positions))
+	for _, position := range positions {
+		if len(position) < 2 {
+			return nil, false
+		}
+		points = append(points, [2]float64{position[0], position[1]})
+	}
+	return points, true
+}
+
+// cleanTrack refuses tracks with invalid coordinates or too many points and drops points repeating the
+// one before, at least two distinct points have to remain
+func cleanTrack(points [][2]float64) ([][2]float64, bool) {
+	if len(points) > maxTrackPoints {
+		return nil, false
+	}
+
+	cleaned := make([][2]float64, 0, len(points))
+	for _, point := range points {
+		if !isValidCoordinate(point[0], point[1]) {
+			return nil, false
+		}
+		if len(cleaned) > 0 && cleaned[len(cleaned)-1] == point {
+			continue
+		}
+		cleaned = append(cleaned, point)
+	}
+	if len(cleaned) < 2 {
+		return nil, false
+	}
+	return cleaned, true
+}
+
+// simplifyTrack drops the points closer than the tolerance in meters to the line through their neighbours.
+// While more than maxPoints remain the tolerance is doubled, the first and last point are always kept.
+func simplifyTrack(points [][2]float64, tolerance float64, maxPoints int) [][2]float64 {
+	simplified := douglasPeucker(points, tolerance)
+	for maxPoints >= 2 && len(simplified) > maxPoints {
+		tolerance = math.Max(tolerance*2, 1)
+		simplified = douglasPeucker(points, tolerance)
+	}
+	return simplified
+}
+
+func douglasPeucker(points [][2]float64, tolerance float64) [][2]float64 {
+	if len(points) <= 2 {
+		return points
+	}
+
+	keep := make([]bool, len(points))
+	keep[0], keep[len(points)-1] = true, true
+	ranges := [][2]int{{0, len(points) - 1}}
+	for len(ranges) > 0 {
+		first, last := ranges[len(ranges)-1][0], ranges[len(ranges)-1][1]
+		ranges = ranges[:len(ranges)-1]
+
+		farthest, farthestDistance := -1, tolerance
+		for i := first + 1; i < last; i++ {
+			if distance := segmentDistance(points[i], points[first], points[last]); distance > farthestDistance {
+				farthest, farthestDistance = i, distance
+			}
+		}
+		if farthest < 0 {
+			continue
+		}
+		keep[farthest] = true
+		ranges = append(ranges, [2]int{first, farthest}, [2]int{farthest, last})
+	}
+
+	simplified := make([][2]float64, 0, len(points))
+	for i, point := range points {
+		if keep[i] {
+			simplified = append(simplified, point)
+		}
+	}
+	return simplified
+}
+
+// segmentDistance is the distance in meters from the point to the segment, the few kilometers between
+// track points are short enough to treat the earth as flat around the segment start
+func segmentDistance(point, start, end [2]float64) float64 {
+	toRadians := math.Pi / 180
+	scaleLong := earthRadius * toRadians * math.Cos(start[1]*toRadians)
+	scaleLat := earthRadius * toRadians
+	px, py := (point[0]-start[0])*scaleLong, (point[1]-start[1])*scaleLat
+	ex, ey := (end[0]-start[0])*scaleLong, (end[1]-start[1])*scaleLat
+
+	t := 0.0
+	if length := ex*ex + ey*ey; length > 0 {
+		t = math.Max(0, math.Min(1, (px*ex+py*ey)/length))
+	}
+	return math.Hypot(px-t*ex, py-t*ey)
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParseTrack", func() {
+	It("should read the track points of a GPX file", func() {
+		points, ok := parseTrack([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+			<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1">
+				<trk><name>commute</name>
+					<trkseg>
+						<trkpt lat="25.0330" lon="121.5654"><ele>10</ele></trkpt>
+						<trkpt lat="25.0340" lon="121.5660"></trkpt>
+					</trkseg>
+					<trkseg>
+						<trkpt lat="25.0350" lon="121.5670"></trkpt>
+					</trkseg>
+				</trk>
+			</gpx>`))
+
+		Expect(ok).To(BeTrue())
+		Expect(points).To(Equal([][2]float64{{121.5654, 25.0330}, {121.5660, 25.0340}, {121.5670, 25.0350}}))
+	})
+
+	It("should fall back to the route points of a GPX file without a track", func() {
+		points, ok := parseTrack([]byte(`<gpx version="1.0"><rte>
+			<rtept lat="25.0" lon="121.5"/><rtept lat="25.1" lon="121.6"/>
+		</rte></gpx>`))
+
+		Expect(ok).To(BeTrue())
+		Expect(points).To(HaveLen(2))
+	})
+
+	It("should read a LineString bare, in a Feature or in a FeatureCollection", func() {
+		lineString := `{"type": "LineString", "coordinates": [[121.5, 25.0, 12], [121.6, 25.1, 15]]}`
+		for _, document := range []string{
+			lineString,
+			`{"type": "Feature", "properties": {}, "geometry": ` + lineString + `}`,
+			`{"type": "FeatureCollection", "features": [{"type": "Feature", "geometry": ` + lineString + `}]}`,
+		} {
+			points, ok := parseTrack([]byte(document))
+			Expect(ok).To(BeTrue())
+			Expect(points).To(Equal([][2]float64{{121.5, 25.0}, {121.6, 25.1}}))
+		}
+	})
+
+	It("should drop repeated points", func() {
+		points, ok := parseTrack([]byte(`{"type": "LineString", "coordinates": [[121.5, 25.0], [121.5, 25.0], [121.6, 25.1]]}`))
+
+		Expect(ok).To(BeTrue())
+		Expect(points).To(HaveLen(2))
+	})
+
+	It("should refuse unusable tracks", func() {
+		for _, document := range []string{
+			``,
+			`not a track`,
+			`{"type": "Point", "coordinates": [121.5, 25.0]}`,
+			`{"type": "LineString", "coordinates": [[121.5, 25.0]]}`,
+			`{"type": "LineString", "coordinates": [[121.5, 25.0], [121.5, 25.0]]}`,
+			`{"type": "LineString", "coordinates": [[121.5, 25.0], [200, 25.1]]}`,
+			`{"type": "LineString", "coordinates": [[121.5], [121.6, 25.1]]}`,
+			`{"type": "FeatureCollection", "features": []}`,
+			`<gpx><trk><trkseg><trkpt lat="25.0"/><trkpt lat="25.1" lon="121.6"/></trkseg></trk></gpx>`,
+			`<gpx><trk><trkseg><trkpt lat="NaN" lon="121.5"/><trkpt lat="25.1" lon="121.6"/></trkseg></trk></gpx>`,
+			`<kml></kml>`,
+		} {
+			_, ok := parseTrack([]byte(document))
+			Expect(ok).To(BeFalse(), document)
+		}
+	})
+})
+
+var _ = Describe("SimplifyTrack", func() {
+	It("should drop points on a straight line and keep the corners", func() {
+		var points [][2]float64
+		for i := 0; i <= 100; i++ {
+			points = append(points, [2]float64{121.5 + float64(i)*0.0001, 25})
+		}
+		for i := 1; i <= 100; i++ {
+			points = append(points, [2]float64{121.51, 25 + float64(i)*0.0001})
+		}
+
+		simplified := simplifyTrack(points, 10, 500)
+
+		Expect(simplified).To(Equal([][2]float64{{121.5, 25}, {121.51, 25}, points[len(points)-1]}))
+	})
+
+	It("should keep detours wider than the tolerance", func() {
+		// the middle point lies about 110 m off the line
+		points := [][2]float64{{121.5, 25}, {121.505, 25.001}, {121.51, 25}}
+
+		Expect(simplifyTrack(points, 10, 500)).To(HaveLen(3))
+		Expect(simplifyTrack(points, 200, 500)).To(HaveLen(2))
+	})
+
+	It("should raise the tolerance until the track fits the point limit", func() {
+		var points [][2]float64
+		for i := 0; i < 1000; i++ {
+			points = append(points, [2]float64{121.5 + float64(i)*0.001, 25 + float64(i%2)*0.001})
+		}
+
+		simplified := simplifyTrack(points, 10, 50)
+
+		Expect(len(simplified)).To(BeNumerically("<=", 50))
+		Expect(simplified[0]).To(Equal(points[0]))
+		Expect(simplified[len(simplified)-1]).To(Equal(points[len(points)-1]))
+	})
+})
+
+var _ = Describe("GpxTrack", func() {
+	It("should write a GPX file that reads back as the same track", func() {
+		points := [][2]float64{{121.5, 25}, {121.55, 25.05}, {121.6, 25.1}}
+
+		body, err := gpxTrack("CoRide route 1", lineStringGeometry(points))
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(body)).To(ContainSubstring(`xmlns="http://www.topografix.com/GPX/1/1"`))
+		Expect(strings.Count(string(body), "<trkpt")).To(Equal(3))
+		parsed, ok := parseTrack(body)
+		Expect(ok).To(BeTrue(), string(body))
+		Expect(parsed).To(Equal(points))
+	})
+})
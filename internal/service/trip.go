@@ -8,12 +8,14 @@ import (
 
 	"github.com/CoRide-tw/backend/internal/constants"
 	"github.com/CoRide-tw/backend/internal/db"
+	"github.com/CoRide-tw/backend/internal/errors/generated/dberr"
 	"github.com/CoRide-tw/backend/internal/model"
 	"github.com/gin-gonic/gin"
 )
 
 type tripSvc struct {
-	Logger *zap.SugaredLogger
+	Logger   *zap.SugaredLogger
+	Notifier *notifier
 }
 
 func (s *tripSvc) List(c *gin.Context) {
@@ -50,6 +52,10 @@ func (s *tripSvc) List(c *gin.Context) {
 }
 
 func (s *tripSvc) Get(c *gin.Context) {
+	if s.getGeoJson(c) {
+		return
+	}
+
 	stringId := c.Param("id")
 	tripId, err := strconv.Atoi(stringId)
 	if err != nil {
@@ -77,18 +83,90 @@ func (s *tripSvc) Create(c *gin.Context) {
 		return
 	}
 
-	// create trip in db
-	tripResp, err := db.CreateTrip(&trip)
+	// only the driver of the request's route may accept it, the same as accepting the request directly
+	request, err := db.GetRequest(trip.RequestId)
 	if err != nil {
+		if errors.Is(err, dberr.ErrRequestNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		s.Logger.Error(err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	if err := db.UpdateRequestStatus(trip.RequestId, constants.RequestStatusAccepted); err != nil {
+	route, err := db.GetRoute(request.RouteId)
+	if err != nil {
 		s.Logger.Error(err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if authUid, _ := c.Get("userId"); authUid != route.DriverId {
+		c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+		return
+	}
+
+	// accept every requested seat, the trip takes its rider and route from the request
+	tripResp, err := db.AcceptRequest(request.Id, 0)
+	if err != nil {
+		writeAcceptRequestError(s.Logger, c, err)
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionTripCreate,
+		EntityType: constants.AuditEntityTrip,
+		EntityId:   tripResp.Id,
+		After:      tripResp,
+		Detail:     gin.H{"requestId": trip.RequestId, "requestStatus": constants.RequestStatusAccepted},
+	})
 
 	c.JSON(http.StatusOK, tripResp)
 }
+
+// Depart lets the driver tell the rider they are on the way to the pickup
+func (s *tripSvc) Depart(c *gin.Context) {
+	tripId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id must be integer"})
+		return
+	}
+
+	trip, err := db.GetTrip(int32(tripId))
+	if err != nil {
+		if errors.Is(err, dberr.ErrTripNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if authUid, _ := c.Get("userId"); authUid != trip.DriverId {
+		c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+		return
+	}
+
+	departed, err := db.DepartTrip(trip.Id)
+	if err != nil {
+		if errors.Is(err, dberr.ErrTripNotScheduled) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionTripDepart,
+		EntityType: constants.AuditEntityTrip,
+		EntityId:   departed.Id,
+		Before:     gin.H{"status": trip.Status},
+		After:      gin.H{"status": departed.Status},
+	})
+	s.Notifier.Notify(departed.RiderId, constants.NotificationKindDriverDeparted,
+		"Your driver is on the way",
+		"Your driver has departed for the pickup, please be ready at the pickup point.",
+		gin.H{"tripId": departed.Id, "routeId": departed.RouteId, "requestId": departed.RequestId},
+	)
+
+	c.JSON(http.StatusOK, departed)
+}
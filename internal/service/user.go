@@ -1,45 +1,60 @@
 package service
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
 	"go.uber.org/zap"
 	"net/http"
-	"net/url"
 	"strconv"
 
-	"github.com/CoRide-tw/backend/internal/config"
+	"github.com/CoRide-tw/backend/internal/constants"
 	"github.com/CoRide-tw/backend/internal/db"
+	"github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/errors/generated/svcerr"
 	"github.com/CoRide-tw/backend/internal/model"
 	"github.com/CoRide-tw/backend/internal/util"
 	"github.com/gin-gonic/gin"
 )
 
 type userSvc struct {
-	Logger *zap.SugaredLogger
+	Logger     *zap.SugaredLogger
+	GoogleKeys publicKeySource
+	Providers  map[string]identityProvider
+	EmailLogin *emailLoginProvider
+	Blobs      blobStore
+	Notifier   *notifier
 }
 
 func (s *userSvc) OauthUrl(c *gin.Context) {
-	baseUrl, err := url.Parse("https://accounts.google.com/o/oauth2/v2/auth")
+	provider, ok := s.Providers[c.DefaultQuery("provider", constants.IdentityProviderGoogle)]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": svcerr.ErrIdentityProviderUnsupported.Error()})
+		return
+	}
+
+	// state is echoed back to the client on the callback, which compares it against this one
+	state, err := util.GenerateRandomToken(16)
 	if err != nil {
 		s.Logger.Error(err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	params := url.Values{}
-	params.Add("client_id", config.Env.GoogleOAuthClientId)
-	params.Add("redirect_uri", config.Env.GoogleOAuthRedirectUrl)
-	params.Add("response_type", "code")
-	params.Add("scope", config.Env.GoogleOauthScope)
-	params.Add("access_type", "offline")
-	params.Add("include_granted_scopes", "true")
-	baseUrl.RawQuery = params.Encode()
+	authUrl, err := provider.AuthUrl(state)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{"url": baseUrl.String()})
+	c.JSON(http.StatusOK, gin.H{"url": authUrl, "state": state})
 }
 
 type oauthCode struct {
-	Code string `json:"code"`
+	Provider string `json:"provider"`
+	Code     string `json:"code"`
+	// Register confirms creating a new account for an identity whose account was deleted
+	Register bool `json:"register"`
 }
 
 func (s *userSvc) OAuthLogin(c *gin.Context) {
@@ -51,43 +66,67 @@ func (s *userSvc) OAuthLogin(c *gin.Context) {
 		return
 	}
 
-	token, err := s.getAccessToken(res.Code)
+	identity, err := s.exchange(res.Provider, res.Code)
 	if err != nil {
 		s.Logger.Error(err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	userData, err := s.getUserData(token.AccessToken)
-	if err != nil {
-		s.Logger.Error(err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+	s.login(c, identity, res.Register)
+}
+
+// exchange resolves the identity behind a credential issued by the provider, google when unspecified
+func (s *userSvc) exchange(providerName string, credential string) (*externalIdentity, error) {
+	if providerName == "" {
+		providerName = constants.IdentityProviderGoogle
+	}
+	provider, ok := s.Providers[providerName]
+	if !ok {
+		return nil, svcerr.ErrIdentityProviderUnsupported
+	}
+	return provider.Exchange(credential)
+}
+
+// login upserts the user owning the verified identity and responds with a fresh session
+func (s *userSvc) login(c *gin.Context, identity *externalIdentity, register bool) {
+	if register {
+		if err := db.ReleaseDeletedUserIdentity(identity.userIdentity()); err != nil {
+			s.Logger.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
 	}
 
 	// upsert user
-	userResp, upsertErr := db.UpsertUser(&model.User{
-		Name:       userData.Name,
-		Email:      userData.Email,
-		GoogleId:   userData.GoogleId,
-		PictureUrl: userData.PictureUrl,
-	})
+	userResp, upsertErr := db.UpsertUserByIdentity(identity.userIdentity(), identity.user())
 	if upsertErr != nil {
+		if errors.Is(upsertErr, dberr.ErrUserDeleted) {
+			c.JSON(http.StatusGone, gin.H{"error": upsertErr.Error()})
+			return
+		}
 		s.Logger.Error(upsertErr)
 		c.JSON(http.StatusBadRequest, gin.H{"error": upsertErr.Error()})
 		return
 	}
 
-	// sign JWT token
-	jwtToken, tokenErr := util.GenerateJWT(userResp.Id, config.Env.CoRideJwtSecret)
+	// start session and sign JWT token
+	tokens, tokenErr := issueTokens(userResp.Id, "", c.Request.UserAgent(), nil)
 	if tokenErr != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		if errors.Is(tokenErr, svcerr.ErrUserSuspended) {
+			c.JSON(http.StatusForbidden, gin.H{"error": tokenErr.Error()})
+			return
+		}
+		s.Logger.Error(tokenErr)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tokenErr.Error()})
 		return
 	}
 
 	c.JSON(200, gin.H{
-		"user":  userResp,
-		"token": jwtToken,
+		"user":         userResp.Private(),
+		"token":        tokens.AccessToken,
+		"refreshToken": tokens.RefreshToken,
+		"expiresAt":    tokens.ExpiresAt,
 	})
 }
 
@@ -118,7 +157,7 @@ func (s *userSvc) Get(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, user)
+	c.JSON(http.StatusOK, user.Private())
 }
 
 func (s *userSvc) Update(c *gin.Context) {
@@ -146,114 +185,134 @@ func (s *userSvc) Update(c *gin.Context) {
 		return
 	}
 
+	before, err := db.GetUser(int32(userId))
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 	updatedUser, err := db.UpdateUser(int32(userId), &user)
 	if err != nil {
 		s.Logger.Error(err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionUserUpdate,
+		EntityType: constants.AuditEntityUser,
+		EntityId:   updatedUser.Id,
+		Before:     before,
+		After:      updatedUser,
+	})
 
-	c.JSON(http.StatusOK, updatedUser)
-}
-
-type oauthExchangeRes struct {
-	AccessToken  string `json:"access_token"`
-	ExpiresIn    int    `json:"expires_in"`
-	RefreshToken string `json:"refresh_token"`
-	Scope        string `json:"scope"`
-	TokenType    string `json:"token_type"`
+	c.JSON(http.StatusOK, updatedUser.Private())
 }
 
-func (s *userSvc) getAccessToken(code string) (*oauthExchangeRes, error) {
-	baseUrl, err := url.Parse("https://oauth2.googleapis.com/token")
+// Profile is the public part of any user, available to every signed in user
+func (s *userSvc) Profile(c *gin.Context) {
+	userId, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		s.Logger.Error(err)
-		return nil, err
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id must be integer"})
+		return
 	}
 
-	params := url.Values{}
-	params.Add("code", code)
-	params.Add("client_id", config.Env.GoogleOAuthClientId)
-	params.Add("client_secret", config.Env.GoogleOAuthClientSecret)
-	params.Add("redirect_uri", config.Env.GoogleOAuthRedirectUrl)
-	params.Add("grant_type", "authorization_code")
-	baseUrl.RawQuery = params.Encode()
-
-	req, err := http.NewRequest(
-		"POST",
-		baseUrl.String(),
-		nil,
-	)
+	profile, err := db.GetPublicProfile(int32(userId))
 	if err != nil {
+		if errors.Is(err, dberr.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		s.Logger.Error(err)
-		return nil, err
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		s.Logger.Error(err)
-		return nil, err
-	}
+	c.JSON(http.StatusOK, profile)
+}
 
-	if resp.StatusCode != 200 {
-		var err error
-		return nil, err
+// Export responds with a JSON archive of all personal data kept about the user
+func (s *userSvc) Export(c *gin.Context) {
+	userId, ok := selfUserId(s.Logger, c)
+	if !ok {
+		return
 	}
 
-	var res oauthExchangeRes
-	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+	export, err := db.ExportUserData(userId)
+	if err != nil {
+		if errors.Is(err, dberr.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		s.Logger.Error(err)
-		return nil, err
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
-	return &res, nil
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"coride-user-%d.json\"", userId))
+	c.JSON(http.StatusOK, export)
 }
 
-type userData struct {
-	Name       string `json:"name"`
-	Email      string `json:"email"`
-	GoogleId   string `json:"sub"`
-	PictureUrl string `json:"picture"`
-}
-
-func (s *userSvc) getUserData(accessToken string) (*userData, error) {
-	baseUrl, err := url.Parse("https://www.googleapis.com/oauth2/v3/userinfo")
-	if err != nil {
-		s.Logger.Error(err)
-		return nil, err
+// Delete anonymizes the account and cancels its upcoming rides, signing in again needs an explicit re-registration
+func (s *userSvc) Delete(c *gin.Context) {
+	userId, ok := selfUserId(s.Logger, c)
+	if !ok {
+		return
 	}
 
-	params := url.Values{}
-	params.Add("access_token", accessToken)
-	baseUrl.RawQuery = params.Encode()
-
-	req, err := http.NewRequest(
-		"GET",
-		baseUrl.String(),
-		nil,
-	)
+	documents, err := db.ListDriverDocumentsByUserId(userId)
 	if err != nil {
 		s.Logger.Error(err)
-		return nil, err
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-
-	resp, err := http.DefaultClient.Do(req)
+	cancelled, err := db.DeleteUser(userId)
 	if err != nil {
+		if errors.Is(err, dberr.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		s.Logger.Error(err)
-		return nil, err
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
-
-	// Read the response as a byte slice
-	var user userData
-	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
-		s.Logger.Error(err)
-		return nil, err
+	// uploaded documents are personal data too, a leftover file is only logged as the account is already gone
+	for _, document := range documents {
+		if err := s.Blobs.Delete(document.BlobKey); err != nil {
+			s.Logger.Errorw("failed to delete driver document", "blobKey", document.BlobKey, "error", err)
+		}
 	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionUserDelete,
+		EntityType: constants.AuditEntityUser,
+		EntityId:   userId,
+	})
+	s.afterUserRequestsCancelled(userId, cancelled)
 
-	return &user, nil
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// afterUserRequestsCancelled tells the other party of every ride the deleted user was in, seats a deleted
+// rider held go to the waitlist while the routes of a deleted driver are gone with their waitlists
+func (s *userSvc) afterUserRequestsCancelled(userId int32, cancelled []*db.CancelledUserRequest) {
+	for _, request := range cancelled {
+		data := gin.H{"requestId": request.RequestId, "routeId": request.RouteId}
+		if request.RiderId != userId {
+			s.Notifier.Notify(request.RiderId, constants.NotificationKindRequestCancelled,
+				"Your ride was cancelled",
+				"The driver deleted their account, so your request on their route was cancelled.",
+				data,
+			)
+			continue
+		}
+		s.Notifier.Notify(request.DriverId, constants.NotificationKindRequestCancelled,
+			"A ride request was cancelled",
+			"The rider deleted their account, so their request on your route was cancelled.",
+			data,
+		)
+		if holdsSeats(request.Status) {
+			promoteWaitlist(s.Logger, s.Notifier, request.RouteId)
+		}
+	}
 }
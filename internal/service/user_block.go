@@ -0,0 +1,98 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	"github.com/CoRide-tw/backend/internal/db"
+	"github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/errors/generated/svcerr"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *userSvc) ListBlocks(c *gin.Context) {
+	userId, ok := selfUserId(s.Logger, c)
+	if !ok {
+		return
+	}
+
+	blocks, err := db.ListUserBlocks(userId)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, blocks)
+}
+
+type blockUserReq struct {
+	UserId int32 `json:"userId" binding:"required"`
+}
+
+func (s *userSvc) BlockUser(c *gin.Context) {
+	userId, ok := selfUserId(s.Logger, c)
+	if !ok {
+		return
+	}
+
+	var req blockUserReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.UserId == userId {
+		c.JSON(http.StatusBadRequest, gin.H{"error": svcerr.ErrTargetIsSelf.Error()})
+		return
+	}
+
+	block, err := db.CreateUserBlock(userId, req.UserId)
+	if err != nil {
+		if errors.Is(err, dberr.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionUserBlock,
+		EntityType: constants.AuditEntityUser,
+		EntityId:   req.UserId,
+	})
+
+	c.JSON(http.StatusOK, block)
+}
+
+func (s *userSvc) UnblockUser(c *gin.Context) {
+	userId, ok := selfUserId(s.Logger, c)
+	if !ok {
+		return
+	}
+	blockedId, err := strconv.Atoi(c.Param("blockedId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "blockedId must be integer"})
+		return
+	}
+
+	if err := db.DeleteUserBlock(userId, int32(blockedId)); err != nil {
+		if errors.Is(err, dberr.ErrUserBlockNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionUserUnblock,
+		EntityType: constants.AuditEntityUser,
+		EntityId:   int32(blockedId),
+	})
+
+	c.JSON(http.StatusOK, gin.H{})
+}
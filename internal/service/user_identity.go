@@ -0,0 +1,121 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	"github.com/CoRide-tw/backend/internal/db"
+	"github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/errors/generated/svcerr"
+	"github.com/CoRide-tw/backend/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *userSvc) ListIdentities(c *gin.Context) {
+	userId, ok := selfUserId(s.Logger, c)
+	if !ok {
+		return
+	}
+
+	identities, err := db.ListUserIdentities(userId)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, identities)
+}
+
+// LinkIdentity attaches another provider's identity to the signed in user, so that either one logs into the same account
+func (s *userSvc) LinkIdentity(c *gin.Context) {
+	userId, ok := selfUserId(s.Logger, c)
+	if !ok {
+		return
+	}
+
+	var req oauthCode
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	identity, err := s.exchange(req.Provider, req.Code)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	linked, err := db.LinkUserIdentity(userId, identity.userIdentity())
+	if err != nil {
+		if errors.Is(err, dberr.ErrUserIdentityAlreadyLinked) || errors.Is(err, dberr.ErrUserIdentityProviderLinked) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionUserLinkIdentity,
+		EntityType: constants.AuditEntityUser,
+		EntityId:   userId,
+		After:      linked,
+	})
+
+	c.JSON(http.StatusOK, linked)
+}
+
+func (s *userSvc) UnlinkIdentity(c *gin.Context) {
+	userId, ok := selfUserId(s.Logger, c)
+	if !ok {
+		return
+	}
+
+	identities, err := db.ListUserIdentities(userId)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// accounts linked before a provider was limited to one may hold several, the subject tells them apart
+	provider, subject := c.Param("provider"), c.Query("subject")
+	var unlinked *model.UserIdentity
+	for _, identity := range identities {
+		if identity.Provider == provider && (subject == "" || identity.Subject == subject) {
+			unlinked = identity
+			break
+		}
+	}
+	if unlinked == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": dberr.ErrUserIdentityNotFound.Error()})
+		return
+	}
+	// keep at least one way to sign in
+	if len(identities) == 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": svcerr.ErrLastIdentityUnlink.Error()})
+		return
+	}
+
+	if err := db.DeleteUserIdentity(userId, unlinked.Provider, unlinked.Subject); err != nil {
+		if errors.Is(err, dberr.ErrUserIdentityNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionUserUnlinkIdentity,
+		EntityType: constants.AuditEntityUser,
+		EntityId:   userId,
+		Before:     unlinked,
+	})
+
+	c.JSON(http.StatusOK, gin.H{})
+}
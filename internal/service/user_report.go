@@ -0,0 +1,171 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/config"
+	"github.com/CoRide-tw/backend/internal/constants"
+	"github.com/CoRide-tw/backend/internal/db"
+	"github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/errors/generated/svcerr"
+	"github.com/CoRide-tw/backend/internal/model"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const maxUserReportDescriptionLength = 2000
+
+type reportSvc struct {
+	Logger *zap.SugaredLogger
+}
+
+type createUserReportReq struct {
+	UserId      int32  `json:"userId" binding:"required"`
+	TripId      *int32 `json:"tripId"`
+	Category    string `json:"category" binding:"required"`
+	Description string `json:"description"`
+}
+
+func (s *reportSvc) Create(c *gin.Context) {
+	var req createUserReportReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !isUserReportCategory(req.Category) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": svcerr.ErrUserReportCategoryInvalid.Error()})
+		return
+	}
+	if len(req.Description) > maxUserReportDescriptionLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "description is too long"})
+		return
+	}
+
+	reporterId, _ := c.Get("userId")
+	if req.UserId == reporterId.(int32) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": svcerr.ErrTargetIsSelf.Error()})
+		return
+	}
+
+	if req.TripId != nil && !s.checkReportTrip(c, *req.TripId, reporterId.(int32), req.UserId) {
+		return
+	}
+
+	report, err := db.CreateUserReport(&model.UserReport{
+		ReporterId:  reporterId.(int32),
+		ReportedId:  req.UserId,
+		TripId:      req.TripId,
+		Category:    req.Category,
+		Description: req.Description,
+	})
+	if err != nil {
+		if errors.Is(err, dberr.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionUserReportCreate,
+		EntityType: constants.AuditEntityUserReport,
+		EntityId:   report.Id,
+		After:      report,
+	})
+
+	// the report is filed either way, a failed threshold check only delays the suspension to the next report
+	if err := s.autoSuspend(c, report.ReportedId); err != nil {
+		s.Logger.Error(err)
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// checkReportTrip only lets a report point at a trip the reporter and the reported user shared.
+// On failure the error response is already written.
+func (s *reportSvc) checkReportTrip(c *gin.Context, tripId, reporterId, reportedId int32) bool {
+	trip, err := db.GetTrip(tripId)
+	if err != nil {
+		if errors.Is(err, dberr.ErrTripNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": svcerr.ErrUserReportTripInvalid.Error()})
+			return false
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if !isTripBetween(trip, reporterId, reportedId) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": svcerr.ErrUserReportTripInvalid.Error()})
+		return false
+	}
+	return true
+}
+
+// autoSuspend suspends a user once enough distinct reporters who shared a trip with them flagged them
+// within the configured window. Staff are never suspended automatically, their reports wait in the
+// moderation queue. The audit entry is attributed to the reporter whose report tipped it over.
+func (s *reportSvc) autoSuspend(c *gin.Context, userId int32) error {
+	reporters, err := db.CountRecentReporters(userId, time.Now().Add(-config.Env.ReportSuspendWindow))
+	if err != nil {
+		return err
+	}
+	if !reachesSuspendThreshold(reporters, config.Env.ReportSuspendThreshold) {
+		return nil
+	}
+
+	user, err := db.GetUser(userId)
+	if err != nil {
+		return err
+	}
+	if user.SuspendedAt != nil || isStaff(user.Roles) {
+		return nil
+	}
+	if err := db.SetUserSuspended(userId, true); err != nil {
+		return err
+	}
+	if err := db.RevokeUserSessions(userId); err != nil {
+		return err
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionUserAutoSuspend,
+		EntityType: constants.AuditEntityUser,
+		EntityId:   userId,
+		Detail: gin.H{
+			"reporters": reporters,
+			"threshold": config.Env.ReportSuspendThreshold,
+			"window":    config.Env.ReportSuspendWindow.String(),
+		},
+	})
+	return nil
+}
+
+// a threshold of zero or less turns automatic suspension off
+func reachesSuspendThreshold(reporters int32, threshold int) bool {
+	return threshold > 0 && int(reporters) >= threshold
+}
+
+func isTripBetween(trip *model.Trip, userId, otherUserId int32) bool {
+	return (trip.RiderId == userId && trip.DriverId == otherUserId) || (trip.DriverId == userId && trip.RiderId == otherUserId)
+}
+
+func isStaff(roles []string) bool {
+	for _, role := range roles {
+		if role == constants.RoleAdmin || role == constants.RoleSupport {
+			return true
+		}
+	}
+	return false
+}
+
+func isUserReportCategory(category string) bool {
+	for _, knownCategory := range constants.UserReportCategories {
+		if category == knownCategory {
+			return true
+		}
+	}
+	return false
+}
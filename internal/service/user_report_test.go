@@ -0,0 +1,38 @@
+package service
+
+import (
+	"github.com/CoRide-tw/backend/internal/constants"
+	"github.com/CoRide-tw/backend/internal/model"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ReachesSuspendThreshold", func() {
+	It("should suspend once the threshold is reached", func() {
+		Expect(reachesSuspendThreshold(2, 3)).To(BeFalse())
+		Expect(reachesSuspendThreshold(3, 3)).To(BeTrue())
+	})
+
+	It("should never suspend when turned off", func() {
+		Expect(reachesSuspendThreshold(100, 0)).To(BeFalse())
+	})
+})
+
+var _ = Describe("IsTripBetween", func() {
+	It("should accept the rider and driver of the trip in either order", func() {
+		trip := &model.Trip{RiderId: 1, DriverId: 2}
+
+		Expect(isTripBetween(trip, 1, 2)).To(BeTrue())
+		Expect(isTripBetween(trip, 2, 1)).To(BeTrue())
+		Expect(isTripBetween(trip, 1, 3)).To(BeFalse())
+		Expect(isTripBetween(trip, 3, 2)).To(BeFalse())
+	})
+})
+
+var _ = Describe("IsStaff", func() {
+	It("should only hold admins and support", func() {
+		Expect(isStaff([]string{constants.RoleRider, constants.RoleSupport})).To(BeTrue())
+		Expect(isStaff([]string{constants.RoleAdmin})).To(BeTrue())
+		Expect(isStaff([]string{constants.RoleRider, constants.RoleDriver})).To(BeFalse())
+	})
+})
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	"github.com/CoRide-tw/backend/internal/db"
+	"github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *userSvc) ListVehicles(c *gin.Context) {
+	userId, ok := selfUserId(s.Logger, c)
+	if !ok {
+		return
+	}
+
+	vehicles, err := db.ListVehiclesByUserId(userId)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, vehicles)
+}
+
+func (s *userSvc) CreateVehicle(c *gin.Context) {
+	userId, ok := selfUserId(s.Logger, c)
+	if !ok {
+		return
+	}
+
+	var vehicle model.Vehicle
+	if err := c.ShouldBindJSON(&vehicle); err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if vehicle.Plate == "" || vehicle.SeatCount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "plate and a positive seatCount are required"})
+		return
+	}
+	vehicle.UserId = userId
+
+	created, err := db.CreateVehicle(&vehicle)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionVehicleCreate,
+		EntityType: constants.AuditEntityVehicle,
+		EntityId:   created.Id,
+		After:      created,
+	})
+
+	c.JSON(http.StatusOK, created)
+}
+
+func (s *userSvc) UpdateVehicle(c *gin.Context) {
+	userId, ok := selfUserId(s.Logger, c)
+	if !ok {
+		return
+	}
+	before, ok := s.ownedVehicle(c, userId)
+	if !ok {
+		return
+	}
+
+	var vehicle model.Vehicle
+	if err := c.ShouldBindJSON(&vehicle); err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if vehicle.SeatCount < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "seatCount must be positive"})
+		return
+	}
+
+	updated, err := db.UpdateVehicle(before.Id, &vehicle)
+	if err != nil {
+		if errors.Is(err, dberr.ErrVehicleSeatsTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionVehicleUpdate,
+		EntityType: constants.AuditEntityVehicle,
+		EntityId:   updated.Id,
+		Before:     before,
+		After:      updated,
+	})
+
+	c.JSON(http.StatusOK, updated)
+}
+
+func (s *userSvc) DeleteVehicle(c *gin.Context) {
+	userId, ok := selfUserId(s.Logger, c)
+	if !ok {
+		return
+	}
+	vehicle, ok := s.ownedVehicle(c, userId)
+	if !ok {
+		return
+	}
+
+	if err := db.DeleteVehicle(vehicle.Id); err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionVehicleDelete,
+		EntityType: constants.AuditEntityVehicle,
+		EntityId:   vehicle.Id,
+		Before:     vehicle,
+	})
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// SetDefaultVehicle picks the vehicle new routes use when they do not name one
+func (s *userSvc) SetDefaultVehicle(c *gin.Context) {
+	userId, ok := selfUserId(s.Logger, c)
+	if !ok {
+		return
+	}
+	vehicle, ok := s.ownedVehicle(c, userId)
+	if !ok {
+		return
+	}
+
+	if err := db.SetDefaultVehicle(userId, vehicle.Id); err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(s.Logger, c, auditEntry{
+		Action:     constants.AuditActionVehicleSetDefault,
+		EntityType: constants.AuditEntityVehicle,
+		EntityId:   vehicle.Id,
+	})
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// ownedVehicle loads the :vehicleId path param owned by userId, otherwise the error response is already written
+func (s *userSvc) ownedVehicle(c *gin.Context, userId int32) (*model.Vehicle, bool) {
+	vehicleId, err := strconv.Atoi(c.Param("vehicleId"))
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "vehicleId must be integer"})
+		return nil, false
+	}
+
+	vehicle, err := db.GetVehicle(int32(vehicleId))
+	if err != nil {
+		if errors.Is(err, dberr.ErrVehicleNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	// other users' vehicles look the same as missing ones
+	if vehicle.UserId != userId {
+		c.JSON(http.StatusNotFound, gin.H{"error": dberr.ErrVehicleNotFound.Error()})
+		return nil, false
+	}
+	return vehicle, true
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/CoRide-tw/backend/internal/constants"
+	"github.com/CoRide-tw/backend/internal/db"
+	"github.com/CoRide-tw/backend/internal/errors/generated/dberr"
+	"github.com/CoRide-tw/backend/internal/model"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ListWaitlist shows the route's driver who is waiting for a seat, in the order they will be promoted
+func (s *routeSvc) ListWaitlist(c *gin.Context) {
+	routeId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id must be integer"})
+		return
+	}
+
+	route, err := db.GetRoute(int32(routeId))
+	if err != nil {
+		if errors.Is(err, dberr.ErrRouteNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if authUid, _ := c.Get("userId"); authUid != route.DriverId {
+		c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+		return
+	}
+
+	waitlist, err := db.ListWaitlist(route.Id)
+	if err != nil {
+		s.Logger.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, waitlist)
+}
+
+// promoteWaitlist fills seats freed on a route from its waitlist and tells everyone involved.
+// The seats were already freed by the caller, so a failure here is logged and the next freed seat retries it.
+func promoteWaitlist(logger *zap.SugaredLogger, n *notifier, routeId int32) {
+	promoted, err := db.PromoteWaitlist(routeId)
+	if err != nil {
+		logger.Errorw("failed to promote waitlist", "routeId", routeId, "error", err)
+		return
+	}
+	if len(promoted) == 0 {
+		return
+	}
+
+	route, err := db.GetRoute(routeId)
+	if err != nil {
+		logger.Errorw("failed to load route for waitlist notifications", "routeId", routeId, "error", err)
+		return
+	}
+	for _, request := range promoted {
+		data := gin.H{"requestId": request.Id, "routeId": request.RouteId, "status": request.Status}
+		if request.Status == constants.RequestStatusAccepted {
+			n.Notify(request.RiderId, constants.NotificationKindRequestAccepted,
+				"Your ride is confirmed",
+				"A seat freed up and the driver accepts waitlisted riders automatically, your ride is booked.",
+				data,
+			)
+			n.Notify(route.DriverId, constants.NotificationKindWaitlistPromoted,
+				"A waitlisted rider joined your route",
+				"A rider from the waitlist took the seats that freed up.",
+				data,
+			)
+			continue
+		}
+
+		n.Notify(request.RiderId, constants.NotificationKindWaitlistPromoted,
+			"You are off the waitlist",
+			"A seat freed up and your request is now waiting for the driver to accept it.",
+			data,
+		)
+		notifyRequestReceived(n, route.DriverId, request)
+	}
+}
+
+func notifyRequestReceived(n *notifier, driverId int32, request *model.Request) {
+	n.Notify(driverId, constants.NotificationKindRequestReceived,
+		"New ride request",
+		"A rider asked to join your route.",
+		gin.H{"requestId": request.Id, "routeId": request.RouteId, "seatCount": request.SeatCount},
+	)
+}
+
+// holdsSeats tells whether giving up the request frees seats on its route
+func holdsSeats(status string) bool {
+	for _, holding := range constants.SeatHoldingRequestStatuses {
+		if status == holding {
+			return true
+		}
+	}
+	return false
+}
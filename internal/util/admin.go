@@ -0,0 +1,110 @@
+package util
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/CoRide-tw/backend/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultAdminListLimit = 50
+	maxAdminListLimit     = 200
+)
+
+type ParsedAdminListQuery struct {
+	Search   string
+	Status   string
+	UserId   int32
+	DriverId int32
+	RiderId  int32
+	RouteId  int32
+	Limit    int32
+	Offset   int32
+}
+
+func ParseAdminListQuery(c *gin.Context) (*ParsedAdminListQuery, error) {
+	parsedQuery := ParsedAdminListQuery{
+		Search: c.Query("q"),
+		Status: c.Query("status"),
+		Limit:  defaultAdminListLimit,
+	}
+
+	for key, target := range map[string]*int32{
+		"userId":   &parsedQuery.UserId,
+		"driverId": &parsedQuery.DriverId,
+		"riderId":  &parsedQuery.RiderId,
+		"routeId":  &parsedQuery.RouteId,
+		"limit":    &parsedQuery.Limit,
+		"offset":   &parsedQuery.Offset,
+	} {
+		stringValue, exist := c.GetQuery(key)
+		if !exist {
+			continue
+		}
+		value, err := strconv.ParseInt(stringValue, 10, 32)
+		if err != nil || value < 0 {
+			return nil, errors.New("invalid " + key)
+		}
+		*target = int32(value)
+	}
+
+	if parsedQuery.Limit == 0 {
+		parsedQuery.Limit = defaultAdminListLimit
+	}
+	if parsedQuery.Limit > maxAdminListLimit {
+		parsedQuery.Limit = maxAdminListLimit
+	}
+	return &parsedQuery, nil
+}
+
+func ParseAuditLogQuery(c *gin.Context) (*model.AuditLogFilter, error) {
+	filter := model.AuditLogFilter{
+		Action:     c.Query("action"),
+		EntityType: c.Query("entityType"),
+		RequestId:  c.Query("requestId"),
+		Limit:      defaultAdminListLimit,
+	}
+
+	for key, target := range map[string]*int32{
+		"actorId":  &filter.ActorId,
+		"entityId": &filter.EntityId,
+		"limit":    &filter.Limit,
+		"offset":   &filter.Offset,
+	} {
+		stringValue, exist := c.GetQuery(key)
+		if !exist {
+			continue
+		}
+		value, err := strconv.ParseInt(stringValue, 10, 32)
+		if err != nil || value < 0 {
+			return nil, errors.New("invalid " + key)
+		}
+		*target = int32(value)
+	}
+
+	for key, target := range map[string]**time.Time{
+		"since": &filter.Since,
+		"until": &filter.Until,
+	} {
+		stringValue, exist := c.GetQuery(key)
+		if !exist {
+			continue
+		}
+		value, err := time.Parse(time.RFC3339, stringValue)
+		if err != nil {
+			return nil, errors.New("invalid " + key + ", expected RFC3339")
+		}
+		*target = &value
+	}
+
+	if filter.Limit == 0 {
+		filter.Limit = defaultAdminListLimit
+	}
+	if filter.Limit > maxAdminListLimit {
+		filter.Limit = maxAdminListLimit
+	}
+	return &filter, nil
+}
@@ -0,0 +1,45 @@
+package util
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	hoursPerWeek           = 7 * 24
+	defaultDemandCellLimit = 200
+	maxDemandCellLimit     = 1000
+)
+
+type ParsedDemandQuery struct {
+	HourOfWeek *int32
+	Limit      int32
+}
+
+// ParseDemandQuery reads the optional hour of the week, counted from Monday 00:00, and the cell limit
+func ParseDemandQuery(c *gin.Context) (*ParsedDemandQuery, error) {
+	parsedQuery := ParsedDemandQuery{Limit: defaultDemandCellLimit}
+
+	if stringHour, exist := c.GetQuery("hourOfWeek"); exist {
+		hour, err := strconv.ParseInt(stringHour, 10, 32)
+		if err != nil || hour < 0 || hour >= hoursPerWeek {
+			return nil, errors.New("hourOfWeek must be between 0 and 167")
+		}
+		hourOfWeek := int32(hour)
+		parsedQuery.HourOfWeek = &hourOfWeek
+	}
+
+	if stringLimit, exist := c.GetQuery("limit"); exist {
+		limit, err := strconv.ParseInt(stringLimit, 10, 32)
+		if err != nil || limit <= 0 {
+			return nil, errors.New("invalid limit")
+		}
+		parsedQuery.Limit = int32(limit)
+	}
+	if parsedQuery.Limit > maxDemandCellLimit {
+		parsedQuery.Limit = maxDemandCellLimit
+	}
+	return &parsedQuery, nil
+}
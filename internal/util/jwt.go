@@ -3,23 +3,24 @@ package util
 import (
 	"time"
 
+	"github.com/CoRide-tw/backend/internal/config"
 	"github.com/CoRide-tw/backend/internal/model"
 	"github.com/golang-jwt/jwt"
 )
 
-func GenerateJWT(id int32, secret string) (*string, error) {
+func GenerateJWT(id int32, sessionId int32, roles []string, keys *config.JwtKeySet, ttl time.Duration) (*string, error) {
 	now := time.Now()
 	claims := model.Claims{
-		ID: id,
+		ID:        id,
+		SessionId: sessionId,
+		Roles:     roles,
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: 0,
+			ExpiresAt: now.Add(ttl).Unix(),
 			IssuedAt:  now.Unix(),
 			Issuer:    "CoRide",
 		},
 	}
-	tokenClaims := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	token, err := tokenClaims.SignedString([]byte(secret))
+	token, err := keys.Sign(claims)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,29 @@
+package util
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ParsedMeetingPointQuery struct {
+	PickupLong float64
+	PickupLat  float64
+}
+
+// ParseMeetingPointQuery requires the pickup, requests only move their pickup to a meeting point
+func ParseMeetingPointQuery(c *gin.Context) (*ParsedMeetingPointQuery, error) {
+	var parsedQuery ParsedMeetingPointQuery
+	var err error
+
+	parsedQuery.PickupLong, err = strconv.ParseFloat(c.Query("pickupLong"), 64)
+	if err != nil {
+		return nil, err
+	}
+	parsedQuery.PickupLat, err = strconv.ParseFloat(c.Query("pickupLat"), 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &parsedQuery, nil
+}
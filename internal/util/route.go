@@ -1,6 +1,7 @@
 package util
 
 import (
+	"errors"
 	"log"
 	"strconv"
 	"time"
@@ -15,6 +16,14 @@ type ParsedListNearestRoutesQuery struct {
 	EndLat          float64
 	PickupStartTime time.Time
 	PickupEndTime   time.Time
+	VerifiedOnly    bool
+	Seats           int32
+	// ride requirements left unset fall back to the rider's saved preferences
+	WomenOnly *bool
+	Smoking   *bool
+	Pets      *bool
+	Quiet     *bool
+	Luggage   string
 }
 
 func ParseListNearestRoutesQuery(c *gin.Context) (*ParsedListNearestRoutesQuery, error) {
@@ -46,5 +55,37 @@ func ParseListNearestRoutesQuery(c *gin.Context) (*ParsedListNearestRoutesQuery,
 	if err != nil {
 		return nil, err
 	}
+	if stringVerifiedOnly, exist := c.GetQuery("verifiedOnly"); exist {
+		parsedQuery.VerifiedOnly, err = strconv.ParseBool(stringVerifiedOnly)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for key, target := range map[string]**bool{
+		"womenOnly": &parsedQuery.WomenOnly,
+		"smoking":   &parsedQuery.Smoking,
+		"pets":      &parsedQuery.Pets,
+		"quiet":     &parsedQuery.Quiet,
+	} {
+		stringValue, exist := c.GetQuery(key)
+		if !exist {
+			continue
+		}
+		value, err := strconv.ParseBool(stringValue)
+		if err != nil {
+			return nil, err
+		}
+		*target = &value
+	}
+	parsedQuery.Luggage = c.Query("luggage")
+
+	parsedQuery.Seats = 1
+	if stringSeats, exist := c.GetQuery("seats"); exist {
+		seats, err := strconv.ParseInt(stringSeats, 10, 32)
+		if err != nil || seats < 1 {
+			return nil, errors.New("invalid seats")
+		}
+		parsedQuery.Seats = int32(seats)
+	}
 	return &parsedQuery, nil
 }